@@ -208,6 +208,45 @@ func (c *Client) ReplayEvent(id string) (*eventline.Event, error) {
 	return &event, nil
 }
 
+func (c *Client) ReplayRawEvents(connector string, replay *eventline.RawEventReplay) (*eventline.RawEventReplayResult, error) {
+	var result eventline.RawEventReplayResult
+
+	uri := NewURL("connectors", "name", connector, "raw_events", "replay")
+
+	err := c.SendRequest("POST", uri, replay, &result)
+	if err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (c *Client) ExportSubscriptions() (eventline.SubscriptionExports, error) {
+	var exports eventline.SubscriptionExports
+
+	uri := NewURL("subscriptions", "export")
+
+	err := c.SendRequest("GET", uri, nil, &exports)
+	if err != nil {
+		return nil, err
+	}
+
+	return exports, nil
+}
+
+func (c *Client) ImportSubscriptions(exports eventline.SubscriptionExports) (eventline.SubscriptionImportResults, error) {
+	var results eventline.SubscriptionImportResults
+
+	uri := NewURL("subscriptions", "import")
+
+	err := c.SendRequest("POST", uri, exports, &results)
+	if err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
 func (c *Client) FetchJobByName(name string) (*eventline.Job, error) {
 	uri := NewURL("jobs", "name", name)
 
@@ -419,3 +458,36 @@ func (c *Client) DeleteIdentity(id string) error {
 
 	return c.SendRequest("DELETE", uri, nil, nil)
 }
+
+func (c *Client) RotateIdentityKey(id eventline.Id, rotation *eventline.IdentityKeyRotation) (*eventline.Identity, error) {
+	uri := NewURL("identities", "id", id.String(), "rotate_key")
+
+	var identity eventline.Identity
+	if err := c.SendRequest("POST", uri, rotation, &identity); err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (c *Client) RetireIdentityKey(id eventline.Id, retirement *eventline.IdentityKeyRetirement) (*eventline.Identity, error) {
+	uri := NewURL("identities", "id", id.String(), "retire_key")
+
+	var identity eventline.Identity
+	if err := c.SendRequest("POST", uri, retirement, &identity); err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (c *Client) TestIdentity(id eventline.Id) (*eventline.IdentityTestResult, error) {
+	uri := NewURL("identities", "id", id.String(), "test")
+
+	var result eventline.IdentityTestResult
+	if err := c.SendRequest("POST", uri, nil, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
@@ -3,6 +3,7 @@ package main
 import (
 	"fmt"
 
+	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/program"
 )
 
@@ -14,6 +15,14 @@ func addEventCommands() {
 		cmdReplayEvent)
 
 	c.AddArgument("event-id", "the identifier of the event")
+
+	// replay-raw-events
+	c = p.AddCommand("replay-raw-events",
+		"create events of the given types from stored raw events",
+		cmdReplayRawEvents)
+
+	c.AddArgument("connector", "the name of the connector")
+	c.AddTrailingArgument("event", "the name of an event to create")
 }
 
 func cmdReplayEvent(p *program.Program) {
@@ -30,3 +39,23 @@ func cmdReplayEvent(p *program.Program) {
 
 	fmt.Printf("%s\n", event.Id)
 }
+
+func cmdReplayRawEvents(p *program.Program) {
+	connector := p.ArgumentValue("connector")
+	enames := p.TrailingArgumentValues("event")
+
+	if len(enames) == 0 {
+		p.Fatal("missing event name(s)")
+	}
+
+	replay := eventline.RawEventReplay{
+		Events: enames,
+	}
+
+	result, err := app.Client.ReplayRawEvents(connector, &replay)
+	if err != nil {
+		p.Fatal("cannot replay raw events: %v", err)
+	}
+
+	p.Info("%d events created", result.NbEvents)
+}
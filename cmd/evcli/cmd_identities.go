@@ -43,6 +43,29 @@ func addIdentityCommands() {
 		cmdDeleteIdentity)
 
 	c.AddArgument("name", "the name of the identity")
+
+	// test-identity
+	c = p.AddCommand("test-identity", "check that an identity is valid",
+		cmdTestIdentity)
+
+	c.AddArgument("name", "the name of the identity")
+
+	// rotate-identity-key
+	c = p.AddCommand("rotate-identity-key",
+		"make a new key the primary key of an identity",
+		cmdRotateIdentityKey)
+
+	c.AddArgument("name", "the name of the identity")
+	c.AddArgument("key", "the new key")
+
+	// retire-identity-key
+	c = p.AddCommand("retire-identity-key",
+		"remove the primary or secondary key of an identity",
+		cmdRetireIdentityKey)
+
+	c.AddArgument("name", "the name of the identity")
+	c.AddArgument("key", "the key to remove, either \"primary\" or "+
+		"\"secondary\"")
 }
 
 func cmdListIdentities(p *program.Program) {
@@ -160,6 +183,85 @@ func cmdDeleteIdentity(p *program.Program) {
 	p.Info("identity %q deleted", identity.Id)
 }
 
+func cmdTestIdentity(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	name := p.ArgumentValue("name")
+
+	identity, err := app.Client.FetchIdentityByName(name)
+	if err != nil {
+		p.Fatal("cannot fetch identity: %v", err)
+	}
+
+	result, err := app.Client.TestIdentity(identity.Id)
+	if err != nil {
+		p.Fatal("cannot test identity: %v", err)
+	}
+
+	if !result.OK {
+		p.Fatal("identity %q is invalid (%dms): %s", name, result.Latency,
+			result.Message)
+	}
+
+	p.Info("identity %q is valid (%dms)", name, result.Latency)
+
+	if result.Account != "" {
+		p.Info("account: %s", result.Account)
+	}
+
+	if len(result.Scopes) > 0 {
+		p.Info("scopes: %s", strings.Join(result.Scopes, ", "))
+	}
+
+	if result.Message != "" {
+		p.Info("%s", result.Message)
+	}
+}
+
+func cmdRotateIdentityKey(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	name := p.ArgumentValue("name")
+	key := p.ArgumentValue("key")
+
+	identity, err := app.Client.FetchIdentityByName(name)
+	if err != nil {
+		p.Fatal("cannot fetch identity: %v", err)
+	}
+
+	rotation := eventline.IdentityKeyRotation{
+		Key: key,
+	}
+
+	if _, err := app.Client.RotateIdentityKey(identity.Id, &rotation); err != nil {
+		p.Fatal("cannot rotate identity key: %v", err)
+	}
+
+	p.Info("key of identity %q rotated", name)
+}
+
+func cmdRetireIdentityKey(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	name := p.ArgumentValue("name")
+	key := p.ArgumentValue("key")
+
+	identity, err := app.Client.FetchIdentityByName(name)
+	if err != nil {
+		p.Fatal("cannot fetch identity: %v", err)
+	}
+
+	retirement := eventline.IdentityKeyRetirement{
+		Key: key,
+	}
+
+	if _, err := app.Client.RetireIdentityKey(identity.Id, &retirement); err != nil {
+		p.Fatal("cannot retire identity key: %v", err)
+	}
+
+	p.Info("%s key of identity %q retired", key, name)
+}
+
 func ParseIdentityFields(ss []string) (map[string]interface{}, error) {
 	// With current connectors, all non-oauth2 identities only use string
 	// fields. If this changes, we will need a way to access identity
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"os"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/exograd/eventline/pkg/utils"
+	"go.n16f.net/program"
+)
+
+func addSubscriptionCommands() {
+	var c *program.Command
+
+	// export-subscriptions
+	c = p.AddCommand("export-subscriptions",
+		"export the subscriptions of all jobs", cmdExportSubscriptions)
+
+	c.AddOption("o", "output", "path", "",
+		"the file to write to instead of the standard output")
+
+	// import-subscriptions
+	c = p.AddCommand("import-subscriptions",
+		"create or update subscriptions from an export file",
+		cmdImportSubscriptions)
+
+	c.AddArgument("path", "the path of the export file")
+}
+
+func cmdExportSubscriptions(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	outputPath := p.OptionValue("output")
+
+	exports, err := app.Client.ExportSubscriptions()
+	if err != nil {
+		p.Fatal("cannot export subscriptions: %v", err)
+	}
+
+	data, err := utils.YAMLEncode(exports)
+	if err != nil {
+		p.Fatal("cannot encode subscriptions: %v", err)
+	}
+
+	if outputPath == "" {
+		os.Stdout.Write(data)
+		return
+	}
+
+	if err := os.WriteFile(outputPath, data, 0600); err != nil {
+		p.Fatal("cannot write %q: %v", outputPath, err)
+	}
+
+	p.Info("%d subscriptions exported to %q", len(exports), outputPath)
+}
+
+func cmdImportSubscriptions(p *program.Program) {
+	app.IdentifyCurrentProject()
+
+	filePath := p.ArgumentValue("path")
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		p.Fatal("cannot read %q: %v", filePath, err)
+	}
+
+	var exports eventline.SubscriptionExports
+	if err := exports.ParseYAML(data); err != nil {
+		p.Fatal("cannot decode %q: %v", filePath, err)
+	}
+
+	results, err := app.Client.ImportSubscriptions(exports)
+	if err != nil {
+		p.Fatal("cannot import subscriptions: %v", err)
+	}
+
+	nbFailures := 0
+
+	for _, result := range results {
+		if result.Status == eventline.SubscriptionImportStatusFailed {
+			nbFailures++
+
+			p.Error("job %q: %s", result.Job, result.Error)
+			for _, verr := range result.ValidationErrors {
+				p.Error("job %q: %v", result.Job, verr)
+			}
+
+			continue
+		}
+
+		p.Info("job %q: %s", result.Job, result.Status)
+	}
+
+	if nbFailures > 0 {
+		p.Fatal("%d/%d subscriptions could not be imported",
+			nbFailures, len(results))
+	}
+}
@@ -33,6 +33,7 @@ func main() {
 	addProjectCommands()
 	addEventCommands()
 	addJobCommands()
+	addSubscriptionCommands()
 	addJobExecutionCommands()
 	addIdentityCommands()
 
@@ -0,0 +1,5 @@
+ALTER TABLE c_github_subscriptions
+  ADD COLUMN webhook_token_hash BYTEA;
+
+CREATE UNIQUE INDEX c_github_subscriptions_webhook_token_hash_idx
+  ON c_github_subscriptions (webhook_token_hash);
@@ -0,0 +1,18 @@
+ALTER TABLE subscriptions
+  ADD COLUMN match_attributes JSONB;
+
+CREATE INDEX subscriptions_connector_event_match_attributes_idx
+  ON subscriptions (connector, event, match_attributes);
+
+UPDATE subscriptions
+  SET match_attributes =
+        jsonb_build_object('organization',
+                           parameters->>'organization',
+                           'repository',
+                           COALESCE(parameters->>'repository', ''))
+  WHERE connector = 'github';
+
+UPDATE subscriptions
+  SET match_attributes = match_attributes || '{"dedicated_hook": "true"}'
+  WHERE connector = 'github'
+    AND (parameters->>'dedicated_hook')::BOOLEAN;
@@ -0,0 +1,9 @@
+CREATE TABLE job_schedules
+  (job_id KSUID PRIMARY KEY REFERENCES jobs (id) ON DELETE CASCADE,
+   project_id KSUID NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+   spec JSONB NOT NULL,
+   last_time TIMESTAMP,
+   next_time TIMESTAMP NOT NULL);
+
+CREATE INDEX job_schedules_next_time_idx
+  ON job_schedules (next_time);
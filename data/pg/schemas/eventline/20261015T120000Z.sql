@@ -0,0 +1,9 @@
+CREATE TABLE c_email_subscriptions (
+    id KSUID PRIMARY KEY REFERENCES subscriptions (id),
+    uid_validity INT8 NOT NULL,
+    last_uid INT8 NOT NULL,
+    next_poll TIMESTAMP NOT NULL,
+    nb_failures INT NOT NULL DEFAULT 0);
+
+CREATE INDEX c_email_subscriptions_next_poll_idx
+  ON c_email_subscriptions (next_poll);
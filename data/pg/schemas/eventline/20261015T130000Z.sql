@@ -0,0 +1,9 @@
+CREATE TABLE c_cloudevents_receptions (
+    source VARCHAR NOT NULL,
+    id VARCHAR NOT NULL,
+    reception_time TIMESTAMP NOT NULL,
+
+    PRIMARY KEY (source, id));
+
+CREATE INDEX c_cloudevents_receptions_reception_time_idx
+  ON c_cloudevents_receptions (reception_time);
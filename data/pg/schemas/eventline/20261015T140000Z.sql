@@ -0,0 +1,12 @@
+CREATE TABLE project_webhook_keys
+  (id KSUID PRIMARY KEY,
+   project_id KSUID NOT NULL REFERENCES projects (id) ON DELETE CASCADE,
+   creation_time TIMESTAMP NOT NULL,
+   key BYTEA NOT NULL);
+
+CREATE INDEX project_webhook_keys_project_id_creation_time_idx
+  ON project_webhook_keys (project_id, creation_time);
+
+ALTER TABLE c_github_subscriptions
+  ADD COLUMN webhook_key_id KSUID
+    REFERENCES project_webhook_keys (id) ON DELETE SET NULL;
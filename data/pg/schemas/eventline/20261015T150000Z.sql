@@ -0,0 +1,8 @@
+DROP INDEX subscriptions_job_id_idx;
+
+-- Subscriptions being terminated are detached from their job. Each job has
+-- therefore at most one subscription able to create events for it, so that
+-- a single delivery never triggers the same job twice.
+CREATE UNIQUE INDEX subscriptions_job_id_idx
+  ON subscriptions (job_id)
+  WHERE job_id IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE step_executions
+  ADD COLUMN exit_code INTEGER,
+  ADD COLUMN signal INTEGER;
@@ -0,0 +1 @@
+ALTER TABLE identities ADD COLUMN disabled BOOLEAN NOT NULL DEFAULT FALSE;
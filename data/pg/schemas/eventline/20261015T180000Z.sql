@@ -0,0 +1 @@
+ALTER TABLE events ADD COLUMN drop_reason VARCHAR NOT NULL DEFAULT '';
@@ -0,0 +1,6 @@
+CREATE TABLE connector_quarantines
+  (connector VARCHAR PRIMARY KEY,
+   start_time TIMESTAMP NOT NULL,
+   reason VARCHAR NOT NULL,
+   nb_deliveries INT8 NOT NULL DEFAULT 0,
+   last_delivery_time TIMESTAMP);
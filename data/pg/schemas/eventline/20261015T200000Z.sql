@@ -0,0 +1,6 @@
+CREATE TABLE c_github_deliveries (
+    id VARCHAR PRIMARY KEY,
+    reception_time TIMESTAMP NOT NULL);
+
+CREATE INDEX c_github_deliveries_reception_time_idx
+  ON c_github_deliveries (reception_time);
@@ -0,0 +1,11 @@
+UPDATE subscriptions
+  SET match_attributes =
+        match_attributes
+        || jsonb_build_object('organization',
+                              lower(match_attributes->>'organization'),
+                              'repository',
+                              lower(match_attributes->>'repository'))
+  WHERE connector = 'github';
+
+CREATE INDEX c_github_subscriptions_lower_organization_repository_idx
+  ON c_github_subscriptions (lower(organization), lower(repository));
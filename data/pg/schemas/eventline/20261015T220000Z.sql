@@ -0,0 +1,3 @@
+CREATE TABLE project_scheduling_times
+  (id KSUID PRIMARY KEY REFERENCES projects (id) ON DELETE CASCADE,
+   last_schedule_time TIMESTAMP NOT NULL);
@@ -0,0 +1,7 @@
+CREATE TABLE job_execution_start_failures
+  (id KSUID PRIMARY KEY REFERENCES job_executions (id) ON DELETE CASCADE,
+   nb_failures INTEGER NOT NULL,
+   next_attempt_time TIMESTAMP NOT NULL);
+
+CREATE INDEX job_execution_start_failures_next_attempt_time_idx
+  ON job_execution_start_failures (next_attempt_time);
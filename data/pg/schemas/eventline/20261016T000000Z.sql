@@ -0,0 +1,4 @@
+CREATE TABLE c_dockerhub_subscriptions
+  (id KSUID PRIMARY KEY REFERENCES subscriptions (id),
+   namespace VARCHAR NOT NULL,
+   repository VARCHAR NOT NULL);
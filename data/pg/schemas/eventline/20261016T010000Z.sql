@@ -0,0 +1,7 @@
+ALTER TABLE c_dockerhub_subscriptions
+  ADD COLUMN next_poll_time TIMESTAMP,
+  ADD COLUMN nb_poll_failures INTEGER DEFAULT 0 NOT NULL,
+  ADD COLUMN known_tags VARCHAR[];
+
+CREATE INDEX c_dockerhub_subscriptions_next_poll_time_idx
+  ON c_dockerhub_subscriptions (next_poll_time);
@@ -0,0 +1,8 @@
+CREATE TABLE event_deduplication_keys
+  (job_id KSUID NOT NULL REFERENCES jobs (id) ON DELETE CASCADE,
+   key VARCHAR NOT NULL,
+   expiration_time TIMESTAMP NOT NULL,
+   PRIMARY KEY (job_id, key));
+
+CREATE INDEX event_deduplication_keys_expiration_time_idx
+  ON event_deduplication_keys (expiration_time);
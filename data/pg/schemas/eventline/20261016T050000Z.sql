@@ -0,0 +1,5 @@
+ALTER TABLE project_settings
+  ADD COLUMN event_retention INTEGER NOT NULL DEFAULT 0;
+
+CREATE INDEX events_creation_time_idx
+  ON events (creation_time);
@@ -0,0 +1,2 @@
+ALTER TABLE c_email_subscriptions
+  ADD COLUMN poll_interval INT NOT NULL DEFAULT 0;
@@ -0,0 +1,7 @@
+ALTER TABLE subscriptions
+  ADD COLUMN last_error VARCHAR,
+  ADD COLUMN last_error_time TIMESTAMP,
+  ADD COLUMN nb_errors INT NOT NULL DEFAULT 0;
+
+CREATE INDEX subscriptions_nb_errors_idx
+  ON subscriptions (nb_errors) WHERE nb_errors > 0;
@@ -0,0 +1,6 @@
+ALTER TABLE project_notification_settings
+  ADD COLUMN webhook_uri VARCHAR NOT NULL DEFAULT '',
+  ADD COLUMN webhook_secret BYTEA;
+
+ALTER TABLE notifications
+  ADD COLUMN webhook_uri VARCHAR NOT NULL DEFAULT '';
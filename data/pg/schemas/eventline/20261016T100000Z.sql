@@ -0,0 +1,17 @@
+CREATE TABLE c_github_queued_deliveries
+  (id KSUID PRIMARY KEY,
+   ordering_key VARCHAR NOT NULL,
+   reception_time TIMESTAMP NOT NULL,
+   target VARCHAR,
+   subscription_id KSUID,
+   raw_event JSONB NOT NULL,
+   payload BYTEA NOT NULL,
+   nb_attempts INTEGER NOT NULL DEFAULT 0,
+   next_attempt_time TIMESTAMP NOT NULL,
+   last_error VARCHAR);
+
+CREATE INDEX c_github_queued_deliveries_ordering_key_idx
+  ON c_github_queued_deliveries (ordering_key, reception_time);
+
+CREATE INDEX c_github_queued_deliveries_next_attempt_time_idx
+  ON c_github_queued_deliveries (next_attempt_time);
@@ -0,0 +1,3 @@
+ALTER TABLE identities ADD COLUMN secret_ref VARCHAR;
+
+ALTER TABLE identities ALTER COLUMN data DROP NOT NULL;
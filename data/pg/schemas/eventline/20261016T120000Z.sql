@@ -0,0 +1,3 @@
+CREATE TABLE deleted_identity_secrets
+  (secret_ref VARCHAR PRIMARY KEY,
+   deletion_time TIMESTAMP NOT NULL);
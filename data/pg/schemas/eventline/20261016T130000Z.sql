@@ -0,0 +1,3 @@
+ALTER TABLE project_notification_settings
+  ADD COLUMN previous_webhook_secret BYTEA,
+  ADD COLUMN previous_webhook_secret_expiration_time TIMESTAMP;
@@ -0,0 +1,19 @@
+UPDATE subscriptions
+  SET match_attributes =
+        jsonb_build_object('namespace', parameters->>'namespace',
+                           'repository', parameters->>'repository')
+  WHERE connector = 'dockerhub';
+
+UPDATE subscriptions
+  SET match_attributes =
+        jsonb_build_object('project', lower(parameters->>'project'))
+  WHERE connector = 'gitlab';
+
+UPDATE subscriptions
+  SET match_attributes =
+        jsonb_build_object('channel', COALESCE(parameters->>'channel', ''))
+  WHERE connector = 'slack';
+
+UPDATE subscriptions
+  SET match_attributes = jsonb_build_object('name', parameters->>'name')
+  WHERE connector = 'webhook';
@@ -0,0 +1,7 @@
+-- Subscriptions matching incoming events are always loaded with a job, so
+-- subscriptions being terminated do not have to be indexed.
+DROP INDEX subscriptions_connector_event_match_attributes_idx;
+
+CREATE INDEX subscriptions_connector_event_match_attributes_idx
+  ON subscriptions (connector, event, match_attributes)
+  WHERE job_id IS NOT NULL;
@@ -0,0 +1,4 @@
+-- Used to detect GitHub push events delivered out of order
+CREATE INDEX events_github_push_old_revision_idx
+  ON events (job_id, (data->>'old_revision'))
+  WHERE connector = 'github' AND name = 'push';
@@ -23,6 +23,7 @@ require (
 	go.n16f.net/service v0.0.0-20240722110736-50b450094c5c
 	go.n16f.net/uuid v0.0.0-20240707135755-e4fd26b968ad
 	golang.org/x/crypto v0.26.0
+	golang.org/x/text v0.17.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -63,7 +64,6 @@ require (
 	golang.org/x/sync v0.8.0 // indirect
 	golang.org/x/sys v0.24.0 // indirect
 	golang.org/x/term v0.23.0 // indirect
-	golang.org/x/text v0.17.0 // indirect
 	golang.org/x/time v0.0.0-20220609170525-579cf78fd858 // indirect
 	gopkg.in/alexcesaro/quotedprintable.v3 v3.0.0-20150716171945-2caba252f4dc // indirect
 	gotest.tools/v3 v3.3.0 // indirect
@@ -0,0 +1,29 @@
+package cloudevents
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type ConnectorCfg struct {
+	Enabled             bool   `json:"enabled"`
+	AccessToken         string `json:"access_token,omitempty"`
+	DeduplicationPeriod int    `json:"deduplication_period,omitempty"` // seconds
+	MaxRequestSize      int    `json:"max_request_size,omitempty"`     // bytes
+}
+
+func (c *Connector) DefaultCfg() eventline.ConnectorCfg {
+	return &ConnectorCfg{
+		DeduplicationPeriod: 86400,
+		MaxRequestSize:      1024 * 1024,
+	}
+}
+
+func (cfg *ConnectorCfg) ValidateJSON(v *ejson.Validator) {
+	if cfg.Enabled {
+		v.CheckStringNotEmpty("access_token", cfg.AccessToken)
+	}
+
+	v.CheckIntMin("deduplication_period", cfg.DeduplicationPeriod, 1)
+	v.CheckIntMin("max_request_size", cfg.MaxRequestSize, 1)
+}
@@ -0,0 +1,321 @@
+package cloudevents
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// The connector supports the three content modes of the HTTP protocol binding
+// of the CloudEvents specification: structured mode where the event is
+// encoded in the request body, batched mode where the request body is an array
+// of structured events, and binary mode where context attributes are
+// transported in header fields and data in the request body.
+
+const (
+	SpecVersion = "1.0"
+
+	StructuredMediaType = "application/cloudevents+json"
+	BatchMediaType      = "application/cloudevents-batch+json"
+
+	binaryHeaderPrefix = "Ce-"
+)
+
+type InvalidEventError struct {
+	Msg string
+}
+
+func NewInvalidEventError(format string, args ...interface{}) *InvalidEventError {
+	return &InvalidEventError{Msg: fmt.Sprintf(format, args...)}
+}
+
+func (err *InvalidEventError) Error() string {
+	return fmt.Sprintf("invalid cloudevent: %s", err.Msg)
+}
+
+func ParseRequestEvents(header http.Header, body []byte) ([]*Event, error) {
+	var mediaType string
+
+	if contentType := header.Get("Content-Type"); contentType != "" {
+		var err error
+
+		mediaType, _, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, NewInvalidEventError("invalid content type %q: %v",
+				contentType, err)
+		}
+	}
+
+	switch mediaType {
+	case StructuredMediaType:
+		event, err := ParseStructuredEvent(body)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Event{event}, nil
+
+	case BatchMediaType:
+		var values []json.RawMessage
+		if err := json.Unmarshal(body, &values); err != nil {
+			return nil, NewInvalidEventError("invalid batch: %v", err)
+		}
+
+		events := make([]*Event, len(values))
+		for i, value := range values {
+			event, err := ParseStructuredEvent(value)
+			if err != nil {
+				return nil, fmt.Errorf("event %d: %w", i, err)
+			}
+
+			events[i] = event
+		}
+
+		return events, nil
+
+	default:
+		event, err := ParseBinaryEvent(header, body)
+		if err != nil {
+			return nil, err
+		}
+
+		return []*Event{event}, nil
+	}
+}
+
+func ParseStructuredEvent(data []byte) (*Event, error) {
+	var attrs map[string]json.RawMessage
+	if err := json.Unmarshal(data, &attrs); err != nil {
+		return nil, NewInvalidEventError("invalid json object: %v", err)
+	}
+
+	var event Event
+
+	stringAttrs := map[string]*string{
+		"specversion":     &event.SpecVersion,
+		"id":              &event.Id,
+		"source":          &event.Source,
+		"type":            &event.Type,
+		"subject":         &event.Subject,
+		"datacontenttype": &event.DataContentType,
+		"dataschema":      &event.DataSchema,
+		"data_base64":     &event.DataBase64,
+	}
+
+	var timeString string
+
+	for name, value := range attrs {
+		if ptr, found := stringAttrs[name]; found {
+			if err := json.Unmarshal(value, ptr); err != nil {
+				return nil, NewInvalidEventError("invalid attribute %q: %v",
+					name, err)
+			}
+
+			continue
+		}
+
+		switch name {
+		case "time":
+			if err := json.Unmarshal(value, &timeString); err != nil {
+				return nil, NewInvalidEventError("invalid attribute %q: %v",
+					name, err)
+			}
+
+		case "data":
+			event.Data = value
+
+		default:
+			extensionValue, err := extensionValueString(value)
+			if err != nil {
+				return nil, NewInvalidEventError("invalid attribute %q: %v",
+					name, err)
+			}
+
+			if event.Extensions == nil {
+				event.Extensions = make(map[string]string)
+			}
+
+			event.Extensions[name] = extensionValue
+		}
+	}
+
+	if err := event.setTime(timeString); err != nil {
+		return nil, err
+	}
+
+	if event.Data != nil && event.DataBase64 != "" {
+		return nil, NewInvalidEventError("attributes \"data\" and " +
+			"\"data_base64\" are mutually exclusive")
+	}
+
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+func ParseBinaryEvent(header http.Header, body []byte) (*Event, error) {
+	var event Event
+	var timeString string
+
+	for name, values := range header {
+		if !strings.HasPrefix(name, binaryHeaderPrefix) {
+			continue
+		}
+
+		attrName := strings.ToLower(name[len(binaryHeaderPrefix):])
+		value := decodeHeaderValue(strings.Join(values, ","))
+
+		switch attrName {
+		case "specversion":
+			event.SpecVersion = value
+		case "id":
+			event.Id = value
+		case "source":
+			event.Source = value
+		case "type":
+			event.Type = value
+		case "subject":
+			event.Subject = value
+		case "time":
+			timeString = value
+		case "dataschema":
+			event.DataSchema = value
+		default:
+			if event.Extensions == nil {
+				event.Extensions = make(map[string]string)
+			}
+
+			event.Extensions[attrName] = value
+		}
+	}
+
+	if event.SpecVersion == "" {
+		return nil, NewInvalidEventError("missing ce-specversion header " +
+			"field; events must be sent in binary, structured or batched " +
+			"content mode")
+	}
+
+	if err := event.setTime(timeString); err != nil {
+		return nil, err
+	}
+
+	event.DataContentType = header.Get("Content-Type")
+
+	if len(body) > 0 {
+		if err := event.setBinaryData(body); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := event.Validate(); err != nil {
+		return nil, err
+	}
+
+	return &event, nil
+}
+
+func (e *Event) Validate() error {
+	if e.SpecVersion != SpecVersion {
+		return NewInvalidEventError("unsupported specversion %q",
+			e.SpecVersion)
+	}
+
+	if e.Id == "" {
+		return NewInvalidEventError("missing or empty attribute \"id\"")
+	}
+
+	if e.Source == "" {
+		return NewInvalidEventError("missing or empty attribute \"source\"")
+	}
+
+	if e.Type == "" {
+		return NewInvalidEventError("missing or empty attribute \"type\"")
+	}
+
+	return nil
+}
+
+func (e *Event) setTime(s string) error {
+	if s == "" {
+		return nil
+	}
+
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return NewInvalidEventError("invalid attribute \"time\": %v", err)
+	}
+
+	t = t.UTC()
+	e.Time = &t
+
+	return nil
+}
+
+// setBinaryData stores the request body of a binary mode event. JSON data are
+// stored as is, text is stored as a JSON string and anything else is encoded
+// in base64.
+func (e *Event) setBinaryData(body []byte) error {
+	mediaType, _, _ := mime.ParseMediaType(e.DataContentType)
+
+	switch {
+	case mediaType == "application/json" ||
+		strings.HasSuffix(mediaType, "+json"):
+		if !json.Valid(body) {
+			return NewInvalidEventError("invalid json data")
+		}
+
+		e.Data = json.RawMessage(bytes.Clone(body))
+
+	case strings.HasPrefix(mediaType, "text/") && utf8.Valid(body):
+		data, err := json.Marshal(string(body))
+		if err != nil {
+			return fmt.Errorf("cannot encode data: %w", err)
+		}
+
+		e.Data = data
+
+	default:
+		e.DataBase64 = base64.StdEncoding.EncodeToString(body)
+	}
+
+	return nil
+}
+
+// Header field values can be percent-encoded (CloudEvents HTTP protocol
+// binding 3.1.3.2).
+func decodeHeaderValue(s string) string {
+	value, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+
+	return value
+}
+
+func extensionValueString(value json.RawMessage) (string, error) {
+	var v interface{}
+	if err := json.Unmarshal(value, &v); err != nil {
+		return "", err
+	}
+
+	switch v2 := v.(type) {
+	case string:
+		return v2, nil
+	case bool, float64:
+		return string(value), nil
+	case nil:
+		return "", nil
+	default:
+		return "", fmt.Errorf("extension attributes must be strings, " +
+			"numbers or booleans")
+	}
+}
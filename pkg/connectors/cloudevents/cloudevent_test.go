@@ -0,0 +1,139 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseStructuredEvent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/cloudevents+json; charset=utf-8")
+
+	body := `{
+  "specversion": "1.0",
+  "id": "42",
+  "source": "/orders",
+  "type": "com.example.order.created",
+  "time": "2024-05-01T10:20:30+02:00",
+  "datacontenttype": "application/json",
+  "tenant": "acme",
+  "priority": 3,
+  "data": {"order_id": 12}
+}`
+
+	events, err := ParseRequestEvents(header, []byte(body))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("42", event.Id)
+	assert.Equal("/orders", event.Source)
+	assert.Equal("com.example.order.created", event.Type)
+	assert.Equal("application/json", event.DataContentType)
+	assert.Equal(map[string]string{"tenant": "acme", "priority": "3"},
+		event.Extensions)
+	assert.JSONEq(`{"order_id": 12}`, string(event.Data))
+
+	if assert.NotNil(event.Time) {
+		assert.Equal("2024-05-01T08:20:30Z",
+			event.Time.Format("2006-01-02T15:04:05Z07:00"))
+	}
+}
+
+func TestParseBatchedEvents(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/cloudevents-batch+json")
+
+	body := `[
+  {"specversion": "1.0", "id": "1", "source": "/a", "type": "t"},
+  {"specversion": "1.0", "id": "2", "source": "/a", "type": "t"}
+]`
+
+	events, err := ParseRequestEvents(header, []byte(body))
+	require.NoError(err)
+	require.Len(events, 2)
+
+	assert.Equal("1", events[0].Id)
+	assert.Equal("2", events[1].Id)
+}
+
+func TestParseBinaryEvent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("ce-specversion", "1.0")
+	header.Set("ce-id", "42")
+	header.Set("ce-source", "/orders")
+	header.Set("ce-type", "com.example.order.created")
+	header.Set("ce-subject", "order%2012")
+	header.Set("ce-tenant", "acme")
+
+	events, err := ParseRequestEvents(header, []byte(`{"order_id": 12}`))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("42", event.Id)
+	assert.Equal("/orders", event.Source)
+	assert.Equal("com.example.order.created", event.Type)
+	assert.Equal("order 12", event.Subject)
+	assert.Equal(map[string]string{"tenant": "acme"}, event.Extensions)
+	assert.JSONEq(`{"order_id": 12}`, string(event.Data))
+
+	header.Set("Content-Type", "text/plain")
+	events, err = ParseRequestEvents(header, []byte("hello"))
+	require.NoError(err)
+
+	var data string
+	require.NoError(json.Unmarshal(events[0].Data, &data))
+	assert.Equal("hello", data)
+
+	header.Set("Content-Type", "application/octet-stream")
+	events, err = ParseRequestEvents(header, []byte{0xff, 0x00})
+	require.NoError(err)
+
+	assert.Nil(events[0].Data)
+	assert.Equal("/wA=", events[0].DataBase64)
+}
+
+func TestParseInvalidEvents(t *testing.T) {
+	assert := assert.New(t)
+
+	structuredHeader := http.Header{}
+	structuredHeader.Set("Content-Type", "application/cloudevents+json")
+
+	invalidBodies := []string{
+		`{"id": "1", "source": "/a", "type": "t"}`,
+		`{"specversion": "0.3", "id": "1", "source": "/a", "type": "t"}`,
+		`{"specversion": "1.0", "source": "/a", "type": "t"}`,
+		`{"specversion": "1.0", "id": "1", "type": "t"}`,
+		`{"specversion": "1.0", "id": "1", "source": "/a"}`,
+		`{"specversion": "1.0", "id": "1", "source": "/a", "type": "t",
+          "time": "yesterday"}`,
+		`{"specversion": "1.0", "id": "1", "source": "/a", "type": "t",
+          "data": {}, "data_base64": "AA=="}`,
+		`[]`,
+	}
+
+	for _, body := range invalidBodies {
+		_, err := ParseRequestEvents(structuredHeader, []byte(body))
+		var invalidEventErr *InvalidEventError
+		assert.ErrorAs(err, &invalidEventErr, body)
+	}
+
+	_, err := ParseRequestEvents(http.Header{}, []byte(`{}`))
+	var invalidEventErr *InvalidEventError
+	assert.ErrorAs(err, &invalidEventErr)
+}
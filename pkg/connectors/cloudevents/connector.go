@@ -0,0 +1,62 @@
+package cloudevents
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type Connector struct {
+	Def *eventline.ConnectorDef
+	Cfg *ConnectorCfg
+	Pg  *pg.Client
+	Log *log.Logger
+}
+
+func NewConnector() *Connector {
+	c := &Connector{}
+
+	def := eventline.NewConnectorDef("cloudevents")
+
+	def.Worker = NewDeduplicationGC(c)
+
+	def.AddEvent(EventDef())
+
+	c.Def = def
+
+	return c
+}
+
+func (c *Connector) Name() string {
+	return "cloudevents"
+}
+
+func (c *Connector) Definition() *eventline.ConnectorDef {
+	return c.Def
+}
+
+func (c *Connector) Enabled() bool {
+	return c.Cfg.Enabled
+}
+
+func (c *Connector) Init(ccfg eventline.ConnectorCfg, initData eventline.ConnectorInitData) error {
+	c.Cfg = ccfg.(*ConnectorCfg)
+	c.Pg = initData.Pg
+	c.Log = initData.Log
+
+	return nil
+}
+
+func (c *Connector) Terminate() {
+}
+
+// Subscriptions are matched with their match attributes, there is nothing to
+// store or to create on an external platform.
+
+func (c *Connector) Subscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	return nil
+}
+
+func (c *Connector) Unsubscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	return nil
+}
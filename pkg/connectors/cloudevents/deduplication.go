@@ -0,0 +1,53 @@
+package cloudevents
+
+import (
+	"context"
+	"time"
+
+	"go.n16f.net/service/pkg/pg"
+)
+
+// RegisterEvent records the reception of an event and returns false if an
+// event with the same source and id was already received during the
+// deduplication period.
+func RegisterEvent(conn pg.Conn, event *Event, period time.Duration) (bool, error) {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	minTime := now.Add(-period)
+
+	// Entries older than the deduplication period are not deleted
+	// immediately, so we replace them.
+	query := `
+INSERT INTO c_cloudevents_receptions AS r
+    (source, id, reception_time)
+  VALUES
+    ($1, $2, $3)
+  ON CONFLICT (source, id) DO UPDATE
+    SET reception_time = EXCLUDED.reception_time
+    WHERE r.reception_time < $4
+`
+	res, err := conn.Exec(ctx, query, event.Source, event.Id, now, minTime)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
+
+func DeleteOldReceptions(conn pg.Conn, period time.Duration) (int64, error) {
+	ctx := context.Background()
+
+	minTime := time.Now().UTC().Add(-period)
+
+	query := `
+DELETE FROM c_cloudevents_receptions
+  WHERE reception_time < $1
+`
+	res, err := conn.Exec(ctx, query, minTime)
+	if err != nil {
+		return -1, err
+	}
+
+	return res.RowsAffected(), nil
+}
@@ -0,0 +1,61 @@
+package cloudevents
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type DeduplicationGC struct {
+	Log *log.Logger
+	Pg  *pg.Client
+
+	connector *Connector
+}
+
+func NewDeduplicationGC(c *Connector) *DeduplicationGC {
+	return &DeduplicationGC{
+		connector: c,
+	}
+}
+
+func (gc *DeduplicationGC) Init(w *eventline.Worker) {
+	gc.Log = w.Log
+	gc.Pg = w.Pg
+}
+
+func (gc *DeduplicationGC) Start() error {
+	return nil
+}
+
+func (gc *DeduplicationGC) Stop() {
+}
+
+func (gc *DeduplicationGC) ProcessJob() (bool, error) {
+	var deleted bool
+
+	period := time.Duration(gc.connector.Cfg.DeduplicationPeriod) *
+		time.Second
+
+	err := gc.Pg.WithTx(func(conn pg.Conn) error {
+		n, err := DeleteOldReceptions(conn, period)
+		if err != nil {
+			return fmt.Errorf("cannot delete receptions: %w", err)
+		} else if n == 0 {
+			return nil
+		}
+
+		gc.Log.Debug(1, "%d receptions deleted", n)
+
+		deleted = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	return deleted, nil
+}
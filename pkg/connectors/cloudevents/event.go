@@ -0,0 +1,29 @@
+package cloudevents
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+// Event is a CloudEvent as defined by the CloudEvents 1.0 specification.
+// Extension attributes are stored separately from context attributes defined
+// by the specification.
+type Event struct {
+	SpecVersion     string            `json:"specversion"`
+	Id              string            `json:"id"`
+	Source          string            `json:"source"`
+	Type            string            `json:"type"`
+	Subject         string            `json:"subject,omitempty"`
+	Time            *time.Time        `json:"time,omitempty"`
+	DataContentType string            `json:"datacontenttype,omitempty"`
+	DataSchema      string            `json:"dataschema,omitempty"`
+	Extensions      map[string]string `json:"extensions,omitempty"`
+	Data            json.RawMessage   `json:"data,omitempty"`
+	DataBase64      string            `json:"data_base64,omitempty"`
+}
+
+func EventDef() *eventline.EventDef {
+	return eventline.NewEventDef("event", &Event{}, &Parameters{})
+}
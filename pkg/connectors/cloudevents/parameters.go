@@ -0,0 +1,27 @@
+package cloudevents
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type Parameters struct {
+	Type   string `json:"type"`
+	Source string `json:"source,omitempty"`
+}
+
+func (p *Parameters) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("type", p.Type)
+}
+
+func (p *Parameters) MatchAttributes() eventline.MatchAttributes {
+	attrs := eventline.MatchAttributes{
+		"type": p.Type,
+	}
+
+	if p.Source != "" {
+		attrs["source"] = p.Source
+	}
+
+	return attrs
+}
@@ -0,0 +1,108 @@
+package cloudevents
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+var (
+	ErrConnectorDisabled  = errors.New("connector disabled")
+	ErrInvalidAccessToken = errors.New("missing or invalid access token")
+	ErrRequestTooLarge    = errors.New("request body too large")
+)
+
+// ProcessRequest handles a HTTP request containing one or more CloudEvents.
+// All events are processed in the same transaction. Events already received
+// during the deduplication period are ignored.
+func (c *Connector) ProcessRequest(req *http.Request) error {
+	if !c.Cfg.Enabled {
+		return ErrConnectorDisabled
+	}
+
+	if !c.checkAccessToken(req) {
+		return ErrInvalidAccessToken
+	}
+
+	maxSize := int64(c.Cfg.MaxRequestSize)
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot read request body: %w", err)
+	} else if int64(len(body)) > maxSize {
+		return ErrRequestTooLarge
+	}
+
+	events, err := ParseRequestEvents(req.Header, body)
+	if err != nil {
+		return err
+	}
+
+	period := time.Duration(c.Cfg.DeduplicationPeriod) * time.Second
+
+	return c.Pg.WithTx(func(conn pg.Conn) error {
+		for _, event := range events {
+			isNew, err := RegisterEvent(conn, event, period)
+			if err != nil {
+				return fmt.Errorf("cannot register event: %w", err)
+			} else if !isNew {
+				c.Log.Debug(1, "ignoring duplicate event %q from %q",
+					event.Id, event.Source)
+				continue
+			}
+
+			if err := c.createEvents(conn, event); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+func (c *Connector) createEvents(conn pg.Conn, event *Event) error {
+	// Subscriptions can match on the type only or on both the type and the
+	// source.
+	attrsList := []eventline.MatchAttributes{
+		{"type": event.Type},
+		{"type": event.Type, "source": event.Source},
+	}
+
+	for _, attrs := range attrsList {
+		subs, err := eventline.LoadSubscriptionsByMatchAttributes(conn,
+			c.Def.Name, "event", attrs)
+		if err != nil {
+			return fmt.Errorf("cannot load subscriptions: %w", err)
+		}
+
+		for _, sub := range subs {
+			newEvent := sub.NewEvent(c.Def.Name, "event", event.Time, event)
+
+			if err := newEvent.Insert(conn); err != nil {
+				return fmt.Errorf("cannot insert event: %w", err)
+			}
+		}
+	}
+
+	return nil
+}
+
+func (c *Connector) checkAccessToken(req *http.Request) bool {
+	authorization := req.Header.Get("Authorization")
+
+	token, found := strings.CutPrefix(authorization, "Bearer ")
+	if !found {
+		return false
+	}
+
+	expectedToken := c.Cfg.AccessToken
+
+	return subtle.ConstantTimeCompare([]byte(token),
+		[]byte(expectedToken)) == 1
+}
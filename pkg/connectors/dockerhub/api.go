@@ -0,0 +1,230 @@
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/shttp"
+)
+
+const (
+	APIURI = "https://hub.docker.com/v2"
+
+	// The maximum number of tag pages fetched when polling a repository;
+	// tags are ordered by update time, so only the oldest tags of very large
+	// repositories are ignored.
+	MaxTagPages  = 10
+	TagsPageSize = 100
+)
+
+type RateLimitError struct {
+	RetryAfter time.Duration // zero if unknown
+}
+
+func (err *RateLimitError) Error() string {
+	if err.RetryAfter == 0 {
+		return "rate limit exceeded"
+	}
+
+	return fmt.Sprintf("rate limit exceeded, retry after %v", err.RetryAfter)
+}
+
+type APIError struct {
+	Status int
+	Body   string
+}
+
+func (err *APIError) Error() string {
+	return fmt.Sprintf("request failed with status %d: %s", err.Status,
+		err.Body)
+}
+
+type Tag struct {
+	Name           string     `json:"name"`
+	Digest         string     `json:"digest,omitempty"`
+	LastUpdateTime *time.Time `json:"last_updated,omitempty"`
+}
+
+type tagPage struct {
+	Next    string `json:"next"`
+	Results []*Tag `json:"results"`
+}
+
+type Client struct {
+	HTTPClient *shttp.Client
+
+	token string // empty for anonymous access
+}
+
+// NewClient returns a client for the DockerHub API. If an identity is
+// provided, the client authenticates with it, which is required for private
+// repositories and gives higher rate limits.
+func (c *Connector) NewClient(ctx context.Context, identity *eventline.Identity) (*Client, error) {
+	httpClientCfg := shttp.ClientCfg{
+		Log:         c.Log,
+		LogRequests: true,
+	}
+
+	httpClient, err := eventline.NewHTTPClient(httpClientCfg, c.proxyURI)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create http client: %w", err)
+	}
+
+	client := Client{
+		HTTPClient: httpClient,
+	}
+
+	if identity == nil {
+		return &client, nil
+	}
+
+	var username, secret string
+
+	switch idata := identity.Data.(type) {
+	case *TokenIdentity:
+		username, secret = idata.Username, idata.Token
+	case *PasswordIdentity:
+		username, secret = idata.Username, idata.Password
+	default:
+		return nil, fmt.Errorf("unsupported identity")
+	}
+
+	if err := client.login(ctx, username, secret); err != nil {
+		return nil, fmt.Errorf("cannot login: %w", err)
+	}
+
+	return &client, nil
+}
+
+func (c *Client) login(ctx context.Context, username, secret string) error {
+	body := struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}{
+		Username: username,
+		Password: secret,
+	}
+
+	var res struct {
+		Token string `json:"token"`
+	}
+
+	uri := APIURI + "/users/login"
+	if err := c.sendRequest(ctx, "POST", uri, body, &res); err != nil {
+		return err
+	}
+
+	if res.Token == "" {
+		return fmt.Errorf("missing token in response")
+	}
+
+	c.token = res.Token
+
+	return nil
+}
+
+// ListTags returns the tags of a repository, most recently updated first.
+func (c *Client) ListTags(ctx context.Context, namespace, repository string) ([]*Tag, error) {
+	query := url.Values{}
+	query.Set("page_size", strconv.Itoa(TagsPageSize))
+	query.Set("ordering", "last_updated")
+
+	uri := fmt.Sprintf("%s/namespaces/%s/repositories/%s/tags?%s", APIURI,
+		url.PathEscape(namespace), url.PathEscape(repository),
+		query.Encode())
+
+	var tags []*Tag
+
+	for i := 0; i < MaxTagPages && uri != ""; i++ {
+		var page tagPage
+		if err := c.sendRequest(ctx, "GET", uri, nil, &page); err != nil {
+			return nil, err
+		}
+
+		tags = append(tags, page.Results...)
+		uri = page.Next
+	}
+
+	return tags, nil
+}
+
+func (c *Client) sendRequest(ctx context.Context, method, uri string, body, dest interface{}) error {
+	var bodyReader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("cannot encode request body: %w", err)
+		}
+
+		bodyReader = bytes.NewReader(data)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, method, uri, bodyReader)
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return fmt.Errorf("cannot read response body: %w", err)
+	}
+
+	if res.StatusCode == 429 {
+		return &RateLimitError{
+			RetryAfter: RateLimitRetryAfter(res.Header, time.Now()),
+		}
+	} else if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return &APIError{Status: res.StatusCode, Body: string(resBody)}
+	}
+
+	if err := json.Unmarshal(resBody, dest); err != nil {
+		return fmt.Errorf("cannot decode response body: %w", err)
+	}
+
+	return nil
+}
+
+// RateLimitRetryAfter returns the delay indicated by a rate limited response,
+// either with the Retry-After header field or with the X-RateLimit-Reset header
+// field containing the time the rate limit is reset.
+func RateLimitRetryAfter(header http.Header, now time.Time) time.Duration {
+	if s := header.Get("Retry-After"); s != "" {
+		if seconds, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return time.Duration(max(seconds, 0)) * time.Second
+		}
+
+		if t, err := http.ParseTime(s); err == nil {
+			return max(t.Sub(now), 0)
+		}
+	}
+
+	if s := header.Get("X-RateLimit-Reset"); s != "" {
+		if timestamp, err := strconv.ParseInt(s, 10, 64); err == nil {
+			return max(time.Unix(timestamp, 0).Sub(now), 0)
+		}
+	}
+
+	return 0
+}
@@ -0,0 +1,132 @@
+package dockerhub
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/shttp"
+)
+
+// DockerHub expects webhook receivers to report the result of the processing
+// of each delivery by sending a request to the callback URI contained in the
+// payload; webhooks whose deliveries are not acknowledged are considered as
+// failing.
+//
+// Since the payload is not signed, the callback URI is only used if it
+// targets DockerHub: an attacker knowing the webhook token must not be able
+// to make Eventline send requests to arbitrary servers.
+
+type CallbackState string
+
+const (
+	CallbackStateSuccess CallbackState = "success"
+	CallbackStateFailure CallbackState = "failure"
+	CallbackStateError   CallbackState = "error"
+)
+
+type Callback struct {
+	State       CallbackState `json:"state"`
+	Description string        `json:"description,omitempty"`
+	Context     string        `json:"context,omitempty"`
+	TargetURI   string        `json:"target_url,omitempty"`
+}
+
+func ValidateCallbackURI(s string) error {
+	uri, err := url.Parse(s)
+	if err != nil {
+		return err
+	}
+
+	if uri.Scheme != "https" {
+		return fmt.Errorf("invalid scheme %q", uri.Scheme)
+	}
+
+	host := strings.ToLower(uri.Hostname())
+	if host != "docker.com" && !strings.HasSuffix(host, ".docker.com") {
+		return fmt.Errorf("invalid host %q", host)
+	}
+
+	return nil
+}
+
+// sendCallback reports the result of the processing of a delivery. Errors are
+// logged: failing to acknowledge a delivery must not cause the delivery to be
+// considered as failed by Eventline.
+func (c *Connector) sendCallback(uri string, processingErr error) {
+	if uri == "" {
+		return
+	}
+
+	if err := ValidateCallbackURI(uri); err != nil {
+		c.Log.Error("ignoring invalid callback uri %q: %v", uri, err)
+		return
+	}
+
+	callback := Callback{
+		State:       CallbackStateSuccess,
+		Description: "delivery processed",
+		Context:     "Eventline",
+	}
+
+	if processingErr != nil {
+		callback.State = CallbackStateError
+		callback.Description = "cannot process delivery"
+	}
+
+	timeout := time.Duration(c.Cfg.CallbackTimeout) * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	if err := c.postCallback(ctx, uri, &callback); err != nil {
+		c.Log.Error("cannot send callback: %v", err)
+	}
+}
+
+func (c *Connector) postCallback(ctx context.Context, uri string, callback *Callback) error {
+	data, err := json.Marshal(callback)
+	if err != nil {
+		return fmt.Errorf("cannot encode request body: %w", err)
+	}
+
+	httpClientCfg := shttp.ClientCfg{
+		Log:                 c.Log,
+		DisableRedirections: true,
+	}
+
+	client, err := eventline.NewHTTPClient(httpClientCfg, c.proxyURI)
+	if err != nil {
+		return fmt.Errorf("cannot create http client: %w", err)
+	}
+	defer client.CloseConnections()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", uri,
+		bytes.NewReader(data))
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("request failed with status %d", res.StatusCode)
+	}
+
+	return nil
+}
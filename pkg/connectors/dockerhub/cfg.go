@@ -0,0 +1,30 @@
+package dockerhub
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type ConnectorCfg struct {
+	Enabled         bool   `json:"enabled"`
+	WebhookToken    string `json:"webhook_token,omitempty"`
+	MaxRequestSize  int    `json:"max_request_size,omitempty"` // bytes
+	CallbackTimeout int    `json:"callback_timeout,omitempty"` // milliseconds
+}
+
+func (c *Connector) DefaultCfg() eventline.ConnectorCfg {
+	return &ConnectorCfg{
+		MaxRequestSize: 1024 * 1024,
+
+		CallbackTimeout: 5000,
+	}
+}
+
+func (cfg *ConnectorCfg) ValidateJSON(v *ejson.Validator) {
+	if cfg.Enabled {
+		v.CheckStringNotEmpty("webhook_token", cfg.WebhookToken)
+	}
+
+	v.CheckIntMin("max_request_size", cfg.MaxRequestSize, 1)
+	v.CheckIntMin("callback_timeout", cfg.CallbackTimeout, 1)
+}
@@ -1,32 +1,40 @@
 package dockerhub
 
 import (
+	"fmt"
+	"net/url"
+	"time"
+
 	"github.com/exograd/eventline/pkg/eventline"
-	"go.n16f.net/ejson"
 	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
 )
 
-type ConnectorCfg struct {
-}
-
 type Connector struct {
 	Def *eventline.ConnectorDef
 	Cfg *ConnectorCfg
+	Pg  *pg.Client
 	Log *log.Logger
+
+	proxyURI *url.URL
 }
 
 func NewConnector() *Connector {
+	c := &Connector{}
+
 	def := eventline.NewConnectorDef("dockerhub")
 
+	def.Worker = NewWorker(c)
+
 	def.AddIdentity(PasswordIdentityDef())
 	def.AddIdentity(TokenIdentityDef())
 
-	return &Connector{
-		Def: def,
-	}
-}
+	def.AddEvent(ImagePushEventDef())
+	def.AddEvent(NewTagEventDef())
+
+	c.Def = def
 
-func (cfg *ConnectorCfg) ValidateJSON(v *ejson.Validator) {
+	return c
 }
 
 func (c *Connector) Name() string {
@@ -37,16 +45,58 @@ func (c *Connector) Definition() *eventline.ConnectorDef {
 	return c.Def
 }
 
-func (c *Connector) DefaultCfg() eventline.ConnectorCfg {
-	return &ConnectorCfg{}
+func (c *Connector) Enabled() bool {
+	return c.Cfg.Enabled
 }
 
 func (c *Connector) Init(ccfg eventline.ConnectorCfg, initData eventline.ConnectorInitData) error {
 	c.Cfg = ccfg.(*ConnectorCfg)
+	c.Pg = initData.Pg
 	c.Log = initData.Log
 
+	c.proxyURI = initData.Proxy
+
 	return nil
 }
 
 func (c *Connector) Terminate() {
 }
+
+// Webhooks are configured by users on DockerHub; subscriptions only record
+// the repository they are associated with, and for new_tag events, the state
+// of polling.
+
+func (c *Connector) Subscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	params := sctx.Subscription.Parameters.(*Parameters)
+
+	s := Subscription{
+		Id:         sctx.Subscription.Id,
+		Namespace:  params.Namespace,
+		Repository: params.Repository,
+	}
+
+	if sctx.Subscription.Event == "new_tag" {
+		now := time.Now().UTC()
+		s.NextPollTime = &now
+	}
+
+	if err := s.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Connector) Unsubscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	var subscription Subscription
+	err := subscription.LoadForUpdate(conn, sctx.Subscription.Id)
+	if err != nil {
+		return fmt.Errorf("cannot load subscription: %w", err)
+	}
+
+	if err := subscription.Delete(conn); err != nil {
+		return fmt.Errorf("cannot delete subscription: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,17 @@
+package dockerhub
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type ImagePushEvent struct {
+	Namespace  string `json:"namespace"`
+	Repository string `json:"repository"`
+	Tag        string `json:"tag"`
+	Pusher     string `json:"pusher,omitempty"`
+}
+
+func ImagePushEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("image_push",
+		&ImagePushEvent{}, &Parameters{})
+}
@@ -0,0 +1,19 @@
+package dockerhub
+
+import (
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type NewTagEvent struct {
+	Namespace      string     `json:"namespace"`
+	Repository     string     `json:"repository"`
+	Tag            string     `json:"tag"`
+	Digest         string     `json:"digest,omitempty"`
+	LastUpdateTime *time.Time `json:"last_update_time,omitempty"`
+}
+
+func NewTagEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("new_tag", &NewTagEvent{}, &Parameters{})
+}
@@ -0,0 +1,34 @@
+package dockerhub
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+// TestIdentity logs in to DockerHub with the identity. DockerHub does not
+// expose the permissions associated with a token.
+func (c *Connector) TestIdentity(ctx context.Context, identity *eventline.Identity) (*eventline.IdentityTestResult, error) {
+	var username string
+
+	switch idata := identity.Data.(type) {
+	case *TokenIdentity:
+		username = idata.Username
+	case *PasswordIdentity:
+		username = idata.Username
+	default:
+		return nil, fmt.Errorf("unsupported identity type %q", identity.Type)
+	}
+
+	if _, err := c.NewClient(ctx, identity); err != nil {
+		return eventline.NewIdentityTestFailure(err), nil
+	}
+
+	result := eventline.IdentityTestResult{
+		OK:      true,
+		Account: username,
+	}
+
+	return &result, nil
+}
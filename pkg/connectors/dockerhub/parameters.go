@@ -0,0 +1,47 @@
+package dockerhub
+
+import (
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+const (
+	DefaultPollInterval = 300 // seconds
+	MinPollInterval     = 60  // seconds
+)
+
+type Parameters struct {
+	Namespace  string `json:"namespace"`
+	Repository string `json:"repository"`
+
+	// Only used by subscriptions to new_tag events, which are produced by
+	// polling the repository.
+	PollInterval int `json:"poll_interval,omitempty"` // seconds
+}
+
+func (p *Parameters) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("namespace", p.Namespace)
+	v.CheckStringNotEmpty("repository", p.Repository)
+
+	if p.PollInterval != 0 {
+		v.CheckIntMin("poll_interval", p.PollInterval, MinPollInterval)
+	}
+}
+
+func (p *Parameters) MatchAttributes() eventline.MatchAttributes {
+	return eventline.MatchAttributes{
+		"namespace":  p.Namespace,
+		"repository": p.Repository,
+	}
+}
+
+func (p *Parameters) PollIntervalDuration() time.Duration {
+	interval := p.PollInterval
+	if interval == 0 {
+		interval = DefaultPollInterval
+	}
+
+	return time.Duration(interval) * time.Second
+}
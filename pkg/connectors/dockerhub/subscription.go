@@ -0,0 +1,127 @@
+package dockerhub
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type UnknownSubscriptionError struct {
+	Id eventline.Id
+}
+
+func (err UnknownSubscriptionError) Error() string {
+	return fmt.Sprintf("unknown subscription %q", err.Id)
+}
+
+type Subscription struct {
+	Id         eventline.Id
+	Namespace  string
+	Repository string
+
+	// Only set for subscriptions to new_tag events
+	NextPollTime   *time.Time
+	NbPollFailures int
+	KnownTags      []string // nil until the first successful poll
+}
+
+// LoadSubscriptionsByRepository returns the subscriptions of an event for a
+// repository, using the namespace and repository as match attributes.
+//
+// The c_dockerhub_subscriptions table is only used to store the polling state
+// of subscriptions to new_tag events.
+func LoadSubscriptionsByRepository(conn pg.Conn, ename, namespace, repository string) (eventline.Subscriptions, error) {
+	params := Parameters{Namespace: namespace, Repository: repository}
+
+	return eventline.LoadSubscriptionsByMatchAttributes(conn, "dockerhub",
+		ename, params.MatchAttributes())
+}
+
+// LoadSubscriptionForPolling returns an active subscription whose repository
+// must be polled.
+func LoadSubscriptionForPolling(conn pg.Conn, now time.Time) (*Subscription, *eventline.Subscription, error) {
+	query := `
+SELECT ds.id, ds.namespace, ds.repository, ds.next_poll_time,
+       ds.nb_poll_failures, ds.known_tags
+  FROM subscriptions AS es
+  JOIN c_dockerhub_subscriptions AS ds ON ds.id = es.id
+  WHERE es.status = 'active'
+    AND ds.next_poll_time <= $1
+  ORDER BY ds.next_poll_time
+  LIMIT 1
+  FOR UPDATE OF ds SKIP LOCKED
+`
+	var s Subscription
+	var es eventline.Subscription
+
+	err := pg.QueryObject(conn, &s, query, now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if err := es.Load(conn, s.Id); err != nil {
+		return nil, nil, err
+	}
+
+	return &s, &es, nil
+}
+
+func (s *Subscription) LoadForUpdate(conn pg.Conn, id eventline.Id) error {
+	query := `
+SELECT id, namespace, repository, next_poll_time, nb_poll_failures,
+       known_tags
+  FROM c_dockerhub_subscriptions
+  WHERE id = $1
+  FOR UPDATE;
+`
+	err := pg.QueryObject(conn, s, query, id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return &UnknownSubscriptionError{Id: id}
+	}
+
+	return err
+}
+
+func (s *Subscription) Insert(conn pg.Conn) error {
+	query := `
+INSERT INTO c_dockerhub_subscriptions
+    (id, namespace, repository, next_poll_time, nb_poll_failures,
+     known_tags)
+  VALUES
+    ($1, $2, $3, $4, $5, $6);
+`
+	return pg.Exec(conn, query,
+		s.Id, s.Namespace, s.Repository, s.NextPollTime, s.NbPollFailures,
+		s.KnownTags)
+}
+
+func (s *Subscription) Update(conn pg.Conn) error {
+	query := `
+UPDATE c_dockerhub_subscriptions SET
+    next_poll_time = $2,
+    nb_poll_failures = $3,
+    known_tags = $4
+  WHERE id = $1
+`
+	return pg.Exec(conn, query,
+		s.Id, s.NextPollTime, s.NbPollFailures, s.KnownTags)
+}
+
+func (s *Subscription) Delete(conn pg.Conn) error {
+	query := `
+DELETE FROM c_dockerhub_subscriptions
+  WHERE id = $1;
+`
+	return pg.Exec(conn, query, s.Id)
+}
+
+func (s *Subscription) FromRow(row pgx.Row) error {
+	return row.Scan(&s.Id, &s.Namespace, &s.Repository, &s.NextPollTime,
+		&s.NbPollFailures, &s.KnownTags)
+}
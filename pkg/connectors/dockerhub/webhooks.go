@@ -0,0 +1,159 @@
+package dockerhub
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// DockerHub webhooks are not signed. Users configure webhooks with a URI
+// containing the webhook token of the connector so that we can reject
+// requests which were not sent by a webhook they created.
+
+var (
+	ErrConnectorDisabled   = errors.New("connector disabled")
+	ErrInvalidWebhookToken = errors.New("invalid webhook token")
+	ErrRequestTooLarge     = errors.New("request body too large")
+)
+
+type InvalidPayloadError struct {
+	Err error
+}
+
+func (err *InvalidPayloadError) Error() string {
+	return fmt.Sprintf("invalid webhook payload: %v", err.Err)
+}
+
+func (err *InvalidPayloadError) Unwrap() error {
+	return err.Err
+}
+
+type WebhookPayload struct {
+	CallbackURI string                    `json:"callback_url"`
+	PushData    *WebhookPayloadPushData   `json:"push_data"`
+	Repository  *WebhookPayloadRepository `json:"repository"`
+}
+
+type WebhookPayloadPushData struct {
+	PushedAt int64  `json:"pushed_at"` // seconds since the epoch
+	Pusher   string `json:"pusher"`
+	Tag      string `json:"tag"`
+}
+
+type WebhookPayloadRepository struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	RepoName  string `json:"repo_name"`
+}
+
+func (p *WebhookPayload) ValidateJSON(v *ejson.Validator) {
+	v.CheckObject("push_data", p.PushData)
+	v.CheckObject("repository", p.Repository)
+}
+
+func (d *WebhookPayloadPushData) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("tag", d.Tag)
+}
+
+func (r *WebhookPayloadRepository) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("name", r.Name)
+	v.CheckStringNotEmpty("namespace", r.Namespace)
+}
+
+func ParseWebhookPayload(data []byte) (*WebhookPayload, error) {
+	var payload WebhookPayload
+	if err := ejson.Unmarshal(data, &payload); err != nil {
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	return &payload, nil
+}
+
+// ImagePushEvent returns the image push event associated with the payload and
+// the time of the push if it is known.
+func (p *WebhookPayload) ImagePushEvent() (*ImagePushEvent, *time.Time) {
+	event := ImagePushEvent{
+		Namespace:  p.Repository.Namespace,
+		Repository: p.Repository.Name,
+		Tag:        p.PushData.Tag,
+		Pusher:     p.PushData.Pusher,
+	}
+
+	var eventTime *time.Time
+	if p.PushData.PushedAt > 0 {
+		t := time.Unix(p.PushData.PushedAt, 0).UTC()
+		eventTime = &t
+	}
+
+	return &event, eventTime
+}
+
+// ProcessWebhookRequest handles a webhook delivery sent by DockerHub for an
+// image push.
+func (c *Connector) ProcessWebhookRequest(req *http.Request, token string) error {
+	if !c.Cfg.Enabled {
+		return ErrConnectorDisabled
+	}
+
+	expectedToken := c.Cfg.WebhookToken
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) != 1 {
+		return ErrInvalidWebhookToken
+	}
+
+	maxSize := int64(c.Cfg.MaxRequestSize)
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot read request body: %w", err)
+	} else if int64(len(body)) > maxSize {
+		return ErrRequestTooLarge
+	}
+
+	payload, err := ParseWebhookPayload(body)
+	if err != nil {
+		return err
+	}
+
+	event, eventTime := payload.ImagePushEvent()
+
+	c.Log.Debug(1, "received image push for %s/%s:%s", event.Namespace,
+		event.Repository, event.Tag)
+
+	err = c.Pg.WithTx(func(conn pg.Conn) error {
+		return c.CreateEvents(conn, "image_push", eventTime, event,
+			event.Namespace, event.Repository)
+	})
+
+	c.sendCallback(payload.CallbackURI, err)
+
+	return err
+}
+
+// CreateEvents creates an event for each subscription associated with the
+// repository.
+func (c *Connector) CreateEvents(conn pg.Conn, ename string, eventTime *time.Time, eventData eventline.EventData, namespace, repository string) error {
+	subs, err := LoadSubscriptionsByRepository(conn, ename, namespace,
+		repository)
+	if err != nil {
+		return fmt.Errorf("cannot load subscriptions: %w", err)
+	}
+
+	events := make(eventline.Events, 0, len(subs))
+
+	for _, sub := range subs {
+		event := sub.NewEvent(c.Def.Name, ename, eventTime, eventData)
+		events = append(events, event)
+	}
+
+	if err := events.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert events: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,114 @@
+package dockerhub
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseWebhookPayload(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	data := []byte(`{
+  "callback_url": "https://registry.hub.docker.com/u/example/app/hook/abc/",
+  "push_data": {
+    "pushed_at": 1417566161,
+    "pusher": "alice",
+    "tag": "1.2.0"
+  },
+  "repository": {
+    "name": "app",
+    "namespace": "example",
+    "repo_name": "example/app"
+  }
+}`)
+
+	payload, err := ParseWebhookPayload(data)
+	require.NoError(err)
+
+	event, eventTime := payload.ImagePushEvent()
+	assert.Equal(&ImagePushEvent{
+		Namespace:  "example",
+		Repository: "app",
+		Tag:        "1.2.0",
+		Pusher:     "alice",
+	}, event)
+
+	if assert.NotNil(eventTime) {
+		assert.Equal(time.Unix(1417566161, 0).UTC(), *eventTime)
+	}
+
+	invalidPayloads := []string{
+		`{}`,
+		`{"push_data": {"tag": "latest"}}`,
+		`{"push_data": {"tag": ""}, "repository": {"name": "app", "namespace": "example"}}`,
+		`{"push_data": {"tag": "latest"}, "repository": {"name": "app"}}`,
+		`not json`,
+	}
+
+	for _, data := range invalidPayloads {
+		_, err := ParseWebhookPayload([]byte(data))
+		var payloadErr *InvalidPayloadError
+		assert.ErrorAs(err, &payloadErr, data)
+	}
+}
+
+func TestValidateCallbackURI(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(ValidateCallbackURI(
+		"https://registry.hub.docker.com/u/example/app/hook/abc/"))
+	assert.NoError(ValidateCallbackURI("https://docker.com/hook"))
+
+	assert.Error(ValidateCallbackURI(
+		"http://registry.hub.docker.com/u/example/app/hook/abc/"))
+	assert.Error(ValidateCallbackURI("https://example.com/hook"))
+	assert.Error(ValidateCallbackURI("https://evildocker.com/hook"))
+	assert.Error(ValidateCallbackURI("https://10.0.0.1/hook"))
+}
+
+func TestPostCallback(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var callback Callback
+
+	server := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			json.NewDecoder(req.Body).Decode(&callback)
+			w.WriteHeader(200)
+		}))
+	defer server.Close()
+
+	c := NewConnector()
+	c.Cfg = c.DefaultCfg().(*ConnectorCfg)
+
+	err := c.postCallback(context.Background(), server.URL,
+		&Callback{State: CallbackStateSuccess, Context: "Eventline"})
+	require.NoError(err)
+
+	assert.Equal(CallbackStateSuccess, callback.State)
+	assert.Equal("Eventline", callback.Context)
+}
+
+func TestParametersMatchAttributes(t *testing.T) {
+	assert := assert.New(t)
+
+	params := Parameters{
+		Namespace:    "example",
+		Repository:   "website",
+		PollInterval: 600,
+	}
+
+	eventParams := Parameters{Namespace: "example", Repository: "website"}
+
+	// The poll interval must not prevent matching
+	assert.Equal(eventParams.MatchAttributes(), params.MatchAttributes())
+}
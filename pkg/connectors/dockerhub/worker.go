@@ -0,0 +1,226 @@
+package dockerhub
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// Webhooks are not available for all repositories, and deliveries can be
+// lost. Subscriptions to new_tag events periodically list the tags of the
+// repository and emit an event for each tag which was not present during the
+// previous poll. The first poll only records existing tags.
+//
+// When polling fails, for example because of DockerHub rate limits, the
+// delay before the next attempt doubles after each failure.
+
+const (
+	PollTimeout       = 60 * time.Second
+	MaxPollRetryDelay = 6 * time.Hour
+)
+
+type Worker struct {
+	Log *log.Logger
+	Pg  *pg.Client
+
+	connector *Connector
+	worker    *eventline.Worker
+}
+
+func NewWorker(c *Connector) *Worker {
+	return &Worker{
+		connector: c,
+	}
+}
+
+func (w *Worker) Init(ew *eventline.Worker) {
+	w.Log = ew.Log
+	w.Pg = ew.Pg
+
+	w.worker = ew
+}
+
+func (w *Worker) Start() error {
+	return nil
+}
+
+func (w *Worker) Stop() {
+}
+
+func (w *Worker) ProcessJob() (bool, error) {
+	var processed bool
+	var events eventline.Events
+
+	err := w.Pg.WithTx(func(conn pg.Conn) error {
+		now := time.Now().UTC()
+
+		s, es, err := LoadSubscriptionForPolling(conn, now)
+		if err != nil {
+			return fmt.Errorf("cannot load subscription: %w", err)
+		} else if s == nil {
+			return nil
+		}
+
+		events, err = w.pollSubscription(conn, s, es, now)
+		if err != nil {
+			return err
+		}
+
+		processed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if len(events) > 0 {
+		w.worker.Cfg.NotificationChan <- events[0]
+	}
+
+	return processed, nil
+}
+
+func (w *Worker) pollSubscription(conn pg.Conn, s *Subscription, es *eventline.Subscription, now time.Time) (eventline.Events, error) {
+	w.Log.Info("polling repository %s/%s for subscription %q", s.Namespace,
+		s.Repository, s.Id)
+
+	params := es.Parameters.(*Parameters)
+
+	tags, err := w.listTags(conn, s, es)
+	if err != nil {
+		var retryAfter time.Duration
+
+		var rateLimitErr *RateLimitError
+		if errors.As(err, &rateLimitErr) {
+			retryAfter = rateLimitErr.RetryAfter
+		}
+
+		s.NbPollFailures++
+
+		delay := PollRetryDelay(params.PollIntervalDuration(),
+			s.NbPollFailures, retryAfter)
+		nextPollTime := now.Add(delay)
+		s.NextPollTime = &nextPollTime
+
+		w.Log.Error("cannot poll repository %s/%s for subscription %q "+
+			"(%d consecutive failures, next attempt in %v): %v",
+			s.Namespace, s.Repository, s.Id, s.NbPollFailures, delay, err)
+
+		if err := s.Update(conn); err != nil {
+			return nil, fmt.Errorf("cannot update subscription: %w", err)
+		}
+
+		return nil, nil
+	}
+
+	newTags := NewTags(s.KnownTags, tags)
+
+	events := make(eventline.Events, len(newTags))
+	for i, tag := range newTags {
+		eventData := NewTagEvent{
+			Namespace:      s.Namespace,
+			Repository:     s.Repository,
+			Tag:            tag.Name,
+			Digest:         tag.Digest,
+			LastUpdateTime: tag.LastUpdateTime,
+		}
+
+		events[i] = es.NewEvent("dockerhub", "new_tag", tag.LastUpdateTime,
+			&eventData)
+	}
+
+	if err := events.Insert(conn); err != nil {
+		return nil, fmt.Errorf("cannot insert events: %w", err)
+	}
+
+	knownTags := make([]string, len(tags))
+	for i, tag := range tags {
+		knownTags[i] = tag.Name
+	}
+
+	nextPollTime := now.Add(params.PollIntervalDuration())
+
+	s.KnownTags = knownTags
+	s.NbPollFailures = 0
+	s.NextPollTime = &nextPollTime
+
+	if err := s.Update(conn); err != nil {
+		return nil, fmt.Errorf("cannot update subscription: %w", err)
+	}
+
+	return events, nil
+}
+
+func (w *Worker) listTags(conn pg.Conn, s *Subscription, es *eventline.Subscription) ([]*Tag, error) {
+	var identity *eventline.Identity
+
+	if es.IdentityId != nil {
+		scope := eventline.NewProjectScope(*es.ProjectId)
+
+		identity = new(eventline.Identity)
+		if err := identity.Load(conn, *es.IdentityId, scope); err != nil {
+			return nil, fmt.Errorf("cannot load identity: %w", err)
+		}
+
+		if identity.Disabled {
+			return nil, &eventline.DisabledIdentityError{Name: identity.Name}
+		}
+
+		if err := identity.ResolveData(); err != nil {
+			return nil, err
+		}
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), PollTimeout)
+	defer cancel()
+
+	client, err := w.connector.NewClient(ctx, identity)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client: %w", err)
+	}
+
+	return client.ListTags(ctx, s.Namespace, s.Repository)
+}
+
+// NewTags returns the tags absent from the set of known tags, oldest first.
+// If known tags are nil, the repository has never been polled and no tag is
+// considered new.
+func NewTags(knownTags []string, tags []*Tag) []*Tag {
+	if knownTags == nil {
+		return nil
+	}
+
+	knownTagSet := make(map[string]struct{}, len(knownTags))
+	for _, name := range knownTags {
+		knownTagSet[name] = struct{}{}
+	}
+
+	// Tags are ordered by update time, most recent first
+	var newTags []*Tag
+	for i := len(tags) - 1; i >= 0; i-- {
+		if _, found := knownTagSet[tags[i].Name]; !found {
+			newTags = append(newTags, tags[i])
+		}
+	}
+
+	return newTags
+}
+
+// PollRetryDelay returns the delay before polling a repository again after
+// nbFailures consecutive failures. If DockerHub indicated when to retry, we
+// never try before.
+func PollRetryDelay(interval time.Duration, nbFailures int, retryAfter time.Duration) time.Duration {
+	delay := interval
+	for i := 0; i < nbFailures && delay < MaxPollRetryDelay; i++ {
+		delay *= 2
+	}
+
+	delay = min(delay, MaxPollRetryDelay)
+
+	return max(delay, retryAfter)
+}
@@ -0,0 +1,43 @@
+package dockerhub
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewTags(t *testing.T) {
+	assert := assert.New(t)
+
+	tags := []*Tag{{Name: "1.2.0"}, {Name: "1.1.0"}, {Name: "latest"}}
+
+	tagNames := func(tags []*Tag) []string {
+		var names []string
+		for _, tag := range tags {
+			names = append(names, tag.Name)
+		}
+		return names
+	}
+
+	assert.Empty(NewTags(nil, tags))
+	assert.Equal([]string{"latest", "1.1.0", "1.2.0"},
+		tagNames(NewTags([]string{}, tags)))
+	assert.Equal([]string{"1.2.0"},
+		tagNames(NewTags([]string{"latest", "1.1.0"}, tags)))
+	assert.Empty(NewTags([]string{"latest", "1.1.0", "1.2.0", "1.0.0"},
+		tags))
+}
+
+func TestPollRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	interval := 5 * time.Minute
+
+	assert.Equal(10*time.Minute, PollRetryDelay(interval, 1, 0))
+	assert.Equal(20*time.Minute, PollRetryDelay(interval, 2, 0))
+	assert.Equal(MaxPollRetryDelay, PollRetryDelay(interval, 10, 0))
+	assert.Equal(MaxPollRetryDelay, PollRetryDelay(interval, 1000, 0))
+	assert.Equal(time.Hour, PollRetryDelay(interval, 1, time.Hour))
+	assert.Equal(10*time.Minute, PollRetryDelay(interval, 1, time.Minute))
+}
@@ -0,0 +1,154 @@
+package email
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type ConnectorCfg struct {
+	PollInterval    int `json:"poll_interval"`     // seconds
+	MaxPollInterval int `json:"max_poll_interval"` // seconds
+	MaxPollDelay    int `json:"max_poll_delay"`    // seconds
+	Timeout         int `json:"timeout"`           // seconds
+}
+
+type Connector struct {
+	Def *eventline.ConnectorDef
+	Cfg *ConnectorCfg
+	Log *log.Logger
+}
+
+func NewConnector() *Connector {
+	c := &Connector{}
+
+	def := eventline.NewConnectorDef("email")
+
+	def.Worker = NewWorker(c)
+
+	def.AddIdentity(IMAPIdentityDef())
+
+	def.AddEvent(MessageEventDef())
+
+	c.Def = def
+
+	return c
+}
+
+func (cfg *ConnectorCfg) ValidateJSON(v *ejson.Validator) {
+	v.CheckIntMin("poll_interval", cfg.PollInterval, 1)
+	v.CheckIntMin("max_poll_interval", cfg.MaxPollInterval, cfg.PollInterval)
+	v.CheckIntMin("max_poll_delay", cfg.MaxPollDelay, cfg.PollInterval)
+	v.CheckIntMin("timeout", cfg.Timeout, 1)
+}
+
+func (c *Connector) Name() string {
+	return "email"
+}
+
+func (c *Connector) Definition() *eventline.ConnectorDef {
+	return c.Def
+}
+
+func (c *Connector) DefaultCfg() eventline.ConnectorCfg {
+	return &ConnectorCfg{
+		PollInterval:    60,
+		MaxPollInterval: 600,
+		MaxPollDelay:    3600,
+		Timeout:         30,
+	}
+}
+
+func (c *Connector) Init(ccfg eventline.ConnectorCfg, initData eventline.ConnectorInitData) error {
+	c.Cfg = ccfg.(*ConnectorCfg)
+	c.Log = initData.Log
+
+	return nil
+}
+
+func (c *Connector) Terminate() {
+}
+
+func (c *Connector) Subscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	params := sctx.Subscription.Parameters.(*Parameters)
+
+	identity, err := imapIdentity(sctx.Identity)
+	if err != nil {
+		return err
+	}
+
+	// We only want to react to messages received after the creation of the
+	// subscription, so we start after the last existing message.
+	client, err := c.connect(identity)
+	if err != nil {
+		return eventline.NewExternalSubscriptionError(err)
+	}
+	defer client.Logout()
+
+	mailbox, err := client.Select(params.MailboxName())
+	if err != nil {
+		return eventline.NewExternalSubscriptionError(err)
+	}
+
+	s := Subscription{
+		Id:           sctx.Subscription.Id,
+		UIDValidity:  int64(mailbox.UIDValidity),
+		LastUID:      int64(mailbox.UIDNext) - 1,
+		NextPoll:     time.Now().UTC(),
+		PollInterval: c.minPollInterval(params),
+	}
+
+	if err := s.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Connector) Unsubscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	if err := DeleteSubscription(conn, sctx.Subscription.Id); err != nil {
+		return fmt.Errorf("cannot delete subscription: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Connector) connect(identity *IMAPIdentity) (*IMAPClient, error) {
+	timeout := time.Duration(c.Cfg.Timeout) * time.Second
+
+	client, err := DialIMAP(identity.Address, identity.Security, timeout)
+	if err != nil {
+		return nil, err
+	}
+
+	if identity.Authentication == IMAPAuthenticationXOAuth2 {
+		err = client.AuthenticateXOAuth2(identity.Login, identity.Password)
+	} else {
+		err = client.Login(identity.Login, identity.Password)
+	}
+
+	if err != nil {
+		client.Close()
+		return nil, err
+	}
+
+	return client, nil
+}
+
+func imapIdentity(identity *eventline.Identity) (*IMAPIdentity, error) {
+	if identity == nil {
+		return nil, fmt.Errorf("missing imap identity")
+	}
+
+	data, ok := identity.Data.(*IMAPIdentity)
+	if !ok {
+		return nil, fmt.Errorf("identity %q is not an imap identity",
+			identity.Name)
+	}
+
+	return data, nil
+}
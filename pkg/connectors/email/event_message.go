@@ -0,0 +1,24 @@
+package email
+
+import (
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type MessageEvent struct {
+	Mailbox   string              `json:"mailbox"`
+	UID       uint32              `json:"uid"`
+	MessageId string              `json:"message_id,omitempty"`
+	Date      *time.Time          `json:"date,omitempty"`
+	From      []string            `json:"from,omitempty"`
+	To        []string            `json:"to,omitempty"`
+	Cc        []string            `json:"cc,omitempty"`
+	Subject   string              `json:"subject"`
+	Headers   map[string][]string `json:"headers"`
+	Body      string              `json:"body"`
+}
+
+func MessageEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("message", &MessageEvent{}, &Parameters{})
+}
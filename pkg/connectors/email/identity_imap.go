@@ -0,0 +1,109 @@
+package email
+
+import (
+	"net"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type IMAPSecurity string
+
+const (
+	IMAPSecurityTLS      IMAPSecurity = "tls"
+	IMAPSecuritySTARTTLS IMAPSecurity = "starttls"
+)
+
+var IMAPSecurityValues = []IMAPSecurity{
+	IMAPSecurityTLS,
+	IMAPSecuritySTARTTLS,
+}
+
+type IMAPAuthentication string
+
+const (
+	IMAPAuthenticationLogin   IMAPAuthentication = "login"
+	IMAPAuthenticationXOAuth2 IMAPAuthentication = "xoauth2"
+)
+
+var IMAPAuthenticationValues = []IMAPAuthentication{
+	IMAPAuthenticationLogin,
+	IMAPAuthenticationXOAuth2,
+}
+
+type IMAPIdentity struct {
+	Address        string             `json:"address"`
+	Security       IMAPSecurity       `json:"security"`
+	Authentication IMAPAuthentication `json:"authentication"`
+	Login          string             `json:"login"`
+	Password       string             `json:"password"`
+}
+
+func IMAPIdentityDef() *eventline.IdentityDef {
+	def := eventline.NewIdentityDef("imap", &IMAPIdentity{})
+	return def
+}
+
+func (i *IMAPIdentity) ValidateJSON(v *ejson.Validator) {
+	if v.CheckStringNotEmpty("address", i.Address) {
+		_, _, err := net.SplitHostPort(i.Address)
+		v.Check("address", err == nil, "invalid_address",
+			"address must be of the form <host>:<port>")
+	}
+
+	v.CheckStringValue("security", i.Security, IMAPSecurityValues)
+	v.CheckStringValue("authentication", i.Authentication,
+		IMAPAuthenticationValues)
+	v.CheckStringNotEmpty("login", i.Login)
+	v.CheckStringNotEmpty("password", i.Password)
+}
+
+func (i *IMAPIdentity) Def() *eventline.IdentityDataDef {
+	view := eventline.NewIdentityDataDef()
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:   "address",
+		Label: "Address",
+		Value: i.Address,
+		Type:  eventline.IdentityDataTypeString,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:                   "security",
+		Label:                 "Security",
+		Value:                 i.Security,
+		Type:                  eventline.IdentityDataTypeEnum,
+		EnumValues:            []string{"tls", "starttls"},
+		PreselectedEnumValues: []string{"tls"},
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:                   "authentication",
+		Label:                 "Authentication",
+		Value:                 i.Authentication,
+		Type:                  eventline.IdentityDataTypeEnum,
+		EnumValues:            []string{"login", "xoauth2"},
+		PreselectedEnumValues: []string{"login"},
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:   "login",
+		Label: "Login",
+		Value: i.Login,
+		Type:  eventline.IdentityDataTypeString,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:    "password",
+		Label:  "Password or access token",
+		Value:  i.Password,
+		Type:   eventline.IdentityDataTypeString,
+		Secret: true,
+	})
+
+	return view
+}
+
+func (i *IMAPIdentity) Environment() map[string]string {
+	return map[string]string{}
+}
@@ -0,0 +1,397 @@
+package email
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The IMAP client only implements the small subset of IMAP4rev1 (RFC 3501)
+// required to poll a mailbox: authentication, mailbox selection, searching
+// and fetching messages by UID, and setting flags.
+
+const maxIMAPLiteralSize = 32 * 1024 * 1024
+
+type IMAPError struct {
+	Command string
+	Status  string
+	Message string
+}
+
+func (err *IMAPError) Error() string {
+	return fmt.Sprintf("imap command %s failed: %s %s",
+		err.Command, err.Status, err.Message)
+}
+
+type IMAPClient struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	w       *bufio.Writer
+	timeout time.Duration
+
+	tagCounter int
+}
+
+type IMAPMailbox struct {
+	UIDValidity uint32
+	UIDNext     uint32
+}
+
+// imapResponse is an untagged response. Literals are extracted from the
+// response line and stored separately.
+type imapResponse struct {
+	Line     string
+	Literals [][]byte
+}
+
+func DialIMAP(address string, security IMAPSecurity, timeout time.Duration) (*IMAPClient, error) {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return nil, fmt.Errorf("invalid address %q: %w", address, err)
+	}
+
+	tlsCfg := tls.Config{ServerName: host}
+
+	dialer := net.Dialer{Timeout: timeout}
+
+	var conn net.Conn
+
+	if security == IMAPSecurityTLS {
+		conn, err = tls.DialWithDialer(&dialer, "tcp", address, &tlsCfg)
+	} else {
+		conn, err = dialer.Dial("tcp", address)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to %q: %w", address, err)
+	}
+
+	c := newIMAPClient(conn, timeout)
+
+	if err := c.readGreeting(); err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	if security == IMAPSecuritySTARTTLS {
+		if _, err := c.command("STARTTLS"); err != nil {
+			conn.Close()
+			return nil, err
+		}
+
+		tlsConn := tls.Client(conn, &tlsCfg)
+		tlsConn.SetDeadline(time.Now().Add(timeout))
+
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, fmt.Errorf("cannot establish tls connection: %w", err)
+		}
+
+		c = newIMAPClient(tlsConn, timeout)
+	}
+
+	return c, nil
+}
+
+func newIMAPClient(conn net.Conn, timeout time.Duration) *IMAPClient {
+	return &IMAPClient{
+		conn:    conn,
+		r:       bufio.NewReader(conn),
+		w:       bufio.NewWriter(conn),
+		timeout: timeout,
+	}
+}
+
+func (c *IMAPClient) Close() error {
+	return c.conn.Close()
+}
+
+func (c *IMAPClient) Logout() {
+	c.command("LOGOUT")
+	c.Close()
+}
+
+func (c *IMAPClient) Login(login, password string) error {
+	_, err := c.command("LOGIN " + imapQuote(login) + " " + imapQuote(password))
+	return err
+}
+
+// AuthenticateXOAuth2 authenticates with an OAuth2 access token using the
+// XOAUTH2 SASL mechanism supported by most large email providers.
+func (c *IMAPClient) AuthenticateXOAuth2(login, token string) error {
+	data := "user=" + login + "\x01auth=Bearer " + token + "\x01\x01"
+	encodedData := base64.StdEncoding.EncodeToString([]byte(data))
+
+	_, err := c.command("AUTHENTICATE XOAUTH2 " + encodedData)
+	return err
+}
+
+func (c *IMAPClient) Select(name string) (*IMAPMailbox, error) {
+	responses, err := c.command("SELECT " + imapQuote(name))
+	if err != nil {
+		return nil, err
+	}
+
+	var mailbox IMAPMailbox
+
+	for _, r := range responses {
+		if value, found := imapResponseCode(r.Line, "UIDVALIDITY"); found {
+			i, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid uid validity %q", value)
+			}
+
+			mailbox.UIDValidity = uint32(i)
+		} else if value, found := imapResponseCode(r.Line, "UIDNEXT"); found {
+			i, err := strconv.ParseUint(value, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid next uid %q", value)
+			}
+
+			mailbox.UIDNext = uint32(i)
+		}
+	}
+
+	if mailbox.UIDValidity == 0 || mailbox.UIDNext == 0 {
+		return nil, fmt.Errorf("missing uid information for mailbox %q", name)
+	}
+
+	return &mailbox, nil
+}
+
+// SearchUIDsAfter returns the UIDs of all messages whose UID is strictly
+// greater than uid.
+func (c *IMAPClient) SearchUIDsAfter(uid uint32) ([]uint32, error) {
+	responses, err := c.command(fmt.Sprintf("UID SEARCH UID %d:*", uid+1))
+	if err != nil {
+		return nil, err
+	}
+
+	var uids []uint32
+
+	for _, r := range responses {
+		fields := strings.Fields(r.Line)
+		if len(fields) < 2 || !strings.EqualFold(fields[1], "SEARCH") {
+			continue
+		}
+
+		for _, field := range fields[2:] {
+			i, err := strconv.ParseUint(field, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("invalid uid %q", field)
+			}
+
+			// "n:*" always matches the last message, even if its UID is
+			// lower than n.
+			if uint32(i) > uid {
+				uids = append(uids, uint32(i))
+			}
+		}
+	}
+
+	return uids, nil
+}
+
+// FetchMessage returns the raw content of a message without setting the
+// \Seen flag.
+func (c *IMAPClient) FetchMessage(uid uint32) ([]byte, error) {
+	responses, err := c.command(fmt.Sprintf("UID FETCH %d (BODY.PEEK[])", uid))
+	if err != nil {
+		return nil, err
+	}
+
+	for _, r := range responses {
+		if strings.Contains(r.Line, "FETCH") && len(r.Literals) > 0 {
+			return r.Literals[0], nil
+		}
+	}
+
+	return nil, fmt.Errorf("message %d not found", uid)
+}
+
+func (c *IMAPClient) MarkSeen(uid uint32) error {
+	_, err := c.command(fmt.Sprintf("UID STORE %d +FLAGS.SILENT (\\Seen)",
+		uid))
+	return err
+}
+
+func (c *IMAPClient) readGreeting() error {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	line, err := c.readLine()
+	if err != nil {
+		return fmt.Errorf("cannot read greeting: %w", err)
+	}
+
+	if !strings.HasPrefix(line, "* OK") && !strings.HasPrefix(line, "* PREAUTH") {
+		return fmt.Errorf("invalid greeting %q", line)
+	}
+
+	return nil
+}
+
+func (c *IMAPClient) command(cmd string) ([]imapResponse, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+
+	c.tagCounter++
+	tag := "a" + strconv.Itoa(c.tagCounter)
+
+	name, _, _ := strings.Cut(cmd, " ")
+
+	if _, err := c.w.WriteString(tag + " " + cmd + "\r\n"); err != nil {
+		return nil, fmt.Errorf("cannot send command: %w", err)
+	}
+
+	if err := c.w.Flush(); err != nil {
+		return nil, fmt.Errorf("cannot send command: %w", err)
+	}
+
+	var responses []imapResponse
+
+	for {
+		r, err := c.readResponse()
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case strings.HasPrefix(r.Line, "* "):
+			responses = append(responses, *r)
+
+		case strings.HasPrefix(r.Line, "+"):
+			// Continuation request; the only command we send which can
+			// trigger one is AUTHENTICATE when credentials are rejected, in
+			// which case we send an empty response to obtain the final
+			// status.
+			if _, err := c.w.WriteString("\r\n"); err != nil {
+				return nil, fmt.Errorf("cannot send response: %w", err)
+			}
+
+			if err := c.w.Flush(); err != nil {
+				return nil, fmt.Errorf("cannot send response: %w", err)
+			}
+
+		case strings.HasPrefix(r.Line, tag+" "):
+			status, message, _ := strings.Cut(r.Line[len(tag)+1:], " ")
+
+			if !strings.EqualFold(status, "OK") {
+				return nil, &IMAPError{
+					Command: name,
+					Status:  status,
+					Message: message,
+				}
+			}
+
+			return responses, nil
+
+		default:
+			return nil, fmt.Errorf("unexpected response %q", r.Line)
+		}
+	}
+}
+
+func (c *IMAPClient) readResponse() (*imapResponse, error) {
+	var r imapResponse
+	var buf strings.Builder
+
+	for {
+		line, err := c.readLine()
+		if err != nil {
+			return nil, err
+		}
+
+		buf.WriteString(line)
+
+		size, found := imapLiteralSize(line)
+		if !found {
+			break
+		}
+
+		if size > maxIMAPLiteralSize {
+			return nil, fmt.Errorf("literal too large (%d bytes)", size)
+		}
+
+		literal := make([]byte, size)
+		if _, err := io.ReadFull(c.r, literal); err != nil {
+			return nil, fmt.Errorf("cannot read literal: %w", err)
+		}
+
+		r.Literals = append(r.Literals, literal)
+	}
+
+	r.Line = buf.String()
+
+	return &r, nil
+}
+
+func (c *IMAPClient) readLine() (string, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return "", fmt.Errorf("cannot read response: %w", err)
+	}
+
+	return strings.TrimRight(line, "\r\n"), nil
+}
+
+// imapLiteralSize returns the size of the literal announced at the end of a
+// line, e.g. "* 1 FETCH (UID 42 BODY[] {1234}".
+func imapLiteralSize(line string) (int, bool) {
+	if !strings.HasSuffix(line, "}") {
+		return 0, false
+	}
+
+	start := strings.LastIndexByte(line, '{')
+	if start == -1 {
+		return 0, false
+	}
+
+	size, err := strconv.Atoi(line[start+1 : len(line)-1])
+	if err != nil || size < 0 {
+		return 0, false
+	}
+
+	return size, true
+}
+
+// imapResponseCode extracts the value of a response code, e.g. "12" for
+// "* OK [UIDNEXT 12] Predicted next UID".
+func imapResponseCode(line, code string) (string, bool) {
+	start := strings.Index(line, "["+code+" ")
+	if start == -1 {
+		return "", false
+	}
+
+	value := line[start+len(code)+2:]
+
+	end := strings.IndexByte(value, ']')
+	if end == -1 {
+		return "", false
+	}
+
+	return value[:end], true
+}
+
+func imapQuote(s string) string {
+	var buf bytes.Buffer
+
+	buf.WriteByte('"')
+
+	for _, c := range []byte(s) {
+		if c == '"' || c == '\\' {
+			buf.WriteByte('\\')
+		}
+
+		buf.WriteByte(c)
+	}
+
+	buf.WriteByte('"')
+
+	return buf.String()
+}
@@ -0,0 +1,179 @@
+package email
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/mail"
+	"strings"
+)
+
+// The body of messages is truncated to keep events reasonably small.
+const maxBodySize = 1024 * 1024
+
+var wordDecoder = mime.WordDecoder{
+	// We do not support charset conversion: text in other character sets is
+	// left encoded.
+	CharsetReader: func(charset string, r io.Reader) (io.Reader, error) {
+		if strings.EqualFold(charset, "us-ascii") {
+			return r, nil
+		}
+
+		return nil, fmt.Errorf("unsupported charset %q", charset)
+	},
+}
+
+func ParseMessage(data []byte, mailbox string, uid uint32) (*MessageEvent, error) {
+	msg, err := mail.ReadMessage(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read message: %w", err)
+	}
+
+	event := MessageEvent{
+		Mailbox:   mailbox,
+		UID:       uid,
+		MessageId: strings.Trim(msg.Header.Get("Message-Id"), "<>"),
+		Subject:   decodeHeader(msg.Header.Get("Subject")),
+		Headers:   make(map[string][]string),
+	}
+
+	for name, values := range msg.Header {
+		decodedValues := make([]string, len(values))
+		for i, value := range values {
+			decodedValues[i] = decodeHeader(value)
+		}
+
+		event.Headers[name] = decodedValues
+	}
+
+	if date, err := msg.Header.Date(); err == nil {
+		date = date.UTC()
+		event.Date = &date
+	}
+
+	event.From = headerAddresses(msg.Header, "From")
+	event.To = headerAddresses(msg.Header, "To")
+	event.Cc = headerAddresses(msg.Header, "Cc")
+
+	contentType := msg.Header.Get("Content-Type")
+	encoding := msg.Header.Get("Content-Transfer-Encoding")
+
+	body, err := textBody(contentType, encoding, msg.Body)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read body: %w", err)
+	}
+
+	if len(body) > maxBodySize {
+		body = body[:maxBodySize]
+	}
+
+	event.Body = string(body)
+
+	return &event, nil
+}
+
+func decodeHeader(value string) string {
+	decodedValue, err := wordDecoder.DecodeHeader(value)
+	if err != nil {
+		return value
+	}
+
+	return decodedValue
+}
+
+func headerAddresses(header mail.Header, name string) []string {
+	addresses, err := header.AddressList(name)
+	if err != nil {
+		return nil
+	}
+
+	addressStrings := make([]string, len(addresses))
+	for i, address := range addresses {
+		addressStrings[i] = address.Address
+	}
+
+	return addressStrings
+}
+
+// textBody returns the first text/plain part of a message, or the body
+// itself for non-multipart messages.
+func textBody(contentType, encoding string, r io.Reader) ([]byte, error) {
+	mediaType := "text/plain"
+	var params map[string]string
+
+	if contentType != "" {
+		var err error
+
+		mediaType, params, err = mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, fmt.Errorf("invalid content type %q: %w",
+				contentType, err)
+		}
+	}
+
+	if strings.HasPrefix(mediaType, "multipart/") {
+		mr := multipart.NewReader(r, params["boundary"])
+
+		for {
+			part, err := mr.NextRawPart()
+			if err == io.EOF {
+				return nil, nil
+			} else if err != nil {
+				return nil, err
+			}
+
+			body, err := textBody(part.Header.Get("Content-Type"),
+				part.Header.Get("Content-Transfer-Encoding"), part)
+			if err != nil {
+				return nil, err
+			} else if body != nil {
+				return body, nil
+			}
+		}
+	}
+
+	if mediaType != "text/plain" {
+		return nil, nil
+	}
+
+	switch strings.ToLower(encoding) {
+	case "quoted-printable":
+		r = quotedprintable.NewReader(r)
+	case "base64":
+		r = base64.NewDecoder(base64.StdEncoding,
+			&newlineStrippingReader{r: r})
+	}
+
+	body, err := io.ReadAll(io.LimitReader(r, maxBodySize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if body == nil {
+		body = []byte{}
+	}
+
+	return body, nil
+}
+
+type newlineStrippingReader struct {
+	r io.Reader
+}
+
+func (r *newlineStrippingReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+
+	j := 0
+	for i := 0; i < n; i++ {
+		if p[i] != '\r' && p[i] != '\n' {
+			p[j] = p[i]
+			j++
+		}
+	}
+
+	return j, err
+}
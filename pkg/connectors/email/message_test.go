@@ -0,0 +1,96 @@
+package email
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseMessage(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	data := strings.Join([]string{
+		"From: Alice <alice@example.com>",
+		"To: bob@example.com, carol@example.com",
+		"Subject: =?utf-8?q?Hello_world?=",
+		"Message-Id: <42@example.com>",
+		"Date: Mon, 02 Jan 2023 15:04:05 +0200",
+		"Content-Type: multipart/alternative; boundary=\"b\"",
+		"",
+		"--b",
+		"Content-Type: text/html",
+		"",
+		"<p>Hello</p>",
+		"--b",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Transfer-Encoding: quoted-printable",
+		"",
+		"Hello =3D world",
+		"--b--",
+		"",
+	}, "\r\n")
+
+	event, err := ParseMessage([]byte(data), "INBOX", 12)
+	require.NoError(err)
+
+	assert.Equal("INBOX", event.Mailbox)
+	assert.Equal(uint32(12), event.UID)
+	assert.Equal("42@example.com", event.MessageId)
+	assert.Equal("Hello world", event.Subject)
+	assert.Equal([]string{"alice@example.com"}, event.From)
+	assert.Equal([]string{"bob@example.com", "carol@example.com"}, event.To)
+	assert.Nil(event.Cc)
+	assert.Equal("Hello = world", event.Body)
+
+	if assert.NotNil(event.Date) {
+		assert.Equal("2023-01-02T13:04:05Z",
+			event.Date.Format("2006-01-02T15:04:05Z07:00"))
+	}
+}
+
+func TestParseMessageBase64(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	data := strings.Join([]string{
+		"From: alice@example.com",
+		"Subject: test",
+		"Content-Transfer-Encoding: base64",
+		"",
+		"SGVsbG8g",
+		"d29ybGQ=",
+		"",
+	}, "\r\n")
+
+	event, err := ParseMessage([]byte(data), "INBOX", 1)
+	require.NoError(err)
+
+	assert.Equal("Hello world", event.Body)
+}
+
+func TestIMAPResponseCode(t *testing.T) {
+	assert := assert.New(t)
+
+	value, found := imapResponseCode("* OK [UIDVALIDITY 3857529045] UIDs valid",
+		"UIDVALIDITY")
+	assert.True(found)
+	assert.Equal("3857529045", value)
+
+	_, found = imapResponseCode("* OK [UIDNEXT 4392] Predicted next UID",
+		"UIDVALIDITY")
+	assert.False(found)
+}
+
+func TestIMAPLiteralSize(t *testing.T) {
+	assert := assert.New(t)
+
+	size, found := imapLiteralSize("* 1 FETCH (UID 12 BODY[] {342}")
+	assert.True(found)
+	assert.Equal(342, size)
+
+	_, found = imapLiteralSize("* 1 FETCH (UID 12 FLAGS (\\Seen))")
+	assert.False(found)
+}
@@ -0,0 +1,78 @@
+package email
+
+import (
+	"regexp"
+
+	"go.n16f.net/ejson"
+)
+
+type Parameters struct {
+	Mailbox string `json:"mailbox,omitempty"`
+	From    string `json:"from,omitempty"`
+	Subject string `json:"subject,omitempty"`
+
+	MinPollInterval int `json:"min_poll_interval,omitempty"` // seconds
+	MaxPollInterval int `json:"max_poll_interval,omitempty"` // seconds
+}
+
+func (p *Parameters) ValidateJSON(v *ejson.Validator) {
+	if p.From != "" {
+		_, err := regexp.Compile(p.From)
+		v.Check("from", err == nil, "invalid_regexp",
+			"invalid regular expression: %v", err)
+	}
+
+	if p.Subject != "" {
+		_, err := regexp.Compile(p.Subject)
+		v.Check("subject", err == nil, "invalid_regexp",
+			"invalid regular expression: %v", err)
+	}
+
+	if p.MinPollInterval != 0 {
+		v.CheckIntMin("min_poll_interval", p.MinPollInterval, 1)
+	}
+
+	if p.MaxPollInterval != 0 {
+		v.CheckIntMin("max_poll_interval", p.MaxPollInterval,
+			max(p.MinPollInterval, 1))
+	}
+}
+
+func (p *Parameters) MailboxName() string {
+	if p.Mailbox == "" {
+		return "INBOX"
+	}
+
+	return p.Mailbox
+}
+
+// Match indicates whether a message matches the sender and subject patterns
+// of the subscription. The sender pattern is matched against each address of
+// the From header field.
+func (p *Parameters) Match(event *MessageEvent) bool {
+	if p.From != "" {
+		re := regexp.MustCompile(p.From)
+
+		fromMatch := false
+		for _, address := range event.From {
+			if re.MatchString(address) {
+				fromMatch = true
+				break
+			}
+		}
+
+		if !fromMatch {
+			return false
+		}
+	}
+
+	if p.Subject != "" {
+		re := regexp.MustCompile(p.Subject)
+
+		if !re.MatchString(event.Subject) {
+			return false
+		}
+	}
+
+	return true
+}
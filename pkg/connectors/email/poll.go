@@ -0,0 +1,35 @@
+package email
+
+// Mailboxes which rarely receive messages do not need to be polled as often
+// as busy ones. The interval between two successful polls of a subscription
+// is doubled after each poll which did not produce any event, up to a maximum,
+// and halved after each poll which did, down to a minimum. Both bounds default
+// to the connector configuration and can be set for each subscription.
+
+func (c *Connector) minPollInterval(params *Parameters) int {
+	if params.MinPollInterval > 0 {
+		return params.MinPollInterval
+	}
+
+	return c.Cfg.PollInterval
+}
+
+func (c *Connector) maxPollInterval(params *Parameters) int {
+	if params.MaxPollInterval > 0 {
+		return max(params.MaxPollInterval, c.minPollInterval(params))
+	}
+
+	return max(c.Cfg.MaxPollInterval, c.minPollInterval(params))
+}
+
+// NextPollInterval returns the interval to use after a successful poll given
+// the current interval and whether the poll produced events or not.
+func NextPollInterval(interval, minInterval, maxInterval int, newEvents bool) int {
+	if newEvents {
+		interval /= 2
+	} else {
+		interval *= 2
+	}
+
+	return min(max(interval, minInterval), maxInterval)
+}
@@ -0,0 +1,33 @@
+package email
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNextPollInterval(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		interval  int
+		newEvents bool
+		next      int
+	}{
+		{0, false, 60},
+		{0, true, 60},
+		{60, false, 120},
+		{120, false, 240},
+		{400, false, 600},
+		{600, false, 600},
+		{600, true, 300},
+		{100, true, 60},
+		{60, true, 60},
+	}
+
+	for _, test := range tests {
+		next := NextPollInterval(test.interval, 60, 600, test.newEvents)
+		assert.Equal(test.next, next,
+			"interval: %d, new events: %v", test.interval, test.newEvents)
+	}
+}
@@ -0,0 +1,92 @@
+package email
+
+import (
+	"errors"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type Subscription struct {
+	Id          eventline.Id
+	UIDValidity int64
+	LastUID     int64
+	NextPoll    time.Time
+	NbFailures  int
+
+	// The current delay between two successful polls; it grows while
+	// polls do not produce any event and shrinks when they do.
+	PollInterval int // seconds
+}
+
+func LoadSubscriptionForProcessing(conn pg.Conn) (*Subscription, *eventline.Subscription, error) {
+	now := time.Now().UTC()
+
+	query := `
+SELECT es.id, s.uid_validity, s.last_uid, s.next_poll, s.nb_failures,
+       s.poll_interval
+  FROM subscriptions AS es
+  JOIN c_email_subscriptions AS s ON s.id = es.id
+  WHERE es.status = 'active'
+    AND s.next_poll <= $1
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+`
+	var s Subscription
+	var es eventline.Subscription
+
+	err := pg.QueryObject(conn, &s, query, now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil, nil
+	} else if err != nil {
+		return nil, nil, err
+	}
+
+	if err := es.Load(conn, s.Id); err != nil {
+		return nil, nil, err
+	}
+
+	return &s, &es, nil
+}
+
+func (s *Subscription) Insert(conn pg.Conn) error {
+	query := `
+INSERT INTO c_email_subscriptions
+    (id, uid_validity, last_uid, next_poll, nb_failures, poll_interval)
+  VALUES
+    ($1, $2, $3, $4, $5, $6);
+`
+	return pg.Exec(conn, query,
+		s.Id, s.UIDValidity, s.LastUID, s.NextPoll, s.NbFailures,
+		s.PollInterval)
+}
+
+func (s *Subscription) Update(conn pg.Conn) error {
+	query := `
+UPDATE c_email_subscriptions SET
+    uid_validity = $2,
+    last_uid = $3,
+    next_poll = $4,
+    nb_failures = $5,
+    poll_interval = $6
+  WHERE id = $1
+`
+	return pg.Exec(conn, query,
+		s.Id, s.UIDValidity, s.LastUID, s.NextPoll, s.NbFailures,
+		s.PollInterval)
+}
+
+func DeleteSubscription(conn pg.Conn, id eventline.Id) error {
+	query := `
+DELETE FROM c_email_subscriptions
+  WHERE id = $1;
+`
+	return pg.Exec(conn, query, id)
+}
+
+func (s *Subscription) FromRow(row pgx.Row) error {
+	return row.Scan(&s.Id, &s.UIDValidity, &s.LastUID, &s.NextPoll,
+		&s.NbFailures, &s.PollInterval)
+}
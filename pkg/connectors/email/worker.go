@@ -0,0 +1,214 @@
+package email
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// The number of messages processed for a subscription in a single poll. Other
+// messages will be processed during the next poll.
+const maxMessagesPerPoll = 50
+
+type Worker struct {
+	Log *log.Logger
+	Pg  *pg.Client
+
+	connector *Connector
+	worker    *eventline.Worker
+}
+
+func NewWorker(c *Connector) *Worker {
+	return &Worker{
+		connector: c,
+	}
+}
+
+func (w *Worker) Init(ew *eventline.Worker) {
+	w.Log = ew.Log
+	w.Pg = ew.Pg
+
+	w.worker = ew
+}
+
+func (w *Worker) Start() error {
+	return nil
+}
+
+func (w *Worker) Stop() {
+}
+
+func (w *Worker) ProcessJob() (bool, error) {
+	var processed bool
+	var events []*eventline.Event
+
+	err := w.Pg.WithTx(func(conn pg.Conn) error {
+		s, es, err := LoadSubscriptionForProcessing(conn)
+		if err != nil {
+			return fmt.Errorf("cannot load subscription: %w", err)
+		} else if s == nil {
+			return nil
+		}
+
+		processed = true
+
+		params := es.Parameters.(*Parameters)
+
+		var healthErr error
+
+		events, err = w.processSubscription(conn, s, es)
+		if err != nil {
+			w.Log.Error("cannot process subscription %q: %v", s.Id, err)
+			s.NbFailures++
+
+			now := time.Now().UTC()
+			healthErr = eventline.RecordSubscriptionError(conn, s.Id, err, now)
+		} else {
+			s.NbFailures = 0
+
+			s.PollInterval = NextPollInterval(s.PollInterval,
+				w.connector.minPollInterval(params),
+				w.connector.maxPollInterval(params), len(events) > 0)
+
+			healthErr = eventline.ClearSubscriptionErrors(conn,
+				eventline.Ids{s.Id})
+		}
+
+		if healthErr != nil {
+			return fmt.Errorf("cannot update subscription health: %w",
+				healthErr)
+		}
+
+		s.NextPoll = w.nextPoll(s.PollInterval, s.NbFailures)
+
+		if err := s.Update(conn); err != nil {
+			return fmt.Errorf("cannot update subscription: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, event := range events {
+		w.worker.Cfg.NotificationChan <- event
+	}
+
+	return processed, nil
+}
+
+func (w *Worker) processSubscription(conn pg.Conn, s *Subscription, es *eventline.Subscription) ([]*eventline.Event, error) {
+	w.Log.Info("processing subscription %q", s.Id)
+
+	params := es.Parameters.(*Parameters)
+
+	var sctx eventline.SubscriptionContext
+	if err := sctx.Load(conn, es); err != nil {
+		return nil, err
+	}
+
+	identity, err := imapIdentity(sctx.Identity)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := w.connector.connect(identity)
+	if err != nil {
+		return nil, err
+	}
+	defer client.Logout()
+
+	mailboxName := params.MailboxName()
+
+	mailbox, err := client.Select(mailboxName)
+	if err != nil {
+		return nil, err
+	}
+
+	// If the UID validity value changed, UIDs previously known are not valid
+	// anymore (RFC 3501 2.3.1.1). Since we cannot know which messages were
+	// already processed, we restart after the last existing message.
+	if int64(mailbox.UIDValidity) != s.UIDValidity {
+		w.Log.Info("uid validity of mailbox %q changed for subscription %q",
+			mailboxName, s.Id)
+
+		s.UIDValidity = int64(mailbox.UIDValidity)
+		s.LastUID = int64(mailbox.UIDNext) - 1
+
+		return nil, nil
+	}
+
+	uids, err := client.SearchUIDsAfter(uint32(s.LastUID))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(uids) > maxMessagesPerPoll {
+		uids = uids[:maxMessagesPerPoll]
+	}
+
+	var events []*eventline.Event
+
+	for _, uid := range uids {
+		data, err := client.FetchMessage(uid)
+		if err != nil {
+			return events, err
+		}
+
+		s.LastUID = int64(uid)
+
+		message, err := ParseMessage(data, mailboxName, uid)
+		if err != nil {
+			w.Log.Error("cannot parse message %d in mailbox %q: %v",
+				uid, mailboxName, err)
+			continue
+		}
+
+		if !params.Match(message) {
+			continue
+		}
+
+		etime := time.Now().UTC()
+		if message.Date != nil {
+			etime = *message.Date
+		}
+
+		event := es.NewEvent("email", "message", &etime, message)
+		if err := event.Insert(conn); err != nil {
+			return events, fmt.Errorf("cannot insert event: %w", err)
+		}
+
+		events = append(events, event)
+
+		if err := client.MarkSeen(uid); err != nil {
+			return events, err
+		}
+	}
+
+	return events, nil
+}
+
+func (w *Worker) nextPoll(pollInterval, nbFailures int) time.Time {
+	cfg := w.connector.Cfg
+
+	if pollInterval == 0 {
+		pollInterval = cfg.PollInterval
+	}
+
+	delay := time.Duration(pollInterval) * time.Second
+	maxDelay := time.Duration(cfg.MaxPollDelay) * time.Second
+
+	for i := 0; i < nbFailures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > maxDelay {
+		delay = maxDelay
+	}
+
+	return time.Now().UTC().Add(delay)
+}
@@ -5,8 +5,13 @@ import (
 	"go.n16f.net/ejson"
 )
 
+// APIKeyIdentity contains a primary key and optionally a secondary key, so
+// that keys can be rotated without downtime: the new key becomes the primary
+// key while the previous one stays valid as secondary key until it is
+// retired. The primary key is the one presented by default.
 type APIKeyIdentity struct {
-	Key string `json:"key"`
+	Key          string `json:"key"`
+	SecondaryKey string `json:"secondary_key,omitempty"`
 }
 
 func APIKeyIdentityDef() *eventline.IdentityDef {
@@ -16,6 +21,11 @@ func APIKeyIdentityDef() *eventline.IdentityDef {
 
 func (i *APIKeyIdentity) ValidateJSON(v *ejson.Validator) {
 	v.CheckStringNotEmpty("key", i.Key)
+
+	if i.SecondaryKey != "" {
+		v.Check("secondary_key", i.SecondaryKey != i.Key, "duplicate_key",
+			"secondary key must be different from the primary key")
+	}
 }
 
 func (i *APIKeyIdentity) Def() *eventline.IdentityDataDef {
@@ -30,9 +40,57 @@ func (i *APIKeyIdentity) Def() *eventline.IdentityDataDef {
 		Secret:   true,
 	})
 
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "secondary_key",
+		Label:    "Secondary API key",
+		Value:    i.SecondaryKey,
+		Type:     eventline.IdentityDataTypeString,
+		Optional: true,
+		Verbatim: true,
+		Secret:   true,
+	})
+
 	return view
 }
 
 func (i *APIKeyIdentity) Environment() map[string]string {
 	return map[string]string{}
 }
+
+// Keys returns all valid keys, the primary key first.
+func (i *APIKeyIdentity) Keys() []string {
+	keys := []string{i.Key}
+
+	if i.SecondaryKey != "" {
+		keys = append(keys, i.SecondaryKey)
+	}
+
+	return keys
+}
+
+// RotateKey makes a new key the primary key; the current primary key becomes
+// the secondary key, replacing any previous secondary key.
+func (i *APIKeyIdentity) RotateKey(key string) {
+	i.SecondaryKey = i.Key
+	i.Key = key
+}
+
+// RetireSecondaryKey removes the secondary key once the grace period of a
+// rotation is over.
+func (i *APIKeyIdentity) RetireSecondaryKey() {
+	i.SecondaryKey = ""
+}
+
+// RetirePrimaryKey removes the primary key and makes the secondary key the
+// primary one, e.g. to revert a rotation. The primary key cannot be retired
+// if there is no secondary key.
+func (i *APIKeyIdentity) RetirePrimaryKey() error {
+	if i.SecondaryKey == "" {
+		return eventline.ErrMissingSecondaryKey
+	}
+
+	i.Key = i.SecondaryKey
+	i.SecondaryKey = ""
+
+	return nil
+}
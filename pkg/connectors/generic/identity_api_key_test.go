@@ -0,0 +1,44 @@
+package generic
+
+import (
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/assert"
+	"go.n16f.net/ejson"
+)
+
+func TestAPIKeyIdentityRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	validate := func(i *APIKeyIdentity) error {
+		v := ejson.NewValidator()
+		i.ValidateJSON(v)
+		return v.Error()
+	}
+
+	i := APIKeyIdentity{Key: "key-1"}
+	assert.NoError(validate(&i))
+	assert.Equal([]string{"key-1"}, i.Keys())
+	assert.ErrorIs(i.RetirePrimaryKey(), eventline.ErrMissingSecondaryKey)
+	assert.Equal("key-1", i.Key)
+
+	i.RotateKey("key-2")
+	assert.NoError(validate(&i))
+	assert.Equal("key-2", i.Key)
+	assert.Equal([]string{"key-2", "key-1"}, i.Keys())
+
+	i.RetireSecondaryKey()
+	assert.NoError(validate(&i))
+	assert.Equal([]string{"key-2"}, i.Keys())
+
+	i.RotateKey("key-3")
+	assert.NoError(i.RetirePrimaryKey())
+	assert.NoError(validate(&i))
+	assert.Equal([]string{"key-2"}, i.Keys())
+
+	assert.Error(validate(&APIKeyIdentity{}))
+	assert.Error(validate(&APIKeyIdentity{SecondaryKey: "key-1"}))
+	assert.Error(validate(&APIKeyIdentity{Key: "key-1",
+		SecondaryKey: "key-1"}))
+}
@@ -1,8 +1,19 @@
 package generic
 
 import (
+	"bytes"
+	"errors"
+	"fmt"
+	"strings"
+
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/ejson"
+	"golang.org/x/crypto/openpgp"
+)
+
+var (
+	ErrMissingGPGPrivateKey = errors.New("missing gpg private key")
+	ErrMissingGPGKey        = errors.New("missing gpg key")
 )
 
 type GPGKeyIdentity struct {
@@ -61,3 +72,96 @@ func (i *GPGKeyIdentity) Def() *eventline.IdentityDataDef {
 func (i *GPGKeyIdentity) Environment() map[string]string {
 	return map[string]string{}
 }
+
+// Sign returns an armored detached signature of a payload. If the private key
+// is protected, it is decrypted with the password of the identity.
+func (i *GPGKeyIdentity) Sign(data []byte) ([]byte, error) {
+	if i.PrivateKey == "" {
+		return nil, ErrMissingGPGPrivateKey
+	}
+
+	entity, err := i.privateEntity()
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	err = openpgp.ArmoredDetachSign(&buf, entity, bytes.NewReader(data), nil)
+	if err != nil {
+		return nil, fmt.Errorf("cannot sign data: %w", err)
+	}
+
+	return buf.Bytes(), nil
+}
+
+// Verify checks a detached signature, either armored or binary, of a payload.
+// The public key is used if the identity has one; the private key is used
+// otherwise.
+func (i *GPGKeyIdentity) Verify(data, signature []byte) error {
+	key := i.PublicKey
+	if key == "" {
+		key = i.PrivateKey
+	}
+
+	if key == "" {
+		return ErrMissingGPGKey
+	}
+
+	keyRing, err := openpgp.ReadArmoredKeyRing(strings.NewReader(key))
+	if err != nil {
+		return fmt.Errorf("cannot read key: %w", err)
+	}
+
+	signed := bytes.NewReader(data)
+	sigReader := bytes.NewReader(signature)
+
+	if bytes.HasPrefix(bytes.TrimSpace(signature), []byte("-----BEGIN")) {
+		_, err = openpgp.CheckArmoredDetachedSignature(keyRing, signed,
+			sigReader)
+	} else {
+		_, err = openpgp.CheckDetachedSignature(keyRing, signed, sigReader)
+	}
+	if err != nil {
+		return fmt.Errorf("invalid signature: %w", err)
+	}
+
+	return nil
+}
+
+func (i *GPGKeyIdentity) privateEntity() (*openpgp.Entity, error) {
+	r := strings.NewReader(i.PrivateKey)
+
+	entities, err := openpgp.ReadArmoredKeyRing(r)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read private key: %w", err)
+	}
+
+	if len(entities) != 1 {
+		return nil, fmt.Errorf("private key contains %d entities instead "+
+			"of one", len(entities))
+	}
+
+	entity := entities[0]
+	if entity.PrivateKey == nil {
+		return nil, ErrMissingGPGPrivateKey
+	}
+
+	passphrase := []byte(i.Password)
+
+	if entity.PrivateKey.Encrypted {
+		if err := entity.PrivateKey.Decrypt(passphrase); err != nil {
+			return nil, fmt.Errorf("cannot decrypt private key: %w", err)
+		}
+	}
+
+	for _, subkey := range entity.Subkeys {
+		if subkey.PrivateKey != nil && subkey.PrivateKey.Encrypted {
+			if err := subkey.PrivateKey.Decrypt(passphrase); err != nil {
+				return nil, fmt.Errorf("cannot decrypt private subkey: %w",
+					err)
+			}
+		}
+	}
+
+	return entity, nil
+}
@@ -230,6 +230,10 @@ func (i *OAuth2Identity) Refresh(httpClient *http.Client) error {
 	return nil
 }
 
+func (i *OAuth2Identity) ExpiresBefore(t time.Time) bool {
+	return i.ExpirationTime != nil && i.ExpirationTime.Before(t)
+}
+
 func (i *OAuth2Identity) RefreshTime() time.Time {
 	now := time.Now().UTC()
 
@@ -254,3 +258,7 @@ func (i *OAuth2Identity) newOAuth2Client(httpClient *http.Client) (*oauth2c.Clie
 func (i *OAuth2Identity) Environment() map[string]string {
 	return map[string]string{}
 }
+
+func (i *OAuth2Identity) AuthenticateHTTPRequest(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+i.AccessToken)
+}
@@ -0,0 +1,218 @@
+package generic
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/exograd/go-oauth2c"
+	"go.n16f.net/ejson"
+)
+
+// The TTL used to schedule refreshes when the token endpoint does not return
+// the lifetime of the access token.
+const DefaultOAuth2ClientCredentialsTTL = 3600
+
+type OAuth2ScopeLocation string
+
+const (
+	OAuth2ScopeLocationBody  OAuth2ScopeLocation = "body"
+	OAuth2ScopeLocationQuery OAuth2ScopeLocation = "query"
+)
+
+var OAuth2ScopeLocationValues = []OAuth2ScopeLocation{
+	OAuth2ScopeLocationBody,
+	OAuth2ScopeLocationQuery,
+}
+
+type OAuth2ClientCredentialsIdentity struct {
+	TokenEndpoint string              `json:"token_endpoint"`
+	ClientId      string              `json:"client_id"`
+	ClientSecret  string              `json:"client_secret"`
+	Scopes        []string            `json:"scopes,omitempty"`
+	ScopeLocation OAuth2ScopeLocation `json:"scope_location,omitempty"`
+
+	AccessToken    string     `json:"access_token,omitempty"`
+	TTL            int        `json:"ttl"`
+	ExpirationTime *time.Time `json:"expiration_time,omitempty"`
+}
+
+func OAuth2ClientCredentialsIdentityDef() *eventline.IdentityDef {
+	def := eventline.NewIdentityDef("oauth2_client_credentials",
+		&OAuth2ClientCredentialsIdentity{})
+	def.Refreshable = true
+	return def
+}
+
+func (i *OAuth2ClientCredentialsIdentity) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringURI("token_endpoint", i.TokenEndpoint)
+	v.CheckStringNotEmpty("client_id", i.ClientId)
+	v.CheckStringNotEmpty("client_secret", i.ClientSecret)
+
+	if i.ScopeLocation != "" {
+		v.CheckStringValue("scope_location", i.ScopeLocation,
+			OAuth2ScopeLocationValues)
+	}
+}
+
+func (i *OAuth2ClientCredentialsIdentity) Def() *eventline.IdentityDataDef {
+	view := eventline.NewIdentityDataDef()
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:   "token_endpoint",
+		Label: "Token endpoint",
+		Value: i.TokenEndpoint,
+		Type:  eventline.IdentityDataTypeURI,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "client_id",
+		Label:    "Client id",
+		Value:    i.ClientId,
+		Type:     eventline.IdentityDataTypeString,
+		Verbatim: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "client_secret",
+		Label:    "Client secret",
+		Value:    i.ClientSecret,
+		Type:     eventline.IdentityDataTypeString,
+		Secret:   true,
+		Verbatim: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "scopes",
+		Label:    "Scopes",
+		Value:    i.Scopes,
+		Type:     eventline.IdentityDataTypeStringList,
+		Optional: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:                   "scope_location",
+		Label:                 "Scope location",
+		Value:                 i.ScopeLocation,
+		Type:                  eventline.IdentityDataTypeEnum,
+		EnumValues:            []string{"body", "query"},
+		PreselectedEnumValues: []string{"body"},
+		Optional:              true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "access_token",
+		Label:    "Access token",
+		Value:    i.AccessToken,
+		Type:     eventline.IdentityDataTypeString,
+		Optional: true,
+		Secret:   true,
+		Verbatim: true,
+		Internal: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "expiration_date",
+		Label:    "Expiration date",
+		Value:    i.ExpirationTime,
+		Type:     eventline.IdentityDataTypeDate,
+		Optional: true,
+		Internal: true,
+	})
+
+	return view
+}
+
+// Refresh fetches a new access token with the client credentials grant (RFC
+// 6749 4.4). There is no refresh token involved: the client authenticates
+// again each time a token is requested.
+func (i *OAuth2ClientCredentialsIdentity) Refresh(httpClient *http.Client) error {
+	client, err := i.newOAuth2Client(httpClient)
+	if err != nil {
+		return fmt.Errorf("cannot create oauth2 client: %w", err)
+	}
+
+	var req oauth2c.TokenClientCredsRequest
+	if i.ScopeLocation != OAuth2ScopeLocationQuery {
+		req.Scope = i.Scopes
+	}
+
+	res, err := client.Token(context.Background(), "client_credentials", &req)
+	if err != nil {
+		return err
+	}
+
+	ttl := int(res.ExpiresIn)
+	if ttl <= 0 {
+		ttl = DefaultOAuth2ClientCredentialsTTL
+	}
+
+	expirationTime := time.Now().UTC().Add(time.Duration(ttl) * time.Second)
+
+	i.AccessToken = res.AccessToken
+	i.TTL = ttl
+	i.ExpirationTime = &expirationTime
+
+	return nil
+}
+
+func (i *OAuth2ClientCredentialsIdentity) ExpiresBefore(t time.Time) bool {
+	if i.AccessToken == "" {
+		return true
+	}
+
+	return i.ExpirationTime != nil && i.ExpirationTime.Before(t)
+}
+
+func (i *OAuth2ClientCredentialsIdentity) RefreshTime() time.Time {
+	now := time.Now().UTC()
+
+	// Identities without any access token must be refreshed as soon as
+	// possible.
+	if i.AccessToken == "" {
+		return now
+	}
+
+	halfTTL := time.Duration(math.Ceil(float64(i.TTL)/2.0)) * time.Second
+
+	return now.Add(halfTTL)
+}
+
+func (i *OAuth2ClientCredentialsIdentity) newOAuth2Client(httpClient *http.Client) (*oauth2c.Client, error) {
+	tokenEndpoint := i.TokenEndpoint
+
+	if i.ScopeLocation == OAuth2ScopeLocationQuery && len(i.Scopes) > 0 {
+		uri, err := url.Parse(tokenEndpoint)
+		if err != nil {
+			return nil, fmt.Errorf("invalid token endpoint: %w", err)
+		}
+
+		query := uri.Query()
+		query.Set("scope", strings.Join(i.Scopes, " "))
+		uri.RawQuery = query.Encode()
+
+		tokenEndpoint = uri.String()
+	}
+
+	options := oauth2c.Options{
+		HTTPClient: httpClient,
+
+		TokenEndpoint: tokenEndpoint,
+	}
+
+	return oauth2c.NewClient(tokenEndpoint, i.ClientId, i.ClientSecret,
+		&options)
+}
+
+func (i *OAuth2ClientCredentialsIdentity) Environment() map[string]string {
+	return map[string]string{}
+}
+
+func (i *OAuth2ClientCredentialsIdentity) AuthenticateHTTPRequest(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+i.AccessToken)
+}
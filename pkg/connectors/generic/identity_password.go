@@ -1,6 +1,8 @@
 package generic
 
 import (
+	"net/http"
+
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/ejson"
 )
@@ -44,3 +46,7 @@ func (i *PasswordIdentity) Def() *eventline.IdentityDataDef {
 func (i *PasswordIdentity) Environment() map[string]string {
 	return map[string]string{}
 }
+
+func (i *PasswordIdentity) AuthenticateHTTPRequest(req *http.Request) {
+	req.SetBasicAuth(i.Login, i.Password)
+}
@@ -1,16 +1,19 @@
 package generic
 
 import (
+	"errors"
 	"strings"
 
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/ejson"
+	"golang.org/x/crypto/ssh"
 )
 
 type SSHKeyIdentity struct {
 	PrivateKey  string `json:"private_key"`
 	PublicKey   string `json:"public_key,omitempty"`
 	Certificate string `json:"certificate,omitempty"`
+	Passphrase  string `json:"passphrase,omitempty"`
 }
 
 func SSHKeyIdentityDef() *eventline.IdentityDef {
@@ -35,6 +38,26 @@ func (i *SSHKeyIdentity) ValidateJSON(v *ejson.Validator) {
 	if i.Certificate != "" && !strings.HasSuffix(i.Certificate, "\n") {
 		i.Certificate += "\n"
 	}
+
+	// Make sure encrypted private keys can be decrypted now instead of
+	// failing when a job uses the identity. Errors returned by the ssh
+	// package are not included since they could leak information about the
+	// key.
+	if i.PrivateKey != "" {
+		_, err := i.Signer()
+
+		var passphraseErr *ssh.PassphraseMissingError
+
+		switch {
+		case errors.As(err, &passphraseErr):
+			v.AddError("passphrase", "missing_value",
+				"private key is encrypted and requires a passphrase")
+
+		case err != nil && i.Passphrase != "":
+			v.AddError("passphrase", "invalid_value",
+				"private key cannot be decrypted with this passphrase")
+		}
+	}
 }
 
 func (i *SSHKeyIdentity) Def() *eventline.IdentityDataDef {
@@ -67,9 +90,29 @@ func (i *SSHKeyIdentity) Def() *eventline.IdentityDataDef {
 		Verbatim: true,
 	})
 
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "passphrase",
+		Label:    "Passphrase",
+		Value:    i.Passphrase,
+		Type:     eventline.IdentityDataTypeString,
+		Optional: true,
+		Secret:   true,
+	})
+
 	return view
 }
 
 func (i *SSHKeyIdentity) Environment() map[string]string {
 	return map[string]string{}
 }
+
+// Signer parses the private key, decrypting it with the passphrase if there
+// is one.
+func (i *SSHKeyIdentity) Signer() (ssh.Signer, error) {
+	if i.Passphrase == "" {
+		return ssh.ParsePrivateKey([]byte(i.PrivateKey))
+	}
+
+	return ssh.ParsePrivateKeyWithPassphrase([]byte(i.PrivateKey),
+		[]byte(i.Passphrase))
+}
@@ -0,0 +1,73 @@
+package generic
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+// Generic identities are not associated with any specific service, so they
+// cannot be tested with an authenticated request. TestIdentity checks what
+// can be checked locally: that keys can be decoded and used, and that OAuth2
+// access tokens have not expired.
+func (c *Connector) TestIdentity(ctx context.Context, identity *eventline.Identity) (*eventline.IdentityTestResult, error) {
+	var result eventline.IdentityTestResult
+
+	switch idata := identity.Data.(type) {
+	case *SSHKeyIdentity:
+		signer, err := idata.Signer()
+		if err != nil {
+			return eventline.NewIdentityTestFailure(err), nil
+		}
+
+		result.Message = fmt.Sprintf("valid %s private key",
+			signer.PublicKey().Type())
+
+	case *GPGKeyIdentity:
+		// Identities containing only a public key can only be used to
+		// verify signatures.
+		if idata.PrivateKey == "" {
+			result.Message = "no private key to check"
+			break
+		}
+
+		data := []byte("eventline")
+
+		signature, err := idata.Sign(data)
+		if err != nil {
+			return eventline.NewIdentityTestFailure(err), nil
+		}
+
+		if err := idata.Verify(data, signature); err != nil {
+			return eventline.NewIdentityTestFailure(err), nil
+		}
+
+		result.Message = "valid private key"
+
+	case *OAuth2Identity:
+		if idata.ExpiresBefore(time.Now()) {
+			err := errors.New("access token expired")
+			return eventline.NewIdentityTestFailure(err), nil
+		}
+
+		result.Scopes = idata.Scopes
+
+	case *OAuth2ClientCredentialsIdentity:
+		if idata.ExpiresBefore(time.Now()) {
+			err := errors.New("access token expired")
+			return eventline.NewIdentityTestFailure(err), nil
+		}
+
+		result.Scopes = idata.Scopes
+
+	default:
+		result.Message = "no check available for this identity type"
+	}
+
+	result.OK = true
+
+	return &result, nil
+}
@@ -0,0 +1,56 @@
+package generic
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/exograd/eventline/pkg/utils"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTestIdentity(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	c := NewConnector()
+	ctx := context.Background()
+
+	test := func(data eventline.IdentityData) *eventline.IdentityTestResult {
+		result, err := c.TestIdentity(ctx, &eventline.Identity{Data: data})
+		require.NoError(err)
+		return result
+	}
+
+	result := test(&GPGKeyIdentity{
+		PrivateKey: testGPGPrivateKey,
+		Password:   "test",
+	})
+	assert.True(result.OK, result.Message)
+
+	result = test(&GPGKeyIdentity{
+		PrivateKey: testGPGPrivateKey,
+		Password:   "foo",
+	})
+	assert.False(result.OK)
+
+	result = test(&SSHKeyIdentity{PrivateKey: "foo"})
+	assert.False(result.OK)
+
+	result = test(&OAuth2Identity{
+		Scopes:         []string{"read"},
+		ExpirationTime: utils.Ref(time.Now().Add(time.Hour)),
+	})
+	assert.True(result.OK)
+	assert.Equal([]string{"read"}, result.Scopes)
+
+	result = test(&OAuth2Identity{
+		ExpirationTime: utils.Ref(time.Now().Add(-time.Hour)),
+	})
+	assert.False(result.OK)
+
+	result = test(&APIKeyIdentity{Key: "foo"})
+	assert.True(result.OK)
+}
@@ -1,21 +1,84 @@
 package github
 
 import (
+	"fmt"
+	"net/url"
+
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/ejson"
 )
 
 type ConnectorCfg struct {
-	Enabled       bool   `json:"enabled"`
-	WebhookSecret string `json:"webhook_secret,omitempty"`
+	Enabled                 bool   `json:"enabled"`
+	WebhookSecret           string `json:"webhook_secret,omitempty"`
+	WebhookStatementTimeout int    `json:"webhook_statement_timeout,omitempty"` // milliseconds
+	StoreRawPayloads        bool   `json:"store_raw_payloads,omitempty"`
+	EnrichmentTimeout       int    `json:"enrichment_timeout,omitempty"`   // milliseconds
+	DeduplicationPeriod     int    `json:"deduplication_period,omitempty"` // seconds
+	BaseURI                 string `json:"base_uri,omitempty"`
+	UploadURI               string `json:"upload_uri,omitempty"`
+	MaxRequestSize          int    `json:"max_request_size,omitempty"` // bytes
 }
 
 func (c *Connector) DefaultCfg() eventline.ConnectorCfg {
-	return &ConnectorCfg{}
+	return &ConnectorCfg{
+		// GitHub considers a delivery as failed after 10 seconds
+		WebhookStatementTimeout: 5000,
+
+		EnrichmentTimeout: 3000,
+
+		DeduplicationPeriod: 86400,
+
+		// GitHub does not deliver payloads larger than 25MB
+		MaxRequestSize: 25 * 1024 * 1024,
+	}
 }
 
 func (cfg *ConnectorCfg) ValidateJSON(v *ejson.Validator) {
 	if cfg.Enabled {
 		v.CheckStringNotEmpty("webhook_secret", cfg.WebhookSecret)
 	}
+
+	v.CheckIntMin("webhook_statement_timeout", cfg.WebhookStatementTimeout, 0)
+	v.CheckIntMin("enrichment_timeout", cfg.EnrichmentTimeout, 1)
+	v.CheckIntMin("deduplication_period", cfg.DeduplicationPeriod, 1)
+	v.CheckIntMin("max_request_size", cfg.MaxRequestSize, 1)
+
+	if cfg.BaseURI != "" {
+		_, err := ParseBaseURI(cfg.BaseURI)
+		v.Check("base_uri", err == nil, "invalid_uri", "invalid uri: %v", err)
+	}
+
+	if cfg.UploadURI != "" {
+		v.Check("upload_uri", cfg.BaseURI != "", "missing_base_uri",
+			"upload uri requires a base uri")
+
+		_, err := ParseBaseURI(cfg.UploadURI)
+		v.Check("upload_uri", err == nil, "invalid_uri", "invalid uri: %v",
+			err)
+	}
+}
+
+// ParseBaseURI parses the base URI of a GitHub Enterprise Server instance,
+// e.g. "https://github.example.com".
+func ParseBaseURI(s string) (*url.URL, error) {
+	uri, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return nil, fmt.Errorf("invalid scheme %q", uri.Scheme)
+	}
+
+	if uri.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+
+	if uri.RawQuery != "" || uri.Fragment != "" {
+		return nil, fmt.Errorf("base uris cannot contain a query or a " +
+			"fragment")
+	}
+
+	return uri, nil
 }
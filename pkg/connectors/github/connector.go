@@ -2,10 +2,13 @@ package github
 
 import (
 	"encoding/base64"
+	"encoding/hex"
 	"fmt"
 	"net/http"
 	"net/url"
+	"strings"
 
+	"github.com/exograd/eventline/pkg/cryptoutils"
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/log"
 	"go.n16f.net/service/pkg/pg"
@@ -20,11 +23,19 @@ type Connector struct {
 	Log *log.Logger
 
 	webHTTPServerURI *url.URL
+	proxyURI         *url.URL
+	baseURI          *url.URL
+	uploadURI        *url.URL
+	recordDelivery   func(error)
 }
 
 func NewConnector() *Connector {
+	c := &Connector{}
+
 	def := eventline.NewConnectorDef("github")
 
+	def.Worker = NewDeliveryWorker(c)
+
 	def.AddIdentity(TokenIdentityDef())
 	def.AddIdentity(OAuth2IdentityDef())
 
@@ -35,11 +46,25 @@ func NewConnector() *Connector {
 	def.AddEvent(TagDeletionEventDef())
 	def.AddEvent(BranchCreationEventDef())
 	def.AddEvent(BranchDeletionEventDef())
+	def.AddEvent(RefCreatedEventDef())
+	def.AddEvent(RefDeletedEventDef())
 	def.AddEvent(PushEventDef())
-
-	return &Connector{
-		Def: def,
-	}
+	def.AddEvent(CommitStatusEventDef())
+	def.AddEvent(CommitCommentEventDef())
+	def.AddEvent(PullRequestOpenedEventDef())
+	def.AddEvent(PullRequestClosedEventDef())
+	def.AddEvent(PullRequestSynchronizedEventDef())
+	def.AddEvent(PullRequestMergedEventDef())
+	def.AddEvent(ReleasePublishedEventDef())
+	def.AddEvent(ReleaseReleasedEventDef())
+	def.AddEvent(ReleasePrereleasedEventDef())
+	def.AddEvent(ReleaseEditedEventDef())
+	def.AddEvent(ReleaseDeletedEventDef())
+	def.AddEvent(WorkflowRunCompletedEventDef())
+
+	c.Def = def
+
+	return c
 }
 
 func (c *Connector) Name() string {
@@ -60,6 +85,28 @@ func (c *Connector) Init(ccfg eventline.ConnectorCfg, initData eventline.Connect
 	c.Log = initData.Log
 
 	c.webHTTPServerURI = initData.WebHTTPServerURI
+	c.proxyURI = initData.Proxy
+	c.recordDelivery = initData.RecordDelivery
+
+	if c.Cfg.BaseURI != "" {
+		baseURI, err := ParseBaseURI(c.Cfg.BaseURI)
+		if err != nil {
+			return fmt.Errorf("invalid base uri %q: %w", c.Cfg.BaseURI, err)
+		}
+
+		c.baseURI = baseURI
+		c.uploadURI = baseURI
+
+		if c.Cfg.UploadURI != "" {
+			uploadURI, err := ParseBaseURI(c.Cfg.UploadURI)
+			if err != nil {
+				return fmt.Errorf("invalid upload uri %q: %w",
+					c.Cfg.UploadURI, err)
+			}
+
+			c.uploadURI = uploadURI
+		}
+	}
 
 	return nil
 }
@@ -70,16 +117,48 @@ func (c *Connector) Terminate() {
 func (c *Connector) Subscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
 	params := sctx.Subscription.Parameters.(*Parameters)
 
-	hookId, err := c.MaybeCreateHook(conn, params, sctx.Identity)
+	var hookId *HookId
+	var tokenHash []byte
+	var webhookKeyId *eventline.Id
+	var err error
+
+	if params.DedicatedHook {
+		// The token is only known by GitHub; we only store its hash
+		token := hex.EncodeToString(cryptoutils.RandomBytes(32))
+		tokenHash = HashWebhookToken(token)
+
+		// The secret of the hook is derived from the webhook key of the
+		// project so that it does not depend on the global webhook secret.
+		projectId := *sctx.Subscription.ProjectId
+
+		var key *eventline.ProjectWebhookKey
+		key, err = eventline.LoadCurrentProjectWebhookKey(conn, projectId)
+		if err != nil {
+			return fmt.Errorf("cannot load project webhook key: %w", err)
+		} else if key == nil {
+			return fmt.Errorf("missing webhook key for project %q", projectId)
+		}
+
+		webhookKeyId = &key.Id
+		secret := DedicatedHookSecret(key, token)
+
+		uri := c.SubscriptionWebhookURI(token)
+		hookId, err = c.CreateHook(conn, params, uri, secret, sctx.Identity)
+	} else {
+		hookId, err = c.MaybeCreateHook(conn, params, sctx.Identity)
+	}
+
 	if err != nil {
 		return fmt.Errorf("cannot create hook: %w", err)
 	}
 
 	s := Subscription{
-		Id:           sctx.Subscription.Id,
-		Organization: params.Organization,
-		Repository:   params.Repository,
-		HookId:       *hookId,
+		Id:               sctx.Subscription.Id,
+		Organization:     params.Organization,
+		Repository:       params.Repository,
+		HookId:           *hookId,
+		WebhookTokenHash: tokenHash,
+		WebhookKeyId:     webhookKeyId,
 	}
 
 	if err := s.Insert(conn); err != nil {
@@ -134,10 +213,37 @@ func (c *Connector) NewClient(identity *eventline.Identity) (*github.Client, err
 		Header:      header,
 	}
 
-	httpClient, err := shttp.NewClient(httpClientCfg)
+	httpClient, err := eventline.NewHTTPClient(httpClientCfg, c.proxyURI)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create http client: %w", err)
 	}
 
-	return github.NewClient(httpClient.Client), nil
+	if c.baseURI == nil {
+		return github.NewClient(httpClient.Client), nil
+	}
+
+	// NewEnterpriseClient appends the "/api/v3/" and "/api/uploads/"
+	// suffixes if they are missing.
+	client, err := github.NewEnterpriseClient(c.baseURI.String(),
+		c.uploadURI.String(), httpClient.Client)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create enterprise client: %w", err)
+	}
+
+	return client, nil
+}
+
+// WebURI returns the URI of the web interface of the GitHub instance, i.e.
+// "https://github.com" unless a GitHub Enterprise Server base URI is
+// configured.
+func (c *Connector) WebURI() *url.URL {
+	if c.baseURI == nil {
+		return &url.URL{Scheme: "https", Host: "github.com"}
+	}
+
+	uri := *c.baseURI
+	uri.Path = strings.TrimSuffix(strings.TrimSuffix(uri.Path, "/"),
+		"/api/v3")
+
+	return &uri
 }
@@ -0,0 +1,68 @@
+package github
+
+import (
+	"context"
+	"time"
+
+	"go.n16f.net/service/pkg/pg"
+)
+
+// GitHub retries deliveries which failed or timed out, and deliveries can be
+// redelivered manually; all attempts use the same delivery id. Delivery ids
+// are recorded when deliveries are queued, so that a delivery which is queued
+// or was processed is never processed again. Delivery ids are deleted when
+// queued deliveries are dropped, so that they can be redelivered.
+
+// RegisterDelivery records the reception of a delivery and returns false if
+// a delivery with the same id was already processed during the deduplication
+// period.
+func RegisterDelivery(conn pg.Conn, deliveryId string, period time.Duration) (bool, error) {
+	ctx := context.Background()
+
+	now := time.Now().UTC()
+	minTime := now.Add(-period)
+
+	// Entries older than the deduplication period are not deleted
+	// immediately, so we replace them.
+	query := `
+INSERT INTO c_github_deliveries AS d
+    (id, reception_time)
+  VALUES
+    ($1, $2)
+  ON CONFLICT (id) DO UPDATE
+    SET reception_time = EXCLUDED.reception_time
+    WHERE d.reception_time < $3
+`
+	res, err := conn.Exec(ctx, query, deliveryId, now, minTime)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
+
+func DeleteOldDeliveries(conn pg.Conn, period time.Duration) (int64, error) {
+	ctx := context.Background()
+
+	minTime := time.Now().UTC().Add(-period)
+
+	query := `
+DELETE FROM c_github_deliveries
+  WHERE reception_time < $1
+`
+	res, err := conn.Exec(ctx, query, minTime)
+	if err != nil {
+		return -1, err
+	}
+
+	return res.RowsAffected(), nil
+}
+
+// DeleteDelivery forgets a delivery so that it can be received again.
+func DeleteDelivery(conn pg.Conn, deliveryId string) error {
+	query := `
+DELETE FROM c_github_deliveries
+  WHERE id = $1
+`
+	return pg.Exec(conn, query, deliveryId)
+}
@@ -0,0 +1,150 @@
+package github
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// DeliveryWorker processes queued webhook deliveries. When the queue is
+// empty, it deletes old entries used for delivery deduplication.
+type DeliveryWorker struct {
+	Log *log.Logger
+	Pg  *pg.Client
+
+	connector *Connector
+	gc        *DeduplicationGC
+}
+
+func NewDeliveryWorker(c *Connector) *DeliveryWorker {
+	return &DeliveryWorker{
+		connector: c,
+		gc:        NewDeduplicationGC(c),
+	}
+}
+
+func (dw *DeliveryWorker) Init(w *eventline.Worker) {
+	dw.Log = w.Log
+	dw.Pg = w.Pg
+
+	dw.gc.Init(w)
+}
+
+func (dw *DeliveryWorker) Start() error {
+	return nil
+}
+
+func (dw *DeliveryWorker) Stop() {
+}
+
+func (dw *DeliveryWorker) ProcessJob() (bool, error) {
+	var delivery *QueuedDelivery
+	var processingErr error
+
+	err := dw.Pg.WithTx(func(conn pg.Conn) error {
+		var err error
+		delivery, err = LoadQueuedDeliveryForProcessing(conn)
+		if err != nil {
+			return fmt.Errorf("cannot load queued delivery: %w", err)
+		} else if delivery == nil {
+			return nil
+		}
+
+		dw.Log.Debug(1, "processing delivery %q", delivery.RawEvent.DeliveryId)
+
+		processingErr = dw.connector.ProcessQueuedDelivery(conn, delivery)
+		if processingErr != nil {
+			return processingErr
+		}
+
+		if err := delivery.Delete(conn); err != nil {
+			return fmt.Errorf("cannot delete queued delivery: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil && !errors.Is(err, processingErr) {
+		return false, err
+	}
+
+	if delivery == nil {
+		return dw.gc.ProcessJob()
+	}
+
+	if recordDelivery := dw.connector.recordDelivery; recordDelivery != nil {
+		recordDelivery(processingErr)
+	}
+
+	if processingErr != nil {
+		// The transaction was rolled back, the failure is recorded in a new
+		// one.
+		if err := dw.recordFailure(delivery.Id, processingErr); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+func (dw *DeliveryWorker) recordFailure(id eventline.Id, processingErr error) error {
+	return dw.Pg.WithTx(func(conn pg.Conn) error {
+		var delivery QueuedDelivery
+		if err := delivery.LoadForUpdate(conn, id); err != nil {
+			return fmt.Errorf("cannot load queued delivery: %w", err)
+		}
+
+		delivery.NbAttempts++
+
+		deliveryId := delivery.RawEvent.DeliveryId
+
+		var decodingErr *DeliveryDecodingError
+
+		if errors.As(processingErr, &decodingErr) {
+			dw.Log.Error("dropping delivery %q: %v", deliveryId,
+				processingErr)
+
+			return dropDelivery(conn, &delivery)
+		} else if delivery.NbAttempts >= MaxDeliveryAttempts {
+			dw.Log.Error("dropping delivery %q after %d attempts: %v",
+				deliveryId, delivery.NbAttempts, processingErr)
+
+			return dropDelivery(conn, &delivery)
+		}
+
+		dw.Log.Error("cannot process delivery %q: %v", deliveryId,
+			processingErr)
+
+		now := time.Now().UTC()
+
+		delivery.NextAttemptTime =
+			now.Add(DeliveryRetryDelay(delivery.NbAttempts))
+		delivery.LastError = processingErr.Error()
+
+		if err := delivery.UpdateAttempt(conn); err != nil {
+			return fmt.Errorf("cannot update queued delivery: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// dropDelivery deletes a queued delivery which will not be processed, and
+// forgets its delivery id so that it can be redelivered from GitHub.
+func dropDelivery(conn pg.Conn, delivery *QueuedDelivery) error {
+	if err := delivery.Delete(conn); err != nil {
+		return fmt.Errorf("cannot delete queued delivery: %w", err)
+	}
+
+	if deliveryId := delivery.RawEvent.DeliveryId; deliveryId != "" {
+		if err := DeleteDelivery(conn, deliveryId); err != nil {
+			return fmt.Errorf("cannot delete delivery %q: %w", deliveryId,
+				err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,97 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/google/go-github/v45/github"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// Webhook payloads do not always contain the data jobs need. Subscriptions
+// with the enrich parameter use their identity to fetch additional data from
+// the GitHub API when the event is received, so that the API is called once
+// instead of once per job execution. Enrichment is best effort: if it fails
+// or takes too long, the event is created without additional data.
+
+type PushEventCommit struct {
+	Revision string `json:"revision"`
+	Message  string `json:"message"`
+	Author   string `json:"author,omitempty"`
+	URI      string `json:"uri,omitempty"`
+}
+
+func (c *Connector) enrichPushEvent(conn pg.Conn, sub *eventline.Subscription, event *PushEvent) error {
+	// There is nothing to compare for the first push of a branch, and the
+	// zero revision cannot be used in a comparison.
+	if event.OldRevision == "" || event.OldRevision == zeroRevision {
+		return nil
+	}
+
+	if sub.IdentityId == nil {
+		return fmt.Errorf("missing subscription identity")
+	}
+
+	scope := eventline.NewProjectScope(*sub.ProjectId)
+
+	var identity eventline.Identity
+	if err := identity.Load(conn, *sub.IdentityId, scope); err != nil {
+		return fmt.Errorf("cannot load identity: %w", err)
+	}
+
+	if identity.Disabled {
+		return &eventline.DisabledIdentityError{Name: identity.Name}
+	}
+
+	if err := identity.ResolveData(); err != nil {
+		return err
+	}
+
+	client, err := c.NewClient(&identity)
+	if err != nil {
+		return fmt.Errorf("cannot create client: %w", err)
+	}
+
+	timeout := time.Duration(c.Cfg.EnrichmentTimeout) * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	comparison, _, err := client.Repositories.CompareCommits(ctx,
+		event.Organization, event.Repository, event.OldRevision,
+		event.NewRevision, nil)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("request timed out after %v", timeout)
+		}
+
+		return fmt.Errorf("cannot compare commits: %w", err)
+	}
+
+	commits := make([]PushEventCommit, len(comparison.Commits))
+	for i, commit := range comparison.Commits {
+		commits[i] = newPushEventCommit(commit)
+	}
+
+	event.Commits = commits
+	event.Enriched = true
+
+	return nil
+}
+
+func newPushEventCommit(commit *github.RepositoryCommit) PushEventCommit {
+	author := commit.GetAuthor().GetLogin()
+	if author == "" {
+		author = commit.GetCommit().GetAuthor().GetName()
+	}
+
+	return PushEventCommit{
+		Revision: commit.GetSHA(),
+		Message:  commit.GetCommit().GetMessage(),
+		Author:   author,
+		URI:      commit.GetHTMLURL(),
+	}
+}
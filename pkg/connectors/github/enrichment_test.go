@@ -0,0 +1,31 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnrichPushEventWithoutPreviousRevision(t *testing.T) {
+	assert := assert.New(t)
+
+	var c Connector
+
+	// The subscription does not have any identity: the push event would fail
+	// to be enriched if there was something to compare.
+	var sub eventline.Subscription
+
+	for _, revision := range []string{"", zeroRevision} {
+		event := PushEvent{
+			Organization: "org",
+			Repository:   "repo",
+			Branch:       "main",
+			OldRevision:  revision,
+			NewRevision:  "8f4e2b1c6d3a9e7f5b2c4d6e8a1f3b5c7d9e0a2b",
+		}
+
+		assert.NoError(c.enrichPushEvent(nil, &sub, &event))
+		assert.Nil(event.Commits)
+	}
+}
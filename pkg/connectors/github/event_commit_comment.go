@@ -0,0 +1,25 @@
+package github
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type CommitCommentEvent struct {
+	Organization string `json:"organization"`
+	Repository   string `json:"repository"`
+	Revision     string `json:"revision"`
+	Author       string `json:"author"`
+	Body         string `json:"body"`
+	Path         string `json:"path,omitempty"`
+	Position     *int   `json:"position,omitempty"`
+	URI          string `json:"uri,omitempty"`
+
+	// The relation between the author and the repository, e.g. OWNER,
+	// MEMBER or CONTRIBUTOR
+	AuthorAssociation string `json:"author_association,omitempty"`
+}
+
+func CommitCommentEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("commit_comment",
+		&CommitCommentEvent{}, &Parameters{})
+}
@@ -0,0 +1,21 @@
+package github
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type CommitStatusEvent struct {
+	Organization string   `json:"organization"`
+	Repository   string   `json:"repository"`
+	Revision     string   `json:"revision"`
+	State        string   `json:"state"`
+	Context      string   `json:"context"`
+	Description  string   `json:"description,omitempty"`
+	TargetURI    string   `json:"target_uri,omitempty"`
+	Branches     []string `json:"branches,omitempty"`
+}
+
+func CommitStatusEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("commit_status",
+		&CommitStatusEvent{}, &Parameters{})
+}
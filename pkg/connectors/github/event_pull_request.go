@@ -0,0 +1,37 @@
+package github
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type PullRequestEvent struct {
+	Organization string `json:"organization"`
+	Repository   string `json:"repository"`
+	Number       int    `json:"number"`
+	Title        string `json:"title"`
+	Author       string `json:"author"`
+	HeadBranch   string `json:"head_branch"`
+	HeadRevision string `json:"head_revision"`
+	BaseBranch   string `json:"base_branch"`
+	URI          string `json:"uri,omitempty"`
+}
+
+func PullRequestOpenedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("pull_request_opened",
+		&PullRequestEvent{}, &Parameters{})
+}
+
+func PullRequestClosedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("pull_request_closed",
+		&PullRequestEvent{}, &Parameters{})
+}
+
+func PullRequestSynchronizedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("pull_request_synchronized",
+		&PullRequestEvent{}, &Parameters{})
+}
+
+func PullRequestMergedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("pull_request_merged",
+		&PullRequestEvent{}, &Parameters{})
+}
@@ -10,6 +10,18 @@ type PushEvent struct {
 	Branch       string `json:"branch"`
 	OldRevision  string `json:"old_revision,omitempty"`
 	NewRevision  string `json:"new_revision"`
+
+	// Paths of the files added, removed or modified by the commits of the
+	// payload
+	ModifiedFiles []string `json:"modified_files,omitempty"`
+
+	// Set when the push following this one on the branch was delivered
+	// first
+	OutOfOrder bool `json:"out_of_order,omitempty"`
+
+	// Only set for subscriptions with enrichment, if it succeeded
+	Enriched bool              `json:"enriched,omitempty"`
+	Commits  []PushEventCommit `json:"commits,omitempty"`
 }
 
 func PushEventDef() *eventline.EventDef {
@@ -1,13 +1,50 @@
 package github
 
 import (
+	"net/http"
+	"strings"
+	"time"
+
 	"github.com/exograd/eventline/pkg/eventline"
 )
 
 type RawEvent struct {
-	DeliveryId string      `json:"delivery_id"`
-	EventType  string      `json:"event_type"`
-	Event      interface{} `json:"event"`
+	DeliveryId    string      `json:"delivery_id"`
+	EventType     string      `json:"event_type"`
+	ReceptionTime *time.Time  `json:"reception_time,omitempty"`
+	Event         interface{} `json:"event"`
+
+	// X-GitHub-* headers of the delivery, with lower case names
+	GitHubHeaders map[string]string `json:"github_headers,omitempty"`
+
+	// Only set if the store_raw_payloads setting is enabled
+	Payload string            `json:"payload,omitempty"`
+	Headers map[string]string `json:"headers,omitempty"`
+}
+
+// RawEventGitHubHeaders returns the headers of a delivery whose name starts
+// with "X-GitHub-". Other headers are ignored since they can be added by any
+// proxy between GitHub and Eventline. Names are converted to lower case so
+// that they do not depend on the way they were transmitted.
+func RawEventGitHubHeaders(header http.Header) map[string]string {
+	const prefix = "x-github-"
+
+	var headers map[string]string
+
+	for name, values := range header {
+		name = strings.ToLower(name)
+		if !strings.HasPrefix(name, prefix) || len(values) == 0 {
+			continue
+		}
+
+		if headers == nil {
+			headers = make(map[string]string)
+		}
+
+		headers[name] = strings.Join(values, ", ")
+	}
+
+	return headers
 }
 
 func RawEventDef() *eventline.EventDef {
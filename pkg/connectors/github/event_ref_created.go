@@ -0,0 +1,17 @@
+package github
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type RefCreatedEvent struct {
+	Organization string `json:"organization"`
+	Repository   string `json:"repository"`
+	Ref          string `json:"ref"`
+	RefType      string `json:"ref_type"` // "branch" or "tag"
+}
+
+func RefCreatedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("ref_created",
+		&RefCreatedEvent{}, &Parameters{})
+}
@@ -0,0 +1,17 @@
+package github
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type RefDeletedEvent struct {
+	Organization string `json:"organization"`
+	Repository   string `json:"repository"`
+	Ref          string `json:"ref"`
+	RefType      string `json:"ref_type"` // "branch" or "tag"
+}
+
+func RefDeletedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("ref_deleted",
+		&RefDeletedEvent{}, &Parameters{})
+}
@@ -0,0 +1,41 @@
+package github
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type ReleaseEvent struct {
+	Organization    string `json:"organization"`
+	Repository      string `json:"repository"`
+	Tag             string `json:"tag"`
+	Name            string `json:"name,omitempty"`
+	Draft           bool   `json:"draft"`
+	Prerelease      bool   `json:"prerelease"`
+	TargetCommitish string `json:"target_commitish,omitempty"`
+	URI             string `json:"uri,omitempty"`
+}
+
+func ReleasePublishedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("release_published",
+		&ReleaseEvent{}, &Parameters{})
+}
+
+func ReleaseReleasedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("release_released",
+		&ReleaseEvent{}, &Parameters{})
+}
+
+func ReleasePrereleasedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("release_prereleased",
+		&ReleaseEvent{}, &Parameters{})
+}
+
+func ReleaseEditedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("release_edited",
+		&ReleaseEvent{}, &Parameters{})
+}
+
+func ReleaseDeletedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("release_deleted",
+		&ReleaseEvent{}, &Parameters{})
+}
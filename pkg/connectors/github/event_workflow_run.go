@@ -0,0 +1,23 @@
+package github
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type WorkflowRunEvent struct {
+	Organization string `json:"organization"`
+	Repository   string `json:"repository"`
+	Workflow     string `json:"workflow"`
+	RunId        int64  `json:"run_id"`
+	RunNumber    int    `json:"run_number,omitempty"`
+	Conclusion   string `json:"conclusion"`
+	HeadBranch   string `json:"head_branch,omitempty"`
+	HeadRevision string `json:"head_revision"`
+	Trigger      string `json:"trigger,omitempty"` // the event which triggered the run
+	URI          string `json:"uri,omitempty"`
+}
+
+func WorkflowRunCompletedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("workflow_run_completed",
+		&WorkflowRunEvent{}, &Parameters{})
+}
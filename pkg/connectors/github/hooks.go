@@ -3,6 +3,7 @@ package github
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/service/pkg/pg"
@@ -29,10 +30,11 @@ func (c *Connector) MaybeCreateHook(conn pg.Conn, params *Parameters, identity *
 		return hookId, nil
 	}
 
-	return c.CreateHook(conn, params, identity)
+	return c.CreateHook(conn, params, c.WebhookURI(params),
+		c.Cfg.WebhookSecret, identity)
 }
 
-func (c *Connector) CreateHook(conn pg.Conn, params *Parameters, identity *eventline.Identity) (*HookId, error) {
+func (c *Connector) CreateHook(conn pg.Conn, params *Parameters, uri, secret string, identity *eventline.Identity) (*HookId, error) {
 	client, err := c.NewClient(identity)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create client: %w", err)
@@ -40,6 +42,10 @@ func (c *Connector) CreateHook(conn pg.Conn, params *Parameters, identity *event
 
 	ctx := context.Background()
 
+	if err := c.checkHookScopes(ctx, client, params, identity); err != nil {
+		return nil, err
+	}
+
 	active := true
 
 	if params.Repository == "" {
@@ -50,9 +56,9 @@ func (c *Connector) CreateHook(conn pg.Conn, params *Parameters, identity *event
 			Active: &active,
 			Events: []string{"*"},
 			Config: map[string]interface{}{
-				"url":          c.WebhookURI(params),
+				"url":          uri,
 				"content_type": "json",
-				"secret":       c.Cfg.WebhookSecret,
+				"secret":       secret,
 			},
 		}
 
@@ -69,9 +75,9 @@ func (c *Connector) CreateHook(conn pg.Conn, params *Parameters, identity *event
 			Active: &active,
 			Events: []string{"*"},
 			Config: map[string]interface{}{
-				"url":          c.WebhookURI(params),
+				"url":          uri,
 				"content_type": "json",
-				"secret":       c.Cfg.WebhookSecret,
+				"secret":       secret,
 			},
 		}
 
@@ -85,6 +91,59 @@ func (c *Connector) CreateHook(conn pg.Conn, params *Parameters, identity *event
 	}
 }
 
+// HookScopes returns the scopes which allow the creation of the hook used for
+// a set of parameters; any one of them is enough. The repo scope grants full
+// control of repositories, including their hooks.
+func HookScopes(params *Parameters) []string {
+	if params.Repository == "" {
+		return []string{"admin:org_hook"}
+	}
+
+	return []string{"admin:repo_hook", "write:repo_hook", "repo"}
+}
+
+func (c *Connector) checkHookScopes(ctx context.Context, client *github.Client, params *Parameters, identity *eventline.Identity) error {
+	scopes, err := c.identityScopes(ctx, client, identity)
+	if err != nil {
+		return fmt.Errorf("cannot obtain identity scopes: %w", err)
+	}
+
+	return eventline.CheckScopes(identity.Name, scopes, HookScopes(params)...)
+}
+
+// Return the scopes granted to an identity. Scopes declared in the identity
+// are used if they exist; if not, we obtain them from the API, which lists
+// the scopes of classic tokens in the X-OAuth-Scopes response header.
+func (c *Connector) identityScopes(ctx context.Context, client *github.Client, identity *eventline.Identity) ([]string, error) {
+	if sdata, ok := identity.Data.(eventline.ScopedIdentityData); ok {
+		if scopes := sdata.GrantedScopes(); scopes != nil {
+			return scopes, nil
+		}
+	}
+
+	_, res, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, err
+	}
+
+	header, found := res.Header["X-Oauth-Scopes"]
+	if !found {
+		// Fine-grained tokens and GitHub App tokens do not use scopes
+		return nil, nil
+	}
+
+	scopes := []string{}
+	for _, value := range header {
+		for _, scope := range strings.Split(value, ",") {
+			if scope = strings.TrimSpace(scope); scope != "" {
+				scopes = append(scopes, scope)
+			}
+		}
+	}
+
+	return scopes, nil
+}
+
 func (c *Connector) MaybeDeleteHook(conn pg.Conn, params *Parameters, identity *eventline.Identity, hookId HookId) error {
 	if err := LockHooks(conn); err != nil {
 		return err
@@ -0,0 +1,29 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHookScopes(t *testing.T) {
+	assert := assert.New(t)
+
+	repoParams := Parameters{Organization: "org", Repository: "repo"}
+	orgParams := Parameters{Organization: "org"}
+
+	check := func(params *Parameters, grantedScopes ...string) error {
+		return eventline.CheckScopes("test", grantedScopes,
+			HookScopes(params)...)
+	}
+
+	assert.NoError(check(&repoParams, "admin:repo_hook"))
+	assert.NoError(check(&repoParams, "write:repo_hook"))
+	assert.NoError(check(&repoParams, "repo", "user"))
+	assert.Error(check(&repoParams, "read:repo_hook"))
+	assert.Error(check(&repoParams, "admin:org_hook"))
+
+	assert.NoError(check(&orgParams, "admin:org_hook"))
+	assert.Error(check(&orgParams, "repo"))
+}
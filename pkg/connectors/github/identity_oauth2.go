@@ -151,16 +151,28 @@ func (i *OAuth2Identity) FetchTokenData(httpClient *http.Client, code, redirecti
 func (i *OAuth2Identity) newOAuth2Client(httpClient *http.Client) (*oauth2c.Client, error) {
 	issuer := "https://github.com/login/oauth"
 
+	// GitHub Enterprise Server instances expose OAuth2 endpoints on their
+	// own host.
+	if c, found := eventline.FindConnector("github"); found {
+		if connector, ok := c.(*Connector); ok && connector.Cfg != nil {
+			issuer = connector.WebURI().JoinPath("login/oauth").String()
+		}
+	}
+
 	options := oauth2c.Options{
 		HTTPClient: httpClient,
 
-		AuthorizationEndpoint: "https://github.com/login/oauth/authorize",
-		TokenEndpoint:         "https://github.com/login/oauth/access_token",
+		AuthorizationEndpoint: issuer + "/authorize",
+		TokenEndpoint:         issuer + "/access_token",
 	}
 
 	return oauth2c.NewClient(issuer, i.ClientId, i.ClientSecret, &options)
 }
 
+func (i *OAuth2Identity) GrantedScopes() []string {
+	return i.Scopes
+}
+
 func (i *OAuth2Identity) Environment() map[string]string {
 	return map[string]string{
 		"GITHUB_USER":  i.Username,
@@ -216,3 +228,7 @@ func RequiredOAuth2Scopes() []string {
 		"read:packages",
 	}
 }
+
+func (i *OAuth2Identity) AuthenticateHTTPRequest(req *http.Request) {
+	req.Header.Set("Authorization", "token "+i.AccessToken)
+}
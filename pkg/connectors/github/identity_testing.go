@@ -0,0 +1,37 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+// TestIdentity fetches the account associated with the identity. For OAuth2
+// and classic personal access tokens, GitHub returns the scopes of the token
+// in the X-OAuth-Scopes header; fine-grained tokens do not have any scope.
+func (c *Connector) TestIdentity(ctx context.Context, identity *eventline.Identity) (*eventline.IdentityTestResult, error) {
+	client, err := c.NewClient(identity)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create client: %w", err)
+	}
+
+	user, res, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return eventline.NewIdentityTestFailure(err), nil
+	}
+
+	result := eventline.IdentityTestResult{
+		OK:      true,
+		Account: user.GetLogin(),
+	}
+
+	for _, scope := range strings.Split(res.Header.Get("X-OAuth-Scopes"), ",") {
+		if scope = strings.TrimSpace(scope); scope != "" {
+			result.Scopes = append(result.Scopes, scope)
+		}
+	}
+
+	return &result, nil
+}
@@ -1,6 +1,7 @@
 package github
 
 import (
+	"net/http"
 	"regexp"
 
 	"github.com/exograd/eventline/pkg/eventline"
@@ -12,8 +13,9 @@ var (
 )
 
 type TokenIdentity struct {
-	Username string `json:"username"`
-	Token    string `json:"token"`
+	Username string   `json:"username"`
+	Token    string   `json:"token"`
+	Scopes   []string `json:"scopes,omitempty"`
 }
 
 func TokenIdentityDef() *eventline.IdentityDef {
@@ -48,12 +50,34 @@ func (i *TokenIdentity) Def() *eventline.IdentityDataDef {
 		Secret:   true,
 	})
 
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:                 "scopes",
+		Label:               "Scopes",
+		Value:               i.Scopes,
+		Type:                eventline.IdentityDataTypeEnumList,
+		EnumValues:          OAuth2Scopes(),
+		MultiselectEnumSize: 8,
+		Optional:            true,
+	})
+
 	return view
 }
 
+func (i *TokenIdentity) GrantedScopes() []string {
+	if len(i.Scopes) == 0 {
+		return nil
+	}
+
+	return i.Scopes
+}
+
 func (i *TokenIdentity) Environment() map[string]string {
 	return map[string]string{
 		"GITHUB_USER":  i.Username,
 		"GITHUB_TOKEN": i.Token,
 	}
 }
+
+func (i *TokenIdentity) AuthenticateHTTPRequest(req *http.Request) {
+	req.SetBasicAuth(i.Username, i.Token)
+}
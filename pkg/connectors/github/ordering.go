@@ -0,0 +1,44 @@
+package github
+
+import (
+	"context"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// GitHub does not guarantee that deliveries are received in order. Push
+// events form a chain through their old and new revisions, which lets us
+// detect a push delivered after the push which followed it.
+//
+// Out of order events are only flagged: they are still dispatched as soon as
+// they are created, and it is up to jobs to ignore them.
+
+// pushEventOutOfOrder indicates whether a push event has been delivered after
+// the next push on the same branch for a job. The query relies on the
+// events_github_push_old_revision_idx partial index; its conditions must
+// match the predicate of the index.
+func pushEventOutOfOrder(conn pg.Conn, jobId eventline.Id, event *PushEvent) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+SELECT EXISTS
+  (SELECT 1
+     FROM events
+     WHERE job_id = $1
+       AND connector = 'github'
+       AND name = 'push'
+       AND data->>'organization' = $2
+       AND data->>'repository' = $3
+       AND data->>'branch' = $4
+       AND data->>'old_revision' = $5)
+`
+	var exists bool
+	err := conn.QueryRow(ctx, query, jobId, event.Organization,
+		event.Repository, event.Branch, event.NewRevision).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
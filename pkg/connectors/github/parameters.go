@@ -1,18 +1,196 @@
 package github
 
 import (
+	"path"
 	"strings"
 
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/exograd/eventline/pkg/utils"
 	"go.n16f.net/ejson"
 )
 
 type Parameters struct {
-	Organization string `json:"organization"`
-	Repository   string `json:"repository,omitempty"`
+	Organization  string `json:"organization"`
+	Repository    string `json:"repository,omitempty"`
+	DedicatedHook bool   `json:"dedicated_hook,omitempty"`
+	Enrich        bool   `json:"enrich,omitempty"`
+
+	// Names or glob patterns of the repositories of the organization covered
+	// by the subscription. Subscriptions with a set of repositories use the
+	// hook of the organization; deliveries are matched against the set when
+	// subscriptions are loaded.
+	Repositories []string `json:"repositories,omitempty"`
+
+	// Glob patterns matched against the name of the branch associated with
+	// events (e.g. "main" for "refs/heads/main").
+	Branches []string `json:"branches,omitempty"`
+
+	// Glob patterns matched against the name of the workflow and list of
+	// conclusions for workflow run events.
+	Workflows   []string `json:"workflows,omitempty"`
+	Conclusions []string `json:"conclusions,omitempty"`
+}
+
+var WorkflowRunConclusionValues = []string{
+	"action_required",
+	"cancelled",
+	"failure",
+	"neutral",
+	"skipped",
+	"stale",
+	"startup_failure",
+	"success",
+	"timed_out",
 }
 
 func (p *Parameters) ValidateJSON(v *ejson.Validator) {
 	v.CheckStringNotEmpty("organization", p.Organization)
+
+	if len(p.Repositories) > 0 {
+		v.Check("repositories", p.Repository == "", "unexpected_value",
+			"repositories cannot be set if repository is set")
+
+		v.WithChild("repositories", func() {
+			for i, pattern := range p.Repositories {
+				if !v.CheckStringNotEmpty(i, pattern) {
+					continue
+				}
+
+				_, err := path.Match(pattern, "")
+				v.Check(i, err == nil, "invalid_pattern",
+					"invalid repository pattern")
+			}
+		})
+	}
+
+	v.WithChild("branches", func() {
+		for i, pattern := range p.Branches {
+			if !v.CheckStringNotEmpty(i, pattern) {
+				continue
+			}
+
+			_, err := path.Match(pattern, "")
+			v.Check(i, err == nil, "invalid_pattern", "invalid branch pattern")
+		}
+	})
+
+	v.WithChild("workflows", func() {
+		for i, pattern := range p.Workflows {
+			if !v.CheckStringNotEmpty(i, pattern) {
+				continue
+			}
+
+			_, err := path.Match(pattern, "")
+			v.Check(i, err == nil, "invalid_pattern",
+				"invalid workflow pattern")
+		}
+	})
+
+	v.WithChild("conclusions", func() {
+		for i, conclusion := range p.Conclusions {
+			v.CheckStringValue(i, conclusion, WorkflowRunConclusionValues)
+		}
+	})
+}
+
+// MatchEvent indicates whether an event is covered by the repositories,
+// branches and workflows of the subscription.
+func (p *Parameters) MatchEvent(eventData eventline.EventData) bool {
+	return p.MatchRepository(EventRepository(eventData)) &&
+		p.MatchBranches(EventBranches(eventData)) &&
+		p.MatchWorkflowRun(eventData)
+}
+
+// MatchRepository indicates whether the subscription covers a repository.
+// Subscriptions without any set of repositories cover all the repositories
+// of the organization, and events which are not associated with any
+// repository.
+func (p *Parameters) MatchRepository(repository string) bool {
+	if len(p.Repositories) == 0 {
+		return true
+	}
+
+	if repository == "" {
+		return false
+	}
+
+	repository = strings.ToLower(repository)
+
+	for _, pattern := range p.Repositories {
+		pattern = strings.ToLower(pattern)
+
+		if match, _ := path.Match(pattern, repository); match {
+			return true
+		}
+	}
+
+	return false
+}
+
+// MatchBranches indicates whether the subscription covers at least one of a
+// set of branches. Subscriptions without branch patterns cover all branches,
+// and events which are not associated with any branch.
+func (p *Parameters) MatchBranches(branches []string) bool {
+	if len(p.Branches) == 0 {
+		return true
+	}
+
+	for _, pattern := range p.Branches {
+		for _, branch := range branches {
+			if match, _ := path.Match(pattern, branch); match {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// MatchWorkflowRun indicates whether the subscription covers the workflow and
+// the conclusion of a workflow run event. Other events are always covered.
+func (p *Parameters) MatchWorkflowRun(eventData eventline.EventData) bool {
+	run, ok := eventData.(*WorkflowRunEvent)
+	if !ok {
+		return true
+	}
+
+	if len(p.Workflows) > 0 {
+		var matched bool
+
+		for _, pattern := range p.Workflows {
+			if match, _ := path.Match(pattern, run.Workflow); match {
+				matched = true
+				break
+			}
+		}
+
+		if !matched {
+			return false
+		}
+	}
+
+	if len(p.Conclusions) > 0 {
+		return utils.StringsContain(p.Conclusions, run.Conclusion)
+	}
+
+	return true
+}
+
+func (p *Parameters) MatchAttributes() eventline.MatchAttributes {
+	// GitHub organization and repository names are case-insensitive, so
+	// match attributes use a canonical lower case form.
+	attrs := eventline.MatchAttributes{
+		"organization": strings.ToLower(p.Organization),
+		"repository":   strings.ToLower(p.Repository),
+	}
+
+	// Subscriptions with a dedicated hook must never match deliveries of
+	// shared hooks.
+	if p.DedicatedHook {
+		attrs["dedicated_hook"] = "true"
+	}
+
+	return attrs
 }
 
 func (p *Parameters) Target() string {
@@ -0,0 +1,195 @@
+package github
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// Webhook deliveries are not processed by the HTTP handler: once their
+// signature has been validated, they are stored in a queue and acknowledged
+// immediately. The connector worker then decodes them and creates events.
+//
+// Deliveries are processed in the order they were received for each ordering
+// key, i.e. for each repository, so that events such as the renaming and the
+// deletion of a repository are never handled in the wrong order. A delivery
+// whose processing fails blocks the following deliveries with the same key
+// until it is either processed or dropped after MaxDeliveryAttempts attempts.
+
+const MaxDeliveryAttempts = 10
+
+type QueuedDelivery struct {
+	Id              eventline.Id
+	OrderingKey     string
+	ReceptionTime   time.Time
+	Target          string        // shared hooks only
+	SubscriptionId  *eventline.Id // dedicated hooks only
+	RawEvent        *RawEvent
+	Payload         []byte
+	NbAttempts      int
+	NextAttemptTime time.Time
+	LastError       string
+}
+
+// DeliveryOrderingKey returns the key used to order the processing of a
+// delivery: the full name of the repository in lower case if the payload
+// references one, the name of the organization otherwise. The fallback value
+// is used for payloads referencing neither.
+func DeliveryOrderingKey(payload []byte, fallback string) string {
+	var value struct {
+		Repository *struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+		Organization *struct {
+			Login string `json:"login"`
+		} `json:"organization"`
+	}
+
+	if err := json.Unmarshal(payload, &value); err == nil {
+		if value.Repository != nil && value.Repository.FullName != "" {
+			return strings.ToLower(value.Repository.FullName)
+		}
+
+		if value.Organization != nil && value.Organization.Login != "" {
+			return strings.ToLower(value.Organization.Login)
+		}
+	}
+
+	return strings.ToLower(fallback)
+}
+
+// DeliveryRetryDelay returns the delay before the next attempt to process a
+// delivery after a failure.
+func DeliveryRetryDelay(nbAttempts int) time.Duration {
+	delay := 5 * time.Second
+
+	for i := 1; i < nbAttempts && delay < 5*time.Minute; i++ {
+		delay *= 2
+	}
+
+	return min(delay, 5*time.Minute)
+}
+
+// LoadQueuedDeliveryForProcessing returns the oldest delivery ready to be
+// processed, ignoring deliveries which follow another queued delivery with
+// the same ordering key.
+func LoadQueuedDeliveryForProcessing(conn pg.Conn) (*QueuedDelivery, error) {
+	now := time.Now().UTC()
+
+	query := `
+SELECT d.id, d.ordering_key, d.reception_time, d.target, d.subscription_id,
+       d.raw_event, d.payload, d.nb_attempts, d.next_attempt_time,
+       d.last_error
+  FROM c_github_queued_deliveries AS d
+  WHERE d.next_attempt_time <= $1
+    AND NOT EXISTS
+      (SELECT 1
+         FROM c_github_queued_deliveries AS d2
+         WHERE d2.ordering_key = d.ordering_key
+           AND (d2.reception_time, d2.id) < (d.reception_time, d.id))
+  ORDER BY d.reception_time
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED;
+`
+	var d QueuedDelivery
+	err := pg.QueryObject(conn, &d, query, now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &d, nil
+}
+
+func (d *QueuedDelivery) LoadForUpdate(conn pg.Conn, id eventline.Id) error {
+	query := `
+SELECT id, ordering_key, reception_time, target, subscription_id,
+       raw_event, payload, nb_attempts, next_attempt_time, last_error
+  FROM c_github_queued_deliveries
+  WHERE id = $1
+  FOR UPDATE;
+`
+	return pg.QueryObject(conn, d, query, id)
+}
+
+func (d *QueuedDelivery) Insert(conn pg.Conn) error {
+	query := `
+INSERT INTO c_github_queued_deliveries
+    (id, ordering_key, reception_time, target, subscription_id,
+     raw_event, payload, nb_attempts, next_attempt_time, last_error)
+  VALUES
+    ($1, $2, $3, $4, $5,
+     $6, $7, $8, $9, $10);
+`
+	rawEvent, err := json.Marshal(d.RawEvent)
+	if err != nil {
+		return fmt.Errorf("cannot encode raw event: %w", err)
+	}
+
+	return pg.Exec(conn, query,
+		d.Id, d.OrderingKey, d.ReceptionTime, nullString(d.Target),
+		d.SubscriptionId, rawEvent, d.Payload, d.NbAttempts,
+		d.NextAttemptTime, nullString(d.LastError))
+}
+
+func (d *QueuedDelivery) UpdateAttempt(conn pg.Conn) error {
+	query := `
+UPDATE c_github_queued_deliveries SET
+    nb_attempts = $2,
+    next_attempt_time = $3,
+    last_error = $4
+  WHERE id = $1;
+`
+	return pg.Exec(conn, query,
+		d.Id, d.NbAttempts, d.NextAttemptTime, nullString(d.LastError))
+}
+
+func (d *QueuedDelivery) Delete(conn pg.Conn) error {
+	query := `
+DELETE FROM c_github_queued_deliveries
+  WHERE id = $1;
+`
+	return pg.Exec(conn, query, d.Id)
+}
+
+func (d *QueuedDelivery) FromRow(row pgx.Row) error {
+	var target, lastError *string
+	var rawEvent []byte
+
+	err := row.Scan(&d.Id, &d.OrderingKey, &d.ReceptionTime, &target,
+		&d.SubscriptionId, &rawEvent, &d.Payload, &d.NbAttempts,
+		&d.NextAttemptTime, &lastError)
+	if err != nil {
+		return err
+	}
+
+	if target != nil {
+		d.Target = *target
+	}
+
+	if lastError != nil {
+		d.LastError = *lastError
+	}
+
+	d.RawEvent = new(RawEvent)
+	if err := json.Unmarshal(rawEvent, d.RawEvent); err != nil {
+		return fmt.Errorf("cannot decode raw event: %w", err)
+	}
+
+	return nil
+}
+
+func nullString(s string) *string {
+	if s == "" {
+		return nil
+	}
+
+	return &s
+}
@@ -0,0 +1,31 @@
+package github
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDeliveryOrderingKey(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("exograd/eventline", DeliveryOrderingKey([]byte(
+		`{"repository": {"full_name": "Exograd/Eventline"},
+          "organization": {"login": "exograd"}}`), "exograd"))
+	assert.Equal("exograd", DeliveryOrderingKey([]byte(
+		`{"organization": {"login": "Exograd"}}`), "foo"))
+	assert.Equal("exograd:eventline", DeliveryOrderingKey([]byte(
+		`{"zen": "Keep it logically awesome."}`), "Exograd:Eventline"))
+	assert.Equal("exograd", DeliveryOrderingKey([]byte(`[]`), "exograd"))
+}
+
+func TestDeliveryRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(5*time.Second, DeliveryRetryDelay(1))
+	assert.Equal(10*time.Second, DeliveryRetryDelay(2))
+	assert.Equal(40*time.Second, DeliveryRetryDelay(4))
+	assert.Equal(5*time.Minute, DeliveryRetryDelay(8))
+	assert.Equal(5*time.Minute, DeliveryRetryDelay(100))
+}
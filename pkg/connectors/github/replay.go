@@ -0,0 +1,237 @@
+package github
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// rawEventReplayBatchSize is the number of raw events processed in each
+// transaction during a replay.
+const rawEventReplayBatchSize = 100
+
+type storedRawEvent struct {
+	Id         eventline.Id
+	Event      RawEvent
+	Parameters Parameters
+}
+
+type storedRawEvents []*storedRawEvent
+
+func (e *storedRawEvent) FromRow(row pgx.Row) error {
+	var rawData []byte
+
+	err := row.Scan(&e.Id, &rawData, &e.Parameters.Organization,
+		&e.Parameters.Repository)
+	if err != nil {
+		return err
+	}
+
+	if err := json.Unmarshal(rawData, &e.Event); err != nil {
+		return fmt.Errorf("cannot decode data: %w", err)
+	}
+
+	return nil
+}
+
+func (es *storedRawEvents) AddFromRow(row pgx.Row) error {
+	var e storedRawEvent
+	if err := e.FromRow(row); err != nil {
+		return err
+	}
+
+	*es = append(*es, &e)
+	return nil
+}
+
+// ReplayRawEvents runs all stored raw events through high level event
+// decoding and creates the events whose name is part of enames. It is used to
+// backfill events of types which were not supported when the webhook was
+// originally delivered.
+//
+// Raw events are only stored for jobs subscribed to them, so deliveries which
+// were not captured by any raw subscription cannot be replayed. Events which
+// already exist for a job, i.e. with the same name and data, are not created
+// again.
+//
+// Raw events are processed in batches, each one in its own transaction, so
+// that large replays do not hold a long transaction; if a batch fails, the
+// events created by previous batches are kept, and replaying again does not
+// create them a second time.
+//
+// The function returns the number of events created.
+func (c *Connector) ReplayRawEvents(enames []string) (int, error) {
+	enameSet := make(map[string]struct{})
+	for _, ename := range enames {
+		enameSet[ename] = struct{}{}
+	}
+
+	// Multiple jobs can be subscribed to raw events for the same target, in
+	// which case the same delivery was stored multiple times.
+	deliveries := make(map[string]struct{})
+
+	var nbEvents int
+	var lastId *eventline.Id
+
+	for {
+		var rawEvents storedRawEvents
+
+		err := c.Pg.WithTx(func(conn pg.Conn) (err error) {
+			rawEvents, err = loadStoredRawEvents(conn, lastId,
+				rawEventReplayBatchSize)
+			if err != nil {
+				return fmt.Errorf("cannot load raw events: %w", err)
+			}
+
+			n, err := c.replayRawEventBatch(conn, rawEvents, enameSet,
+				deliveries)
+			if err != nil {
+				return err
+			}
+
+			nbEvents += n
+			return nil
+		})
+		if err != nil {
+			return 0, err
+		}
+
+		if len(rawEvents) < rawEventReplayBatchSize {
+			break
+		}
+
+		lastId = &rawEvents[len(rawEvents)-1].Id
+	}
+
+	return nbEvents, nil
+}
+
+func (c *Connector) replayRawEventBatch(conn pg.Conn, rawEvents storedRawEvents, enameSet, deliveries map[string]struct{}) (int, error) {
+	var nbEvents int
+
+	for _, rawEvent := range rawEvents {
+		params := &rawEvent.Parameters
+
+		deliveryKey := rawEvent.Event.DeliveryId + "/" + params.Target()
+		if _, found := deliveries[deliveryKey]; found {
+			continue
+		}
+		deliveries[deliveryKey] = struct{}{}
+
+		// Use the original payload if it was stored
+		payload := []byte(rawEvent.Event.Payload)
+		if len(payload) == 0 {
+			var err error
+
+			payload, err = json.Marshal(rawEvent.Event.Event)
+			if err != nil {
+				return 0, fmt.Errorf("cannot encode payload: %w", err)
+			}
+		}
+
+		events, err := DecodeWebhookEvents(rawEvent.Event.EventType, payload)
+		if err != nil {
+			c.Log.Error("cannot decode delivery %q: %v",
+				rawEvent.Event.DeliveryId, err)
+			continue
+		}
+
+		for _, event := range events {
+			if _, found := enameSet[event.Name]; !found {
+				continue
+			}
+
+			n, err := c.replayEvent(conn, event, params)
+			if err != nil {
+				return 0, err
+			}
+
+			nbEvents += n
+		}
+	}
+
+	return nbEvents, nil
+}
+
+func (c *Connector) replayEvent(conn pg.Conn, event *WebhookEvent, params *Parameters) (int, error) {
+	subs, err := LoadSubscriptionsByParams(conn, event.Name, params,
+		event.Data)
+	if err != nil {
+		return 0, fmt.Errorf("cannot load subscriptions: %w", err)
+	}
+
+	var nbEvents int
+
+	for _, sub := range subs {
+		exists, err := eventExists(conn, *sub.JobId, event)
+		if err != nil {
+			return 0, fmt.Errorf("cannot check event existence: %w", err)
+		} else if exists {
+			continue
+		}
+
+		newEvent := sub.NewEvent(c.Def.Name, event.Name, event.Time,
+			event.Data)
+
+		if err := newEvent.Insert(conn); err != nil {
+			return 0, fmt.Errorf("cannot insert event: %w", err)
+		}
+
+		nbEvents++
+	}
+
+	return nbEvents, nil
+}
+
+func loadStoredRawEvents(conn pg.Conn, afterId *eventline.Id, limit int) (storedRawEvents, error) {
+	// Raw events do not reference the subscription they were created for, so
+	// we use the current subscription of the job to find the target of the
+	// webhook. Events are paginated by identifier, identifiers being ordered
+	// by creation time.
+	query := `
+SELECT e.id, e.data, gs.organization, gs.repository
+  FROM events AS e
+  JOIN subscriptions AS es ON es.job_id = e.job_id
+  JOIN c_github_subscriptions AS gs ON gs.id = es.id
+  WHERE e.connector = 'github'
+    AND e.name = 'raw'
+    AND e.original_event_id IS NULL
+    AND ($1::KSUID IS NULL OR e.id > $1)
+  ORDER BY e.id
+  LIMIT $2
+`
+
+	var events storedRawEvents
+	err := pg.QueryObjects(conn, &events, query, afterId, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	return events, nil
+}
+
+func eventExists(conn pg.Conn, jobId eventline.Id, event *WebhookEvent) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+SELECT EXISTS
+  (SELECT 1
+     FROM events
+     WHERE job_id = $1
+       AND connector = 'github'
+       AND name = $2
+       AND data = $3)
+`
+	var exists bool
+	err := conn.QueryRow(ctx, query, jobId, event.Name, event.Data).
+		Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+
+	return exists, nil
+}
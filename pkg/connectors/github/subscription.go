@@ -2,6 +2,7 @@ package github
 
 import (
 	"context"
+	"crypto/sha256"
 	"errors"
 	"fmt"
 
@@ -19,35 +20,40 @@ func (err UnknownSubscriptionError) Error() string {
 }
 
 type Subscription struct {
-	Id           eventline.Id
-	Organization string
-	Repository   string // optional
-	HookId       HookId // either an org hook or a repo hook
+	Id               eventline.Id
+	Organization     string
+	Repository       string // optional
+	HookId           HookId // either an org hook or a repo hook
+	WebhookTokenHash []byte // only for subscriptions with a dedicated hook
+
+	// The project webhook key used to derive the secret of the dedicated
+	// hook. Dedicated hooks created before the introduction of project
+	// webhook keys use the global webhook secret.
+	WebhookKeyId *eventline.Id
+}
+
+func HashWebhookToken(token string) []byte {
+	hash := sha256.Sum256([]byte(token))
+	return hash[:]
 }
 
 func LoadHookIdByParameters(conn pg.Conn, params *Parameters) (*HookId, error) {
 	ctx := context.Background()
 
-	repoCond := "TRUE"
-	if params.Repository != "" {
-		repoCond = "repository = $2"
-	}
-
-	query := fmt.Sprintf(`
+	// Organization hooks and repository hooks are never shared: an
+	// organization subscription must not reuse the hook of a repository.
+	// Names are case-insensitive on GitHub.
+	query := `
 SELECT hook_id
   FROM c_github_subscriptions
-  WHERE organization = $1
-    AND %s
+  WHERE lower(organization) = lower($1)
+    AND lower(repository) = lower($2)
+    AND webhook_token_hash IS NULL
   LIMIT 1
-`, repoCond)
-
-	args := []interface{}{params.Organization}
-	if params.Repository != "" {
-		args = append(args, params.Repository)
-	}
-
+`
 	var hookId HookId
-	err := conn.QueryRow(ctx, query, args...).Scan(&hookId)
+	err := conn.QueryRow(ctx, query, params.Organization,
+		params.Repository).Scan(&hookId)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	} else if err != nil {
@@ -59,7 +65,8 @@ SELECT hook_id
 
 func (s *Subscription) LoadForUpdate(conn pg.Conn, id eventline.Id) error {
 	query := `
-SELECT id, organization, repository, hook_id
+SELECT id, organization, repository, hook_id, webhook_token_hash,
+       webhook_key_id
   FROM c_github_subscriptions
   WHERE id = $1;
 `
@@ -71,6 +78,36 @@ SELECT id, organization, repository, hook_id
 	return err
 }
 
+func LoadSubscriptionByWebhookTokenHash(conn pg.Conn, tokenHash []byte) (*eventline.Subscription, error) {
+	query := `
+SELECT es.id, es.project_id, es.job_id, es.identity_id, es.connector, es.event,
+       es.parameters, es.creation_time, es.status, es.update_delay,
+       es.last_update_time, es.next_update_time
+  FROM subscriptions AS es
+  JOIN c_github_subscriptions AS gs ON gs.id = es.id
+  WHERE gs.webhook_token_hash = $1
+`
+	var sub eventline.Subscription
+	err := pg.QueryObject(conn, &sub, query, tokenHash)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &sub, nil
+}
+
+func (s *Subscription) LoadByWebhookTokenHash(conn pg.Conn, tokenHash []byte) error {
+	query := `
+SELECT id, organization, repository, hook_id, webhook_token_hash,
+       webhook_key_id
+  FROM c_github_subscriptions
+  WHERE webhook_token_hash = $1;
+`
+	return pg.QueryObject(conn, s, query, tokenHash)
+}
+
 func CountSubscriptionsByHookId(conn pg.Conn, hookId HookId) (int64, error) {
 	ctx := context.Background()
 
@@ -91,12 +128,14 @@ SELECT COUNT(*)
 func (s *Subscription) Insert(conn pg.Conn) error {
 	query := `
 INSERT INTO c_github_subscriptions
-    (id, organization, repository, hook_id)
+    (id, organization, repository, hook_id, webhook_token_hash,
+     webhook_key_id)
   VALUES
-    ($1, $2, $3, $4);
+    ($1, $2, $3, $4, $5, $6);
 `
 	return pg.Exec(conn, query,
-		s.Id, s.Organization, s.Repository, s.HookId)
+		s.Id, s.Organization, s.Repository, s.HookId, s.WebhookTokenHash,
+		s.WebhookKeyId)
 }
 
 func (s *Subscription) Delete(conn pg.Conn) error {
@@ -108,5 +147,6 @@ DELETE FROM c_github_subscriptions
 }
 
 func (s *Subscription) FromRow(row pgx.Row) error {
-	return row.Scan(&s.Id, &s.Organization, &s.Repository, &s.HookId)
+	return row.Scan(&s.Id, &s.Organization, &s.Repository, &s.HookId,
+		&s.WebhookTokenHash, &s.WebhookKeyId)
 }
@@ -1,19 +1,48 @@
 package github
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
 	"net/http"
 	"net/url"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
 	"github.com/exograd/eventline/pkg/utils"
+	"github.com/jackc/pgx/v5/pgconn"
 	"go.n16f.net/service/pkg/pg"
 	"github.com/google/go-github/v45/github"
 )
 
+var (
+	ErrUnknownWebhookToken = errors.New("unknown webhook token")
+	ErrWebhookTimeout      = errors.New("webhook processing timeout")
+	ErrRequestTooLarge     = errors.New("request body too large")
+	ErrInvalidSignature    = errors.New("invalid signature")
+)
+
+// InvalidPayloadError is returned when the body of a webhook request cannot be
+// decoded. Such requests are rejected and are not counted as connector
+// failures.
+type InvalidPayloadError struct {
+	Err error
+}
+
+func (err *InvalidPayloadError) Error() string {
+	return fmt.Sprintf("invalid payload: %v", err.Err)
+}
+
+func (err *InvalidPayloadError) Unwrap() error {
+	return err.Err
+}
+
 type InvalidWebhookEventError struct {
 	Msg string
 }
@@ -26,6 +55,28 @@ func (err *InvalidWebhookEventError) Error() string {
 	return fmt.Sprintf("invalid webhook event: %s", err.Msg)
 }
 
+// DeliveryDecodingError is returned when a queued delivery cannot be decoded.
+// Retrying the delivery would not help, so it is dropped.
+type DeliveryDecodingError struct {
+	Err error
+}
+
+func (err *DeliveryDecodingError) Error() string {
+	return fmt.Sprintf("cannot decode delivery: %v", err.Err)
+}
+
+func (err *DeliveryDecodingError) Unwrap() error {
+	return err.Err
+}
+
+type WebhookEvent struct {
+	Name string
+	Time *time.Time
+	Data eventline.EventData
+}
+
+type WebhookEvents []*WebhookEvent
+
 func (c *Connector) WebhookURI(params *Parameters) string {
 	targetPart := url.PathEscape(params.Target())
 	path := "/ext/connectors/github/hooks/" + targetPart
@@ -33,70 +84,488 @@ func (c *Connector) WebhookURI(params *Parameters) string {
 	return uri.String()
 }
 
+// SubscriptionWebhookURI returns the URI of the dedicated hook of a
+// subscription. The token identifies the subscription.
+func (c *Connector) SubscriptionWebhookURI(token string) string {
+	path := "/ext/connectors/github/subscriptions/" + url.PathEscape(token)
+	uri := c.webHTTPServerURI.ResolveReference(&url.URL{Path: path})
+	return uri.String()
+}
+
+// DedicatedHookSecret returns the secret of the dedicated hook identified by
+// a token.
+func DedicatedHookSecret(key *eventline.ProjectWebhookKey, token string) string {
+	return key.WebhookSecret("github/" + token)
+}
+
+// ProcessWebhookRequest handles deliveries of shared hooks. A shared hook
+// serves subscriptions of all projects, so it is always signed with the global
+// webhook secret and not with a project webhook key.
 func (c *Connector) ProcessWebhookRequest(req *http.Request, params *Parameters) error {
-	secret := c.Cfg.WebhookSecret
-	payload, err := github.ValidatePayload(req, []byte(secret))
+	payload, rawEventData, err := c.readWebhookRequest(req,
+		c.Cfg.WebhookSecret)
 	if err != nil {
-		return fmt.Errorf("invalid signature: %w", err)
+		return err
+	}
+
+	if rawEventData.EventType == "ping" {
+		return c.processPingEvent(payload)
+	}
+
+	delivery := c.newQueuedDelivery(payload, rawEventData, params.Target())
+	delivery.Target = params.Target()
+
+	return c.withWebhookTx(func(conn pg.Conn) error {
+		return c.enqueueDelivery(conn, delivery)
+	})
+}
+
+// ProcessSubscriptionWebhookRequest handles deliveries of the dedicated hook
+// of a subscription. Events are only created for this subscription, whatever
+// the organization and repository referenced in the payload.
+//
+// Errors, for example an invalid signature caused by a hook configured with the
+// wrong secret, are recorded on the subscription.
+func (c *Connector) ProcessSubscriptionWebhookRequest(req *http.Request, token string) error {
+	var subId *eventline.Id
+
+	err := c.withWebhookTx(func(conn pg.Conn) error {
+		tokenHash := HashWebhookToken(token)
+
+		sub, err := LoadSubscriptionByWebhookTokenHash(conn, tokenHash)
+		if err != nil {
+			return fmt.Errorf("cannot load subscription: %w", err)
+		} else if sub == nil {
+			return ErrUnknownWebhookToken
+		}
+
+		subId = &sub.Id
+
+		// The secret depends on the subscription, so the payload can only be
+		// validated once the subscription has been loaded.
+		secret, err := c.dedicatedHookSecret(conn, tokenHash, token)
+		if err != nil {
+			return err
+		}
+
+		payload, rawEventData, err := c.readWebhookRequest(req, secret)
+		if err != nil {
+			return err
+		}
+
+		if rawEventData.EventType == "ping" {
+			return c.processPingEvent(payload)
+		}
+
+		params := sub.Parameters.(*Parameters)
+
+		delivery := c.newQueuedDelivery(payload, rawEventData,
+			params.Target())
+		delivery.SubscriptionId = &sub.Id
+
+		if err := c.enqueueDelivery(conn, delivery); err != nil {
+			return err
+		}
+
+		return eventline.ClearSubscriptionErrors(conn, eventline.Ids{sub.Id})
+	})
+	if err != nil && subId != nil && !errors.Is(err, ErrWebhookTimeout) {
+		c.recordSubscriptionError(*subId, err)
+	}
+
+	return err
+}
+
+func (c *Connector) newQueuedDelivery(payload []byte, rawEventData *RawEvent, target string) *QueuedDelivery {
+	now := time.Now().UTC()
+
+	return &QueuedDelivery{
+		Id:              eventline.GenerateId(),
+		OrderingKey:     DeliveryOrderingKey(payload, target),
+		ReceptionTime:   *rawEventData.ReceptionTime,
+		RawEvent:        rawEventData,
+		Payload:         payload,
+		NextAttemptTime: now,
+	}
+}
+
+// enqueueDelivery stores a delivery for processing by the connector worker.
+// Duplicate deliveries are ignored.
+func (c *Connector) enqueueDelivery(conn pg.Conn, delivery *QueuedDelivery) error {
+	if isNew, err := c.registerDelivery(conn, delivery.RawEvent); err != nil {
+		return err
+	} else if !isNew {
+		return nil
+	}
+
+	if err := delivery.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert queued delivery: %w", err)
+	}
+
+	return nil
+}
+
+// ProcessQueuedDelivery decodes a queued delivery and creates the associated
+// events. It must be called in the transaction used to delete the delivery
+// from the queue.
+//
+// Deliveries which cannot be decoded do not create any event, not even a raw
+// event; a DeliveryDecodingError is returned so that the delivery is dropped
+// and reported as failed.
+func (c *Connector) ProcessQueuedDelivery(conn pg.Conn, delivery *QueuedDelivery) error {
+	if delivery.SubscriptionId != nil {
+		return c.processSubscriptionDelivery(conn, delivery)
+	}
+
+	var params Parameters
+	params.ParseTarget(delivery.Target)
+
+	rawEventData := delivery.RawEvent
+
+	events, err := DecodeWebhookEvents(rawEventData.EventType,
+		delivery.Payload)
+	if err != nil {
+		return &DeliveryDecodingError{Err: err}
 	}
 
 	// Raw events are generated for all types of payloads
+	err = c.CreateEvents(conn, "raw", nil, rawEventData, &params)
+	if err != nil {
+		return fmt.Errorf("cannot create event: %w", err)
+	}
+
+	for _, event := range events {
+		err := c.CreateEvents(conn, event.Name, event.Time, event.Data,
+			&params)
+		if err != nil {
+			return fmt.Errorf("cannot create event: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (c *Connector) processSubscriptionDelivery(conn pg.Conn, delivery *QueuedDelivery) error {
+	var sub eventline.Subscription
+	if err := sub.Load(conn, *delivery.SubscriptionId); err != nil {
+		var unknownSubscriptionErr *eventline.UnknownSubscriptionError
+		if errors.As(err, &unknownSubscriptionErr) {
+			// The subscription was deleted after the delivery was queued
+			return nil
+		}
+
+		return fmt.Errorf("cannot load subscription: %w", err)
+	}
+
+	rawEventData := delivery.RawEvent
+
+	var events WebhookEvents
+
+	if sub.Event == "raw" {
+		events = WebhookEvents{&WebhookEvent{
+			Name: "raw",
+			Data: rawEventData,
+		}}
+	} else {
+		var err error
+		events, err = DecodeWebhookEvents(rawEventData.EventType,
+			delivery.Payload)
+		if err != nil {
+			c.Log.Error("cannot decode delivery %q: %v",
+				rawEventData.DeliveryId, err)
+
+			now := time.Now().UTC()
+			return eventline.RecordSubscriptionError(conn, sub.Id, err, now)
+		}
+	}
+
+	params := sub.Parameters.(*Parameters)
+
+	for _, event := range events {
+		if event.Name != sub.Event {
+			continue
+		}
+
+		if !params.MatchEvent(event.Data) {
+			continue
+		}
+
+		err := c.insertEvent(conn, &sub, event.Name, event.Time,
+			event.Data)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (c *Connector) recordSubscriptionError(subId eventline.Id, err error) {
+	now := time.Now().UTC()
+
+	err2 := c.Pg.WithTx(func(conn pg.Conn) error {
+		return eventline.RecordSubscriptionError(conn, subId, err, now)
+	})
+	if err2 != nil {
+		c.Log.Error("cannot record error of subscription %q: %v",
+			subId, err2)
+	}
+}
+
+// processPingEvent handles the ping event sent by GitHub when a hook is
+// created or when a delivery is triggered from the GitHub interface. It only
+// confirms that the hook works: no event is created.
+func (c *Connector) processPingEvent(payload []byte) error {
+	var event github.PingEvent
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return &InvalidPayloadError{Err: err}
+	}
+
+	if event.HookID == nil {
+		return NewInvalidWebhookEventError("missing hook id")
+	}
+
+	if event.Zen == nil {
+		return NewInvalidWebhookEventError("missing zen")
+	}
+
+	c.Log.Info("received ping event for hook %d", *event.HookID)
+
+	return nil
+}
+
+// registerDelivery returns false if the delivery was already processed. It
+// must be called in the transaction used to create events.
+func (c *Connector) registerDelivery(conn pg.Conn, rawEventData *RawEvent) (bool, error) {
+	deliveryId := rawEventData.DeliveryId
+	if deliveryId == "" {
+		return true, nil
+	}
+
+	period := time.Duration(c.Cfg.DeduplicationPeriod) * time.Second
+
+	isNew, err := RegisterDelivery(conn, deliveryId, period)
+	if err != nil {
+		return false, fmt.Errorf("cannot register delivery: %w", err)
+	}
+
+	if !isNew {
+		c.Log.Debug(1, "ignoring duplicate delivery %q", deliveryId)
+	}
+
+	return isNew, nil
+}
+
+func (c *Connector) dedicatedHookSecret(conn pg.Conn, tokenHash []byte, token string) (string, error) {
+	var sub Subscription
+	if err := sub.LoadByWebhookTokenHash(conn, tokenHash); err != nil {
+		return "", fmt.Errorf("cannot load github subscription: %w", err)
+	}
+
+	if sub.WebhookKeyId == nil {
+		return c.Cfg.WebhookSecret, nil
+	}
+
+	var key eventline.ProjectWebhookKey
+	if err := key.Load(conn, *sub.WebhookKeyId); err != nil {
+		return "", fmt.Errorf("cannot load project webhook key: %w", err)
+	}
+
+	return DedicatedHookSecret(&key, token), nil
+}
+
+func (c *Connector) readWebhookRequest(req *http.Request, secret string) ([]byte, *RawEvent, error) {
+	// The body is read before the signature can be validated, so its size
+	// must be limited whoever the sender is. The response writer is not
+	// available here; the HTTP handler replies with a 413 status itself.
+	maxSize := int64(c.Cfg.MaxRequestSize)
+	body, err := io.ReadAll(http.MaxBytesReader(nil, req.Body, maxSize))
+	if err != nil {
+		var maxBytesErr *http.MaxBytesError
+		if errors.As(err, &maxBytesErr) {
+			return nil, nil, ErrRequestTooLarge
+		}
+
+		return nil, nil, fmt.Errorf("cannot read request body: %w", err)
+	}
+
+	algorithm, err := ValidateWebhookSignature(req.Header, body, secret)
+	if err != nil {
+		return nil, nil, fmt.Errorf("%w: %v", ErrInvalidSignature, err)
+	}
+
+	c.Log.Debug(1, "webhook signature validated with %s", algorithm)
+
+	contentType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		err = fmt.Errorf("invalid content type: %w", err)
+		return nil, nil, &InvalidPayloadError{Err: err}
+	}
+
+	// The signature has already been validated; we only use go-github to
+	// extract the payload, which depends on the content type.
+	payload, err := github.ValidatePayloadFromBody(contentType,
+		bytes.NewReader(body), "", nil)
+	if err != nil {
+		return nil, nil, &InvalidPayloadError{Err: err}
+	}
+
 	var rawMsg interface{}
 	if err := json.Unmarshal(payload, &rawMsg); err != nil {
-		return fmt.Errorf("cannot decode payload: %w", err)
+		return nil, nil, &InvalidPayloadError{Err: err}
 	}
 
+	now := time.Now().UTC()
+
 	rawEventData := RawEvent{
-		DeliveryId: github.DeliveryID(req),
-		EventType:  github.WebHookType(req),
-		Event:      rawMsg,
+		DeliveryId:    github.DeliveryID(req),
+		EventType:     github.WebHookType(req),
+		ReceptionTime: &now,
+		Event:         rawMsg,
+		GitHubHeaders: RawEventGitHubHeaders(req.Header),
 	}
 
-	if err := c.CreateEvents("raw", nil, &rawEventData, params); err != nil {
-		return fmt.Errorf("cannot create event: %w", err)
+	if c.Cfg.StoreRawPayloads {
+		rawEventData.Payload = string(payload)
+
+		rawEventData.Headers = make(map[string]string)
+		for name, values := range req.Header {
+			rawEventData.Headers[name] = strings.Join(values, ", ")
+		}
 	}
 
-	// Decode the payload to determine which high level events to create
-	event, err := github.ParseWebHook(github.WebHookType(req), payload)
+	return payload, &rawEventData, nil
+}
+
+// ValidateWebhookSignature validates the HMAC signature of a request body and
+// returns the algorithm used. The SHA256 signature is used if it is present;
+// the SHA1 signature is only used as a fallback for senders which do not
+// provide a SHA256 signature.
+func ValidateWebhookSignature(header http.Header, body []byte, secret string) (string, error) {
+	signatures := []struct {
+		algorithm string
+		header    string
+	}{
+		{"sha256", github.SHA256SignatureHeader},
+		{"sha1", github.SHA1SignatureHeader},
+	}
+
+	for _, s := range signatures {
+		signature := header.Get(s.header)
+		if signature == "" {
+			continue
+		}
+
+		// The prefix indicates the hash function used by ValidateSignature;
+		// it must match the header so that a SHA256 header cannot be used
+		// to smuggle a weaker signature.
+		if !strings.HasPrefix(signature, s.algorithm+"=") {
+			return "", fmt.Errorf("invalid %s header", s.header)
+		}
+
+		err := github.ValidateSignature(signature, body, []byte(secret))
+		if err != nil {
+			return "", err
+		}
+
+		return s.algorithm, nil
+	}
+
+	return "", fmt.Errorf("missing %s and %s headers",
+		github.SHA256SignatureHeader, github.SHA1SignatureHeader)
+}
+
+// DecodeWebhookEvents returns the high level events matching a webhook
+// payload. It does not perform any signature validation, and can therefore
+// be used both for new deliveries and for stored raw events.
+func DecodeWebhookEvents(eventType string, payload []byte) (WebhookEvents, error) {
+	event, err := github.ParseWebHook(eventType, payload)
 	if err != nil {
-		return fmt.Errorf("cannot parse webhook event: %w", err)
+		return nil, fmt.Errorf("cannot parse webhook event: %w", err)
 	}
 
 	switch e := event.(type) {
 	case *github.RepositoryEvent:
 		if e.Action == nil {
-			return NewInvalidWebhookEventError("missing action")
+			return nil, NewInvalidWebhookEventError("missing action")
 		}
 
 		switch *e.Action {
 		case "created":
-			return c.processWebhookEventRepositoryCreated(e, params)
+			return decodeWebhookEventRepositoryCreated(e)
 		case "deleted":
-			return c.processWebhookEventRepositoryDeleted(e, params)
+			return decodeWebhookEventRepositoryDeleted(e)
 		}
 
+	case *github.CreateEvent:
+		return decodeWebhookEventCreate(e)
+
+	case *github.DeleteEvent:
+		return decodeWebhookEventDelete(e)
+
 	case *github.PushEvent:
-		return c.processWebhookEventPush(e, params)
+		return decodeWebhookEventPush(e)
+
+	case *github.StatusEvent:
+		return decodeWebhookEventStatus(e)
+
+	case *github.CommitCommentEvent:
+		if e.Action == nil {
+			return nil, NewInvalidWebhookEventError("missing action")
+		}
+
+		if *e.Action == "created" {
+			return decodeWebhookEventCommitComment(e, payload)
+		}
+
+	case *github.PullRequestEvent:
+		if e.Action == nil {
+			return nil, NewInvalidWebhookEventError("missing action")
+		}
+
+		switch *e.Action {
+		case "opened", "closed", "synchronize":
+			return decodeWebhookEventPullRequest(e)
+		}
+
+	case *github.WorkflowRunEvent:
+		if e.Action == nil {
+			return nil, NewInvalidWebhookEventError("missing action")
+		}
+
+		if *e.Action == "completed" {
+			return decodeWebhookEventWorkflowRun(e)
+		}
+
+	case *github.ReleaseEvent:
+		if e.Action == nil {
+			return nil, NewInvalidWebhookEventError("missing action")
+		}
+
+		switch *e.Action {
+		case "published", "released", "prereleased", "edited", "deleted":
+			return decodeWebhookEventRelease(e)
+		}
 	}
 
-	return nil
+	return nil, nil
 }
 
-func (c *Connector) processWebhookEventRepositoryCreated(e *github.RepositoryEvent, params *Parameters) error {
+func decodeWebhookEventRepositoryCreated(e *github.RepositoryEvent) (WebhookEvents, error) {
 	if e.Org == nil {
-		return NewInvalidWebhookEventError("missing organization")
+		return nil, NewInvalidWebhookEventError("missing organization")
 	}
 
 	if e.Org.Login == nil {
-		return NewInvalidWebhookEventError("missing organization login")
+		return nil, NewInvalidWebhookEventError("missing organization login")
 	}
 
 	if e.Repo == nil {
-		return NewInvalidWebhookEventError("missing repository")
+		return nil, NewInvalidWebhookEventError("missing repository")
 	}
 
 	if e.Repo.Name == nil {
-		return NewInvalidWebhookEventError("missing repository name")
+		return nil, NewInvalidWebhookEventError("missing repository name")
 	}
 
 	var eventTime *time.Time
@@ -109,29 +578,30 @@ func (c *Connector) processWebhookEventRepositoryCreated(e *github.RepositoryEve
 		Repository:   *e.Repo.Name,
 	}
 
-	err := c.CreateEvents("repository_creation", eventTime, &eventData, params)
-	if err != nil {
-		return fmt.Errorf("cannot create event: %w", err)
+	event := WebhookEvent{
+		Name: "repository_creation",
+		Time: eventTime,
+		Data: &eventData,
 	}
 
-	return nil
+	return WebhookEvents{&event}, nil
 }
 
-func (c *Connector) processWebhookEventRepositoryDeleted(e *github.RepositoryEvent, params *Parameters) error {
+func decodeWebhookEventRepositoryDeleted(e *github.RepositoryEvent) (WebhookEvents, error) {
 	if e.Org == nil {
-		return NewInvalidWebhookEventError("missing organization")
+		return nil, NewInvalidWebhookEventError("missing organization")
 	}
 
 	if e.Org.Login == nil {
-		return NewInvalidWebhookEventError("missing organization login")
+		return nil, NewInvalidWebhookEventError("missing organization login")
 	}
 
 	if e.Repo == nil {
-		return NewInvalidWebhookEventError("missing repository")
+		return nil, NewInvalidWebhookEventError("missing repository")
 	}
 
 	if e.Repo.Name == nil {
-		return NewInvalidWebhookEventError("missing repository name")
+		return nil, NewInvalidWebhookEventError("missing repository name")
 	}
 
 	var eventTime *time.Time
@@ -144,45 +614,151 @@ func (c *Connector) processWebhookEventRepositoryDeleted(e *github.RepositoryEve
 		Repository:   *e.Repo.Name,
 	}
 
-	err := c.CreateEvents("repository_deletion", eventTime, &eventData, params)
+	event := WebhookEvent{
+		Name: "repository_deletion",
+		Time: eventTime,
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+// Create events are also sent when a repository is created, with the
+// "repository" ref type; they are ignored since repository_creation events
+// cover this case.
+
+func decodeWebhookEventCreate(e *github.CreateEvent) (WebhookEvents, error) {
+	organization, repository, err := decodeWebhookEventRepo(e.Repo)
 	if err != nil {
-		return fmt.Errorf("cannot create event: %w", err)
+		return nil, err
 	}
 
-	return nil
+	ref, refType, err := decodeWebhookEventRef(e.Ref, e.RefType)
+	if err != nil {
+		return nil, err
+	} else if refType == "" {
+		return nil, nil
+	}
+
+	eventData := RefCreatedEvent{
+		Organization: organization,
+		Repository:   repository,
+		Ref:          ref,
+		RefType:      refType,
+	}
+
+	event := WebhookEvent{
+		Name: "ref_created",
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func decodeWebhookEventDelete(e *github.DeleteEvent) (WebhookEvents, error) {
+	organization, repository, err := decodeWebhookEventRepo(e.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	ref, refType, err := decodeWebhookEventRef(e.Ref, e.RefType)
+	if err != nil {
+		return nil, err
+	} else if refType == "" {
+		return nil, nil
+	}
+
+	eventData := RefDeletedEvent{
+		Organization: organization,
+		Repository:   repository,
+		Ref:          ref,
+		RefType:      refType,
+	}
+
+	event := WebhookEvent{
+		Name: "ref_deleted",
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
 }
 
-func (c *Connector) processWebhookEventPush(e *github.PushEvent, params *Parameters) error {
+// decodeWebhookEventRepo returns the owner and the name of the repository of
+// an event. The owner is used as organization since create, delete and
+// release payloads do not always contain an organization.
+func decodeWebhookEventRepo(repo *github.Repository) (string, string, error) {
+	if repo == nil {
+		return "", "", NewInvalidWebhookEventError("missing repository")
+	}
+
+	if repo.Name == nil {
+		return "", "", NewInvalidWebhookEventError("missing repository name")
+	}
+
+	if repo.Owner == nil || repo.Owner.Login == nil {
+		return "", "",
+			NewInvalidWebhookEventError("missing repository owner")
+	}
+
+	return *repo.Owner.Login, *repo.Name, nil
+}
+
+// decodeWebhookEventRef returns the ref and the ref type of a create or
+// delete event. The ref type is empty for refs which are neither branches nor
+// tags.
+func decodeWebhookEventRef(ref, refType *string) (string, string, error) {
+	if ref == nil {
+		return "", "", NewInvalidWebhookEventError("missing ref")
+	}
+
+	if refType == nil {
+		return "", "", NewInvalidWebhookEventError("missing ref type")
+	}
+
+	switch *refType {
+	case "branch", "tag":
+		return *ref, *refType, nil
+	}
+
+	return *ref, "", nil
+}
+
+// GitHub uses a revision made of zeros when there is no previous revision,
+// e.g. for the first push of a branch.
+const zeroRevision = "0000000000000000000000000000000000000000"
+
+func decodeWebhookEventPush(e *github.PushEvent) (WebhookEvents, error) {
 	const tagsRefPrefix = "refs/tags/"
 	const headsRefPrefix = "refs/heads/"
-	const zeroHash = "0000000000000000000000000000000000000000"
+
+	var events WebhookEvents
 
 	if e.Organization == nil {
-		return NewInvalidWebhookEventError("missing organization")
+		return nil, NewInvalidWebhookEventError("missing organization")
 	}
 
 	if e.Organization.Login == nil {
-		return NewInvalidWebhookEventError("missing organization login")
+		return nil, NewInvalidWebhookEventError("missing organization login")
 	}
 
 	if e.Repo == nil {
-		return NewInvalidWebhookEventError("missing repository")
+		return nil, NewInvalidWebhookEventError("missing repository")
 	}
 
 	if e.Repo.Name == nil {
-		return NewInvalidWebhookEventError("missing repository name")
+		return nil, NewInvalidWebhookEventError("missing repository name")
 	}
 
 	if e.Ref == nil {
-		return NewInvalidWebhookEventError("missing ref")
+		return nil, NewInvalidWebhookEventError("missing ref")
 	}
 
 	if e.Before == nil {
-		return NewInvalidWebhookEventError("missing before hash")
+		return nil, NewInvalidWebhookEventError("missing before hash")
 	}
 
 	if e.After == nil {
-		return NewInvalidWebhookEventError("missing after hash")
+		return nil, NewInvalidWebhookEventError("missing after hash")
 	}
 
 	ref := *e.Ref
@@ -199,10 +775,10 @@ func (c *Connector) processWebhookEventPush(e *github.PushEvent, params *Paramet
 			Revision:     *e.After,
 		}
 
-		err := c.CreateEvents("tag_creation", nil, &eventData, params)
-		if err != nil {
-			return fmt.Errorf("cannot create event: %w", err)
-		}
+		events = append(events, &WebhookEvent{
+			Name: "tag_creation",
+			Data: &eventData,
+		})
 
 	case strings.HasPrefix(ref, tagsRefPrefix) && deleted:
 		eventData := TagDeletionEvent{
@@ -212,10 +788,10 @@ func (c *Connector) processWebhookEventPush(e *github.PushEvent, params *Paramet
 			Revision:     *e.Before,
 		}
 
-		err := c.CreateEvents("tag_deletion", nil, &eventData, params)
-		if err != nil {
-			return fmt.Errorf("cannot create event: %w", err)
-		}
+		events = append(events, &WebhookEvent{
+			Name: "tag_deletion",
+			Data: &eventData,
+		})
 
 	case strings.HasPrefix(ref, headsRefPrefix) && created:
 		eventData := BranchCreationEvent{
@@ -225,10 +801,10 @@ func (c *Connector) processWebhookEventPush(e *github.PushEvent, params *Paramet
 			Revision:     *e.After,
 		}
 
-		err := c.CreateEvents("branch_creation", nil, &eventData, params)
-		if err != nil {
-			return fmt.Errorf("cannot create event: %w", err)
-		}
+		events = append(events, &WebhookEvent{
+			Name: "branch_creation",
+			Data: &eventData,
+		})
 
 	case strings.HasPrefix(ref, headsRefPrefix) && deleted:
 		eventData := BranchDeletionEvent{
@@ -238,10 +814,10 @@ func (c *Connector) processWebhookEventPush(e *github.PushEvent, params *Paramet
 			Revision:     *e.Before,
 		}
 
-		err := c.CreateEvents("branch_deletion", nil, &eventData, params)
-		if err != nil {
-			return fmt.Errorf("cannot create event: %w", err)
-		}
+		events = append(events, &WebhookEvent{
+			Name: "branch_deletion",
+			Data: &eventData,
+		})
 
 	case strings.HasPrefix(ref, headsRefPrefix) && !created && !deleted:
 		eventData := PushEvent{
@@ -249,71 +825,562 @@ func (c *Connector) processWebhookEventPush(e *github.PushEvent, params *Paramet
 			Repository:   *e.Repo.Name,
 			Branch:       ref[len(headsRefPrefix):],
 			NewRevision:  *e.After,
+
+			ModifiedFiles: pushEventModifiedFiles(e.Commits),
 		}
 
 		// The first push in a new repository does not have a previous
 		// revision.
-		if *e.Before != zeroHash {
+		if *e.Before != zeroRevision {
 			eventData.OldRevision = *e.Before
 		}
 
-		err := c.CreateEvents("push", nil, &eventData, params)
+		var eventTime *time.Time
+		if e.HeadCommit != nil && e.HeadCommit.Timestamp != nil {
+			eventTime = utils.Ref(e.HeadCommit.Timestamp.UTC())
+		}
+
+		events = append(events, &WebhookEvent{
+			Name: "push",
+			Time: eventTime,
+			Data: &eventData,
+		})
+	}
+
+	return events, nil
+}
+
+// pushEventModifiedFiles returns the sorted list of the files added, removed
+// or modified by a set of commits. Note that GitHub only includes the first
+// 20 commits in push payloads.
+func pushEventModifiedFiles(commits []*github.HeadCommit) []string {
+	fileSet := make(map[string]struct{})
+
+	for _, commit := range commits {
+		for _, files := range [][]string{
+			commit.Added, commit.Removed, commit.Modified,
+		} {
+			for _, file := range files {
+				fileSet[file] = struct{}{}
+			}
+		}
+	}
+
+	if len(fileSet) == 0 {
+		return nil
+	}
+
+	files := make([]string, 0, len(fileSet))
+	for file := range fileSet {
+		files = append(files, file)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
+
+func decodeWebhookEventStatus(e *github.StatusEvent) (WebhookEvents, error) {
+	if e.Repo == nil {
+		return nil, NewInvalidWebhookEventError("missing repository")
+	}
+
+	if e.Repo.Name == nil {
+		return nil, NewInvalidWebhookEventError("missing repository name")
+	}
+
+	if e.Repo.Owner == nil || e.Repo.Owner.Login == nil {
+		return nil, NewInvalidWebhookEventError("missing repository owner")
+	}
+
+	if e.SHA == nil {
+		return nil, NewInvalidWebhookEventError("missing hash")
+	}
+
+	if e.State == nil {
+		return nil, NewInvalidWebhookEventError("missing state")
+	}
+
+	var eventTime *time.Time
+	if e.UpdatedAt != nil {
+		eventTime = utils.Ref(e.UpdatedAt.UTC())
+	}
+
+	eventData := CommitStatusEvent{
+		Organization: *e.Repo.Owner.Login,
+		Repository:   *e.Repo.Name,
+		Revision:     *e.SHA,
+		State:        *e.State,
+		Context:      e.GetContext(),
+		Description:  e.GetDescription(),
+		TargetURI:    e.GetTargetURL(),
+	}
+
+	for _, branch := range e.Branches {
+		if branch.Name != nil {
+			eventData.Branches = append(eventData.Branches, *branch.Name)
+		}
+	}
+
+	event := WebhookEvent{
+		Name: "commit_status",
+		Time: eventTime,
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func decodeWebhookEventCommitComment(e *github.CommitCommentEvent, payload []byte) (WebhookEvents, error) {
+	if e.Repo == nil {
+		return nil, NewInvalidWebhookEventError("missing repository")
+	}
+
+	if e.Repo.Name == nil {
+		return nil, NewInvalidWebhookEventError("missing repository name")
+	}
+
+	if e.Repo.Owner == nil || e.Repo.Owner.Login == nil {
+		return nil, NewInvalidWebhookEventError("missing repository owner")
+	}
+
+	if e.Comment == nil {
+		return nil, NewInvalidWebhookEventError("missing comment")
+	}
+
+	comment := e.Comment
+
+	if comment.CommitID == nil {
+		return nil, NewInvalidWebhookEventError("missing comment commit id")
+	}
+
+	var eventTime *time.Time
+	if comment.CreatedAt != nil {
+		eventTime = utils.Ref(comment.CreatedAt.UTC())
+	}
+
+	eventData := CommitCommentEvent{
+		Organization: *e.Repo.Owner.Login,
+		Repository:   *e.Repo.Name,
+		Revision:     *comment.CommitID,
+		Author:       comment.GetUser().GetLogin(),
+		Body:         comment.GetBody(),
+		Path:         comment.GetPath(),
+		Position:     comment.Position,
+		URI:          comment.GetHTMLURL(),
+	}
+
+	// The github package does not decode the author association of commit
+	// comments.
+	var payloadData struct {
+		Comment struct {
+			AuthorAssociation *string `json:"author_association"`
+		} `json:"comment"`
+	}
+
+	if err := json.Unmarshal(payload, &payloadData); err != nil {
+		return nil, fmt.Errorf("cannot decode payload: %w", err)
+	}
+
+	if association := payloadData.Comment.AuthorAssociation; association != nil {
+		eventData.AuthorAssociation = *association
+	}
+
+	event := WebhookEvent{
+		Name: "commit_comment",
+		Time: eventTime,
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+// withWebhookTx runs a function in a transaction whose statements are bounded
+// by the webhook statement timeout. If the timeout is reached, the function
+// returns ErrWebhookTimeout so that the HTTP handler can ask the sender to
+// retry the delivery later instead of blocking until the sender gives up.
+func (c *Connector) withWebhookTx(fn func(pg.Conn) error) error {
+	err := c.Pg.WithTx(func(conn pg.Conn) error {
+		if timeout := c.Cfg.WebhookStatementTimeout; timeout > 0 {
+			query := `SELECT set_config('statement_timeout', $1, true)`
+			if err := pg.Exec(conn, query, strconv.Itoa(timeout)); err != nil {
+				return fmt.Errorf("cannot set statement timeout: %w", err)
+			}
+		}
+
+		return fn(conn)
+	})
+
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) && pgErr.Code == "57014" { // query_canceled
+		return fmt.Errorf("%w: %v", ErrWebhookTimeout, err)
+	}
+
+	return err
+}
+
+func decodeWebhookEventPullRequest(e *github.PullRequestEvent) (WebhookEvents, error) {
+	if e.Repo == nil {
+		return nil, NewInvalidWebhookEventError("missing repository")
+	}
+
+	if e.Repo.Name == nil {
+		return nil, NewInvalidWebhookEventError("missing repository name")
+	}
+
+	if e.Repo.Owner == nil || e.Repo.Owner.Login == nil {
+		return nil, NewInvalidWebhookEventError("missing repository owner")
+	}
+
+	if e.PullRequest == nil {
+		return nil, NewInvalidWebhookEventError("missing pull request")
+	}
+
+	pr := e.PullRequest
+
+	if pr.Number == nil {
+		return nil, NewInvalidWebhookEventError("missing pull request number")
+	}
+
+	if pr.Head == nil || pr.Head.Ref == nil || pr.Head.SHA == nil {
+		return nil, NewInvalidWebhookEventError("missing pull request head")
+	}
+
+	if pr.Base == nil || pr.Base.Ref == nil {
+		return nil, NewInvalidWebhookEventError("missing pull request base")
+	}
+
+	var name string
+	var eventTime *time.Time
+
+	switch *e.Action {
+	case "opened":
+		name = "pull_request_opened"
+		eventTime = pr.CreatedAt
+
+	case "closed":
+		// GitHub does not have a specific action for merges: a merged pull
+		// request is closed with the merged flag set.
+		if pr.GetMerged() {
+			name = "pull_request_merged"
+			eventTime = pr.MergedAt
+		} else {
+			name = "pull_request_closed"
+			eventTime = pr.ClosedAt
+		}
+
+	case "synchronize":
+		name = "pull_request_synchronized"
+		eventTime = pr.UpdatedAt
+	}
+
+	if eventTime != nil {
+		eventTime = utils.Ref(eventTime.UTC())
+	}
+
+	eventData := PullRequestEvent{
+		Organization: *e.Repo.Owner.Login,
+		Repository:   *e.Repo.Name,
+		Number:       *pr.Number,
+		Title:        pr.GetTitle(),
+		Author:       pr.GetUser().GetLogin(),
+		HeadBranch:   *pr.Head.Ref,
+		HeadRevision: *pr.Head.SHA,
+		BaseBranch:   *pr.Base.Ref,
+		URI:          pr.GetHTMLURL(),
+	}
+
+	event := WebhookEvent{
+		Name: name,
+		Time: eventTime,
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func decodeWebhookEventRelease(e *github.ReleaseEvent) (WebhookEvents, error) {
+	organization, repository, err := decodeWebhookEventRepo(e.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	release := e.Release
+	if release == nil {
+		return nil, NewInvalidWebhookEventError("missing release")
+	}
+
+	if release.TagName == nil {
+		return nil, NewInvalidWebhookEventError("missing release tag name")
+	}
+
+	var eventTime *time.Time
+	if release.PublishedAt != nil {
+		eventTime = utils.Ref(release.PublishedAt.UTC())
+	}
+
+	eventData := ReleaseEvent{
+		Organization:    organization,
+		Repository:      repository,
+		Tag:             *release.TagName,
+		Name:            release.GetName(),
+		Draft:           release.GetDraft(),
+		Prerelease:      release.GetPrerelease(),
+		TargetCommitish: release.GetTargetCommitish(),
+		URI:             release.GetHTMLURL(),
+	}
+
+	event := WebhookEvent{
+		Name: "release_" + *e.Action,
+		Time: eventTime,
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func decodeWebhookEventWorkflowRun(e *github.WorkflowRunEvent) (WebhookEvents, error) {
+	organization, repository, err := decodeWebhookEventRepo(e.Repo)
+	if err != nil {
+		return nil, err
+	}
+
+	run := e.WorkflowRun
+	if run == nil {
+		return nil, NewInvalidWebhookEventError("missing workflow run")
+	}
+
+	if run.ID == nil {
+		return nil, NewInvalidWebhookEventError("missing workflow run id")
+	}
+
+	if run.HeadSHA == nil {
+		return nil, NewInvalidWebhookEventError("missing workflow run head sha")
+	}
+
+	if run.Conclusion == nil {
+		return nil,
+			NewInvalidWebhookEventError("missing workflow run conclusion")
+	}
+
+	// The name of a run is the name of its workflow unless the workflow
+	// defines a specific run name.
+	workflow := e.GetWorkflow().GetName()
+	if workflow == "" {
+		workflow = run.GetName()
+	}
+
+	if workflow == "" {
+		return nil, NewInvalidWebhookEventError("missing workflow name")
+	}
+
+	var eventTime *time.Time
+	if run.UpdatedAt != nil {
+		eventTime = utils.Ref(run.UpdatedAt.UTC())
+	}
+
+	eventData := WorkflowRunEvent{
+		Organization: organization,
+		Repository:   repository,
+		Workflow:     workflow,
+		RunId:        *run.ID,
+		RunNumber:    run.GetRunNumber(),
+		Conclusion:   *run.Conclusion,
+		HeadBranch:   run.GetHeadBranch(),
+		HeadRevision: *run.HeadSHA,
+		Trigger:      run.GetEvent(),
+		URI:          run.GetHTMLURL(),
+	}
+
+	event := WebhookEvent{
+		Name: "workflow_run_completed",
+		Time: eventTime,
+		Data: &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func (c *Connector) CreateEvents(conn pg.Conn, ename string, eventTime *time.Time, eventData eventline.EventData, params *Parameters) error {
+	subs, err := LoadSubscriptionsByParams(conn, ename, params, eventData)
+	if err != nil {
+		return fmt.Errorf("cannot load subscriptions: %w", err)
+	}
+
+	// A delivery can match lots of subscriptions; events are inserted with
+	// a single query instead of one query per subscription.
+	events := make(eventline.Events, 0, len(subs))
+
+	for _, sub := range subs {
+		event, err := c.newEvent(conn, sub, ename, eventTime, eventData)
 		if err != nil {
-			return fmt.Errorf("cannot create event: %w", err)
+			return err
 		}
+
+		events = append(events, event)
+	}
+
+	if err := events.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert events: %w", err)
 	}
 
 	return nil
 }
 
-func (c *Connector) CreateEvents(ename string, eventTime *time.Time, eventData eventline.EventData, params *Parameters) error {
-	return c.Pg.WithTx(func(conn pg.Conn) error {
-		var subs eventline.Subscriptions
+func (c *Connector) insertEvent(conn pg.Conn, sub *eventline.Subscription, ename string, eventTime *time.Time, eventData eventline.EventData) error {
+	event, err := c.newEvent(conn, sub, ename, eventTime, eventData)
+	if err != nil {
+		return err
+	}
+
+	if err := event.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert event: %w", err)
+	}
 
-		subs, err := LoadSubscriptionsByParams(conn, ename, params)
+	return nil
+}
+
+func (c *Connector) newEvent(conn pg.Conn, sub *eventline.Subscription, ename string, eventTime *time.Time, eventData eventline.EventData) (*eventline.Event, error) {
+	if pushEvent, ok := eventData.(*PushEvent); ok {
+		// Event data are shared by all subscriptions
+		pushEvent2 := *pushEvent
+		pushEvent = &pushEvent2
+		eventData = pushEvent
+
+		outOfOrder, err := pushEventOutOfOrder(conn, *sub.JobId, pushEvent)
 		if err != nil {
-			return fmt.Errorf("cannot load subscriptions: %w", err)
+			return nil, fmt.Errorf("cannot check push event order: %w", err)
 		}
 
-		for _, sub := range subs {
-			event := sub.NewEvent(c.Def.Name, ename, eventTime, eventData)
+		if outOfOrder {
+			c.Log.Info("push event for revision %s of %s/%s:%s delivered "+
+				"out of order for job %q", pushEvent.NewRevision,
+				pushEvent.Organization, pushEvent.Repository,
+				pushEvent.Branch, *sub.JobId)
 
-			if err := event.Insert(conn); err != nil {
-				return fmt.Errorf("cannot insert event: %w", err)
+			pushEvent.OutOfOrder = true
+		}
+
+		params := sub.Parameters.(*Parameters)
+		if params.Enrich {
+			if err := c.enrichPushEvent(conn, sub, pushEvent); err != nil {
+				c.Log.Error("cannot enrich push event for revision %s of "+
+					"%s/%s:%s for job %q: %v", pushEvent.NewRevision,
+					pushEvent.Organization, pushEvent.Repository,
+					pushEvent.Branch, *sub.JobId, err)
 			}
 		}
+	}
 
-		return nil
-	})
+	return sub.NewEvent(c.Def.Name, ename, eventTime, eventData), nil
 }
 
-func LoadSubscriptionsByParams(conn pg.Conn, ename string, params *Parameters) (eventline.Subscriptions, error) {
-	repoCond := "TRUE"
-	if params.Repository != "" {
-		repoCond = "gs.repository = $3"
+// LoadSubscriptionsByParams returns the subscriptions matching a delivery of
+// the shared hook identified by params. Each subscription only matches the
+// hook of its own organization or repository, so that deliveries are never
+// processed twice for the same subscription. Subscriptions restricted to a set
+// of repositories or branches are only returned if the event matches them;
+// these are glob patterns which cannot be evaluated by an index, so they are
+// matched once the subscriptions of the hook have been loaded.
+func LoadSubscriptionsByParams(conn pg.Conn, ename string, params *Parameters, eventData eventline.EventData) (eventline.Subscriptions, error) {
+	subs, err := eventline.LoadSubscriptionsByMatchAttributes(conn, "github",
+		ename, params.MatchAttributes())
+	if err != nil {
+		return nil, err
 	}
 
-	query := fmt.Sprintf(`
-SELECT es.id, es.project_id, es.job_id, es.identity_id, es.connector, es.event,
-       es.parameters, es.creation_time, es.status, es.update_delay,
-       es.last_update_time, es.next_update_time
-  FROM subscriptions AS es
-  JOIN c_github_subscriptions AS gs ON gs.id = es.id
-  WHERE es.connector = 'github'
-    AND es.event = $1
-    AND gs.organization = $2
-    AND %s
-`, repoCond)
+	var matchingSubs eventline.Subscriptions
 
-	args := []interface{}{ename, params.Organization}
-	if params.Repository != "" {
-		args = append(args, params.Repository)
+	for _, sub := range subs {
+		if sub.Parameters.(*Parameters).MatchEvent(eventData) {
+			matchingSubs = append(matchingSubs, sub)
+		}
 	}
 
-	var subs eventline.Subscriptions
-	if err := pg.QueryObjects(conn, &subs, query, args...); err != nil {
-		return nil, err
+	return matchingSubs, nil
+}
+
+// EventRepository returns the name of the repository an event is associated
+// with, or an empty string if there is none.
+func EventRepository(eventData eventline.EventData) string {
+	switch data := eventData.(type) {
+	case *RawEvent:
+		event, _ := data.Event.(map[string]interface{})
+		repository, _ := event["repository"].(map[string]interface{})
+		name, _ := repository["name"].(string)
+		return name
+
+	case *BranchCreationEvent:
+		return data.Repository
+	case *BranchDeletionEvent:
+		return data.Repository
+	case *CommitCommentEvent:
+		return data.Repository
+	case *CommitStatusEvent:
+		return data.Repository
+	case *PushEvent:
+		return data.Repository
+	case *RepositoryCreationEvent:
+		return data.Repository
+	case *RepositoryDeletionEvent:
+		return data.Repository
+	case *TagCreationEvent:
+		return data.Repository
+	case *TagDeletionEvent:
+		return data.Repository
+	case *PullRequestEvent:
+		return data.Repository
+	case *RefCreatedEvent:
+		return data.Repository
+	case *RefDeletedEvent:
+		return data.Repository
+	case *ReleaseEvent:
+		return data.Repository
+	case *WorkflowRunEvent:
+		return data.Repository
+	}
+
+	return ""
+}
+
+// EventBranches returns the names of the branches an event is associated
+// with. Most events are associated with at most one branch, but commit
+// statuses apply to all the branches containing the commit. Pull requests are
+// associated with their base branch.
+func EventBranches(eventData eventline.EventData) []string {
+	const headsRefPrefix = "refs/heads/"
+
+	switch data := eventData.(type) {
+	case *RawEvent:
+		event, _ := data.Event.(map[string]interface{})
+		ref, _ := event["ref"].(string)
+		if strings.HasPrefix(ref, headsRefPrefix) {
+			return []string{ref[len(headsRefPrefix):]}
+		}
+
+	case *BranchCreationEvent:
+		return []string{data.Branch}
+	case *BranchDeletionEvent:
+		return []string{data.Branch}
+	case *CommitStatusEvent:
+		return data.Branches
+	case *PushEvent:
+		return []string{data.Branch}
+	case *PullRequestEvent:
+		return []string{data.BaseBranch}
+	case *RefCreatedEvent:
+		if data.RefType == "branch" {
+			return []string{data.Ref}
+		}
+	case *RefDeletedEvent:
+		if data.RefType == "branch" {
+			return []string{data.Ref}
+		}
+	case *WorkflowRunEvent:
+		if data.HeadBranch != "" {
+			return []string{data.HeadBranch}
+		}
 	}
 
-	return subs, nil
+	return nil
 }
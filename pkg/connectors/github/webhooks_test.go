@@ -0,0 +1,537 @@
+package github
+
+import (
+	"crypto/hmac"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"hash"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.n16f.net/log"
+)
+
+func TestDecodeWebhookEventsStatus(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "sha": "1d1a8a6c9d2c8c6a4a2c6f8f2d3b7a1e9c0d5b4a",
+  "state": "success",
+  "context": "ci/tests",
+  "branches": [{"name": "main"}],
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err := DecodeWebhookEvents("status", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	assert.Equal("commit_status", events[0].Name)
+	assert.Equal(&CommitStatusEvent{
+		Organization: "org",
+		Repository:   "repo",
+		Revision:     "1d1a8a6c9d2c8c6a4a2c6f8f2d3b7a1e9c0d5b4a",
+		State:        "success",
+		Context:      "ci/tests",
+		Branches:     []string{"main"},
+	}, events[0].Data)
+
+	_, err = DecodeWebhookEvents("status",
+		[]byte(`{"repository": {"name": "repo"}}`))
+	assert.Error(err)
+}
+
+func TestDecodeWebhookEventsCommitComment(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "action": "created",
+  "comment": {
+    "commit_id": "1d1a8a6c9d2c8c6a4a2c6f8f2d3b7a1e9c0d5b4a",
+    "user": {"login": "bob"},
+    "body": "LGTM",
+    "author_association": "MEMBER"
+  },
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err := DecodeWebhookEvents("commit_comment", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	assert.Equal("commit_comment", events[0].Name)
+	assert.Equal(&CommitCommentEvent{
+		Organization:      "org",
+		Repository:        "repo",
+		Revision:          "1d1a8a6c9d2c8c6a4a2c6f8f2d3b7a1e9c0d5b4a",
+		Author:            "bob",
+		Body:              "LGTM",
+		AuthorAssociation: "MEMBER",
+	}, events[0].Data)
+}
+
+func TestDecodeWebhookEventsRef(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "ref": "feature/foo",
+  "ref_type": "branch",
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err := DecodeWebhookEvents("create", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	assert.Equal("ref_created", events[0].Name)
+	assert.Equal(&RefCreatedEvent{
+		Organization: "org",
+		Repository:   "repo",
+		Ref:          "feature/foo",
+		RefType:      "branch",
+	}, events[0].Data)
+	assert.Equal([]string{"feature/foo"}, EventBranches(events[0].Data))
+
+	payload = `{
+  "ref": "v1.0.0",
+  "ref_type": "tag",
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err = DecodeWebhookEvents("delete", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	assert.Equal("ref_deleted", events[0].Name)
+	assert.Equal(&RefDeletedEvent{
+		Organization: "org",
+		Repository:   "repo",
+		Ref:          "v1.0.0",
+		RefType:      "tag",
+	}, events[0].Data)
+	assert.Empty(EventBranches(events[0].Data))
+
+	// Repository creation
+	payload = `{
+  "ref": "main",
+  "ref_type": "repository",
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err = DecodeWebhookEvents("create", []byte(payload))
+	require.NoError(err)
+	assert.Empty(events)
+
+	// Missing ref type
+	payload = `{
+  "ref": "main",
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	_, err = DecodeWebhookEvents("delete", []byte(payload))
+	var invalidEventErr *InvalidWebhookEventError
+	assert.ErrorAs(err, &invalidEventErr)
+}
+
+func TestDecodeWebhookEventsRelease(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "action": "published",
+  "release": {
+    "tag_name": "v1.2.0",
+    "name": "Version 1.2.0",
+    "draft": false,
+    "prerelease": true,
+    "target_commitish": "main",
+    "html_url": "https://github.com/org/repo/releases/tag/v1.2.0",
+    "published_at": "2026-10-14T08:30:00Z"
+  },
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err := DecodeWebhookEvents("release", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("release_published", event.Name)
+	if assert.NotNil(event.Time) {
+		assert.Equal(time.Date(2026, 10, 14, 8, 30, 0, 0, time.UTC),
+			*event.Time)
+	}
+	assert.Equal(&ReleaseEvent{
+		Organization:    "org",
+		Repository:      "repo",
+		Tag:             "v1.2.0",
+		Name:            "Version 1.2.0",
+		Prerelease:      true,
+		TargetCommitish: "main",
+		URI:             "https://github.com/org/repo/releases/tag/v1.2.0",
+	}, event.Data)
+
+	// Ignored action
+	payload = `{
+  "action": "created",
+  "release": {"tag_name": "v1.2.0"},
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err = DecodeWebhookEvents("release", []byte(payload))
+	require.NoError(err)
+	assert.Empty(events)
+}
+
+func TestDecodeWebhookEventsWorkflowRun(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "action": "completed",
+  "workflow": {"name": "build"},
+  "workflow_run": {
+    "id": 30433642,
+    "name": "build",
+    "run_number": 562,
+    "head_branch": "main",
+    "head_sha": "acb5820ced9479c074f688cc328bf03f341a511d",
+    "event": "push",
+    "status": "completed",
+    "conclusion": "success",
+    "html_url": "https://github.com/org/repo/actions/runs/30433642",
+    "updated_at": "2026-10-14T09:00:00Z"
+  },
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err := DecodeWebhookEvents("workflow_run", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("workflow_run_completed", event.Name)
+	assert.Equal(&WorkflowRunEvent{
+		Organization: "org",
+		Repository:   "repo",
+		Workflow:     "build",
+		RunId:        30433642,
+		RunNumber:    562,
+		Conclusion:   "success",
+		HeadBranch:   "main",
+		HeadRevision: "acb5820ced9479c074f688cc328bf03f341a511d",
+		Trigger:      "push",
+		URI:          "https://github.com/org/repo/actions/runs/30433642",
+	}, event.Data)
+
+	params := Parameters{
+		Organization: "org",
+		Branches:     []string{"main"},
+		Workflows:    []string{"build*"},
+		Conclusions:  []string{"success"},
+	}
+	assert.True(params.MatchEvent(event.Data))
+
+	params.Conclusions = []string{"failure"}
+	assert.False(params.MatchEvent(event.Data))
+
+	params.Conclusions = nil
+	params.Workflows = []string{"deploy"}
+	assert.False(params.MatchEvent(event.Data))
+
+	// Runs which are not completed are ignored
+	payload = `{
+  "action": "requested",
+  "workflow_run": {"id": 30433642, "head_sha": "acb5820"},
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`
+
+	events, err = DecodeWebhookEvents("workflow_run", []byte(payload))
+	require.NoError(err)
+	assert.Empty(events)
+}
+
+func TestParametersMatchRepository(t *testing.T) {
+	assert := assert.New(t)
+
+	params := Parameters{Organization: "org"}
+	assert.True(params.MatchRepository("repo"))
+	assert.True(params.MatchRepository(""))
+
+	params.Repositories = []string{"api", "team-*"}
+	assert.True(params.MatchRepository("api"))
+	assert.True(params.MatchRepository("team-web"))
+	assert.False(params.MatchRepository("api-v2"))
+	assert.False(params.MatchRepository("web"))
+	assert.False(params.MatchRepository(""))
+	assert.True(params.MatchRepository("API"))
+	assert.True(params.MatchRepository("Team-Web"))
+}
+
+func TestParametersMatchAttributes(t *testing.T) {
+	assert := assert.New(t)
+
+	params1 := Parameters{Organization: "MyOrg", Repository: "MyRepo"}
+	params2 := Parameters{Organization: "myorg", Repository: "myrepo"}
+
+	assert.Equal(params1.MatchAttributes(), params2.MatchAttributes())
+}
+
+func TestEventRepository(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal("repo", EventRepository(&PushEvent{Repository: "repo"}))
+
+	rawEvent := RawEvent{
+		Event: map[string]interface{}{
+			"repository": map[string]interface{}{"name": "repo"},
+		},
+	}
+	assert.Equal("repo", EventRepository(&rawEvent))
+
+	rawEvent.Event = map[string]interface{}{"organization": "org"}
+	assert.Equal("", EventRepository(&rawEvent))
+}
+
+func TestDecodeWebhookEventsPush(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "ref": "refs/heads/main",
+  "before": "1d1a8a6c9d2c8c6a4a2c6f8f2d3b7a1e9c0d5b4a",
+  "after": "8f4e2b1c6d3a9e7f5b2c4d6e8a1f3b5c7d9e0a2b",
+  "organization": {"login": "org"},
+  "repository": {"name": "repo", "owner": {"login": "org"}},
+  "commits": [
+    {"added": ["b.txt"], "modified": ["a.txt"]},
+    {"removed": ["c.txt"], "modified": ["a.txt"]}
+  ],
+  "head_commit": {"timestamp": "2026-10-15T12:00:00+02:00"}
+}`
+
+	events, err := DecodeWebhookEvents("push", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	assert.Equal("push", events[0].Name)
+	assert.Equal(&PushEvent{
+		Organization:  "org",
+		Repository:    "repo",
+		Branch:        "main",
+		OldRevision:   "1d1a8a6c9d2c8c6a4a2c6f8f2d3b7a1e9c0d5b4a",
+		NewRevision:   "8f4e2b1c6d3a9e7f5b2c4d6e8a1f3b5c7d9e0a2b",
+		ModifiedFiles: []string{"a.txt", "b.txt", "c.txt"},
+	}, events[0].Data)
+
+	require.NotNil(events[0].Time)
+	assert.Equal(time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC),
+		*events[0].Time)
+}
+
+func TestParametersMatchBranches(t *testing.T) {
+	assert := assert.New(t)
+
+	params := Parameters{Organization: "org"}
+	assert.True(params.MatchBranches([]string{"main"}))
+	assert.True(params.MatchBranches(nil))
+
+	params.Branches = []string{"main", "release/*"}
+	assert.True(params.MatchBranches([]string{"main"}))
+	assert.True(params.MatchBranches([]string{"release/1.0"}))
+	assert.True(params.MatchBranches([]string{"dev", "main"}))
+	assert.False(params.MatchBranches([]string{"main-old"}))
+	assert.False(params.MatchBranches([]string{"release/1.0/fix"}))
+	assert.False(params.MatchBranches(nil))
+
+	assert.True(params.MatchEvent(&PushEvent{Branch: "release/2.0"}))
+	assert.False(params.MatchEvent(&PushEvent{Branch: "feature"}))
+	assert.False(params.MatchEvent(&TagCreationEvent{Tag: "v1.0"}))
+}
+
+func TestDecodeWebhookEventsPullRequest(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := func(action string, merged bool) []byte {
+		return []byte(`{
+  "action": "` + action + `",
+  "pull_request": {
+    "number": 42,
+    "title": "Fix the build",
+    "user": {"login": "bob"},
+    "head": {"ref": "fix-build", "sha": "8f4e2b1c6d3a9e7f5b2c4d6e8a1f3b5c7d9e0a2b"},
+    "base": {"ref": "main"},
+    "merged": ` + strconv.FormatBool(merged) + `,
+    "created_at": "2026-10-15T10:00:00Z",
+    "merged_at": "2026-10-15T11:00:00Z",
+    "closed_at": "2026-10-15T11:00:00Z"
+  },
+  "repository": {"name": "repo", "owner": {"login": "org"}}
+}`)
+	}
+
+	eventData := &PullRequestEvent{
+		Organization: "org",
+		Repository:   "repo",
+		Number:       42,
+		Title:        "Fix the build",
+		Author:       "bob",
+		HeadBranch:   "fix-build",
+		HeadRevision: "8f4e2b1c6d3a9e7f5b2c4d6e8a1f3b5c7d9e0a2b",
+		BaseBranch:   "main",
+	}
+
+	events, err := DecodeWebhookEvents("pull_request",
+		payload("opened", false))
+	require.NoError(err)
+	require.Len(events, 1)
+	assert.Equal("pull_request_opened", events[0].Name)
+	assert.Equal(eventData, events[0].Data)
+	assert.Equal(time.Date(2026, 10, 15, 10, 0, 0, 0, time.UTC),
+		*events[0].Time)
+
+	events, err = DecodeWebhookEvents("pull_request",
+		payload("closed", true))
+	require.NoError(err)
+	require.Len(events, 1)
+	assert.Equal("pull_request_merged", events[0].Name)
+
+	events, err = DecodeWebhookEvents("pull_request",
+		payload("closed", false))
+	require.NoError(err)
+	require.Len(events, 1)
+	assert.Equal("pull_request_closed", events[0].Name)
+
+	events, err = DecodeWebhookEvents("pull_request",
+		payload("labeled", false))
+	require.NoError(err)
+	assert.Len(events, 0)
+
+	_, err = DecodeWebhookEvents("pull_request",
+		[]byte(`{"action": "opened", "repository": {"name": "repo"}}`))
+	assert.Error(err)
+}
+
+func TestValidateWebhookSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	body := []byte(`{"zen": "Keep it logically awesome."}`)
+	secret := "secret"
+
+	sign := func(hashFunc func() hash.Hash, key string) string {
+		mac := hmac.New(hashFunc, []byte(key))
+		mac.Write(body)
+		return hex.EncodeToString(mac.Sum(nil))
+	}
+
+	sha256Signature := "sha256=" + sign(sha256.New, secret)
+	sha1Signature := "sha1=" + sign(sha1.New, secret)
+
+	tests := []struct {
+		headers   map[string]string
+		algorithm string
+	}{
+		{map[string]string{"X-Hub-Signature-256": sha256Signature}, "sha256"},
+		{map[string]string{"X-Hub-Signature": sha1Signature}, "sha1"},
+		{map[string]string{
+			"X-Hub-Signature-256": sha256Signature,
+			"X-Hub-Signature":     sha1Signature,
+		}, "sha256"},
+
+		// Invalid signatures
+		{map[string]string{}, ""},
+		{map[string]string{
+			"X-Hub-Signature-256": "sha256=" + sign(sha256.New, "foo"),
+			"X-Hub-Signature":     sha1Signature,
+		}, ""},
+		{map[string]string{"X-Hub-Signature-256": sha1Signature}, ""},
+		{map[string]string{"X-Hub-Signature": "sha1=" + sign(sha1.New, "foo")},
+			""},
+	}
+
+	for _, test := range tests {
+		header := make(http.Header)
+		for name, value := range test.headers {
+			header.Set(name, value)
+		}
+
+		algorithm, err := ValidateWebhookSignature(header, body, secret)
+		if test.algorithm == "" {
+			assert.Error(err, "headers: %v", test.headers)
+		} else if assert.NoError(err, "headers: %v", test.headers) {
+			assert.Equal(test.algorithm, algorithm, "headers: %v",
+				test.headers)
+		}
+	}
+}
+
+func TestReadWebhookRequestSizeLimit(t *testing.T) {
+	assert := assert.New(t)
+
+	c := Connector{Cfg: &ConnectorCfg{MaxRequestSize: 16}}
+
+	body := strings.NewReader(`{"zen": "Keep it logically awesome."}`)
+	req := httptest.NewRequest("POST", "/ext/connectors/github/hooks/org",
+		body)
+
+	_, _, err := c.readWebhookRequest(req, "secret")
+	assert.ErrorIs(err, ErrRequestTooLarge)
+}
+
+func TestReadWebhookRequestInvalidRequests(t *testing.T) {
+	assert := assert.New(t)
+
+	c := Connector{
+		Cfg: &ConnectorCfg{MaxRequestSize: 1024},
+		Log: log.DefaultLogger("github"),
+	}
+
+	newRequest := func(body string, secret string) *http.Request {
+		req := httptest.NewRequest("POST",
+			"/ext/connectors/github/hooks/org", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(body))
+		req.Header.Set("X-Hub-Signature-256",
+			"sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+		return req
+	}
+
+	_, _, err := c.readWebhookRequest(newRequest(`{}`, "other"), "secret")
+	assert.ErrorIs(err, ErrInvalidSignature)
+
+	_, _, err = c.readWebhookRequest(newRequest(`{`, "secret"), "secret")
+	assert.ErrorAs(err, new(*InvalidPayloadError))
+}
+
+func TestRawEventGitHubHeaders(t *testing.T) {
+	assert := assert.New(t)
+
+	header := make(http.Header)
+	header.Set("X-GitHub-Event", "push")
+	header.Set("X-GitHub-Delivery", "72d3162e-cc78-11e3-81ab-4c9367dc0958")
+	header.Set("X-GitHub-Hook-ID", "292430182")
+	header.Set("X-Hub-Signature-256", "sha256=abcd")
+	header.Set("X-Forwarded-For", "192.0.2.1")
+
+	assert.Equal(map[string]string{
+		"x-github-event":    "push",
+		"x-github-delivery": "72d3162e-cc78-11e3-81ab-4c9367dc0958",
+		"x-github-hook-id":  "292430182",
+	}, RawEventGitHubHeaders(header))
+
+	assert.Nil(RawEventGitHubHeaders(make(http.Header)))
+}
@@ -0,0 +1,61 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type ConnectorCfg struct {
+	Enabled        bool   `json:"enabled"`
+	BaseURI        string `json:"base_uri,omitempty"`
+	WebhookSecret  string `json:"webhook_secret,omitempty"`
+	MaxRequestSize int    `json:"max_request_size,omitempty"` // bytes
+}
+
+func (c *Connector) DefaultCfg() eventline.ConnectorCfg {
+	return &ConnectorCfg{
+		BaseURI: "https://gitlab.com",
+
+		// Push payloads contain up to 20 commits with the list of files
+		// they modify.
+		MaxRequestSize: 4 * 1024 * 1024,
+	}
+}
+
+func (cfg *ConnectorCfg) ValidateJSON(v *ejson.Validator) {
+	if cfg.Enabled {
+		v.CheckStringNotEmpty("webhook_secret", cfg.WebhookSecret)
+	}
+
+	_, err := ParseBaseURI(cfg.BaseURI)
+	v.Check("base_uri", err == nil, "invalid_uri", "invalid uri: %v", err)
+
+	v.CheckIntMin("max_request_size", cfg.MaxRequestSize, 1)
+}
+
+// ParseBaseURI parses the base URI of a GitLab instance, e.g.
+// "https://gitlab.example.com".
+func ParseBaseURI(s string) (*url.URL, error) {
+	uri, err := url.Parse(s)
+	if err != nil {
+		return nil, err
+	}
+
+	if uri.Scheme != "http" && uri.Scheme != "https" {
+		return nil, fmt.Errorf("invalid scheme %q", uri.Scheme)
+	}
+
+	if uri.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+
+	if uri.RawQuery != "" || uri.Fragment != "" {
+		return nil, fmt.Errorf("base uris cannot contain a query or a " +
+			"fragment")
+	}
+
+	return uri, nil
+}
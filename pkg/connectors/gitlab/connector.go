@@ -0,0 +1,98 @@
+package gitlab
+
+import (
+	"fmt"
+	"net/url"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type Connector struct {
+	Def *eventline.ConnectorDef
+	Cfg *ConnectorCfg
+	Pg  *pg.Client
+	Log *log.Logger
+
+	webHTTPServerURI *url.URL
+	baseURI          *url.URL
+}
+
+func NewConnector() *Connector {
+	c := &Connector{}
+
+	def := eventline.NewConnectorDef("gitlab")
+
+	def.AddIdentity(TokenIdentityDef())
+	def.AddIdentity(OAuth2IdentityDef())
+
+	def.AddEvent(PushEventDef())
+	def.AddEvent(TagCreationEventDef())
+	def.AddEvent(TagDeletionEventDef())
+	def.AddEvent(MergeRequestOpenedEventDef())
+	def.AddEvent(MergeRequestUpdatedEventDef())
+	def.AddEvent(MergeRequestClosedEventDef())
+	def.AddEvent(MergeRequestMergedEventDef())
+
+	c.Def = def
+
+	return c
+}
+
+func (c *Connector) Name() string {
+	return "gitlab"
+}
+
+func (c *Connector) Definition() *eventline.ConnectorDef {
+	return c.Def
+}
+
+func (c *Connector) Enabled() bool {
+	return c.Cfg.Enabled
+}
+
+func (c *Connector) Init(ccfg eventline.ConnectorCfg, initData eventline.ConnectorInitData) error {
+	c.Cfg = ccfg.(*ConnectorCfg)
+	c.Pg = initData.Pg
+	c.Log = initData.Log
+
+	c.webHTTPServerURI = initData.WebHTTPServerURI
+
+	baseURI, err := ParseBaseURI(c.Cfg.BaseURI)
+	if err != nil {
+		return fmt.Errorf("invalid base uri %q: %w", c.Cfg.BaseURI, err)
+	}
+
+	c.baseURI = baseURI
+
+	return nil
+}
+
+func (c *Connector) Terminate() {
+}
+
+// Webhooks are configured by users on GitLab, either on projects or on
+// groups; subscriptions only record the project and the branch they are
+// associated with.
+
+// Subscriptions are matched with their match attributes, there is nothing to
+// store or to create on an external platform.
+
+func (c *Connector) Subscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	return nil
+}
+
+func (c *Connector) Unsubscribe(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	return nil
+}
+
+// BaseURI returns the URI of the GitLab instance, i.e. "https://gitlab.com"
+// unless the connector is configured for a self-hosted instance.
+func (c *Connector) BaseURI() *url.URL {
+	if c.baseURI == nil {
+		return &url.URL{Scheme: "https", Host: "gitlab.com"}
+	}
+
+	return c.baseURI
+}
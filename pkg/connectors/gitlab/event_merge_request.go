@@ -0,0 +1,36 @@
+package gitlab
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type MergeRequestEvent struct {
+	Project        string `json:"project"`
+	IId            int    `json:"iid"`
+	Title          string `json:"title"`
+	User           string `json:"user,omitempty"`
+	SourceBranch   string `json:"source_branch"`
+	SourceRevision string `json:"source_revision,omitempty"`
+	TargetBranch   string `json:"target_branch"`
+	URI            string `json:"uri,omitempty"`
+}
+
+func MergeRequestOpenedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("merge_request_opened",
+		&MergeRequestEvent{}, &Parameters{})
+}
+
+func MergeRequestUpdatedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("merge_request_updated",
+		&MergeRequestEvent{}, &Parameters{})
+}
+
+func MergeRequestClosedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("merge_request_closed",
+		&MergeRequestEvent{}, &Parameters{})
+}
+
+func MergeRequestMergedEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("merge_request_merged",
+		&MergeRequestEvent{}, &Parameters{})
+}
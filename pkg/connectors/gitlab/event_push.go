@@ -0,0 +1,22 @@
+package gitlab
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type PushEvent struct {
+	Project     string `json:"project"`
+	Branch      string `json:"branch"`
+	OldRevision string `json:"old_revision,omitempty"`
+	NewRevision string `json:"new_revision"`
+	User        string `json:"user,omitempty"`
+
+	// Paths of the files added, removed or modified by the commits of the
+	// payload
+	ModifiedFiles []string `json:"modified_files,omitempty"`
+}
+
+func PushEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("push",
+		&PushEvent{}, &Parameters{})
+}
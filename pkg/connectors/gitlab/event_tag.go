@@ -0,0 +1,22 @@
+package gitlab
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type TagEvent struct {
+	Project  string `json:"project"`
+	Tag      string `json:"tag"`
+	Revision string `json:"revision"`
+	User     string `json:"user,omitempty"`
+}
+
+func TagCreationEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("tag_creation",
+		&TagEvent{}, &Parameters{})
+}
+
+func TagDeletionEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("tag_deletion",
+		&TagEvent{}, &Parameters{})
+}
@@ -0,0 +1,242 @@
+package gitlab
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/exograd/go-oauth2c"
+	"go.n16f.net/ejson"
+)
+
+type OAuth2Identity struct {
+	Username string `json:"username,omitempty"`
+
+	ClientId     string   `json:"client_id"`
+	ClientSecret string   `json:"client_secret"`
+	Scopes       []string `json:"scopes"`
+
+	AccessToken    string     `json:"access_token,omitempty"`
+	RefreshToken   string     `json:"refresh_token,omitempty"`
+	TTL            int        `json:"ttl"`
+	ExpirationTime *time.Time `json:"expiration_time,omitempty"`
+}
+
+func OAuth2IdentityDef() *eventline.IdentityDef {
+	def := eventline.NewIdentityDef("oauth2", &OAuth2Identity{})
+	def.DeferredReadiness = true
+	def.Refreshable = true
+	return def
+}
+
+func OAuth2Scopes() []string {
+	return []string{
+		"api",
+		"read_api",
+		"read_user",
+		"read_repository",
+		"write_repository",
+		"read_registry",
+		"write_registry",
+	}
+}
+
+func (i *OAuth2Identity) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("client_id", i.ClientId)
+	v.CheckStringNotEmpty("client_secret", i.ClientSecret)
+
+	v.CheckArrayNotEmpty("scopes", i.Scopes)
+}
+
+func (i *OAuth2Identity) Def() *eventline.IdentityDataDef {
+	view := eventline.NewIdentityDataDef()
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "username",
+		Label:    "Username",
+		Value:    i.Username,
+		Type:     eventline.IdentityDataTypeString,
+		Optional: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "client_id",
+		Label:    "Client id",
+		Value:    i.ClientId,
+		Type:     eventline.IdentityDataTypeString,
+		Verbatim: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "client_secret",
+		Label:    "Client secret",
+		Value:    i.ClientSecret,
+		Type:     eventline.IdentityDataTypeString,
+		Secret:   true,
+		Verbatim: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:                 "scopes",
+		Label:               "Scopes",
+		Value:               i.Scopes,
+		Type:                eventline.IdentityDataTypeEnumList,
+		EnumValues:          OAuth2Scopes(),
+		MultiselectEnumSize: len(OAuth2Scopes()),
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "access_token",
+		Label:    "Access token",
+		Value:    i.AccessToken,
+		Type:     eventline.IdentityDataTypeString,
+		Optional: true,
+		Secret:   true,
+		Verbatim: true,
+		Internal: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "refresh_token",
+		Label:    "Refresh token",
+		Value:    i.RefreshToken,
+		Type:     eventline.IdentityDataTypeString,
+		Optional: true,
+		Secret:   true,
+		Verbatim: true,
+		Internal: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "expiration_date",
+		Label:    "Expiration date",
+		Value:    i.ExpirationTime,
+		Type:     eventline.IdentityDataTypeDate,
+		Optional: true,
+		Internal: true,
+	})
+
+	return view
+}
+
+func (i *OAuth2Identity) RedirectionURI(httpClient *http.Client, state, redirectionURI string) (string, error) {
+	client, err := i.newOAuth2Client(httpClient)
+	if err != nil {
+		return "", fmt.Errorf("cannot create oauth2 client: %w", err)
+	}
+
+	req := oauth2c.AuthorizeRequest{
+		RedirectURI: redirectionURI,
+		State:       state,
+		Scope:       i.Scopes,
+	}
+
+	uri := client.AuthorizeURL("code", &req)
+
+	return uri.String(), nil
+}
+
+func (i *OAuth2Identity) FetchTokenData(httpClient *http.Client, code, redirectionURI string) error {
+	client, err := i.newOAuth2Client(httpClient)
+	if err != nil {
+		return fmt.Errorf("cannot create oauth2 client: %w", err)
+	}
+
+	req := oauth2c.TokenCodeRequest{
+		Code:        code,
+		RedirectURI: redirectionURI,
+	}
+
+	res, err := client.Token(context.Background(), "authorization_code", &req)
+	if err != nil {
+		return err
+	}
+
+	i.setTokenData(res)
+
+	return nil
+}
+
+// GitLab access tokens expire after two hours; they are renewed with the
+// refresh token, which is replaced at each refresh.
+func (i *OAuth2Identity) Refresh(httpClient *http.Client) error {
+	if i.RefreshToken == "" {
+		return fmt.Errorf("missing refresh token")
+	}
+
+	client, err := i.newOAuth2Client(httpClient)
+	if err != nil {
+		return fmt.Errorf("cannot create oauth2 client: %w", err)
+	}
+
+	req := oauth2c.TokenRefreshRequest{
+		RefreshToken: i.RefreshToken,
+	}
+
+	res, err := client.Token(context.Background(), "refresh_token", &req)
+	if err != nil {
+		return err
+	}
+
+	i.setTokenData(res)
+
+	return nil
+}
+
+func (i *OAuth2Identity) setTokenData(res *oauth2c.TokenResponse) {
+	ttl := time.Duration(res.ExpiresIn) * time.Second
+	expirationTime := time.Now().UTC().Add(ttl)
+
+	i.AccessToken = res.AccessToken
+	i.RefreshToken = res.RefreshToken
+	i.TTL = int(res.ExpiresIn)
+	i.ExpirationTime = &expirationTime
+}
+
+func (i *OAuth2Identity) ExpiresBefore(t time.Time) bool {
+	return i.ExpirationTime != nil && i.ExpirationTime.Before(t)
+}
+
+func (i *OAuth2Identity) RefreshTime() time.Time {
+	now := time.Now().UTC()
+
+	halfTTL := time.Duration(math.Ceil(float64(i.TTL)/2.0)) * time.Second
+
+	return now.Add(halfTTL)
+}
+
+func (i *OAuth2Identity) newOAuth2Client(httpClient *http.Client) (*oauth2c.Client, error) {
+	baseURI := &url.URL{Scheme: "https", Host: "gitlab.com"}
+
+	// Self-hosted instances expose OAuth2 endpoints on their own host
+	if c, found := eventline.FindConnector("gitlab"); found {
+		if connector, ok := c.(*Connector); ok && connector.Cfg != nil {
+			baseURI = connector.BaseURI()
+		}
+	}
+
+	issuer := baseURI.String()
+
+	options := oauth2c.Options{
+		HTTPClient: httpClient,
+
+		AuthorizationEndpoint: baseURI.JoinPath("oauth/authorize").String(),
+		TokenEndpoint:         baseURI.JoinPath("oauth/token").String(),
+	}
+
+	return oauth2c.NewClient(issuer, i.ClientId, i.ClientSecret, &options)
+}
+
+func (i *OAuth2Identity) Environment() map[string]string {
+	return map[string]string{
+		"GITLAB_TOKEN": i.AccessToken,
+	}
+}
+
+func (i *OAuth2Identity) AuthenticateHTTPRequest(req *http.Request) {
+	req.Header.Set("Authorization", "Bearer "+i.AccessToken)
+}
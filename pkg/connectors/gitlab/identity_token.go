@@ -0,0 +1,55 @@
+package gitlab
+
+import (
+	"net/http"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type TokenIdentity struct {
+	Username string `json:"username,omitempty"`
+	Token    string `json:"token"`
+}
+
+func TokenIdentityDef() *eventline.IdentityDef {
+	def := eventline.NewIdentityDef("token", &TokenIdentity{})
+	return def
+}
+
+func (i *TokenIdentity) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("token", i.Token)
+}
+
+func (i *TokenIdentity) Def() *eventline.IdentityDataDef {
+	view := eventline.NewIdentityDataDef()
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "username",
+		Label:    "Username",
+		Value:    i.Username,
+		Type:     eventline.IdentityDataTypeString,
+		Optional: true,
+	})
+
+	view.AddEntry(&eventline.IdentityDataEntry{
+		Key:      "token",
+		Label:    "Token",
+		Value:    i.Token,
+		Type:     eventline.IdentityDataTypeString,
+		Verbatim: true,
+		Secret:   true,
+	})
+
+	return view
+}
+
+func (i *TokenIdentity) Environment() map[string]string {
+	return map[string]string{
+		"GITLAB_TOKEN": i.Token,
+	}
+}
+
+func (i *TokenIdentity) AuthenticateHTTPRequest(req *http.Request) {
+	req.Header.Set("PRIVATE-TOKEN", i.Token)
+}
@@ -0,0 +1,36 @@
+package gitlab
+
+import (
+	"strings"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type Parameters struct {
+	// The full path of the project, including its namespace, e.g.
+	// "group/subgroup/project".
+	Project string `json:"project"`
+
+	// Events associated with a branch, i.e. pushes and merge requests (for
+	// their target branch), are ignored if they do not match the branch.
+	Branch string `json:"branch,omitempty"`
+}
+
+func (p *Parameters) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("project", p.Project)
+}
+
+func (p *Parameters) MatchAttributes() eventline.MatchAttributes {
+	// Project paths are case-insensitive on GitLab, so match attributes use
+	// a canonical lower case form.
+	return eventline.MatchAttributes{
+		"project": strings.ToLower(p.Project),
+	}
+}
+
+// MatchBranch returns true if events associated with a branch match the
+// subscription. Events without branch always match.
+func (p *Parameters) MatchBranch(branch string) bool {
+	return branch == "" || p.Branch == "" || p.Branch == branch
+}
@@ -0,0 +1,29 @@
+package gitlab
+
+import (
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParametersMatching(t *testing.T) {
+	assert := assert.New(t)
+
+	params := Parameters{Project: "Group/Project", Branch: "main"}
+
+	assert.Equal(eventline.MatchAttributes{"project": "group/project"},
+		params.MatchAttributes())
+
+	// The attributes of an event must be equal to the attributes of the
+	// subscription whatever the case of the project path.
+	eventParams := Parameters{Project: "group/PROJECT"}
+	assert.Equal(params.MatchAttributes(), eventParams.MatchAttributes())
+
+	assert.True(params.MatchBranch("main"))
+	assert.True(params.MatchBranch(""))
+	assert.False(params.MatchBranch("dev"))
+
+	params.Branch = ""
+	assert.True(params.MatchBranch("dev"))
+}
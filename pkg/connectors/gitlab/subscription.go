@@ -0,0 +1,31 @@
+package gitlab
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// LoadSubscriptionsByParams returns the subscriptions of an event for a
+// project. Subscriptions are selected with their match attributes, i.e. the
+// project, and subscriptions restricted to a branch are only returned if the
+// event is associated with this branch; events which are not associated with
+// any branch, such as tag events, match all subscriptions of the project.
+func LoadSubscriptionsByParams(conn pg.Conn, ename, project, branch string) (eventline.Subscriptions, error) {
+	params := Parameters{Project: project}
+
+	subs, err := eventline.LoadSubscriptionsByMatchAttributes(conn, "gitlab",
+		ename, params.MatchAttributes())
+	if err != nil {
+		return nil, err
+	}
+
+	var matchingSubs eventline.Subscriptions
+
+	for _, sub := range subs {
+		if sub.Parameters.(*Parameters).MatchBranch(branch) {
+			matchingSubs = append(matchingSubs, sub)
+		}
+	}
+
+	return matchingSubs, nil
+}
@@ -0,0 +1,364 @@
+package gitlab
+
+import (
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// GitLab webhooks are not signed: they contain the secret token configured
+// in the settings of the webhook in the X-Gitlab-Token header field. Users
+// configure webhooks on projects or groups with the URI returned by
+// WebhookURI and the webhook secret of the connector.
+
+const zeroRevision = "0000000000000000000000000000000000000000"
+
+var (
+	ErrConnectorDisabled   = errors.New("connector disabled")
+	ErrInvalidWebhookToken = errors.New("invalid webhook token")
+	ErrRequestTooLarge     = errors.New("request body too large")
+)
+
+type InvalidPayloadError struct {
+	Err error
+}
+
+func (err *InvalidPayloadError) Error() string {
+	return fmt.Sprintf("invalid webhook payload: %v", err.Err)
+}
+
+func (err *InvalidPayloadError) Unwrap() error {
+	return err.Err
+}
+
+type WebhookEvent struct {
+	Name    string
+	Project string
+	Branch  string // empty for events not associated with a branch
+	Data    eventline.EventData
+}
+
+type WebhookEvents []*WebhookEvent
+
+type WebhookPayload struct {
+	Before       string                 `json:"before"`
+	After        string                 `json:"after"`
+	Ref          string                 `json:"ref"`
+	CheckoutSHA  string                 `json:"checkout_sha"`
+	UserUsername string                 `json:"user_username"`
+	Project      *WebhookPayloadProject `json:"project"`
+	Commits      []WebhookPayloadCommit `json:"commits"`
+
+	// Merge request events
+	User             *WebhookPayloadUser         `json:"user"`
+	ObjectAttributes *WebhookPayloadMergeRequest `json:"object_attributes"`
+}
+
+type WebhookPayloadProject struct {
+	PathWithNamespace string `json:"path_with_namespace"`
+}
+
+type WebhookPayloadCommit struct {
+	Added    []string `json:"added"`
+	Modified []string `json:"modified"`
+	Removed  []string `json:"removed"`
+}
+
+type WebhookPayloadUser struct {
+	Username string `json:"username"`
+}
+
+type WebhookPayloadMergeRequest struct {
+	IId          int                       `json:"iid"`
+	Title        string                    `json:"title"`
+	Action       string                    `json:"action"`
+	SourceBranch string                    `json:"source_branch"`
+	TargetBranch string                    `json:"target_branch"`
+	URL          string                    `json:"url"`
+	OldRevision  string                    `json:"oldrev"`
+	LastCommit   *WebhookPayloadLastCommit `json:"last_commit"`
+}
+
+type WebhookPayloadLastCommit struct {
+	Id string `json:"id"`
+}
+
+func (p *WebhookPayload) ValidateJSON(v *ejson.Validator) {
+	v.CheckObject("project", p.Project)
+	v.CheckOptionalObject("object_attributes", p.ObjectAttributes)
+}
+
+func (p *WebhookPayloadProject) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("path_with_namespace", p.PathWithNamespace)
+}
+
+func (mr *WebhookPayloadMergeRequest) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("source_branch", mr.SourceBranch)
+	v.CheckStringNotEmpty("target_branch", mr.TargetBranch)
+}
+
+func (c *Connector) WebhookURI() string {
+	path := "/ext/connectors/gitlab/hooks"
+	uri := c.webHTTPServerURI.ResolveReference(&url.URL{Path: path})
+	return uri.String()
+}
+
+func (c *Connector) WebhookSecret() string {
+	return c.Cfg.WebhookSecret
+}
+
+// ProcessWebhookRequest handles a webhook delivery sent by GitLab. Events
+// which do not match any high level event, for example pushes deleting a
+// branch, are ignored.
+func (c *Connector) ProcessWebhookRequest(req *http.Request) error {
+	if !c.Cfg.Enabled {
+		return ErrConnectorDisabled
+	}
+
+	token := req.Header.Get("X-Gitlab-Token")
+	expectedToken := c.WebhookSecret()
+	if subtle.ConstantTimeCompare([]byte(token), []byte(expectedToken)) != 1 {
+		return ErrInvalidWebhookToken
+	}
+
+	maxSize := int64(c.Cfg.MaxRequestSize)
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot read request body: %w", err)
+	} else if int64(len(body)) > maxSize {
+		return ErrRequestTooLarge
+	}
+
+	eventType := req.Header.Get("X-Gitlab-Event")
+
+	events, err := DecodeWebhookEvents(eventType, body)
+	if err != nil {
+		return err
+	}
+
+	if len(events) == 0 {
+		c.Log.Debug(1, "ignoring %q webhook event", eventType)
+		return nil
+	}
+
+	return c.Pg.WithTx(func(conn pg.Conn) error {
+		for _, event := range events {
+			c.Log.Debug(1, "received %s event for %s", event.Name,
+				event.Project)
+
+			err := c.CreateEvents(conn, event.Name, event.Data,
+				event.Project, event.Branch)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+}
+
+// CreateEvents creates an event for each subscription matching the project
+// and branch.
+func (c *Connector) CreateEvents(conn pg.Conn, ename string, eventData eventline.EventData, project, branch string) error {
+	subs, err := LoadSubscriptionsByParams(conn, ename, project, branch)
+	if err != nil {
+		return fmt.Errorf("cannot load subscriptions: %w", err)
+	}
+
+	events := make(eventline.Events, 0, len(subs))
+
+	for _, sub := range subs {
+		event := sub.NewEvent(c.Def.Name, ename, nil, eventData)
+		events = append(events, event)
+	}
+
+	if err := events.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert events: %w", err)
+	}
+
+	return nil
+}
+
+// DecodeWebhookEvents returns the high level events associated with a
+// webhook payload. The event type is the value of the X-Gitlab-Event header
+// field.
+func DecodeWebhookEvents(eventType string, data []byte) (WebhookEvents, error) {
+	var decode func(*WebhookPayload) (WebhookEvents, error)
+
+	switch eventType {
+	case "Push Hook":
+		decode = decodeWebhookEventPush
+	case "Tag Push Hook":
+		decode = decodeWebhookEventTagPush
+	case "Merge Request Hook":
+		decode = decodeWebhookEventMergeRequest
+	default:
+		return nil, nil
+	}
+
+	var payload WebhookPayload
+	if err := ejson.Unmarshal(data, &payload); err != nil {
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	return decode(&payload)
+}
+
+func decodeWebhookEventPush(p *WebhookPayload) (WebhookEvents, error) {
+	branch, found := strings.CutPrefix(p.Ref, "refs/heads/")
+	if !found {
+		err := fmt.Errorf("invalid branch reference %q", p.Ref)
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	// Branch deletion
+	if p.After == zeroRevision {
+		return nil, nil
+	}
+
+	eventData := PushEvent{
+		Project:       p.Project.PathWithNamespace,
+		Branch:        branch,
+		NewRevision:   p.After,
+		User:          p.UserUsername,
+		ModifiedFiles: p.modifiedFiles(),
+	}
+
+	if p.Before != zeroRevision {
+		eventData.OldRevision = p.Before
+	}
+
+	event := WebhookEvent{
+		Name:    "push",
+		Project: eventData.Project,
+		Branch:  branch,
+		Data:    &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func decodeWebhookEventTagPush(p *WebhookPayload) (WebhookEvents, error) {
+	tag, found := strings.CutPrefix(p.Ref, "refs/tags/")
+	if !found {
+		err := fmt.Errorf("invalid tag reference %q", p.Ref)
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	eventData := TagEvent{
+		Project: p.Project.PathWithNamespace,
+		Tag:     tag,
+		User:    p.UserUsername,
+	}
+
+	event := WebhookEvent{
+		Project: eventData.Project,
+		Data:    &eventData,
+	}
+
+	if p.After == zeroRevision {
+		event.Name = "tag_deletion"
+		eventData.Revision = p.Before
+	} else {
+		// For annotated tags, the after field contains the identifier of
+		// the tag object and not the one of the commit.
+		event.Name = "tag_creation"
+		eventData.Revision = p.CheckoutSHA
+		if eventData.Revision == "" {
+			eventData.Revision = p.After
+		}
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func decodeWebhookEventMergeRequest(p *WebhookPayload) (WebhookEvents, error) {
+	mr := p.ObjectAttributes
+	if mr == nil {
+		err := errors.New("missing merge request attributes")
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	var ename string
+
+	switch mr.Action {
+	case "open", "reopen":
+		ename = "merge_request_opened"
+	case "update":
+		// Updates are also sent when the title or the labels of the merge
+		// request change; we are only interested in new commits.
+		if mr.OldRevision == "" {
+			return nil, nil
+		}
+
+		ename = "merge_request_updated"
+	case "close":
+		ename = "merge_request_closed"
+	case "merge":
+		ename = "merge_request_merged"
+	default:
+		return nil, nil
+	}
+
+	eventData := MergeRequestEvent{
+		Project:      p.Project.PathWithNamespace,
+		IId:          mr.IId,
+		Title:        mr.Title,
+		SourceBranch: mr.SourceBranch,
+		TargetBranch: mr.TargetBranch,
+		URI:          mr.URL,
+	}
+
+	if p.User != nil {
+		eventData.User = p.User.Username
+	}
+
+	if mr.LastCommit != nil {
+		eventData.SourceRevision = mr.LastCommit.Id
+	}
+
+	event := WebhookEvent{
+		Name:    ename,
+		Project: eventData.Project,
+		Branch:  mr.TargetBranch,
+		Data:    &eventData,
+	}
+
+	return WebhookEvents{&event}, nil
+}
+
+func (p *WebhookPayload) modifiedFiles() []string {
+	paths := make(map[string]struct{})
+
+	for _, commit := range p.Commits {
+		for _, list := range [][]string{
+			commit.Added, commit.Modified, commit.Removed,
+		} {
+			for _, path := range list {
+				paths[path] = struct{}{}
+			}
+		}
+	}
+
+	if len(paths) == 0 {
+		return nil
+	}
+
+	files := make([]string, 0, len(paths))
+	for path := range paths {
+		files = append(files, path)
+	}
+
+	sort.Strings(files)
+
+	return files
+}
@@ -0,0 +1,123 @@
+package gitlab
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDecodeWebhookEventsPush(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "object_kind": "push",
+  "before": "95790bf891e76fee5e1747ab589903a6a1f80f22",
+  "after": "da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+  "ref": "refs/heads/main",
+  "user_username": "jsmith",
+  "project": {"path_with_namespace": "group/sub/project"},
+  "commits": [
+    {"added": ["b.txt"], "modified": ["a.txt"], "removed": []},
+    {"added": [], "modified": ["a.txt"], "removed": ["c.txt"]}
+  ]
+}`
+
+	events, err := DecodeWebhookEvents("Push Hook", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("push", event.Name)
+	assert.Equal("group/sub/project", event.Project)
+	assert.Equal("main", event.Branch)
+
+	eventData := event.Data.(*PushEvent)
+	assert.Equal("95790bf891e76fee5e1747ab589903a6a1f80f22",
+		eventData.OldRevision)
+	assert.Equal("da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+		eventData.NewRevision)
+	assert.Equal("jsmith", eventData.User)
+	assert.Equal([]string{"a.txt", "b.txt", "c.txt"}, eventData.ModifiedFiles)
+
+	// Branch deletion
+	payload = `{
+  "before": "95790bf891e76fee5e1747ab589903a6a1f80f22",
+  "after": "0000000000000000000000000000000000000000",
+  "ref": "refs/heads/main",
+  "project": {"path_with_namespace": "group/project"}
+}`
+
+	events, err = DecodeWebhookEvents("Push Hook", []byte(payload))
+	require.NoError(err)
+	assert.Empty(events)
+}
+
+func TestDecodeWebhookEventsTagPush(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "object_kind": "tag_push",
+  "before": "0000000000000000000000000000000000000000",
+  "after": "82b3d5ae55f7080f1e6022629cdb57bfae7cccc7",
+  "ref": "refs/tags/v1.0.0",
+  "checkout_sha": "5937ac0a7beb003549fc5fd26fc247adbce4a52e",
+  "project": {"path_with_namespace": "group/project"}
+}`
+
+	events, err := DecodeWebhookEvents("Tag Push Hook", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("tag_creation", event.Name)
+	assert.Equal("", event.Branch)
+
+	eventData := event.Data.(*TagEvent)
+	assert.Equal("v1.0.0", eventData.Tag)
+	assert.Equal("5937ac0a7beb003549fc5fd26fc247adbce4a52e",
+		eventData.Revision)
+}
+
+func TestDecodeWebhookEventsMergeRequest(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := `{
+  "object_kind": "merge_request",
+  "user": {"username": "jsmith"},
+  "project": {"path_with_namespace": "group/project"},
+  "object_attributes": {
+    "iid": 42,
+    "title": "Fix things",
+    "action": "update",
+    "source_branch": "fix",
+    "target_branch": "main",
+    "url": "https://gitlab.example.com/group/project/-/merge_requests/42",
+    "oldrev": "95790bf891e76fee5e1747ab589903a6a1f80f22",
+    "last_commit": {"id": "da1560886d4f094c3e6c9ef40349f7d38b5d27d7"}
+  }
+}`
+
+	events, err := DecodeWebhookEvents("Merge Request Hook", []byte(payload))
+	require.NoError(err)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("merge_request_updated", event.Name)
+	assert.Equal("main", event.Branch)
+
+	eventData := event.Data.(*MergeRequestEvent)
+	assert.Equal(42, eventData.IId)
+	assert.Equal("fix", eventData.SourceBranch)
+	assert.Equal("da1560886d4f094c3e6c9ef40349f7d38b5d27d7",
+		eventData.SourceRevision)
+	assert.Equal("jsmith", eventData.User)
+
+	// Unknown event types are ignored
+	events, err = DecodeWebhookEvents("Note Hook", []byte(payload))
+	require.NoError(err)
+	assert.Empty(events)
+}
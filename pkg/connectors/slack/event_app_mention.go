@@ -0,0 +1,20 @@
+package slack
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type AppMentionEvent struct {
+	EventId         string `json:"event_id"`
+	TeamId          string `json:"team_id"`
+	ChannelId       string `json:"channel_id"`
+	UserId          string `json:"user_id"`
+	Text            string `json:"text"`
+	Timestamp       string `json:"timestamp"`
+	ThreadTimestamp string `json:"thread_timestamp,omitempty"`
+}
+
+func AppMentionEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("app_mention",
+		&AppMentionEvent{}, &Parameters{})
+}
@@ -0,0 +1,22 @@
+package slack
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type SlashCommandEvent struct {
+	Command     string `json:"command"`
+	Text        string `json:"text,omitempty"`
+	TeamId      string `json:"team_id"`
+	ChannelId   string `json:"channel_id"`
+	ChannelName string `json:"channel_name,omitempty"`
+	UserId      string `json:"user_id"`
+	UserName    string `json:"user_name,omitempty"`
+	ResponseURI string `json:"response_uri,omitempty"`
+	TriggerId   string `json:"trigger_id,omitempty"`
+}
+
+func SlashCommandEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("slash_command",
+		&SlashCommandEvent{}, &Parameters{})
+}
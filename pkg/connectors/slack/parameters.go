@@ -0,0 +1,38 @@
+package slack
+
+import (
+	"regexp"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+var commandRE = regexp.MustCompile(`^/[^\s/]+$`)
+
+type Parameters struct {
+	// The name of the slash command including the leading slash, e.g.
+	// "/deploy". Only used for slash command events.
+	Command string `json:"command,omitempty"`
+
+	// The identifier of the channel, e.g. "C0123456789". Events sent in
+	// other channels are ignored.
+	Channel string `json:"channel,omitempty"`
+}
+
+func (p *Parameters) ValidateJSON(v *ejson.Validator) {
+	if p.Command != "" {
+		v.CheckStringMatch("command", p.Command, commandRE)
+	}
+}
+
+func (p *Parameters) MatchAttributes() eventline.MatchAttributes {
+	return eventline.MatchAttributes{
+		"channel": p.Channel,
+	}
+}
+
+// MatchCommand returns true if events associated with a command match the
+// subscription. Events without command always match.
+func (p *Parameters) MatchCommand(command string) bool {
+	return command == "" || p.Command == "" || p.Command == command
+}
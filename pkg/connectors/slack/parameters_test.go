@@ -0,0 +1,29 @@
+package slack
+
+import (
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParametersMatching(t *testing.T) {
+	assert := assert.New(t)
+
+	params := Parameters{Command: "/deploy", Channel: "C0123456789"}
+
+	assert.Equal(eventline.MatchAttributes{"channel": "C0123456789"},
+		params.MatchAttributes())
+
+	// Subscriptions without channel are found with an empty channel
+	// attribute.
+	assert.Equal(eventline.MatchAttributes{"channel": ""},
+		(&Parameters{Command: "/deploy"}).MatchAttributes())
+
+	assert.True(params.MatchCommand("/deploy"))
+	assert.True(params.MatchCommand(""))
+	assert.False(params.MatchCommand("/rollback"))
+
+	params.Command = ""
+	assert.True(params.MatchCommand("/rollback"))
+}
@@ -0,0 +1,39 @@
+package slack
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// LoadSubscriptionsByParams returns the subscriptions of an event matching a
+// command and a channel. Subscriptions are selected with their match
+// attributes, i.e. the channel, subscriptions without channel matching all
+// channels. Subscriptions without command match all commands, and events
+// which are not associated with a command, such as mentions, match all
+// subscriptions of the channel.
+func LoadSubscriptionsByParams(conn pg.Conn, ename, command, channel string) (eventline.Subscriptions, error) {
+	channels := []string{""}
+	if channel != "" {
+		channels = append(channels, channel)
+	}
+
+	var matchingSubs eventline.Subscriptions
+
+	for _, channel := range channels {
+		params := Parameters{Channel: channel}
+
+		subs, err := eventline.LoadSubscriptionsByMatchAttributes(conn,
+			"slack", ename, params.MatchAttributes())
+		if err != nil {
+			return nil, err
+		}
+
+		for _, sub := range subs {
+			if sub.Parameters.(*Parameters).MatchCommand(command) {
+				matchingSubs = append(matchingSubs, sub)
+			}
+		}
+	}
+
+	return matchingSubs, nil
+}
@@ -0,0 +1,302 @@
+package slack
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// Slack signs requests with the signing secret of the application: the
+// X-Slack-Signature header field contains "v0=" followed by the hex-encoded
+// HMAC-SHA256 signature of "v0:<timestamp>:<body>", the timestamp being the
+// value of the X-Slack-Request-Timestamp header field. Requests whose
+// timestamp is too far from the current time are rejected so that captured
+// requests cannot be replayed.
+//
+// Slash commands are sent as form data while the Events API sends JSON
+// documents; both are accepted on the same URI.
+
+const MaxRequestAge = 5 * time.Minute
+
+var (
+	ErrConnectorDisabled = errors.New("connector disabled")
+	ErrMissingSignature  = errors.New("missing signature")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrExpiredRequest    = errors.New("request timestamp too old")
+	ErrRequestTooLarge   = errors.New("request body too large")
+)
+
+type InvalidPayloadError struct {
+	Err error
+}
+
+func (err *InvalidPayloadError) Error() string {
+	return fmt.Sprintf("invalid payload: %v", err.Err)
+}
+
+func (err *InvalidPayloadError) Unwrap() error {
+	return err.Err
+}
+
+type WebhookEvent struct {
+	Name    string
+	Command string // empty for events not associated with a command
+	Channel string
+	Time    *time.Time
+	Data    eventline.EventData
+}
+
+type WebhookEvents []*WebhookEvent
+
+// EventsAPIPayload is the envelope of the documents sent by the Slack Events
+// API. The URL verification challenge sent when the URI is configured uses
+// the same envelope.
+type EventsAPIPayload struct {
+	Type      string                 `json:"type"`
+	Challenge string                 `json:"challenge,omitempty"`
+	TeamId    string                 `json:"team_id,omitempty"`
+	EventId   string                 `json:"event_id,omitempty"`
+	EventTime int64                  `json:"event_time,omitempty"`
+	Event     *EventsAPIPayloadEvent `json:"event,omitempty"`
+}
+
+type EventsAPIPayloadEvent struct {
+	Type            string `json:"type"`
+	User            string `json:"user"`
+	Text            string `json:"text"`
+	Channel         string `json:"channel"`
+	Timestamp       string `json:"ts"`
+	ThreadTimestamp string `json:"thread_ts"`
+}
+
+type URLVerificationResponse struct {
+	Challenge string `json:"challenge"`
+}
+
+func (c *Connector) RequestURI() string {
+	path := "/ext/connectors/slack/events"
+	uri := c.webHTTPServerURI.ResolveReference(&url.URL{Path: path})
+	return uri.String()
+}
+
+func Signature(timestamp string, body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte("v0:" + timestamp + ":"))
+	mac.Write(body)
+	return "v0=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func ValidateSignature(header http.Header, body []byte, secret string, now time.Time) error {
+	signature := header.Get("X-Slack-Signature")
+	timestamp := header.Get("X-Slack-Request-Timestamp")
+	if signature == "" || timestamp == "" {
+		return ErrMissingSignature
+	}
+
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return ErrInvalidSignature
+	}
+
+	age := now.Sub(time.Unix(seconds, 0))
+	if age > MaxRequestAge || age < -MaxRequestAge {
+		return ErrExpiredRequest
+	}
+
+	expectedSignature := Signature(timestamp, body, secret)
+	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ProcessRequest handles a request sent by Slack and returns the value to
+// send in the response body, if any.
+func (c *Connector) ProcessRequest(req *http.Request) (interface{}, error) {
+	if !c.Cfg.Enabled {
+		return nil, ErrConnectorDisabled
+	}
+
+	maxSize := int64(c.Cfg.MaxRequestSize)
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read request body: %w", err)
+	} else if int64(len(body)) > maxSize {
+		return nil, ErrRequestTooLarge
+	}
+
+	now := time.Now().UTC()
+
+	err = ValidateSignature(req.Header, body, c.Cfg.SigningSecret, now)
+	if err != nil {
+		return nil, err
+	}
+
+	mediaType, _, err := mime.ParseMediaType(req.Header.Get("Content-Type"))
+	if err != nil {
+		err = fmt.Errorf("invalid content type: %w", err)
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	var events WebhookEvents
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		values, err := url.ParseQuery(string(body))
+		if err != nil {
+			return nil, &InvalidPayloadError{Err: err}
+		}
+
+		event, err := DecodeSlashCommand(values)
+		if err != nil {
+			return nil, err
+		}
+
+		events = WebhookEvents{event}
+
+	case "application/json":
+		var payload EventsAPIPayload
+		if err := json.Unmarshal(body, &payload); err != nil {
+			return nil, &InvalidPayloadError{Err: err}
+		}
+
+		if payload.Type == "url_verification" {
+			return &URLVerificationResponse{Challenge: payload.Challenge}, nil
+		}
+
+		events = DecodeEventsAPIPayload(&payload)
+
+	default:
+		err := fmt.Errorf("unsupported content type %q", mediaType)
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	if len(events) == 0 {
+		return nil, nil
+	}
+
+	err = c.Pg.WithTx(func(conn pg.Conn) error {
+		for _, event := range events {
+			c.Log.Debug(1, "received %s event in channel %s", event.Name,
+				event.Channel)
+
+			err := c.CreateEvents(conn, event.Name, event.Time, event.Data,
+				event.Command, event.Channel)
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return nil, nil
+}
+
+// CreateEvents creates an event for each subscription matching the command
+// and channel.
+func (c *Connector) CreateEvents(conn pg.Conn, ename string, eventTime *time.Time, eventData eventline.EventData, command, channel string) error {
+	subs, err := LoadSubscriptionsByParams(conn, ename, command, channel)
+	if err != nil {
+		return fmt.Errorf("cannot load subscriptions: %w", err)
+	}
+
+	events := make(eventline.Events, 0, len(subs))
+
+	for _, sub := range subs {
+		event := sub.NewEvent(c.Def.Name, ename, eventTime, eventData)
+		events = append(events, event)
+	}
+
+	if err := events.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert events: %w", err)
+	}
+
+	return nil
+}
+
+// DecodeSlashCommand decodes the form data sent by Slack when a slash command
+// is used.
+func DecodeSlashCommand(values url.Values) (*WebhookEvent, error) {
+	eventData := SlashCommandEvent{
+		Command:     values.Get("command"),
+		Text:        values.Get("text"),
+		TeamId:      values.Get("team_id"),
+		ChannelId:   values.Get("channel_id"),
+		ChannelName: values.Get("channel_name"),
+		UserId:      values.Get("user_id"),
+		UserName:    values.Get("user_name"),
+		ResponseURI: values.Get("response_url"),
+		TriggerId:   values.Get("trigger_id"),
+	}
+
+	if eventData.Command == "" {
+		err := errors.New("missing command")
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	if eventData.ChannelId == "" {
+		err := errors.New("missing channel id")
+		return nil, &InvalidPayloadError{Err: err}
+	}
+
+	event := WebhookEvent{
+		Name:    "slash_command",
+		Command: eventData.Command,
+		Channel: eventData.ChannelId,
+		Data:    &eventData,
+	}
+
+	return &event, nil
+}
+
+// DecodeEventsAPIPayload returns the high level events associated with an
+// Events API payload. Event types other than app_mention are ignored.
+func DecodeEventsAPIPayload(p *EventsAPIPayload) WebhookEvents {
+	if p.Type != "event_callback" || p.Event == nil {
+		return nil
+	}
+
+	if p.Event.Type != "app_mention" {
+		return nil
+	}
+
+	eventData := AppMentionEvent{
+		EventId:         p.EventId,
+		TeamId:          p.TeamId,
+		ChannelId:       p.Event.Channel,
+		UserId:          p.Event.User,
+		Text:            p.Event.Text,
+		Timestamp:       p.Event.Timestamp,
+		ThreadTimestamp: p.Event.ThreadTimestamp,
+	}
+
+	event := WebhookEvent{
+		Name:    "app_mention",
+		Channel: eventData.ChannelId,
+		Data:    &eventData,
+	}
+
+	if p.EventTime > 0 {
+		eventTime := time.Unix(p.EventTime, 0).UTC()
+		event.Time = &eventTime
+	}
+
+	return WebhookEvents{&event}
+}
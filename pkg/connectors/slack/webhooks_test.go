@@ -0,0 +1,118 @@
+package slack
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	secret := "8f742231b10e8888abcd99yyyzzz85a5"
+	body := []byte("token=xyzz0WbapA4vBCDEFasx0q6G&command=%2Fdeploy")
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+
+	header := func(timestamp, signature string) http.Header {
+		h := make(http.Header)
+		if timestamp != "" {
+			h.Set("X-Slack-Request-Timestamp", timestamp)
+		}
+		if signature != "" {
+			h.Set("X-Slack-Signature", signature)
+		}
+		return h
+	}
+
+	signature := Signature(timestamp, body, secret)
+
+	assert.NoError(ValidateSignature(header(timestamp, signature), body,
+		secret, now))
+	assert.NoError(ValidateSignature(header(timestamp, signature), body,
+		secret, now.Add(time.Minute)))
+
+	assert.ErrorIs(ValidateSignature(header("", ""), body, secret, now),
+		ErrMissingSignature)
+	assert.ErrorIs(ValidateSignature(header(timestamp, ""), body, secret, now),
+		ErrMissingSignature)
+
+	assert.ErrorIs(ValidateSignature(header(timestamp, signature),
+		[]byte("command=%2Fdestroy"), secret, now), ErrInvalidSignature)
+	assert.ErrorIs(ValidateSignature(header(timestamp, signature), body,
+		"other-secret", now), ErrInvalidSignature)
+	assert.ErrorIs(ValidateSignature(header("foo", signature), body,
+		secret, now), ErrInvalidSignature)
+
+	assert.ErrorIs(ValidateSignature(header(timestamp, signature), body,
+		secret, now.Add(10*time.Minute)), ErrExpiredRequest)
+}
+
+func TestDecodeSlashCommand(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	values, err := url.ParseQuery("command=%2Fdeploy&text=prod&" +
+		"team_id=T0001&channel_id=C2147483705&channel_name=ops&" +
+		"user_id=U2147483697&user_name=jsmith&" +
+		"response_url=https%3A%2F%2Fhooks.slack.com%2Fcommands%2F1234")
+	require.NoError(err)
+
+	event, err := DecodeSlashCommand(values)
+	require.NoError(err)
+
+	assert.Equal("slash_command", event.Name)
+	assert.Equal("/deploy", event.Command)
+	assert.Equal("C2147483705", event.Channel)
+
+	eventData := event.Data.(*SlashCommandEvent)
+	assert.Equal("prod", eventData.Text)
+	assert.Equal("jsmith", eventData.UserName)
+	assert.Equal("https://hooks.slack.com/commands/1234",
+		eventData.ResponseURI)
+
+	_, err = DecodeSlashCommand(url.Values{"channel_id": {"C2147483705"}})
+	var invalidPayloadErr *InvalidPayloadError
+	assert.ErrorAs(err, &invalidPayloadErr)
+}
+
+func TestDecodeEventsAPIPayload(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	payload := EventsAPIPayload{
+		Type:      "event_callback",
+		TeamId:    "T0001",
+		EventId:   "Ev0LAN670R",
+		EventTime: 1515449522,
+		Event: &EventsAPIPayloadEvent{
+			Type:      "app_mention",
+			User:      "U061F7AUR",
+			Text:      "<@U0LAN0Z89> deploy prod",
+			Channel:   "C0LAN2Q65",
+			Timestamp: "1515449522.000016",
+		},
+	}
+
+	events := DecodeEventsAPIPayload(&payload)
+	require.Len(events, 1)
+
+	event := events[0]
+	assert.Equal("app_mention", event.Name)
+	assert.Equal("", event.Command)
+	assert.Equal("C0LAN2Q65", event.Channel)
+	require.NotNil(event.Time)
+	assert.Equal(int64(1515449522), event.Time.Unix())
+
+	eventData := event.Data.(*AppMentionEvent)
+	assert.Equal("Ev0LAN670R", eventData.EventId)
+	assert.Equal("<@U0LAN0Z89> deploy prod", eventData.Text)
+
+	payload.Event.Type = "message"
+	assert.Empty(DecodeEventsAPIPayload(&payload))
+}
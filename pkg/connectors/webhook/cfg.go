@@ -0,0 +1,26 @@
+package webhook
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+type ConnectorCfg struct {
+	Enabled        bool   `json:"enabled"`
+	Secret         string `json:"secret,omitempty"`
+	MaxRequestSize int    `json:"max_request_size,omitempty"` // bytes
+}
+
+func (c *Connector) DefaultCfg() eventline.ConnectorCfg {
+	return &ConnectorCfg{
+		MaxRequestSize: 1024 * 1024,
+	}
+}
+
+func (cfg *ConnectorCfg) ValidateJSON(v *ejson.Validator) {
+	if cfg.Enabled {
+		v.CheckStringNotEmpty("secret", cfg.Secret)
+	}
+
+	v.CheckIntMin("max_request_size", cfg.MaxRequestSize, 1)
+}
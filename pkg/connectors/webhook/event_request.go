@@ -0,0 +1,18 @@
+package webhook
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+type RequestEvent struct {
+	Name string `json:"name"`
+
+	// Values extracted from the body of the request with the paths of the
+	// subscription. Fields whose path does not match anything are not set.
+	Fields map[string]interface{} `json:"fields,omitempty"`
+}
+
+func RequestEventDef() *eventline.EventDef {
+	return eventline.NewEventDef("request",
+		&RequestEvent{}, &Parameters{})
+}
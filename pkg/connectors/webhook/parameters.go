@@ -0,0 +1,41 @@
+package webhook
+
+import (
+	"regexp"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+)
+
+var nameRE = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+type Parameters struct {
+	// The name of the webhook, used in its URI. Names are not scoped to
+	// projects: all the subscriptions with the same name receive the same
+	// requests.
+	Name string `json:"name"`
+
+	// Each entry associates the name of a field of the event with a path
+	// in the body of the request, e.g. "$.repository.name".
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+func (p *Parameters) ValidateJSON(v *ejson.Validator) {
+	if v.CheckStringNotEmpty("name", p.Name) {
+		v.CheckStringMatch("name", p.Name, nameRE)
+	}
+
+	v.WithChild("fields", func() {
+		for name, expr := range p.Fields {
+			_, err := ParsePath(expr)
+			v.Check(name, err == nil, "invalid_path", "invalid path: %v",
+				err)
+		}
+	})
+}
+
+func (p *Parameters) MatchAttributes() eventline.MatchAttributes {
+	return eventline.MatchAttributes{
+		"name": p.Name,
+	}
+}
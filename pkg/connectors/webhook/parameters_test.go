@@ -0,0 +1,21 @@
+package webhook
+
+import (
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParametersMatchAttributes(t *testing.T) {
+	assert := assert.New(t)
+
+	params := Parameters{
+		Name:   "deploy",
+		Fields: map[string]string{"repository": "$.repository.name"},
+	}
+
+	// Fields are applied to requests and must not prevent matching
+	assert.Equal(eventline.MatchAttributes{"name": "deploy"},
+		params.MatchAttributes())
+}
@@ -0,0 +1,115 @@
+package webhook
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Paths are a small subset of JSONPath: the "$" root followed by a sequence
+// of member accesses (".name" or "['name']") and array indexes ("[0]").
+// Wildcards, slices and filters are not supported.
+
+type PathSegment struct {
+	Key     string
+	Index   int
+	IsIndex bool
+}
+
+type Path []PathSegment
+
+func ParsePath(s string) (Path, error) {
+	if !strings.HasPrefix(s, "$") {
+		return nil, errors.New("path must start with \"$\"")
+	}
+
+	var path Path
+
+	rest := s[1:]
+
+	for len(rest) > 0 {
+		switch rest[0] {
+		case '.':
+			rest = rest[1:]
+
+			end := strings.IndexAny(rest, ".[")
+			if end == -1 {
+				end = len(rest)
+			}
+
+			key := rest[:end]
+			if key == "" {
+				return nil, errors.New("empty member name")
+			}
+
+			path = append(path, PathSegment{Key: key})
+			rest = rest[end:]
+
+		case '[':
+			end := strings.IndexByte(rest, ']')
+			if end == -1 {
+				return nil, errors.New("missing \"]\"")
+			}
+
+			content := rest[1:end]
+			rest = rest[end+1:]
+
+			segment, err := parseBracketSegment(content)
+			if err != nil {
+				return nil, err
+			}
+
+			path = append(path, segment)
+
+		default:
+			return nil, fmt.Errorf("unexpected character %q", rest[0])
+		}
+	}
+
+	return path, nil
+}
+
+func parseBracketSegment(s string) (PathSegment, error) {
+	if len(s) >= 2 && (s[0] == '\'' || s[0] == '"') {
+		if s[len(s)-1] != s[0] {
+			return PathSegment{}, errors.New("unterminated member name")
+		}
+
+		return PathSegment{Key: s[1 : len(s)-1]}, nil
+	}
+
+	index, err := strconv.Atoi(s)
+	if err != nil || index < 0 {
+		return PathSegment{}, fmt.Errorf("invalid array index %q", s)
+	}
+
+	return PathSegment{Index: index, IsIndex: true}, nil
+}
+
+// Evaluate returns the value designated by the path in a JSON value decoded
+// with encoding/json, and false if there is no such value.
+func (p Path) Evaluate(value interface{}) (interface{}, bool) {
+	for _, segment := range p {
+		if segment.IsIndex {
+			array, ok := value.([]interface{})
+			if !ok || segment.Index >= len(array) {
+				return nil, false
+			}
+
+			value = array[segment.Index]
+		} else {
+			object, ok := value.(map[string]interface{})
+			if !ok {
+				return nil, false
+			}
+
+			value, ok = object[segment.Key]
+			if !ok {
+				return nil, false
+			}
+		}
+	}
+
+	return value, true
+}
@@ -0,0 +1,71 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePath(t *testing.T) {
+	assert := assert.New(t)
+
+	tests := []struct {
+		s    string
+		path Path
+	}{
+		{"$", nil},
+		{"$.a", Path{{Key: "a"}}},
+		{"$.a.b", Path{{Key: "a"}, {Key: "b"}}},
+		{"$.a[2]", Path{{Key: "a"}, {Index: 2, IsIndex: true}}},
+		{"$['a b'].c", Path{{Key: "a b"}, {Key: "c"}}},
+	}
+
+	for _, test := range tests {
+		path, err := ParsePath(test.s)
+		if assert.NoError(err, test.s) {
+			assert.Equal(test.path, path, test.s)
+		}
+	}
+
+	for _, s := range []string{"", "a", "$.", "$..a", "$[", "$[-1]", "$['a]"} {
+		_, err := ParsePath(s)
+		assert.Error(err, s)
+	}
+}
+
+func TestNewRequestEvent(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	var payload interface{}
+	err := json.Unmarshal([]byte(`{
+  "repository": {"name": "app"},
+  "builds": [{"id": 42, "status": "success"}]
+}`), &payload)
+	require.NoError(err)
+
+	params := Parameters{
+		Name: "ci",
+		Fields: map[string]string{
+			"repository": "$.repository.name",
+			"status":     "$.builds[0].status",
+			"build":      "$.builds[0]",
+			"missing":    "$.builds[1].status",
+		},
+	}
+
+	event, err := NewRequestEvent("ci", &params, payload)
+	require.NoError(err)
+
+	assert.Equal("ci", event.Name)
+	assert.Equal(map[string]interface{}{
+		"repository": "app",
+		"status":     "success",
+		"build": map[string]interface{}{
+			"id":     float64(42),
+			"status": "success",
+		},
+	}, event.Fields)
+}
@@ -0,0 +1,15 @@
+package webhook
+
+import (
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// LoadSubscriptionsByName returns the subscriptions associated with a
+// webhook, using the name of the webhook as match attribute.
+func LoadSubscriptionsByName(conn pg.Conn, ename, name string) (eventline.Subscriptions, error) {
+	params := Parameters{Name: name}
+
+	return eventline.LoadSubscriptionsByMatchAttributes(conn, "webhook", ename,
+		params.MatchAttributes())
+}
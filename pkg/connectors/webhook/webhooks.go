@@ -0,0 +1,200 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// Requests are signed with the secret of the connector: the
+// X-Eventline-Signature-256 header field contains "sha256=" followed by the
+// hex-encoded HMAC-SHA256 signature of the body, the same way GitHub signs
+// its payloads.
+
+const SignatureHeader = "X-Eventline-Signature-256"
+
+var (
+	ErrConnectorDisabled = errors.New("connector disabled")
+	ErrMissingSignature  = errors.New("missing signature")
+	ErrInvalidSignature  = errors.New("invalid signature")
+	ErrRequestTooLarge   = errors.New("request body too large")
+)
+
+type InvalidPayloadError struct {
+	Err error
+}
+
+func (err *InvalidPayloadError) Error() string {
+	return fmt.Sprintf("invalid webhook payload: %v", err.Err)
+}
+
+func (err *InvalidPayloadError) Unwrap() error {
+	return err.Err
+}
+
+func (c *Connector) WebhookURI(name string) string {
+	path := "/ext/connectors/webhook/hooks/" + url.PathEscape(name)
+	uri := c.webHTTPServerURI.ResolveReference(&url.URL{Path: path})
+	return uri.String()
+}
+
+// Signature returns the value of the signature header field for a request
+// body.
+func Signature(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func ValidateSignature(header http.Header, body []byte, secret string) error {
+	signature := header.Get(SignatureHeader)
+	if signature == "" {
+		return ErrMissingSignature
+	}
+
+	if !strings.HasPrefix(signature, "sha256=") {
+		return ErrInvalidSignature
+	}
+
+	expectedSignature := Signature(body, secret)
+	if !hmac.Equal([]byte(signature), []byte(expectedSignature)) {
+		return ErrInvalidSignature
+	}
+
+	return nil
+}
+
+// ProcessWebhookRequest handles a request sent to a webhook. The body must be
+// a JSON value; an event is created for each subscription to the webhook,
+// with the fields extracted by the paths of the subscription.
+func (c *Connector) ProcessWebhookRequest(req *http.Request, name string) error {
+	if !c.Cfg.Enabled {
+		return ErrConnectorDisabled
+	}
+
+	maxSize := int64(c.Cfg.MaxRequestSize)
+	body, err := io.ReadAll(io.LimitReader(req.Body, maxSize+1))
+	if err != nil {
+		return fmt.Errorf("cannot read request body: %w", err)
+	} else if int64(len(body)) > maxSize {
+		return ErrRequestTooLarge
+	}
+
+	if err := ValidateSignature(req.Header, body, c.Cfg.Secret); err != nil {
+		// An invalid signature usually means that the sender was configured
+		// with the wrong secret; this is reported on the subscriptions of
+		// the webhook so that it can be diagnosed.
+		if err2 := c.recordSubscriptionErrors(name, err); err2 != nil {
+			c.Log.Error("cannot record subscription errors: %v", err2)
+		}
+
+		return err
+	}
+
+	var payload interface{}
+	if err := json.Unmarshal(body, &payload); err != nil {
+		return &InvalidPayloadError{Err: err}
+	}
+
+	c.Log.Debug(1, "received request for webhook %q", name)
+
+	return c.Pg.WithTx(func(conn pg.Conn) error {
+		return c.CreateEvents(conn, "request", name, payload)
+	})
+}
+
+// CreateEvents creates an event for each subscription to a webhook.
+func (c *Connector) CreateEvents(conn pg.Conn, ename, name string, payload interface{}) error {
+	subs, err := LoadSubscriptionsByName(conn, ename, name)
+	if err != nil {
+		return fmt.Errorf("cannot load subscriptions: %w", err)
+	}
+
+	events := make(eventline.Events, 0, len(subs))
+	subIds := make(eventline.Ids, 0, len(subs))
+
+	for _, sub := range subs {
+		subIds = append(subIds, sub.Id)
+
+		params := sub.Parameters.(*Parameters)
+
+		eventData, err := NewRequestEvent(name, params, payload)
+		if err != nil {
+			return fmt.Errorf("cannot create event for subscription %q: %w",
+				sub.Id, err)
+		}
+
+		event := sub.NewEvent(c.Def.Name, ename, nil, eventData)
+		events = append(events, event)
+	}
+
+	if err := events.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert events: %w", err)
+	}
+
+	if err := eventline.ClearSubscriptionErrors(conn, subIds); err != nil {
+		return fmt.Errorf("cannot clear subscription errors: %w", err)
+	}
+
+	return nil
+}
+
+func (c *Connector) recordSubscriptionErrors(name string, err error) error {
+	now := time.Now().UTC()
+
+	return c.Pg.WithTx(func(conn pg.Conn) error {
+		subs, err2 := LoadSubscriptionsByName(conn, "request", name)
+		if err2 != nil {
+			return fmt.Errorf("cannot load subscriptions: %w", err2)
+		}
+
+		for _, sub := range subs {
+			err2 := eventline.RecordSubscriptionError(conn, sub.Id, err, now)
+			if err2 != nil {
+				return err2
+			}
+		}
+
+		return nil
+	})
+}
+
+// NewRequestEvent applies the paths of a subscription to the body of a
+// request.
+func NewRequestEvent(name string, params *Parameters, payload interface{}) (*RequestEvent, error) {
+	event := RequestEvent{
+		Name: name,
+	}
+
+	for field, expr := range params.Fields {
+		path, err := ParsePath(expr)
+		if err != nil {
+			return nil, fmt.Errorf("invalid path %q for field %q: %w",
+				expr, field, err)
+		}
+
+		value, found := path.Evaluate(payload)
+		if !found {
+			continue
+		}
+
+		if event.Fields == nil {
+			event.Fields = make(map[string]interface{})
+		}
+
+		event.Fields[field] = value
+	}
+
+	return &event, nil
+}
@@ -1,17 +1,25 @@
 package eventline
 
 import (
+	"context"
 	"net/url"
 
 	"go.n16f.net/ejson"
 	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/influx"
 	"go.n16f.net/service/pkg/pg"
 )
 
 type ConnectorInitData struct {
 	Pg               *pg.Client
+	Influx           *influx.Client // nil if Influx is not configured
 	Log              *log.Logger
 	WebHTTPServerURI *url.URL
+	Proxy            *url.URL // nil if no proxy is configured
+
+	// Used by connectors processing deliveries asynchronously to report the
+	// outcome of each delivery once it has been processed.
+	RecordDelivery func(error)
 }
 
 type ConnectorCfg interface {
@@ -34,6 +42,17 @@ type SubscribableConnector interface {
 	Unsubscribe(pg.Conn, *SubscriptionContext) error
 }
 
+// TestableConnector is implemented by connectors able to check that an
+// identity is valid, usually with a lightweight authenticated request to the
+// service the identity is used with. Credentials which are rejected are
+// reported in the result; errors are only returned when the test could not be
+// performed.
+type TestableConnector interface {
+	Connector
+
+	TestIdentity(context.Context, *Identity) (*IdentityTestResult, error)
+}
+
 // The optional aspect of the connector is related to events only. But at this
 // point I do not have a better idea for a name.
 type OptionalConnector interface {
@@ -0,0 +1,182 @@
+package eventline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// A connector is quarantined when the processing of its webhook deliveries
+// fails too often. While quarantined, deliveries are counted but not
+// processed, until an administrator clears the quarantine. The quarantine is
+// stored in the database so that it applies to all Eventline instances.
+
+type UnknownConnectorQuarantineError struct {
+	Connector string
+}
+
+func (err UnknownConnectorQuarantineError) Error() string {
+	return fmt.Sprintf("connector %q is not quarantined", err.Connector)
+}
+
+type ConnectorQuarantine struct {
+	Connector        string     `json:"connector"`
+	StartTime        time.Time  `json:"start_time"`
+	Reason           string     `json:"reason"`
+	NbDeliveries     int64      `json:"nb_deliveries"`
+	LastDeliveryTime *time.Time `json:"last_delivery_time,omitempty"`
+}
+
+type ConnectorQuarantines []*ConnectorQuarantine
+
+func (qs *ConnectorQuarantines) LoadAll(conn pg.Conn) error {
+	query := `
+SELECT connector, start_time, reason, nb_deliveries, last_delivery_time
+  FROM connector_quarantines
+  ORDER BY connector
+`
+	return pg.QueryObjects(conn, qs, query)
+}
+
+// Insert creates the quarantine unless the connector is already quarantined,
+// in which case the existing quarantine is kept unchanged.
+func (q *ConnectorQuarantine) Insert(conn pg.Conn) error {
+	query := `
+INSERT INTO connector_quarantines
+    (connector, start_time, reason, nb_deliveries, last_delivery_time)
+  VALUES
+    ($1, $2, $3, $4, $5)
+  ON CONFLICT (connector) DO NOTHING;
+`
+	return pg.Exec(conn, query,
+		q.Connector, q.StartTime, q.Reason, q.NbDeliveries,
+		q.LastDeliveryTime)
+}
+
+// RecordConnectorQuarantineDelivery records the arrival of a delivery if the
+// connector is quarantined. It returns true if the connector is quarantined.
+func RecordConnectorQuarantineDelivery(conn pg.Conn, connector string, now time.Time) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+UPDATE connector_quarantines SET
+    nb_deliveries = nb_deliveries + 1,
+    last_delivery_time = $2
+  WHERE connector = $1
+`
+	res, err := conn.Exec(ctx, query, connector, now)
+	if err != nil {
+		return false, err
+	}
+
+	return res.RowsAffected() > 0, nil
+}
+
+func DeleteConnectorQuarantine(conn pg.Conn, connector string) error {
+	ctx := context.Background()
+
+	query := `
+DELETE FROM connector_quarantines
+  WHERE connector = $1
+`
+	res, err := conn.Exec(ctx, query, connector)
+	if err != nil {
+		return err
+	}
+
+	if res.RowsAffected() == 0 {
+		return &UnknownConnectorQuarantineError{Connector: connector}
+	}
+
+	return nil
+}
+
+func (q *ConnectorQuarantine) FromRow(row pgx.Row) error {
+	return row.Scan(&q.Connector, &q.StartTime, &q.Reason, &q.NbDeliveries,
+		&q.LastDeliveryTime)
+}
+
+func (qs *ConnectorQuarantines) AddFromRow(row pgx.Row) error {
+	var q ConnectorQuarantine
+	if err := q.FromRow(row); err != nil {
+		return err
+	}
+
+	*qs = append(*qs, &q)
+	return nil
+}
+
+// ConnectorFailureTracker counts successful and failed deliveries over a
+// sliding window, using one bucket per second. It is not safe for concurrent
+// use.
+type ConnectorFailureTracker struct {
+	Window           time.Duration
+	FailureThreshold int // minimum number of failures in the window
+	FailureRate      int // minimum percentage of failed deliveries
+
+	buckets []connectorFailureBucket
+}
+
+type connectorFailureBucket struct {
+	second      int64
+	nbSuccesses int
+	nbFailures  int
+}
+
+func (t *ConnectorFailureTracker) Record(now time.Time, failure bool) {
+	t.prune(now)
+
+	second := now.Unix()
+
+	n := len(t.buckets)
+	if n == 0 || t.buckets[n-1].second != second {
+		t.buckets = append(t.buckets, connectorFailureBucket{second: second})
+		n++
+	}
+
+	if failure {
+		t.buckets[n-1].nbFailures++
+	} else {
+		t.buckets[n-1].nbSuccesses++
+	}
+}
+
+// Tripped indicates whether the failures in the window are frequent enough
+// to quarantine the connector.
+func (t *ConnectorFailureTracker) Tripped(now time.Time) bool {
+	if t.FailureThreshold <= 0 {
+		return false
+	}
+
+	t.prune(now)
+
+	var nbSuccesses, nbFailures int
+	for _, b := range t.buckets {
+		nbSuccesses += b.nbSuccesses
+		nbFailures += b.nbFailures
+	}
+
+	if nbFailures < t.FailureThreshold {
+		return false
+	}
+
+	return nbFailures*100 >= (nbSuccesses+nbFailures)*t.FailureRate
+}
+
+func (t *ConnectorFailureTracker) Reset() {
+	t.buckets = nil
+}
+
+func (t *ConnectorFailureTracker) prune(now time.Time) {
+	minSecond := now.Add(-t.Window).Unix()
+
+	i := 0
+	for i < len(t.buckets) && t.buckets[i].second <= minSecond {
+		i++
+	}
+
+	t.buckets = t.buckets[i:]
+}
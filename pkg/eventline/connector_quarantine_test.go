@@ -0,0 +1,57 @@
+package eventline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConnectorFailureTracker(t *testing.T) {
+	assert := assert.New(t)
+
+	tracker := ConnectorFailureTracker{
+		Window:           time.Minute,
+		FailureThreshold: 3,
+		FailureRate:      50,
+	}
+
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	at := func(seconds int) time.Time {
+		return now.Add(time.Duration(seconds) * time.Second)
+	}
+
+	for i := 0; i < 4; i++ {
+		tracker.Record(at(0), false)
+	}
+
+	// Not enough failures
+	tracker.Record(at(1), true)
+	tracker.Record(at(2), true)
+	assert.False(tracker.Tripped(at(2)))
+
+	// Failure rate too low
+	tracker.Record(at(3), true)
+	assert.False(tracker.Tripped(at(3)))
+
+	// Successes leave the window
+	assert.True(tracker.Tripped(at(60)))
+
+	// All failures leave the window
+	assert.False(tracker.Tripped(at(120)))
+
+	tracker.Record(at(121), true)
+	tracker.Record(at(121), true)
+	tracker.Record(at(122), true)
+	assert.True(tracker.Tripped(at(122)))
+
+	tracker.Reset()
+	assert.False(tracker.Tripped(at(122)))
+
+	// Disabled tracker
+	tracker.FailureThreshold = 0
+	for i := 0; i < 10; i++ {
+		tracker.Record(at(130), true)
+	}
+	assert.False(tracker.Tripped(at(130)))
+}
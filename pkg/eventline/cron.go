@@ -0,0 +1,232 @@
+package eventline
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CronSchedule is a parsed cron expression using the traditional five fields:
+// minute, hour, day of month, month and day of week.
+//
+// Each field can be "*", a single value, a range ("a-b"), a step ("*/n",
+// "a-b/n" or "a/n") or a comma-separated list of these forms. Months and days
+// of week can be referred to by their three letter english names. Day of week
+// 0 and 7 both refer to Sunday. The "@yearly", "@annually", "@monthly",
+// "@weekly", "@daily", "@midnight" and "@hourly" shortcuts are also supported.
+//
+// As with the original cron implementation, if both the day of month and the
+// day of week fields are restricted, a day matches if either field matches.
+type CronSchedule struct {
+	minutes     uint64
+	hours       uint64
+	daysOfMonth uint64
+	months      uint64
+	daysOfWeek  uint64
+
+	anyDayOfMonth bool
+	anyDayOfWeek  bool
+}
+
+type cronField struct {
+	name  string
+	min   int
+	max   int
+	names []string
+}
+
+var cronFields = []cronField{
+	{"minute", 0, 59, nil},
+	{"hour", 0, 23, nil},
+	{"day of month", 1, 31, nil},
+	{"month", 1, 12, []string{"jan", "feb", "mar", "apr", "may", "jun",
+		"jul", "aug", "sep", "oct", "nov", "dec"}},
+	{"day of week", 0, 7, []string{"sun", "mon", "tue", "wed", "thu",
+		"fri", "sat"}},
+}
+
+var cronShortcuts = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+func ParseCronSchedule(s string) (*CronSchedule, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "@") {
+		expansion, found := cronShortcuts[strings.ToLower(s)]
+		if !found {
+			return nil, fmt.Errorf("unknown shortcut %q", s)
+		}
+
+		s = expansion
+	}
+
+	parts := strings.Fields(s)
+	if len(parts) != len(cronFields) {
+		return nil, fmt.Errorf("invalid number of fields: expected %d, "+
+			"got %d", len(cronFields), len(parts))
+	}
+
+	sets := make([]uint64, len(cronFields))
+
+	for i, field := range cronFields {
+		set, err := field.parse(parts[i])
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s field %q: %w",
+				field.name, parts[i], err)
+		}
+
+		sets[i] = set
+	}
+
+	cs := CronSchedule{
+		minutes:     sets[0],
+		hours:       sets[1],
+		daysOfMonth: sets[2],
+		months:      sets[3],
+		daysOfWeek:  sets[4],
+
+		anyDayOfMonth: parts[2] == "*" || strings.HasPrefix(parts[2], "*/"),
+		anyDayOfWeek:  parts[4] == "*" || strings.HasPrefix(parts[4], "*/"),
+	}
+
+	// Sunday can be either 0 or 7
+	if cs.daysOfWeek&(1<<7) != 0 {
+		cs.daysOfWeek |= 1
+	}
+
+	return &cs, nil
+}
+
+func (f *cronField) parse(s string) (uint64, error) {
+	var set uint64
+
+	for _, part := range strings.Split(s, ",") {
+		rangeString, stepString, hasStep := strings.Cut(part, "/")
+
+		step := 1
+		if hasStep {
+			i, err := strconv.Atoi(stepString)
+			if err != nil || i < 1 {
+				return 0, fmt.Errorf("invalid step %q", stepString)
+			}
+
+			step = i
+		}
+
+		var start, end int
+
+		if rangeString == "*" {
+			start, end = f.min, f.max
+		} else if startString, endString, found := strings.Cut(rangeString, "-"); found {
+			var err error
+
+			if start, err = f.parseValue(startString); err != nil {
+				return 0, err
+			}
+
+			if end, err = f.parseValue(endString); err != nil {
+				return 0, err
+			}
+
+			if end < start {
+				return 0, fmt.Errorf("invalid range %q", rangeString)
+			}
+		} else {
+			var err error
+
+			if start, err = f.parseValue(rangeString); err != nil {
+				return 0, err
+			}
+
+			// "a/n" means "from a to the maximum value, every n"
+			end = start
+			if hasStep {
+				end = f.max
+			}
+		}
+
+		for i := start; i <= end; i += step {
+			set |= 1 << i
+		}
+	}
+
+	return set, nil
+}
+
+func (f *cronField) parseValue(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return i + f.min, nil
+		}
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value %q", s)
+	}
+
+	if i < f.min || i > f.max {
+		return 0, fmt.Errorf("value %d out of range [%d, %d]",
+			i, f.min, f.max)
+	}
+
+	return i, nil
+}
+
+// Next returns the first time strictly after t matching the schedule in the
+// location of t. It returns the zero time if there is no such time in the
+// next five years, which can only happen for schedules such as "0 0 31 2 *".
+func (s *CronSchedule) Next(t time.Time) time.Time {
+	loc := t.Location()
+
+	t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), 0, 0,
+		loc).Add(time.Minute)
+
+	limit := t.AddDate(5, 0, 0)
+
+	for t.Before(limit) {
+		if s.months&(1<<int(t.Month())) == 0 {
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if !s.matchDay(t) {
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, loc)
+			continue
+		}
+
+		if s.hours&(1<<t.Hour()) == 0 {
+			t = time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0,
+				loc).Add(time.Hour)
+			continue
+		}
+
+		if s.minutes&(1<<t.Minute()) == 0 {
+			t = t.Add(time.Minute)
+			continue
+		}
+
+		return t
+	}
+
+	return time.Time{}
+}
+
+func (s *CronSchedule) matchDay(t time.Time) bool {
+	domMatch := s.daysOfMonth&(1<<t.Day()) != 0
+	dowMatch := s.daysOfWeek&(1<<int(t.Weekday())) != 0
+
+	if s.anyDayOfMonth || s.anyDayOfWeek {
+		return domMatch && dowMatch
+	}
+
+	return domMatch || dowMatch
+}
@@ -0,0 +1,123 @@
+package eventline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.n16f.net/program"
+)
+
+func TestCronScheduleNext(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	next := func(expr, t string) time.Time {
+		cs, err := ParseCronSchedule(expr)
+		require.NoError(err, expr)
+
+		return cs.Next(testCronTime(t))
+	}
+
+	assert.Equal(testCronTime("2020-05-01T10:21:00Z"),
+		next("* * * * *", "2020-05-01T10:20:30Z"))
+	assert.Equal(testCronTime("2020-05-02T02:00:00Z"),
+		next("0 2 * * *", "2020-05-01T02:00:00Z"))
+	assert.Equal(testCronTime("2020-05-01T10:30:00Z"),
+		next("*/15 * * * *", "2020-05-01T10:17:00Z"))
+	assert.Equal(testCronTime("2020-05-01T11:05:00Z"),
+		next("5-10/5 * * * *", "2020-05-01T10:10:00Z"))
+	assert.Equal(testCronTime("2021-01-01T00:00:00Z"),
+		next("@yearly", "2020-05-01T10:17:00Z"))
+	assert.Equal(testCronTime("2020-05-04T09:00:00Z"),
+		next("0 9 * * mon-fri", "2020-05-01T09:00:00Z"))
+	assert.Equal(testCronTime("2020-05-03T00:00:00Z"),
+		next("0 0 * * 7", "2020-05-01T09:00:00Z"))
+	assert.Equal(testCronTime("2020-06-15T00:00:00Z"),
+		next("0 0 15 jun *", "2020-05-01T09:00:00Z"))
+
+	// Day of month and day of week both restricted: either matches
+	assert.Equal(testCronTime("2020-05-03T00:00:00Z"),
+		next("0 0 10 * sun", "2020-05-01T09:00:00Z"))
+
+	// Timezones and daylight saving time
+	paris, err := time.LoadLocation("Europe/Paris")
+	require.NoError(err)
+
+	cs, err := ParseCronSchedule("30 2 * * *")
+	require.NoError(err)
+
+	assert.Equal(time.Date(2020, 3, 30, 2, 30, 0, 0, paris),
+		cs.Next(time.Date(2020, 3, 28, 3, 0, 0, 0, paris)))
+}
+
+func TestParseCronScheduleErrors(t *testing.T) {
+	assert := assert.New(t)
+
+	for _, expr := range []string{
+		"",
+		"* * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"*/0 * * * *",
+		"10-5 * * * *",
+		"foo * * * *",
+		"@never",
+	} {
+		_, err := ParseCronSchedule(expr)
+		assert.Error(err, expr)
+	}
+}
+
+func TestJobScheduleTicks(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	schedule := func(catchUp JobScheduleCatchUp) *JobSchedule {
+		return &JobSchedule{
+			Spec: &JobScheduleSpec{
+				Cron:    "0 * * * *",
+				CatchUp: catchUp,
+			},
+			NextTime: testCronTime("2020-05-01T10:00:00Z"),
+		}
+	}
+
+	now := testCronTime("2020-05-01T12:30:00Z")
+
+	ticks, next, err := schedule(JobScheduleCatchUpAll).Ticks(now)
+	require.NoError(err)
+	assert.Equal([]time.Time{
+		testCronTime("2020-05-01T10:00:00Z"),
+		testCronTime("2020-05-01T11:00:00Z"),
+		testCronTime("2020-05-01T12:00:00Z"),
+	}, ticks)
+	assert.Equal(testCronTime("2020-05-01T13:00:00Z"), next)
+
+	ticks, next, err = schedule("").Ticks(now)
+	require.NoError(err)
+	assert.Equal([]time.Time{testCronTime("2020-05-01T12:00:00Z")}, ticks)
+	assert.Equal(testCronTime("2020-05-01T13:00:00Z"), next)
+
+	ticks, _, err = schedule(JobScheduleCatchUpNone).Ticks(now)
+	require.NoError(err)
+	assert.Empty(ticks)
+
+	ticks, _, err = schedule(JobScheduleCatchUpNone).
+		Ticks(testCronTime("2020-05-01T10:00:20Z"))
+	require.NoError(err)
+	assert.Equal([]time.Time{testCronTime("2020-05-01T10:00:00Z")}, ticks)
+}
+
+func testCronTime(s string) time.Time {
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		program.Panicf("invalid time %q: %v", s, err)
+	}
+
+	return t
+}
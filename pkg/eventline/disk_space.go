@@ -0,0 +1,102 @@
+package eventline
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Concurrent executions on the same host each create their own directory;
+// without any limit, they can fill the disk and cause all executions on the
+// host to fail at the same time. Runners supporting it can be configured with
+// a minimum amount of free disk space: executions fail before uploading any
+// file if the free space, minus the size of the file set, would be below this
+// limit.
+//
+// Free space is only a point-in-time measure: two executions starting at the
+// same time on the same host would both see the same free space. Each
+// execution therefore reserves the size of its file set until the files have
+// been written, and reservations of other executions are subtracted from the
+// free space. Data written by steps while they are running are not accounted
+// for.
+
+type InsufficientDiskSpaceError struct {
+	Path      string
+	Available int64 // bytes
+	Required  int64 // bytes
+}
+
+func (err *InsufficientDiskSpaceError) Error() string {
+	return fmt.Sprintf("insufficient disk space on runner host: %d bytes "+
+		"available in %q, %d bytes required", err.Available, err.Path,
+		err.Required)
+}
+
+// CheckDiskSpace returns an InsufficientDiskSpaceError if there is not
+// enough free space to write a file set and keep at least minFreeSpace bytes
+// available. A minFreeSpace value of 0 disables the check.
+func CheckDiskSpace(dirPath string, available, minFreeSpace int64, fileSet *FileSet) error {
+	if minFreeSpace == 0 {
+		return nil
+	}
+
+	required := minFreeSpace + fileSet.Size()
+
+	if available < required {
+		return &InsufficientDiskSpaceError{
+			Path:      dirPath,
+			Available: available,
+			Required:  required,
+		}
+	}
+
+	return nil
+}
+
+type DiskSpaceReservations struct {
+	reservations map[string]int64 // bytes
+	mutex        sync.Mutex
+}
+
+var GlobalDiskSpaceReservations = NewDiskSpaceReservations()
+
+func NewDiskSpaceReservations() *DiskSpaceReservations {
+	return &DiskSpaceReservations{
+		reservations: make(map[string]int64),
+	}
+}
+
+// Reserve checks that there is enough free space to write a file set, taking
+// into account space reserved by other executions on the same host, and
+// reserves the size of the file set. The key identifies the host and the
+// directory. The function returned must be called to release the reservation
+// once the file set has been written.
+func (r *DiskSpaceReservations) Reserve(key, dirPath string, available, minFreeSpace int64, fileSet *FileSet) (func(), error) {
+	if minFreeSpace == 0 {
+		return func() {}, nil
+	}
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	available -= r.reservations[key]
+
+	err := CheckDiskSpace(dirPath, available, minFreeSpace, fileSet)
+	if err != nil {
+		return nil, err
+	}
+
+	size := fileSet.Size()
+	r.reservations[key] += size
+
+	release := func() {
+		r.mutex.Lock()
+		defer r.mutex.Unlock()
+
+		r.reservations[key] -= size
+		if r.reservations[key] <= 0 {
+			delete(r.reservations, key)
+		}
+	}
+
+	return release, nil
+}
@@ -0,0 +1,59 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckDiskSpace(t *testing.T) {
+	assert := assert.New(t)
+
+	fileSet := NewFileSet()
+	fileSet.AddFile("a", []byte("hello"), 0600)
+	fileSet.AddFile("b", []byte("world!"), 0600)
+
+	assert.Equal(int64(11), fileSet.Size())
+
+	assert.NoError(CheckDiskSpace("/tmp", 0, 0, fileSet))
+	assert.NoError(CheckDiskSpace("/tmp", 111, 100, fileSet))
+
+	err := CheckDiskSpace("/tmp", 110, 100, fileSet)
+	var diskSpaceErr *InsufficientDiskSpaceError
+	if assert.ErrorAs(err, &diskSpaceErr) {
+		assert.Equal(int64(110), diskSpaceErr.Available)
+		assert.Equal(int64(111), diskSpaceErr.Required)
+	}
+}
+
+func TestDiskSpaceReservations(t *testing.T) {
+	assert := assert.New(t)
+
+	reservations := NewDiskSpaceReservations()
+
+	fileSet := NewFileSet()
+	fileSet.AddFile("a", []byte("0123456789"), 0600)
+
+	release1, err := reservations.Reserve("host1", "/tmp", 115, 100, fileSet)
+	assert.NoError(err)
+
+	// The first file set has not been written yet, so both executions cannot
+	// rely on the same free space.
+	_, err = reservations.Reserve("host1", "/tmp", 115, 100, fileSet)
+	var diskSpaceErr *InsufficientDiskSpaceError
+	if assert.ErrorAs(err, &diskSpaceErr) {
+		assert.Equal(int64(105), diskSpaceErr.Available)
+	}
+
+	// Reservations are per host
+	release2, err := reservations.Reserve("host2", "/tmp", 115, 100, fileSet)
+	assert.NoError(err)
+	release2()
+
+	release1()
+	assert.Empty(reservations.reservations)
+
+	release3, err := reservations.Reserve("host1", "/tmp", 115, 100, fileSet)
+	assert.NoError(err)
+	release3()
+}
@@ -0,0 +1,205 @@
+package eventline
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/exograd/eventline/pkg/utils"
+	"go.n16f.net/ejson"
+)
+
+// Environment values in job specifications can reference fields of other
+// Eventline objects with the ${<type>:<reference>} syntax, e.g.
+// ${identity:github.username} or ${project:name}. References are resolved
+// when the execution starts. Other ${...} sequences are left untouched.
+
+type EnvironmentReferenceType string
+
+const (
+	EnvironmentReferenceTypeIdentity EnvironmentReferenceType = "identity"
+	EnvironmentReferenceTypeProject  EnvironmentReferenceType = "project"
+)
+
+var ProjectEnvironmentReferenceFields = []string{"id", "name"}
+
+var environmentReferenceRE = regexp.MustCompile(`\$\{(identity|project):([^}]*)\}`)
+
+type EnvironmentReference struct {
+	Type     EnvironmentReferenceType
+	Identity string // identity references only
+	Field    string
+}
+
+func (r *EnvironmentReference) String() string {
+	if r.Type == EnvironmentReferenceTypeIdentity {
+		return "${identity:" + r.Identity + "." + r.Field + "}"
+	}
+
+	return "${" + string(r.Type) + ":" + r.Field + "}"
+}
+
+func (r *EnvironmentReference) Parse(typeString, s string) error {
+	switch EnvironmentReferenceType(typeString) {
+	case EnvironmentReferenceTypeIdentity:
+		identity, field, found := strings.Cut(s, ".")
+		if !found || identity == "" || field == "" {
+			return fmt.Errorf("invalid identity reference %q: references "+
+				"must be of the form <identity>.<field>", s)
+		}
+
+		r.Identity = identity
+		r.Field = field
+
+	case EnvironmentReferenceTypeProject:
+		if !utils.StringsContain(ProjectEnvironmentReferenceFields, s) {
+			return fmt.Errorf("invalid project field %q", s)
+		}
+
+		r.Field = s
+
+	default:
+		return fmt.Errorf("unknown reference type %q", typeString)
+	}
+
+	r.Type = EnvironmentReferenceType(typeString)
+
+	return nil
+}
+
+// ParseEnvironmentReferences returns all references contained in an
+// environment value.
+func ParseEnvironmentReferences(value string) ([]*EnvironmentReference, error) {
+	var refs []*EnvironmentReference
+
+	for _, match := range environmentReferenceRE.FindAllStringSubmatch(value, -1) {
+		var ref EnvironmentReference
+		if err := ref.Parse(match[1], match[2]); err != nil {
+			return nil, err
+		}
+
+		refs = append(refs, &ref)
+	}
+
+	return refs, nil
+}
+
+func CheckEnvironment(v *ejson.Validator, token interface{}, env map[string]string) {
+	v.WithChild(token, func() {
+		for name, value := range env {
+			_, err := ParseEnvironmentReferences(value)
+			v.Check(name, err == nil, "invalid_environment_reference",
+				"%v", err)
+		}
+	})
+}
+
+// EnvironmentReferenceIdentityNames returns the names of all identities
+// referenced in the values of an environment. Invalid references are ignored.
+func EnvironmentReferenceIdentityNames(env map[string]string) []string {
+	var names []string
+
+	for _, value := range env {
+		refs, _ := ParseEnvironmentReferences(value)
+
+		for _, ref := range refs {
+			if ref.Type == EnvironmentReferenceTypeIdentity {
+				names = append(names, ref.Identity)
+			}
+		}
+	}
+
+	return names
+}
+
+// EnvironmentResolver resolves the references contained in environment
+// values. The values of secret identity fields are collected in Secrets so
+// that they can be masked in step outputs.
+type EnvironmentResolver struct {
+	Project    *Project
+	Identities map[string]*Identity
+
+	Secrets []string
+}
+
+func (r *EnvironmentResolver) Resolve(value string) (string, error) {
+	var err error
+
+	resolvedValue := environmentReferenceRE.ReplaceAllStringFunc(value,
+		func(s string) string {
+			if err != nil {
+				return ""
+			}
+
+			match := environmentReferenceRE.FindStringSubmatch(s)
+
+			var ref EnvironmentReference
+			if err = ref.Parse(match[1], match[2]); err != nil {
+				return ""
+			}
+
+			var refValue string
+			refValue, err = r.resolveReference(&ref)
+			return refValue
+		})
+	if err != nil {
+		return "", err
+	}
+
+	return resolvedValue, nil
+}
+
+func (r *EnvironmentResolver) resolveReference(ref *EnvironmentReference) (string, error) {
+	switch ref.Type {
+	case EnvironmentReferenceTypeIdentity:
+		identity, found := r.Identities[ref.Identity]
+		if !found {
+			return "", fmt.Errorf("%s: unknown identity %q", ref,
+				ref.Identity)
+		}
+
+		entry := identity.Data.Def().Entry(ref.Field)
+		if entry == nil {
+			return "", fmt.Errorf("%s: unknown field %q for identity type "+
+				"%s/%s", ref, ref.Field, identity.Connector, identity.Type)
+		}
+
+		value := IdentityDataEntryValueString(entry.Value)
+		if entry.Secret {
+			r.Secrets = append(r.Secrets, value)
+		}
+
+		return value, nil
+
+	case EnvironmentReferenceTypeProject:
+		switch ref.Field {
+		case "id":
+			return r.Project.Id.String(), nil
+		case "name":
+			return r.Project.Name, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: invalid reference", ref)
+}
+
+func IdentityDataEntryValueString(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case []string:
+		return strings.Join(v, ",")
+	case time.Time:
+		return v.Format(time.RFC3339)
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		return v.Format(time.RFC3339)
+	default:
+		return fmt.Sprintf("%v", v)
+	}
+}
@@ -0,0 +1,101 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.n16f.net/ejson"
+)
+
+type testIdentityData struct {
+	Login    string
+	Password string
+}
+
+func (i *testIdentityData) ValidateJSON(v *ejson.Validator) {
+}
+
+func (i *testIdentityData) Def() *IdentityDataDef {
+	def := NewIdentityDataDef()
+
+	def.AddEntry(&IdentityDataEntry{
+		Key:   "login",
+		Value: i.Login,
+		Type:  IdentityDataTypeString,
+	})
+
+	def.AddEntry(&IdentityDataEntry{
+		Key:    "password",
+		Value:  i.Password,
+		Type:   IdentityDataTypeString,
+		Secret: true,
+	})
+
+	return def
+}
+
+func (i *testIdentityData) Environment() map[string]string {
+	return map[string]string{}
+}
+
+func TestParseEnvironmentReferences(t *testing.T) {
+	assert := assert.New(t)
+
+	refs, err := ParseEnvironmentReferences("foo")
+	if assert.NoError(err) {
+		assert.Empty(refs)
+	}
+
+	refs, err = ParseEnvironmentReferences("${HOME:-/tmp}")
+	if assert.NoError(err) {
+		assert.Empty(refs)
+	}
+
+	refs, err = ParseEnvironmentReferences("${identity:db.login}@${project:name}")
+	if assert.NoError(err) && assert.Len(refs, 2) {
+		assert.Equal(EnvironmentReferenceTypeIdentity, refs[0].Type)
+		assert.Equal("db", refs[0].Identity)
+		assert.Equal("login", refs[0].Field)
+
+		assert.Equal(EnvironmentReferenceTypeProject, refs[1].Type)
+		assert.Equal("name", refs[1].Field)
+	}
+
+	_, err = ParseEnvironmentReferences("${identity:db}")
+	assert.Error(err)
+
+	_, err = ParseEnvironmentReferences("${project:foo}")
+	assert.Error(err)
+}
+
+func TestEnvironmentResolver(t *testing.T) {
+	assert := assert.New(t)
+
+	resolver := EnvironmentResolver{
+		Project: &Project{Name: "main"},
+		Identities: map[string]*Identity{
+			"db": {
+				Name: "db",
+				Data: &testIdentityData{Login: "bob", Password: "secret"},
+			},
+		},
+	}
+
+	value, err := resolver.Resolve("${identity:db.login}@${project:name}")
+	if assert.NoError(err) {
+		assert.Equal("bob@main", value)
+	}
+	assert.Empty(resolver.Secrets)
+
+	value, err = resolver.Resolve("${identity:db.password}")
+	if assert.NoError(err) {
+		assert.Equal("secret", value)
+	}
+	assert.Equal([]string{"secret"}, resolver.Secrets)
+
+	_, err = resolver.Resolve("${identity:db.token}")
+	assert.Error(err)
+
+	_, err = resolver.Resolve("${identity:unknown.login}")
+	assert.Error(err)
+}
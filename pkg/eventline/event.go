@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -49,6 +50,7 @@ type Event struct {
 	DataValue       interface{} `json:"-"`
 	Processed       bool        `json:"processed,omitempty"`
 	OriginalEventId *Id         `json:"original_event_id,omitempty"`
+	DropReason      string      `json:"drop_reason,omitempty"`
 }
 
 type Events []*Event
@@ -105,7 +107,8 @@ func (e *Event) Def() *EventDef {
 func (e *Event) Load(conn pg.Conn, id Id, scope Scope) error {
 	query := fmt.Sprintf(`
 SELECT id, project_id, job_id, creation_time, event_time,
-       connector, name, data, processed, original_event_id
+       connector, name, data, processed, original_event_id,
+       drop_reason
   FROM events
   WHERE %s AND id = $1
 `, scope.SQLCondition())
@@ -121,7 +124,8 @@ SELECT id, project_id, job_id, creation_time, event_time,
 func LoadEventForProcessing(conn pg.Conn) (*Event, error) {
 	query := `
 SELECT id, project_id, job_id, creation_time, event_time,
-       connector, name, data, processed, original_event_id
+       connector, name, data, processed, original_event_id,
+       drop_reason
   FROM events
   WHERE processed = FALSE and job_id IS NOT NULL
   LIMIT 1
@@ -141,7 +145,8 @@ SELECT id, project_id, job_id, creation_time, event_time,
 func LoadEventPage(conn pg.Conn, cursor *Cursor, scope Scope) (*Page, error) {
 	query := fmt.Sprintf(`
 SELECT id, project_id, job_id, creation_time, event_time,
-       connector, name, data, processed, original_event_id
+       connector, name, data, processed, original_event_id,
+       drop_reason
   FROM events
   WHERE %s AND %s
 `, scope.SQLCondition(), cursor.SQLConditionOrderLimit(EventSorts))
@@ -155,29 +160,97 @@ SELECT id, project_id, job_id, creation_time, event_time,
 }
 
 func (e *Event) Insert(conn pg.Conn) error {
+	es, err := DeduplicateEvents(conn, Events{e}, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("cannot deduplicate events: %w", err)
+	} else if len(es) == 0 {
+		return nil
+	}
+
 	query := `
 INSERT INTO events
     (id, project_id, job_id, creation_time, event_time,
-     connector, name, data, processed, original_event_id)
+     connector, name, data, processed, original_event_id,
+     drop_reason)
   VALUES
     ($1, $2, $3, $4, $5,
-     $6, $7, $8, $9, $10);
+     $6, $7, $8, $9, $10,
+     $11);
 `
 
 	return pg.Exec(conn, query,
 		e.Id, e.ProjectId, e.JobId, e.CreationTime, e.EventTime,
-		e.Connector, e.Name, e.Data, e.Processed, e.OriginalEventId)
+		e.Connector, e.Name, e.Data, e.Processed, e.OriginalEventId,
+		e.DropReason)
+}
+
+// Insert inserts all events with multi-row INSERT queries to limit the number
+// of round trips. Rows are inserted in chunks since PostgreSQL limits the
+// number of parameters of a query. Duplicate events are discarded (see
+// DeduplicateEvents).
+func (es Events) Insert(conn pg.Conn) error {
+	const nbColumns = 11
+	const maxRows = 1000
+
+	es, err := DeduplicateEvents(conn, es, time.Now().UTC())
+	if err != nil {
+		return fmt.Errorf("cannot deduplicate events: %w", err)
+	}
+
+	for start := 0; start < len(es); start += maxRows {
+		end := min(start+maxRows, len(es))
+		chunk := es[start:end]
+
+		var buf strings.Builder
+		args := make([]interface{}, 0, len(chunk)*nbColumns)
+
+		buf.WriteString(`
+INSERT INTO events
+    (id, project_id, job_id, creation_time, event_time,
+     connector, name, data, processed, original_event_id,
+     drop_reason)
+  VALUES
+`)
+
+		for i, e := range chunk {
+			if i > 0 {
+				buf.WriteString(",\n")
+			}
+
+			buf.WriteString("    (")
+			for j := 0; j < nbColumns; j++ {
+				if j > 0 {
+					buf.WriteString(", ")
+				}
+
+				fmt.Fprintf(&buf, "$%d", i*nbColumns+j+1)
+			}
+			buf.WriteString(")")
+
+			args = append(args,
+				e.Id, e.ProjectId, e.JobId, e.CreationTime, e.EventTime,
+				e.Connector, e.Name, e.Data, e.Processed, e.OriginalEventId,
+				e.DropReason)
+		}
+
+		if err := pg.Exec(conn, buf.String(), args...); err != nil {
+			return err
+		}
+	}
+
+	return nil
 }
 
 func (e *Event) Update(conn pg.Conn) error {
 	query := `
 UPDATE events SET
-    processed = $2
+    processed = $2,
+    drop_reason = $3
   WHERE id = $1
 `
 
 	return pg.Exec(conn, query,
-		e.Id, e.Processed)
+		e.Id, e.Processed, e.DropReason)
 }
 
 func (es Events) Page(cursor *Cursor) *Page {
@@ -194,7 +267,8 @@ func (e *Event) FromRow(row pgx.Row) error {
 	var rawData []byte
 
 	err := row.Scan(&e.Id, &e.ProjectId, &e.JobId, &e.CreationTime, &e.EventTime,
-		&e.Connector, &e.Name, &rawData, &e.Processed, &originalEventId)
+		&e.Connector, &e.Name, &rawData, &e.Processed, &originalEventId,
+		&e.DropReason)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,203 @@
+package eventline
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// Triggers can collapse events which share the same deduplication key, i.e.
+// the value found at a specific path in event data, within a time window.
+// The first event of a window is inserted; the following ones are silently
+// discarded. Connectors do not have to know anything about it: deduplication
+// happens when events are inserted.
+
+type TriggerDeduplication struct {
+	Key    ejson.Pointer `json:"key"`
+	Window int           `json:"window"` // seconds
+}
+
+func (d *TriggerDeduplication) ValidateJSON(v *ejson.Validator) {
+	v.CheckIntMin("window", d.Window, 1)
+}
+
+func (d *TriggerDeduplication) WindowDuration() time.Duration {
+	return time.Duration(d.Window) * time.Second
+}
+
+// EventKey returns the deduplication key of an event, or false if event data
+// do not contain any value at the path of the key.
+func (d *TriggerDeduplication) EventKey(e *Event) (string, bool, error) {
+	value := e.DataValue
+
+	if value == nil {
+		data, err := json.Marshal(e.Data)
+		if err != nil {
+			return "", false, fmt.Errorf("cannot encode event data: %w", err)
+		}
+
+		if err := json.Unmarshal(data, &value); err != nil {
+			return "", false, fmt.Errorf("cannot decode event data: %w", err)
+		}
+	}
+
+	keyValue := d.Key.Find(value)
+	if keyValue == nil {
+		return "", false, nil
+	}
+
+	if s, ok := keyValue.(string); ok {
+		return s, true, nil
+	}
+
+	key, err := json.Marshal(keyValue)
+	if err != nil {
+		return "", false, fmt.Errorf("cannot encode key: %w", err)
+	}
+
+	return string(key), true, nil
+}
+
+// DeduplicateEvents returns the events which are not duplicates of an event
+// inserted during the deduplication window of their job. Replayed events are
+// never considered as duplicates.
+func DeduplicateEvents(conn pg.Conn, es Events, now time.Time) (Events, error) {
+	var jobIds Ids
+	for _, e := range es {
+		if e.OriginalEventId == nil {
+			jobIds = append(jobIds, e.JobId)
+		}
+	}
+
+	if len(jobIds) == 0 {
+		return es, nil
+	}
+
+	settings, err := LoadTriggerDeduplications(conn, jobIds)
+	if err != nil {
+		return nil, fmt.Errorf("cannot load deduplication settings: %w", err)
+	} else if len(settings) == 0 {
+		return es, nil
+	}
+
+	if err := DeleteExpiredEventDeduplicationKeys(conn, now); err != nil {
+		return nil, fmt.Errorf("cannot delete expired deduplication keys: %w",
+			err)
+	}
+
+	uniqueEvents := make(Events, 0, len(es))
+
+	for _, e := range es {
+		d := settings[e.JobId]
+		if d == nil || e.OriginalEventId != nil {
+			uniqueEvents = append(uniqueEvents, e)
+			continue
+		}
+
+		key, found, err := d.EventKey(e)
+		if err != nil {
+			return nil, err
+		} else if !found {
+			uniqueEvents = append(uniqueEvents, e)
+			continue
+		}
+
+		expirationTime := now.Add(d.WindowDuration())
+
+		isNew, err := RegisterEventDeduplicationKey(conn, e.JobId, key,
+			expirationTime, now)
+		if err != nil {
+			return nil, fmt.Errorf("cannot register deduplication key: %w",
+				err)
+		}
+
+		if isNew {
+			uniqueEvents = append(uniqueEvents, e)
+		}
+	}
+
+	return uniqueEvents, nil
+}
+
+func LoadTriggerDeduplications(conn pg.Conn, jobIds Ids) (map[Id]*TriggerDeduplication, error) {
+	ctx := context.Background()
+
+	query := `
+SELECT id, spec->'trigger'->'deduplication'
+  FROM jobs
+  WHERE id = ANY ($1)
+    AND spec->'trigger'->'deduplication' IS NOT NULL
+`
+	rows, err := conn.Query(ctx, query, jobIds)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	settings := make(map[Id]*TriggerDeduplication)
+
+	for rows.Next() {
+		var id Id
+		var data []byte
+
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+
+		var d TriggerDeduplication
+		if err := json.Unmarshal(data, &d); err != nil {
+			return nil, fmt.Errorf("cannot decode deduplication settings "+
+				"of job %q: %w", id, err)
+		}
+
+		settings[id] = &d
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return settings, nil
+}
+
+// RegisterEventDeduplicationKey records a deduplication key for a job and
+// returns true, or returns false if the key was already recorded and has not
+// expired yet.
+func RegisterEventDeduplicationKey(conn pg.Conn, jobId Id, key string, expirationTime, now time.Time) (bool, error) {
+	ctx := context.Background()
+
+	query := `
+INSERT INTO event_deduplication_keys
+    (job_id, key, expiration_time)
+  VALUES
+    ($1, $2, $3)
+  ON CONFLICT (job_id, key) DO UPDATE
+    SET expiration_time = EXCLUDED.expiration_time
+    WHERE event_deduplication_keys.expiration_time <= $4
+  RETURNING job_id
+`
+	var id Id
+	err := conn.QueryRow(ctx, query, jobId, key, expirationTime,
+		now).Scan(&id)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return false, nil
+	} else if err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+func DeleteExpiredEventDeduplicationKeys(conn pg.Conn, now time.Time) error {
+	query := `
+DELETE FROM event_deduplication_keys
+  WHERE expiration_time <= $1
+`
+	return pg.Exec(conn, query, now)
+}
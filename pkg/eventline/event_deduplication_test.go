@@ -0,0 +1,37 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"go.n16f.net/ejson"
+)
+
+func TestTriggerDeduplicationEventKey(t *testing.T) {
+	assert := assert.New(t)
+
+	type eventData struct {
+		Revision string `json:"revision"`
+		Build    int    `json:"build"`
+	}
+
+	event := Event{Data: &eventData{Revision: "abc", Build: 42}}
+
+	d := TriggerDeduplication{Key: ejson.NewPointer("revision")}
+	key, found, err := d.EventKey(&event)
+	if assert.NoError(err) && assert.True(found) {
+		assert.Equal("abc", key)
+	}
+
+	d = TriggerDeduplication{Key: ejson.NewPointer("build")}
+	key, found, err = d.EventKey(&event)
+	if assert.NoError(err) && assert.True(found) {
+		assert.Equal("42", key)
+	}
+
+	d = TriggerDeduplication{Key: ejson.NewPointer("missing")}
+	_, found, err = d.EventKey(&event)
+	if assert.NoError(err) {
+		assert.False(found)
+	}
+}
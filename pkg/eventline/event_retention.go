@@ -0,0 +1,39 @@
+package eventline
+
+import (
+	"context"
+	"time"
+
+	"go.n16f.net/service/pkg/pg"
+)
+
+// DeleteOldEvents deletes at most batchSize processed events older than the
+// retention period of their project, or the global retention period if the
+// project does not define one. A retention of 0 means that events are kept
+// forever. Events referenced by a job execution are kept until the job
+// execution is deleted.
+func DeleteOldEvents(conn pg.Conn, retention int, now time.Time, batchSize int) (int64, error) {
+	ctx := context.Background()
+
+	query := `
+WITH old_events AS
+  (SELECT e.id
+     FROM events AS e
+     JOIN project_settings AS ps ON ps.id = e.project_id
+     WHERE e.processed
+       AND COALESCE(NULLIF(ps.event_retention, 0), $1) > 0
+       AND e.creation_time < $2 - make_interval(
+             days => COALESCE(NULLIF(ps.event_retention, 0), $1))
+       AND NOT EXISTS
+             (SELECT 1 FROM job_executions AS je WHERE je.event_id = e.id)
+     LIMIT $3)
+DELETE FROM events
+  WHERE id IN (SELECT id FROM old_events)
+`
+	res, err := conn.Exec(ctx, query, retention, now, batchSize)
+	if err != nil {
+		return -1, err
+	}
+
+	return res.RowsAffected(), nil
+}
@@ -36,6 +36,10 @@ func (ctx *ExecutionContext) Load(conn pg.Conn, je *JobExecution) error {
 		return fmt.Errorf("cannot load identities: %w", err)
 	}
 
+	if err := identities.ResolveData(); err != nil {
+		return err
+	}
+
 	now := time.Now().UTC()
 
 	ctx.Identities = make(map[string]*Identity)
@@ -43,6 +43,16 @@ func (s *FileSet) AddPrefix(prefix string) {
 	s.Files = files
 }
 
+// Size returns the total size of the content of all files in bytes.
+func (s *FileSet) Size() int64 {
+	var size int64
+	for _, file := range s.Files {
+		size += int64(len(file.Content))
+	}
+
+	return size
+}
+
 func (s *FileSet) Write(rootPath string) error {
 	if err := os.RemoveAll(rootPath); err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
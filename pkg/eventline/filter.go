@@ -2,7 +2,9 @@ package eventline
 
 import (
 	"encoding/json"
+	"path"
 	"regexp"
+	"strings"
 
 	"go.n16f.net/ejson"
 )
@@ -15,6 +17,10 @@ type Filter struct {
 	MatchesRE      *regexp.Regexp `json:"-"`
 	DoesNotMatch   string         `json:"does_not_match,omitempty"`
 	DoesNotMatchRE *regexp.Regexp `json:"-"`
+	Contains       interface{}    `json:"contains,omitempty"`
+	DoesNotContain interface{}    `json:"does_not_contain,omitempty"`
+	MatchesGlob    string         `json:"matches_glob,omitempty"`
+	AnyOf          Filters        `json:"any_of,omitempty"`
 }
 
 type Filters []*Filter
@@ -48,6 +54,14 @@ func (f *Filter) ValidateJSON(v *ejson.Validator) {
 		v.Check("does_not_match", err == nil,
 			"invalid_regexp", "invalid regexp: %v", err)
 	}
+
+	if f.MatchesGlob != "" {
+		_, err = path.Match(f.MatchesGlob, "")
+		v.Check("matches_glob", err == nil,
+			"invalid_glob_pattern", "invalid glob pattern")
+	}
+
+	v.CheckObjectArray("any_of", f.AnyOf)
 }
 
 func (f *Filter) Match(obj interface{}) bool {
@@ -78,6 +92,22 @@ func (f *Filter) Match(obj interface{}) bool {
 		}
 	}
 
+	if v2 := f.Contains; v2 != nil && !valueContains(v, v2) {
+		return false
+	}
+
+	if v2 := f.DoesNotContain; v2 != nil && valueContains(v, v2) {
+		return false
+	}
+
+	if f.MatchesGlob != "" && !valueMatchesGlob(v, f.MatchesGlob) {
+		return false
+	}
+
+	if len(f.AnyOf) > 0 && !f.AnyOf.MatchAny(obj) {
+		return false
+	}
+
 	return true
 }
 
@@ -90,3 +120,55 @@ func (fs Filters) Match(obj interface{}) bool {
 
 	return true
 }
+
+func (fs Filters) MatchAny(obj interface{}) bool {
+	for _, f := range fs {
+		if f.Match(obj) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// valueContains indicates whether a string contains a substring or whether
+// an array contains an element equal to a value. Other values never contain
+// anything.
+func valueContains(v, v2 interface{}) bool {
+	switch vv := v.(type) {
+	case string:
+		s, ok := v2.(string)
+		return ok && strings.Contains(vv, s)
+
+	case []interface{}:
+		for _, elt := range vv {
+			if ejson.Equal(elt, v2) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// valueMatchesGlob indicates whether a string matches a glob pattern or
+// whether an array contains at least one string matching it. Patterns are
+// validated before filters are used, so matching cannot fail.
+func valueMatchesGlob(v interface{}, pattern string) bool {
+	switch vv := v.(type) {
+	case string:
+		match, _ := path.Match(pattern, vv)
+		return match
+
+	case []interface{}:
+		for _, elt := range vv {
+			if s, ok := elt.(string); ok {
+				if match, _ := path.Match(pattern, s); match {
+					return true
+				}
+			}
+		}
+	}
+
+	return false
+}
@@ -6,6 +6,7 @@ import (
 	"testing"
 
 	"go.n16f.net/ejson"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
 
@@ -36,3 +37,45 @@ func TestFilterSerialization(t *testing.T) {
 
 	require.Equal(*f1, f2)
 }
+
+func TestFilterMatch(t *testing.T) {
+	assert := assert.New(t)
+
+	var obj interface{}
+	data := `{
+  "branch": "main",
+  "message": "Fix the build [deploy]",
+  "modified": ["doc/README.md", "src/main.go"]
+}`
+	require.NoError(t, json.Unmarshal([]byte(data), &obj))
+
+	tests := []struct {
+		filter string
+		match  bool
+	}{
+		{`{"path": "/message", "contains": "[deploy]"}`, true},
+		{`{"path": "/message", "contains": "[skip]"}`, false},
+		{`{"path": "/message", "does_not_contain": "[skip]"}`, true},
+		{`{"path": "/modified", "contains": "src/main.go"}`, true},
+		{`{"path": "/modified", "contains": "src/foo.go"}`, false},
+		{`{"path": "/modified", "matches_glob": "src/*.go"}`, true},
+		{`{"path": "/modified", "matches_glob": "test/*"}`, false},
+		{`{"path": "/branch", "matches_glob": "ma*"}`, true},
+		{`{"path": "/unknown", "matches_glob": "*"}`, false},
+		{`{"any_of": [{"path": "/message", "contains": "[skip]"},
+                      {"path": "/modified", "matches_glob": "src/*"}]}`, true},
+		{`{"any_of": [{"path": "/message", "contains": "[skip]"},
+                      {"path": "/branch", "is_equal_to": "dev"}]}`, false},
+	}
+
+	for _, test := range tests {
+		var f Filter
+		require.NoError(t, json.Unmarshal([]byte(test.filter), &f))
+
+		v := ejson.NewValidator()
+		f.ValidateJSON(v)
+		require.NoError(t, v.Error())
+
+		assert.Equal(test.match, f.Match(obj), test.filter)
+	}
+}
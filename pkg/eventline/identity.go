@@ -5,6 +5,7 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/jackc/pgx/v5"
@@ -38,6 +39,24 @@ func (err UnknownIdentityNameError) Error() string {
 	return fmt.Sprintf("unknown identity %q", err.Name)
 }
 
+type DisabledIdentityError struct {
+	Name string
+}
+
+func (err DisabledIdentityError) Error() string {
+	return fmt.Sprintf("identity %q is disabled", err.Name)
+}
+
+type MissingIdentityScopeError struct {
+	Identity string
+	Scopes   []string // any of them is sufficient
+}
+
+func (err MissingIdentityScopeError) Error() string {
+	return fmt.Sprintf("identity %q lacks required scope %s",
+		err.Identity, strings.Join(err.Scopes, " or "))
+}
+
 type IdentityStatus string
 
 const (
@@ -62,6 +81,7 @@ type Identity struct {
 	Name         string          `json:"name"`
 	Status       IdentityStatus  `json:"status"`
 	ErrorMessage string          `json:"error_message,omitempty"`
+	Disabled     bool            `json:"disabled,omitempty"`
 	CreationTime time.Time       `json:"creation_time"`
 	UpdateTime   time.Time       `json:"update_time"`
 	LastUseTime  *time.Time      `json:"last_use_time,omitempty"`
@@ -70,6 +90,7 @@ type Identity struct {
 	Type         string          `json:"type"`
 	Data         IdentityData    `json:"-"`
 	RawData      json.RawMessage `json:"data"`
+	SecretRef    *string         `json:"secret_ref,omitempty"`
 }
 
 type Identities []*Identity
@@ -89,6 +110,28 @@ func (ni *NewIdentity) ValidateJSON(v *ejson.Validator) {
 	})
 }
 
+// IdentityKeyRotation makes a new key the primary key of an identity, the
+// current primary key becoming the secondary key.
+type IdentityKeyRotation struct {
+	Key string `json:"key"`
+}
+
+func (r *IdentityKeyRotation) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("key", r.Key)
+}
+
+var IdentityKeyValues = []string{"primary", "secondary"}
+
+// IdentityKeyRetirement removes either the primary or the secondary key of
+// an identity.
+type IdentityKeyRetirement struct {
+	Key string `json:"key"`
+}
+
+func (r *IdentityKeyRetirement) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringValue("key", r.Key, IdentityKeyValues)
+}
+
 func (i *Identity) SortKey(sort string) (key string) {
 	switch sort {
 	case "id":
@@ -102,6 +145,18 @@ func (i *Identity) SortKey(sort string) (key string) {
 	return
 }
 
+// DataDef returns the definition of identity data. If data have not been
+// resolved, the generic definition of the identity type is returned.
+func (i *Identity) DataDef() *IdentityDataDef {
+	if i.Data != nil {
+		return i.Data.Def()
+	}
+
+	cdef := GetConnectorDef(i.Connector)
+	idef := cdef.Identity(i.Type)
+	return idef.DataDef
+}
+
 func (i *Identity) Refreshable() bool {
 	cdef := GetConnectorDef(i.Connector)
 	idef := cdef.Identity(i.Type)
@@ -188,12 +243,16 @@ func (pi *Identity) UnmarshalJSON(data []byte) error {
 	}
 	idef := cdef.Identity(i.Type)
 
-	idata, err := idef.DecodeData(i.RawData)
-	if err != nil {
-		return fmt.Errorf("cannot decode data: %w", err)
-	}
+	// Data are null for identities stored in a secret backend whose data
+	// have not been resolved.
+	if len(i.RawData) > 0 && string(i.RawData) != "null" {
+		idata, err := idef.DecodeData(i.RawData)
+		if err != nil {
+			return fmt.Errorf("cannot decode data: %w", err)
+		}
 
-	i.Data = idata
+		i.Data = idata
+	}
 
 	*pi = Identity(i)
 	return nil
@@ -281,9 +340,9 @@ SELECT 1
 
 func (i *Identity) Load(conn pg.Conn, id Id, scope Scope) error {
 	query := fmt.Sprintf(`
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE %s AND id = $1
 `, scope.SQLCondition())
@@ -298,9 +357,9 @@ SELECT id, project_id, name, status, error_message,
 
 func (i *Identity) LoadForUpdate(conn pg.Conn, id Id, scope Scope) error {
 	query := fmt.Sprintf(`
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE %s AND id = $1
   FOR UPDATE
@@ -316,9 +375,9 @@ SELECT id, project_id, name, status, error_message,
 
 func (i *Identity) LoadByName(conn pg.Conn, name string, scope Scope) error {
 	query := fmt.Sprintf(`
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE %s AND name = $1
 `, scope.SQLCondition())
@@ -333,9 +392,9 @@ SELECT id, project_id, name, status, error_message,
 
 func (is *Identities) LoadByNames(conn pg.Conn, names []string, scope Scope) error {
 	query := fmt.Sprintf(`
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE %s AND name = ANY ($1);
 `, scope.SQLCondition())
@@ -345,9 +404,9 @@ SELECT id, project_id, name, status, error_message,
 
 func (is *Identities) LoadByNamesForUpdate(conn pg.Conn, names []string, scope Scope) error {
 	query := fmt.Sprintf(`
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE %s AND name = ANY ($1)
   FOR UPDATE;
@@ -358,9 +417,9 @@ SELECT id, project_id, name, status, error_message,
 
 func (is *Identities) LoadAllForUpdate(conn pg.Conn, scope Scope) error {
 	query := fmt.Sprintf(`
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE %s
   FOR UPDATE
@@ -390,11 +449,12 @@ func LoadIdentityForRefresh(conn pg.Conn) (*Identity, error) {
 	now := time.Now().UTC()
 
 	query := `
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE refresh_time < $1
+    AND disabled = FALSE
   ORDER BY refresh_time
   LIMIT 1
   FOR UPDATE SKIP LOCKED
@@ -411,11 +471,42 @@ SELECT id, project_id, name, status, error_message,
 	return &i, nil
 }
 
+// LoadIdentityForDataMigration returns an identity whose data must be moved
+// to the secret backend if toBackend is true, or back to the database if it is
+// false.
+func LoadIdentityForDataMigration(conn pg.Conn, toBackend bool) (*Identity, error) {
+	condition := "secret_ref IS NOT NULL"
+	if toBackend {
+		condition = "secret_ref IS NULL"
+	}
+
+	query := fmt.Sprintf(`
+SELECT id, project_id, name, status, error_message, disabled,
+       creation_time, update_time, last_use_time, refresh_time,
+       connector, type, data, secret_ref
+  FROM identities
+  WHERE %s
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+`, condition)
+
+	var i Identity
+
+	err := pg.QueryObject(conn, &i, query)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &i, nil
+}
+
 func LoadIdentityPage(conn pg.Conn, cursor *Cursor, scope Scope) (*Page, error) {
 	query := fmt.Sprintf(`
-SELECT id, project_id, name, status, error_message,
+SELECT id, project_id, name, status, error_message, disabled,
        creation_time, update_time, last_use_time, refresh_time,
-       connector, type, data
+       connector, type, data, secret_ref
   FROM identities
   WHERE %s AND %s
 `, scope.SQLCondition(), cursor.SQLConditionOrderLimit(IdentitySorts))
@@ -431,23 +522,23 @@ SELECT id, project_id, name, status, error_message,
 func (i *Identity) Insert(conn pg.Conn) error {
 	query := `
 INSERT INTO identities
-    (id, project_id, name, status, error_message,
+    (id, project_id, name, status, error_message, disabled,
      creation_time, update_time, last_use_time, refresh_time,
-     connector, type, data)
+     connector, type, data, secret_ref)
   VALUES
-    ($1, $2, $3, $4, $5,
-     $6, $7, $8, $9,
-     $10, $11, $12);
+    ($1, $2, $3, $4, $5, $6,
+     $7, $8, $9, $10,
+     $11, $12, $13, $14);
 `
-	encryptedData, err := i.encodeAndEncryptData()
+	encryptedData, err := i.storeData()
 	if err != nil {
 		return err
 	}
 
 	return pg.Exec(conn, query,
-		i.Id, i.ProjectId, i.Name, i.Status, i.ErrorMessage,
+		i.Id, i.ProjectId, i.Name, i.Status, i.ErrorMessage, i.Disabled,
 		i.CreationTime, i.UpdateTime, i.LastUseTime, i.RefreshTime,
-		i.Connector, i.Type, encryptedData)
+		i.Connector, i.Type, encryptedData, i.SecretRef)
 }
 
 func (i *Identity) Update(conn pg.Conn) error {
@@ -456,23 +547,26 @@ UPDATE identities SET
     name = $2,
     status = $3,
     error_message = $4,
-    update_time = $5,
-    last_use_time = $6,
-    refresh_time = $7,
-    connector = $8,
-    type = $9,
-    data = $10
+    disabled = $5,
+    update_time = $6,
+    last_use_time = $7,
+    refresh_time = $8,
+    connector = $9,
+    type = $10,
+    data = $11,
+    secret_ref = $12
   WHERE id = $1
 `
 
-	encryptedData, err := i.encodeAndEncryptData()
+	encryptedData, err := i.storeData()
 	if err != nil {
 		return err
 	}
 
 	return pg.Exec(conn, query,
-		i.Id, i.Name, i.Status, i.ErrorMessage, i.UpdateTime, i.LastUseTime,
-		i.RefreshTime, i.Connector, i.Type, encryptedData)
+		i.Id, i.Name, i.Status, i.ErrorMessage, i.Disabled, i.UpdateTime,
+		i.LastUseTime, i.RefreshTime, i.Connector, i.Type, encryptedData,
+		i.SecretRef)
 }
 
 func (i *Identity) UpdateLastUseTime(conn pg.Conn) error {
@@ -501,21 +595,118 @@ func (i *Identity) Delete(conn pg.Conn) error {
 DELETE FROM identities
   WHERE id = $1
 `
-	return pg.Exec(conn, query, i.Id)
+	if err := pg.Exec(conn, query, i.Id); err != nil {
+		return err
+	}
+
+	if i.SecretRef != nil {
+		if err := InsertDeletedIdentitySecret(conn, *i.SecretRef); err != nil {
+			return fmt.Errorf("cannot record deleted secret: %w", err)
+		}
+	}
+
+	return nil
 }
 
-func (i *Identity) encodeAndEncryptData() ([]byte, error) {
+// storeData returns the encrypted data to store in the database, or nil if
+// data are stored in the secret backend, in which case the secret reference
+// of the identity is set. Identities already stored in the backend stay there
+// until they are explicitly moved back to the database.
+func (i *Identity) storeData() ([]byte, error) {
+	if i.Data == nil {
+		// Data were not resolved and therefore have not changed
+		return nil, nil
+	}
+
 	decryptedData, err := json.Marshal(i.Data)
 	if err != nil {
 		return nil, fmt.Errorf("cannot encode data: %w", err)
 	}
 
-	encryptedData, err := EncryptAES256(decryptedData)
+	if i.SecretRef == nil && !UseGlobalSecretBackend {
+		encryptedData, err := EncryptAES256(decryptedData)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encrypt data: %w", err)
+		}
+
+		return encryptedData, nil
+	}
+
+	backend := GlobalSecretBackend
+	if backend == nil {
+		return nil, ErrMissingSecretBackend
+	}
+
+	ctx := context.Background()
+
+	ref, err := backend.WriteIdentitySecret(ctx, i.Id, decryptedData)
+	if err != nil {
+		return nil, fmt.Errorf("cannot write secret: %w", err)
+	}
+
+	i.SecretRef = &ref
+
+	return nil, nil
+}
+
+// DataResolved returns true if identity data are available, i.e. if they are
+// stored in the database or if they have been read from the secret backend
+// with ResolveData.
+func (i *Identity) DataResolved() bool {
+	return i.Data != nil
+}
+
+// ResolveData reads identity data from the secret backend if they are stored
+// there. It must be called before using the data of an identity loaded from
+// the database.
+func (i *Identity) ResolveData() error {
+	if i.Data != nil || i.SecretRef == nil {
+		return nil
+	}
+
+	backend := GlobalSecretBackend
+	if backend == nil {
+		return fmt.Errorf("cannot read data of identity %q: %w", i.Id,
+			ErrMissingSecretBackend)
+	}
+
+	ctx := context.Background()
+
+	data, err := backend.ReadSecret(ctx, *i.SecretRef)
+	if err != nil {
+		return fmt.Errorf("cannot read data of identity %q: %w", i.Id, err)
+	}
+
+	if err := i.decodeData(data); err != nil {
+		return fmt.Errorf("cannot decode data of identity %q: %w", i.Id, err)
+	}
+
+	return nil
+}
+
+func (is Identities) ResolveData() error {
+	for _, i := range is {
+		if err := i.ResolveData(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (i *Identity) decodeData(data []byte) error {
+	cdef := GetConnectorDef(i.Connector)
+	idef := cdef.Identity(i.Type)
+
+	idata, err := idef.DecodeData(data)
 	if err != nil {
-		return nil, fmt.Errorf("cannot encrypt data: %w", err)
+		return err
 	}
 
-	return encryptedData, nil
+	i.RawData = data
+	i.Data = idata
+
+	return nil
 }
 
 func (is Identities) Page(cursor *Cursor) *Page {
@@ -532,8 +723,8 @@ func (i *Identity) FromRow(row pgx.Row) error {
 	var encryptedData []byte
 
 	err := row.Scan(&i.Id, &projectId, &i.Name, &i.Status, &i.ErrorMessage,
-		&i.CreationTime, &i.UpdateTime, &i.LastUseTime, &i.RefreshTime,
-		&i.Connector, &i.Type, &encryptedData)
+		&i.Disabled, &i.CreationTime, &i.UpdateTime, &i.LastUseTime,
+		&i.RefreshTime, &i.Connector, &i.Type, &encryptedData, &i.SecretRef)
 	if err != nil {
 		return err
 	}
@@ -542,21 +733,20 @@ func (i *Identity) FromRow(row pgx.Row) error {
 		i.ProjectId = &projectId
 	}
 
-	i.RawData, err = DecryptAES256(encryptedData)
+	// Data stored in the secret backend are only read by ResolveData
+	if i.SecretRef != nil {
+		return nil
+	}
+
+	data, err := DecryptAES256(encryptedData)
 	if err != nil {
 		return fmt.Errorf("cannot decrypt data of identity %q: %w", i.Id, err)
 	}
 
-	cdef := GetConnectorDef(i.Connector)
-	idef := cdef.Identity(i.Type)
-
-	idata, err := idef.DecodeData(i.RawData)
-	if err != nil {
+	if err := i.decodeData(data); err != nil {
 		return fmt.Errorf("cannot decode data of identity %q: %w", i.Id, err)
 	}
 
-	i.Data = idata
-
 	return nil
 }
 
@@ -569,3 +759,26 @@ func (is *Identities) AddFromRow(row pgx.Row) error {
 	*is = append(*is, &i)
 	return nil
 }
+
+// CheckScopes makes sure that at least one of the scopes listed was granted
+// to an identity. A nil list of granted scopes is accepted: the scopes are
+// unknown and the provider will report the error if a scope is actually
+// missing.
+func CheckScopes(identityName string, grantedScopes []string, scopes ...string) error {
+	if grantedScopes == nil {
+		return nil
+	}
+
+	for _, scope := range scopes {
+		for _, grantedScope := range grantedScopes {
+			if grantedScope == scope {
+				return nil
+			}
+		}
+	}
+
+	return MissingIdentityScopeError{
+		Identity: identityName,
+		Scopes:   scopes,
+	}
+}
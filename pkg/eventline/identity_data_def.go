@@ -38,3 +38,13 @@ func NewIdentityDataDef() *IdentityDataDef {
 func (v *IdentityDataDef) AddEntry(e *IdentityDataEntry) {
 	v.Entries = append(v.Entries, e)
 }
+
+func (v *IdentityDataDef) Entry(key string) *IdentityDataEntry {
+	for _, e := range v.Entries {
+		if e.Key == key {
+			return e
+		}
+	}
+
+	return nil
+}
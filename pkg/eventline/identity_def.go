@@ -2,6 +2,7 @@ package eventline
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"reflect"
@@ -10,6 +11,8 @@ import (
 	"go.n16f.net/ejson"
 )
 
+var ErrMissingSecondaryKey = errors.New("missing secondary key")
+
 type UnknownIdentityDefError struct {
 	Connector string
 	Type      string
@@ -37,6 +40,15 @@ type IdentityData interface {
 	Environment() map[string]string
 }
 
+// ScopedIdentityData is implemented by identities whose capabilities are
+// described by a list of scopes. A nil list means that the scopes are not
+// known.
+type ScopedIdentityData interface {
+	IdentityData
+
+	GrantedScopes() []string
+}
+
 type OAuth2IdentityData interface {
 	IdentityData
 
@@ -44,13 +56,40 @@ type OAuth2IdentityData interface {
 	FetchTokenData(*http.Client, string, string) error
 }
 
-type RefreshableOAuth2IdentityData interface {
-	OAuth2IdentityData
+// RefreshableIdentityData is implemented by identities whose data are
+// periodically renewed by the identity refresher, typically access tokens.
+type RefreshableIdentityData interface {
+	IdentityData
 
 	Refresh(*http.Client) error
 	RefreshTime() time.Time
 }
 
+// ExpirableIdentityData is implemented by refreshable identities whose data
+// stop being usable after a known point in time.
+type ExpirableIdentityData interface {
+	RefreshableIdentityData
+
+	ExpiresBefore(time.Time) bool
+}
+
+// KeyRotatableIdentityData is implemented by identities containing a primary
+// key and an optional secondary key, so that keys can be rotated without
+// downtime. RetirePrimaryKey returns ErrMissingSecondaryKey if there is no
+// secondary key to replace the primary key.
+type KeyRotatableIdentityData interface {
+	IdentityData
+
+	RotateKey(string)
+	RetireSecondaryKey()
+	RetirePrimaryKey() error
+}
+
+type RefreshableOAuth2IdentityData interface {
+	OAuth2IdentityData
+	RefreshableIdentityData
+}
+
 func NewIdentityDef(typeName string, dataValue IdentityData) *IdentityDef {
 	return &IdentityDef{
 		Type: typeName,
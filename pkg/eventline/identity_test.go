@@ -0,0 +1,25 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCheckScopes(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.NoError(CheckScopes("i", nil, "a"))
+	assert.NoError(CheckScopes("i", []string{"a"}, "a"))
+	assert.NoError(CheckScopes("i", []string{"a", "b"}, "c", "b"))
+
+	err := CheckScopes("i", []string{}, "a")
+	if assert.Error(err) {
+		assert.Equal(`identity "i" lacks required scope a`, err.Error())
+	}
+
+	err = CheckScopes("i", []string{"a"}, "b", "c")
+	if assert.Error(err) {
+		assert.Equal(`identity "i" lacks required scope b or c`, err.Error())
+	}
+}
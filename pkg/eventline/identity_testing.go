@@ -0,0 +1,16 @@
+package eventline
+
+type IdentityTestResult struct {
+	OK      bool     `json:"ok"`
+	Latency int      `json:"latency"` // milliseconds
+	Message string   `json:"message,omitempty"`
+	Account string   `json:"account,omitempty"`
+	Scopes  []string `json:"scopes,omitempty"`
+}
+
+func NewIdentityTestFailure(err error) *IdentityTestResult {
+	return &IdentityTestResult{
+		OK:      false,
+		Message: err.Error(),
+	}
+}
@@ -63,6 +63,23 @@ var StepFailureActionValues = []StepFailureAction{
 	StepFailureActionContinue,
 }
 
+type StepRetryBackoff string
+
+const (
+	StepRetryBackoffConstant    StepRetryBackoff = "constant"
+	StepRetryBackoffExponential StepRetryBackoff = "exponential"
+)
+
+var StepRetryBackoffValues = []StepRetryBackoff{
+	StepRetryBackoffConstant,
+	StepRetryBackoffExponential,
+}
+
+const (
+	DefaultStepRetryDelay = 10 * time.Second
+	MaxStepRetryDelay     = 10 * time.Minute
+)
+
 type Job struct {
 	Id           Id        `json:"id"`
 	ProjectId    Id        `json:"project_id"`
@@ -78,8 +95,9 @@ type JobSpec struct {
 	Name        string `json:"name"`
 	Description string `json:"description"`
 
-	Trigger    *Trigger   `json:"trigger,omitempty"`
-	Parameters Parameters `json:"parameters,omitempty"`
+	Trigger    *Trigger         `json:"trigger,omitempty"`
+	Schedule   *JobScheduleSpec `json:"schedule,omitempty"`
+	Parameters Parameters       `json:"parameters,omitempty"`
 
 	Runner     *JobRunner `json:"runner"`
 	Concurrent bool       `json:"concurrent,omitempty"`
@@ -89,6 +107,8 @@ type JobSpec struct {
 	Identities  []string          `json:"identities,omitempty"`
 	Environment map[string]string `json:"environment,omitempty"`
 	Steps       Steps             `json:"steps"`
+
+	OutputEncoding string `json:"output_encoding,omitempty"`
 }
 
 type JobSpecs []*JobSpec
@@ -110,6 +130,8 @@ type Trigger struct {
 	RawParameters json.RawMessage        `json:"parameters,omitempty"`
 	Identity      string                 `json:"identity,omitempty"`
 	Filters       Filters                `json:"filters,omitempty"`
+	TTL           int                    `json:"ttl,omitempty"` // seconds
+	Deduplication *TriggerDeduplication  `json:"deduplication,omitempty"`
 }
 
 type Step struct {
@@ -118,8 +140,14 @@ type Step struct {
 	Code    string       `json:"code,omitempty"`
 	Command *StepCommand `json:"command,omitempty"`
 	Script  *StepScript  `json:"script,omitempty"`
+	HTTP    *StepHTTP    `json:"http,omitempty"`
 
 	OnFailure StepFailureAction `json:"on_failure,omitempty"`
+	Timeout   int               `json:"timeout,omitempty"` // seconds
+
+	Retries      int              `json:"retries,omitempty"`
+	RetryDelay   int              `json:"retry_delay,omitempty"` // seconds
+	RetryBackoff StepRetryBackoff `json:"retry_backoff,omitempty"`
 }
 
 type Steps []*Step
@@ -163,6 +191,7 @@ func (spec JobSpec) ValidateJSON(v *ejson.Validator) {
 	}
 
 	v.CheckOptionalObject("trigger", spec.Trigger)
+	v.CheckOptionalObject("schedule", spec.Schedule)
 	v.CheckObjectArray("parameters", spec.Parameters)
 
 	v.CheckOptionalObject("runner", spec.Runner)
@@ -177,12 +206,18 @@ func (spec JobSpec) ValidateJSON(v *ejson.Validator) {
 		}
 	})
 
+	CheckEnvironment(v, "environment", spec.Environment)
+
 	v.CheckObjectArray("steps", spec.Steps)
 	for i, s := range spec.Steps {
 		if s.Label == "" {
 			s.Label = "Step " + strconv.Itoa(i+1)
 		}
 	}
+
+	if spec.OutputEncoding != "" {
+		CheckOutputEncoding(v, "output_encoding", spec.OutputEncoding)
+	}
 }
 
 func (r *JobRunner) ValidateJSON(v *ejson.Validator) {
@@ -254,6 +289,35 @@ func (t *Trigger) ValidateJSON(v *ejson.Validator) {
 	v.CheckOptionalObject("parameters", t.Parameters)
 
 	v.CheckObjectArray("filters", t.Filters)
+
+	if t.TTL != 0 {
+		v.CheckIntMin("ttl", t.TTL, 1)
+	}
+
+	v.CheckOptionalObject("deduplication", t.Deduplication)
+}
+
+// EventExpired indicates whether it is too late to act on an event. Events
+// do not expire if the trigger does not have a TTL.
+//
+// Replayed events keep the time of the original event, but replaying is an
+// explicit decision: their TTL starts when they are replayed.
+func (t *Trigger) EventExpired(event *Event, now time.Time) bool {
+	if t.TTL == 0 {
+		return false
+	}
+
+	refTime := event.EventTime
+	if event.OriginalEventId != nil {
+		refTime = event.CreationTime
+	}
+
+	return now.Sub(refTime) > time.Duration(t.TTL)*time.Second
+}
+
+func (t *Trigger) EventExpirationMessage() string {
+	return fmt.Sprintf("event expired: not processed within %s",
+		time.Duration(t.TTL)*time.Second)
 }
 
 func (pt *Trigger) MarshalJSON() ([]byte, error) {
@@ -313,17 +377,40 @@ func (s *Step) ValidateJSON(v *ejson.Validator) {
 	if s.Script != nil {
 		n += 1
 	}
+	if s.HTTP != nil {
+		n += 1
+	}
 
 	if n == 0 {
 		v.AddError(ejson.Pointer{}, "missing_step_content",
-			"missing code, command or script member")
+			"missing code, command, script or http member")
 	} else if n > 1 {
 		v.AddError(ejson.Pointer{}, "multiple_step_contents",
-			"multiple code, command or script members")
+			"multiple code, command, script or http members")
 	}
 
 	v.CheckOptionalObject("command", s.Command)
 	v.CheckOptionalObject("script", s.Script)
+	v.CheckOptionalObject("http", s.HTTP)
+
+	if s.OnFailure != "" {
+		v.CheckStringValue("on_failure", s.OnFailure, StepFailureActionValues)
+	}
+
+	if s.Timeout != 0 {
+		v.CheckIntMin("timeout", s.Timeout, 1)
+	}
+
+	v.CheckIntMin("retries", s.Retries, 0)
+
+	if s.RetryDelay != 0 {
+		v.CheckIntMin("retry_delay", s.RetryDelay, 1)
+	}
+
+	if s.RetryBackoff != "" {
+		v.CheckStringValue("retry_backoff", s.RetryBackoff,
+			StepRetryBackoffValues)
+	}
 }
 
 func (s *StepCommand) ValidateJSON(v *ejson.Validator) {
@@ -373,6 +460,13 @@ func (spec *JobSpec) IdentityNames() []string {
 	}
 
 	names = append(names, spec.Identities...)
+	names = append(names, EnvironmentReferenceIdentityNames(spec.Environment)...)
+
+	for _, step := range spec.Steps {
+		if step.HTTP != nil && step.HTTP.Identity != "" {
+			names = append(names, step.HTTP.Identity)
+		}
+	}
 
 	return names
 }
@@ -605,3 +699,24 @@ func (s *Step) AbortOnFailure() bool {
 		return true
 	}
 }
+
+// RetryDelayDuration returns the delay to wait before a new attempt to
+// execute the step after attempt n (starting at 1) failed.
+func (s *Step) RetryDelayDuration(n int) time.Duration {
+	delay := DefaultStepRetryDelay
+	if s.RetryDelay > 0 {
+		delay = time.Duration(s.RetryDelay) * time.Second
+	}
+
+	if s.RetryBackoff == StepRetryBackoffExponential {
+		for i := 1; i < n && delay < MaxStepRetryDelay; i++ {
+			delay *= 2
+		}
+	}
+
+	if delay > MaxStepRetryDelay {
+		delay = MaxStepRetryDelay
+	}
+
+	return delay
+}
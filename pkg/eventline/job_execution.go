@@ -99,6 +99,31 @@ type JobExecution struct {
 
 type JobExecutions []*JobExecution
 
+// JobExecutionResult is a job execution with a summary of the result of each
+// of its steps.
+type JobExecutionResult struct {
+	*JobExecution
+
+	Steps StepExecutionSummaries `json:"steps"`
+}
+
+func NewJobExecutionResult(je *JobExecution, ses StepExecutions) *JobExecutionResult {
+	steps := make(StepExecutionSummaries, len(ses))
+	for i, se := range ses {
+		var step *Step
+		if i < len(je.JobSpec.Steps) {
+			step = je.JobSpec.Steps[i]
+		}
+
+		steps[i] = se.Summary(step)
+	}
+
+	return &JobExecutionResult{
+		JobExecution: je,
+		Steps:        steps,
+	}
+}
+
 func (je *JobExecution) SortKey(sort string) (key string) {
 	switch sort {
 	case "id":
@@ -201,14 +226,27 @@ SELECT id, project_id, job_id, job_spec, event_id, parameters,
 	return &lastJe, nil
 }
 
-func LoadJobExecutionForScheduling(conn pg.Conn) (*JobExecution, error) {
+// LoadJobExecutionForScheduling returns the next job execution to start. If
+// maxPerProject is not zero, job executions of projects which already have
+// maxPerProject started job executions are ignored, so that they stay pending
+// without preventing the execution of jobs of other projects.
+//
+// Projects are served in turn: we select the oldest job execution of the
+// project whose last job execution was started the longest time ago.
+//
+// Job executions which could not be started are ignored until the time of
+// their next attempt.
+func LoadJobExecutionForScheduling(conn pg.Conn, maxPerProject int, now time.Time) (*JobExecution, error) {
 	query := `
 SELECT je1.id, je1.project_id, je1.job_id, je1.job_spec, je1.event_id,
        je1.parameters, je1.creation_time, je1.update_time, je1.scheduled_time,
        je1.status, je1.start_time, je1.end_time, je1.refresh_time,
        je1.expiration_time, je1.failure_message
   FROM job_executions AS je1
+    LEFT JOIN project_scheduling_times AS pst ON pst.id = je1.project_id
+    LEFT JOIN job_execution_start_failures AS jesf ON jesf.id = je1.id
   WHERE je1.status = 'created'
+    AND (jesf.next_attempt_time IS NULL OR jesf.next_attempt_time <= $2)
     AND (((je1.job_spec->'concurrent')::BOOLEAN IS TRUE)
          OR
          (NOT EXISTS
@@ -217,12 +255,18 @@ SELECT je1.id, je1.project_id, je1.job_id, je1.job_spec, je1.event_id,
               WHERE je2.job_id = je1.job_id
                 AND je2.id <> je1.id
                 AND je2.status = 'started')))
-  ORDER BY scheduled_time
+    AND ($1 = 0
+         OR
+         (SELECT COUNT(*)
+            FROM job_executions AS je3
+            WHERE je3.project_id = je1.project_id
+              AND je3.status = 'started') < $1)
+  ORDER BY pst.last_schedule_time ASC NULLS FIRST, je1.scheduled_time
   LIMIT 1
-  FOR UPDATE SKIP LOCKED;
+  FOR UPDATE OF je1 SKIP LOCKED;
 `
 	var je JobExecution
-	err := pg.QueryObject(conn, &je, query)
+	err := pg.QueryObject(conn, &je, query, maxPerProject, now)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return nil, nil
 	} else if err != nil {
@@ -342,6 +386,24 @@ SELECT COUNT(*)
 	return count, nil
 }
 
+func CountPendingJobExecutions(conn pg.Conn, scope Scope) (int64, error) {
+	ctx := context.Background()
+
+	query := fmt.Sprintf(`
+SELECT COUNT(*)
+  FROM job_executions
+  WHERE %s AND status = 'created';
+`, scope.SQLCondition())
+
+	var count int64
+	err := conn.QueryRow(ctx, query).Scan(&count)
+	if err != nil {
+		return -1, err
+	}
+
+	return count, nil
+}
+
 func (je *JobExecution) Insert(conn pg.Conn) error {
 	var parameters interface{}
 	if je.Parameters != nil {
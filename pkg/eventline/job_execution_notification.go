@@ -0,0 +1,87 @@
+package eventline
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"time"
+)
+
+// Webhook notifications are signed with the secret configured in the
+// notification settings of the project: the X-Eventline-Signature-256 header
+// field contains "sha256=" followed by the hex-encoded HMAC-SHA256 signature
+// of the request body, and the X-Eventline-Key-Id header field identifies the
+// secret. While the secret is being rotated, the request is also signed with
+// the previous secret, using the X-Eventline-Previous-* header fields.
+const (
+	NotificationSignatureHeader         = "X-Eventline-Signature-256"
+	NotificationKeyIdHeader             = "X-Eventline-Key-Id"
+	NotificationPreviousSignatureHeader = "X-Eventline-Previous-Signature-256"
+	NotificationPreviousKeyIdHeader     = "X-Eventline-Previous-Key-Id"
+)
+
+// JobExecutionNotification is the document sent to the notification webhook
+// of a project when a job execution finishes.
+type JobExecutionNotification struct {
+	Event           string                `json:"event"`
+	ProjectId       Id                    `json:"project_id"`
+	JobId           Id                    `json:"job_id"`
+	JobName         string                `json:"job_name"`
+	JobExecutionId  Id                    `json:"job_execution_id"`
+	JobExecutionURI string                `json:"job_execution_uri"`
+	Status          JobExecutionStatus    `json:"status"`
+	StartTime       *time.Time            `json:"start_time,omitempty"`
+	EndTime         *time.Time            `json:"end_time,omitempty"`
+	Duration        *float64              `json:"duration,omitempty"` // seconds
+	FailureMessage  string                `json:"failure_message,omitempty"`
+	FailedStep      *StepExecutionSummary `json:"failed_step,omitempty"`
+}
+
+func NewJobExecutionNotification(je *JobExecution, ses StepExecutions, jeURI string) *JobExecutionNotification {
+	n := JobExecutionNotification{
+		Event:           "job_execution_finished",
+		ProjectId:       je.ProjectId,
+		JobId:           je.JobId,
+		JobName:         je.JobSpec.Name,
+		JobExecutionId:  je.Id,
+		JobExecutionURI: jeURI,
+		Status:          je.Status,
+		StartTime:       je.StartTime,
+		EndTime:         je.EndTime,
+		FailureMessage:  je.FailureMessage,
+	}
+
+	if d := je.Duration(); d != nil {
+		seconds := d.Seconds()
+		n.Duration = &seconds
+	}
+
+	for i, se := range ses {
+		if se.Status != StepExecutionStatusFailed {
+			continue
+		}
+
+		var step *Step
+		if i < len(je.JobSpec.Steps) {
+			step = je.JobSpec.Steps[i]
+		}
+
+		n.FailedStep = se.Summary(step)
+		break
+	}
+
+	return &n
+}
+
+// NotificationKeyId returns the identifier of a webhook secret, so that
+// receivers can select the secret used to verify signatures.
+func NotificationKeyId(secret string) string {
+	hash := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(hash[:8])
+}
+
+func NotificationSignature(body []byte, secret string) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
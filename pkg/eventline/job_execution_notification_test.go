@@ -0,0 +1,60 @@
+package eventline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewJobExecutionNotification(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	startTime := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(90 * time.Second)
+
+	je := JobExecution{
+		Id:    GenerateId(),
+		JobId: GenerateId(),
+		JobSpec: &JobSpec{
+			Name: "deploy",
+			Steps: Steps{
+				&Step{Label: "build"},
+				&Step{Label: "upload"},
+			},
+		},
+		Status:         JobExecutionStatusFailed,
+		StartTime:      &startTime,
+		EndTime:        &endTime,
+		FailureMessage: "step 2 failed",
+	}
+
+	ses := StepExecutions{
+		&StepExecution{Position: 1, Status: StepExecutionStatusSuccessful},
+		&StepExecution{Position: 2, Status: StepExecutionStatusFailed,
+			FailureMessage: "exit code 1"},
+	}
+
+	n := NewJobExecutionNotification(&je, ses, "http://localhost")
+
+	assert.Equal("deploy", n.JobName)
+	assert.Equal(JobExecutionStatusFailed, n.Status)
+
+	require.NotNil(n.Duration)
+	assert.Equal(90.0, *n.Duration)
+
+	require.NotNil(n.FailedStep)
+	assert.Equal(2, n.FailedStep.Position)
+	assert.Equal("upload", n.FailedStep.Label)
+	assert.Equal("exit code 1", n.FailedStep.FailureMessage)
+}
+
+func TestNotificationSignature(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(
+		"sha256=88aab3ede8d3adf94d26ab90d3bafd4a2083070c3bcce9c014ee04a443847c0b",
+		NotificationSignature([]byte("hello"), "secret"))
+}
@@ -0,0 +1,78 @@
+package eventline
+
+import (
+	"math/rand/v2"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// When a job execution cannot be started, the scheduler waits before trying
+// again, doubling the delay after each failure so that a failing resource is
+// not overloaded. Other job executions are scheduled normally in the mean
+// time. Delays are randomized so that job executions which failed at the same
+// time are not retried at the same time.
+
+const (
+	MinJobExecutionStartRetryDelay = 10 * time.Second
+	MaxJobExecutionStartRetryDelay = 10 * time.Minute
+)
+
+type JobExecutionStartFailure struct {
+	Id              Id
+	NbFailures      int
+	NextAttemptTime time.Time
+}
+
+// RecordJobExecutionStartFailure increments the number of start failures of
+// a job execution and sets the time of the next attempt.
+func RecordJobExecutionStartFailure(conn pg.Conn, id Id, now time.Time) (*JobExecutionStartFailure, error) {
+	var f JobExecutionStartFailure
+
+	query := `
+INSERT INTO job_execution_start_failures AS jesf
+    (id, nb_failures, next_attempt_time)
+  VALUES
+    ($1, 1, $2)
+  ON CONFLICT (id) DO UPDATE SET
+    nb_failures = jesf.nb_failures + 1
+  RETURNING id, nb_failures, next_attempt_time;
+`
+	if err := pg.QueryObject(conn, &f, query, id, now); err != nil {
+		return nil, err
+	}
+
+	f.NextAttemptTime = now.Add(JobExecutionStartRetryDelay(f.NbFailures))
+
+	query = `
+UPDATE job_execution_start_failures SET
+    next_attempt_time = $2
+  WHERE id = $1;
+`
+	if err := pg.Exec(conn, query, f.Id, f.NextAttemptTime); err != nil {
+		return nil, err
+	}
+
+	return &f, nil
+}
+
+func (f *JobExecutionStartFailure) FromRow(row pgx.Row) error {
+	return row.Scan(&f.Id, &f.NbFailures, &f.NextAttemptTime)
+}
+
+// JobExecutionStartRetryDelay returns the delay to wait before trying to start
+// a job execution again after nbFailures consecutive failures.
+func JobExecutionStartRetryDelay(nbFailures int) time.Duration {
+	delay := MinJobExecutionStartRetryDelay
+	for i := 1; i < nbFailures && delay < MaxJobExecutionStartRetryDelay; i++ {
+		delay *= 2
+	}
+
+	if delay > MaxJobExecutionStartRetryDelay {
+		delay = MaxJobExecutionStartRetryDelay
+	}
+
+	// Random delay between 50% and 100% of the computed value
+	return delay/2 + time.Duration(rand.Int64N(int64(delay/2)+1))
+}
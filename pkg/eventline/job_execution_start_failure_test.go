@@ -0,0 +1,26 @@
+package eventline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobExecutionStartRetryDelay(t *testing.T) {
+	assert := assert.New(t)
+
+	checkDelay := func(nbFailures int, max time.Duration) {
+		for i := 0; i < 100; i++ {
+			delay := JobExecutionStartRetryDelay(nbFailures)
+			assert.GreaterOrEqual(delay, max/2, "failures: %d", nbFailures)
+			assert.LessOrEqual(delay, max, "failures: %d", nbFailures)
+		}
+	}
+
+	checkDelay(1, 10*time.Second)
+	checkDelay(2, 20*time.Second)
+	checkDelay(3, 40*time.Second)
+	checkDelay(7, MaxJobExecutionStartRetryDelay)
+	checkDelay(1000, MaxJobExecutionStartRetryDelay)
+}
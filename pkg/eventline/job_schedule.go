@@ -0,0 +1,212 @@
+package eventline
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type JobScheduleCatchUp string
+
+const (
+	// Ticks missed by more than JobScheduleGracePeriod are ignored.
+	JobScheduleCatchUpNone JobScheduleCatchUp = "none"
+
+	// A single execution is created for all missed ticks.
+	JobScheduleCatchUpOnce JobScheduleCatchUp = "once"
+
+	// An execution is created for each missed tick, up to
+	// MaxJobScheduleCatchUpExecutions.
+	JobScheduleCatchUpAll JobScheduleCatchUp = "all"
+)
+
+var JobScheduleCatchUpValues = []JobScheduleCatchUp{
+	JobScheduleCatchUpNone,
+	JobScheduleCatchUpOnce,
+	JobScheduleCatchUpAll,
+}
+
+const (
+	JobScheduleGracePeriod          = time.Minute
+	MaxJobScheduleCatchUpExecutions = 100
+)
+
+type JobScheduleSpec struct {
+	Cron     string             `json:"cron"`
+	Timezone string             `json:"timezone,omitempty"`
+	CatchUp  JobScheduleCatchUp `json:"catch_up,omitempty"`
+}
+
+type JobSchedule struct {
+	JobId     Id
+	ProjectId Id
+	Spec      *JobScheduleSpec
+	LastTime  *time.Time
+	NextTime  time.Time
+}
+
+func (spec *JobScheduleSpec) ValidateJSON(v *ejson.Validator) {
+	if v.CheckStringNotEmpty("cron", spec.Cron) {
+		_, err := ParseCronSchedule(spec.Cron)
+		v.Check("cron", err == nil, "invalid_cron_expression",
+			"invalid cron expression: %v", err)
+	}
+
+	if spec.Timezone != "" {
+		_, err := time.LoadLocation(spec.Timezone)
+		v.Check("timezone", err == nil, "invalid_timezone",
+			"invalid timezone: %v", err)
+	}
+
+	if spec.CatchUp != "" {
+		v.CheckStringValue("catch_up", spec.CatchUp, JobScheduleCatchUpValues)
+	}
+}
+
+func (spec *JobScheduleSpec) CatchUpPolicy() JobScheduleCatchUp {
+	if spec.CatchUp == "" {
+		return JobScheduleCatchUpOnce
+	}
+
+	return spec.CatchUp
+}
+
+// NextTime returns the first tick strictly after t, evaluating the cron
+// expression in the timezone of the schedule (UTC by default). The returned
+// time is always in UTC.
+func (spec *JobScheduleSpec) NextTime(t time.Time) (time.Time, error) {
+	cs, err := ParseCronSchedule(spec.Cron)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid cron expression: %w", err)
+	}
+
+	loc := time.UTC
+	if spec.Timezone != "" {
+		loc, err = time.LoadLocation(spec.Timezone)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("invalid timezone: %w", err)
+		}
+	}
+
+	next := cs.Next(t.In(loc))
+	if next.IsZero() {
+		return time.Time{}, fmt.Errorf("cron expression %q never matches",
+			spec.Cron)
+	}
+
+	return next.UTC(), nil
+}
+
+// Ticks returns the ticks which are due at time now and for which an
+// execution must be created according to the catch-up policy, and the time of
+// the next tick after now.
+func (s *JobSchedule) Ticks(now time.Time) ([]time.Time, time.Time, error) {
+	var dueTicks []time.Time
+
+	t := s.NextTime
+	for !t.After(now) {
+		dueTicks = append(dueTicks, t)
+		if len(dueTicks) > MaxJobScheduleCatchUpExecutions {
+			dueTicks = dueTicks[1:]
+		}
+
+		next, err := s.Spec.NextTime(t)
+		if err != nil {
+			return nil, time.Time{}, err
+		}
+
+		t = next
+	}
+
+	if len(dueTicks) == 0 {
+		return nil, t, nil
+	}
+
+	lastTick := dueTicks[len(dueTicks)-1]
+
+	switch s.Spec.CatchUpPolicy() {
+	case JobScheduleCatchUpNone:
+		if now.Sub(lastTick) > JobScheduleGracePeriod {
+			dueTicks = nil
+		} else {
+			dueTicks = []time.Time{lastTick}
+		}
+
+	case JobScheduleCatchUpOnce:
+		dueTicks = []time.Time{lastTick}
+	}
+
+	return dueTicks, t, nil
+}
+
+func LoadJobSchedule(conn pg.Conn, jobId Id) (*JobSchedule, error) {
+	query := `
+SELECT job_id, project_id, spec, last_time, next_time
+  FROM job_schedules
+  WHERE job_id = $1
+  FOR UPDATE;
+`
+	var s JobSchedule
+	err := pg.QueryObject(conn, &s, query, jobId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func LoadJobScheduleForProcessing(conn pg.Conn) (*JobSchedule, error) {
+	now := time.Now().UTC()
+
+	query := `
+SELECT job_id, project_id, spec, last_time, next_time
+  FROM job_schedules
+  WHERE next_time <= $1
+  ORDER BY next_time
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED;
+`
+	var s JobSchedule
+	err := pg.QueryObject(conn, &s, query, now)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+func (s *JobSchedule) Upsert(conn pg.Conn) error {
+	query := `
+INSERT INTO job_schedules
+    (job_id, project_id, spec, last_time, next_time)
+  VALUES
+    ($1, $2, $3, $4, $5)
+  ON CONFLICT (job_id) DO UPDATE SET
+    spec = EXCLUDED.spec,
+    last_time = EXCLUDED.last_time,
+    next_time = EXCLUDED.next_time;
+`
+	return pg.Exec(conn, query,
+		s.JobId, s.ProjectId, s.Spec, s.LastTime, s.NextTime)
+}
+
+func DeleteJobSchedule(conn pg.Conn, jobId Id) error {
+	query := `
+DELETE FROM job_schedules
+  WHERE job_id = $1;
+`
+	return pg.Exec(conn, query, jobId)
+}
+
+func (s *JobSchedule) FromRow(row pgx.Row) error {
+	return row.Scan(&s.JobId, &s.ProjectId, &s.Spec, &s.LastTime,
+		&s.NextTime)
+}
@@ -0,0 +1,83 @@
+package eventline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.n16f.net/ejson"
+)
+
+func TestStepOnFailure(t *testing.T) {
+	assert := assert.New(t)
+
+	validate := func(s *Step) error {
+		v := ejson.NewValidator()
+		s.ValidateJSON(v)
+		return v.Error()
+	}
+
+	s := Step{Code: "true"}
+	assert.NoError(validate(&s))
+	assert.True(s.AbortOnFailure())
+
+	s.OnFailure = StepFailureActionAbort
+	assert.NoError(validate(&s))
+	assert.True(s.AbortOnFailure())
+
+	s.OnFailure = StepFailureActionContinue
+	assert.NoError(validate(&s))
+	assert.False(s.AbortOnFailure())
+
+	s.OnFailure = "ignore"
+	assert.Error(validate(&s))
+}
+
+func TestTriggerEventExpired(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Now().UTC()
+
+	event := Event{
+		CreationTime: now.Add(-10 * time.Second),
+		EventTime:    now.Add(-time.Hour),
+	}
+
+	trigger := Trigger{}
+	assert.False(trigger.EventExpired(&event, now))
+
+	trigger.TTL = 7200
+	assert.False(trigger.EventExpired(&event, now))
+
+	trigger.TTL = 60
+	assert.True(trigger.EventExpired(&event, now))
+
+	// Replayed events expire relative to their creation
+	event.OriginalEventId = &Id{}
+	assert.False(trigger.EventExpired(&event, now))
+
+	trigger.TTL = 5
+	assert.True(trigger.EventExpired(&event, now))
+}
+
+func TestStepRetryDelayDuration(t *testing.T) {
+	assert := assert.New(t)
+
+	s := Step{Code: "true"}
+	assert.Equal(DefaultStepRetryDelay, s.RetryDelayDuration(1))
+	assert.Equal(DefaultStepRetryDelay, s.RetryDelayDuration(3))
+
+	s.RetryDelay = 5
+	assert.Equal(5*time.Second, s.RetryDelayDuration(1))
+	assert.Equal(5*time.Second, s.RetryDelayDuration(3))
+
+	s.RetryBackoff = StepRetryBackoffExponential
+	assert.Equal(5*time.Second, s.RetryDelayDuration(1))
+	assert.Equal(10*time.Second, s.RetryDelayDuration(2))
+	assert.Equal(20*time.Second, s.RetryDelayDuration(3))
+	assert.Equal(MaxStepRetryDelay, s.RetryDelayDuration(20))
+	assert.Equal(MaxStepRetryDelay, s.RetryDelayDuration(1000))
+
+	s.RetryDelay = 3600
+	assert.Equal(MaxStepRetryDelay, s.RetryDelayDuration(1))
+}
@@ -0,0 +1,138 @@
+package eventline
+
+import (
+	"sync"
+	"time"
+)
+
+type LifecycleEventType string
+
+const (
+	LifecycleEventJobExecutionQueued   LifecycleEventType = "job_execution_queued"
+	LifecycleEventJobExecutionStarted  LifecycleEventType = "job_execution_started"
+	LifecycleEventStepStarted          LifecycleEventType = "step_started"
+	LifecycleEventStepFinished         LifecycleEventType = "step_finished"
+	LifecycleEventJobExecutionFinished LifecycleEventType = "job_execution_finished"
+)
+
+// LifecycleEvent describes a state transition of a job execution or of one of
+// its steps. Lifecycle events are internal to Eventline and unrelated to the
+// events created by connectors.
+type LifecycleEvent struct {
+	Type           LifecycleEventType `json:"type"`
+	Time           time.Time          `json:"time"`
+	ProjectId      Id                 `json:"project_id"`
+	JobId          Id                 `json:"job_id"`
+	JobExecutionId Id                 `json:"job_execution_id"`
+	Status         string             `json:"status"`
+	FailureMessage string             `json:"failure_message,omitempty"`
+
+	// Step events only
+	StepExecutionId *Id `json:"step_execution_id,omitempty"`
+	StepPosition    int `json:"step_position,omitempty"`
+}
+
+func NewJobExecutionLifecycleEvent(t LifecycleEventType, je *JobExecution) *LifecycleEvent {
+	return &LifecycleEvent{
+		Type:           t,
+		Time:           time.Now().UTC(),
+		ProjectId:      je.ProjectId,
+		JobId:          je.JobId,
+		JobExecutionId: je.Id,
+		Status:         string(je.Status),
+		FailureMessage: je.FailureMessage,
+	}
+}
+
+func NewStepExecutionLifecycleEvent(t LifecycleEventType, je *JobExecution, se *StepExecution) *LifecycleEvent {
+	seId := se.Id
+
+	return &LifecycleEvent{
+		Type:            t,
+		Time:            time.Now().UTC(),
+		ProjectId:       je.ProjectId,
+		JobId:           je.JobId,
+		JobExecutionId:  je.Id,
+		Status:          string(se.Status),
+		FailureMessage:  se.FailureMessage,
+		StepExecutionId: &seId,
+		StepPosition:    se.Position,
+	}
+}
+
+type LifecycleEventListener struct {
+	ProjectId Id
+	C         chan *LifecycleEvent
+}
+
+// LifecycleEventBus dispatches lifecycle events to listeners. Publication
+// never blocks: if a listener is too slow to read its events, new events are
+// dropped for this listener.
+type LifecycleEventBus struct {
+	listeners map[*LifecycleEventListener]struct{}
+	closed    bool
+	mutex     sync.Mutex
+}
+
+func NewLifecycleEventBus() *LifecycleEventBus {
+	return &LifecycleEventBus{
+		listeners: make(map[*LifecycleEventListener]struct{}),
+	}
+}
+
+func (b *LifecycleEventBus) Publish(e *LifecycleEvent) {
+	if b == nil {
+		return
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for l := range b.listeners {
+		if l.ProjectId != e.ProjectId {
+			continue
+		}
+
+		select {
+		case l.C <- e:
+		default:
+		}
+	}
+}
+
+func (b *LifecycleEventBus) Listen(projectId Id) *LifecycleEventListener {
+	l := LifecycleEventListener{
+		ProjectId: projectId,
+		C:         make(chan *LifecycleEvent, 100),
+	}
+
+	b.mutex.Lock()
+	if b.closed {
+		close(l.C)
+	} else {
+		b.listeners[&l] = struct{}{}
+	}
+	b.mutex.Unlock()
+
+	return &l
+}
+
+func (b *LifecycleEventBus) Unlisten(l *LifecycleEventListener) {
+	b.mutex.Lock()
+	delete(b.listeners, l)
+	b.mutex.Unlock()
+}
+
+// Close closes the channels of all listeners, signaling them that no more
+// events will be published.
+func (b *LifecycleEventBus) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for l := range b.listeners {
+		close(l.C)
+	}
+
+	b.listeners = make(map[*LifecycleEventListener]struct{})
+	b.closed = true
+}
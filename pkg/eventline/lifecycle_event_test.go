@@ -0,0 +1,40 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLifecycleEventBus(t *testing.T) {
+	assert := assert.New(t)
+
+	projectId1 := GenerateId()
+	projectId2 := GenerateId()
+
+	bus := NewLifecycleEventBus()
+
+	l1 := bus.Listen(projectId1)
+	l2 := bus.Listen(projectId2)
+
+	je := JobExecution{Id: GenerateId(), ProjectId: projectId1}
+	bus.Publish(NewJobExecutionLifecycleEvent(
+		LifecycleEventJobExecutionQueued, &je))
+
+	if assert.Len(l1.C, 1) {
+		e := <-l1.C
+		assert.Equal(LifecycleEventJobExecutionQueued, e.Type)
+		assert.Equal(je.Id, e.JobExecutionId)
+	}
+
+	assert.Len(l2.C, 0)
+
+	bus.Unlisten(l2)
+	bus.Close()
+
+	_, ok := <-l1.C
+	assert.False(ok)
+
+	_, ok = <-bus.Listen(projectId1).C
+	assert.False(ok)
+}
@@ -8,6 +8,8 @@ import (
 	"github.com/jackc/pgx/v5"
 )
 
+// Notifications are either email messages sent to a list of recipients or,
+// if WebhookURI is set, JSON documents sent to a webhook.
 type Notification struct {
 	Id               Id
 	ProjectId        Id
@@ -15,6 +17,7 @@ type Notification struct {
 	Message          []byte
 	NextDeliveryTime time.Time
 	DeliveryDelay    int // seconds
+	WebhookURI       string
 }
 
 func LoadNotificationForDelivery(conn pg.Conn) (*Notification, error) {
@@ -22,7 +25,7 @@ func LoadNotificationForDelivery(conn pg.Conn) (*Notification, error) {
 
 	query := `
 SELECT id, project_id, recipients, message, next_delivery_time,
-       delivery_delay
+       delivery_delay, webhook_uri
   FROM notifications
   WHERE next_delivery_time < $1
   ORDER BY next_delivery_time
@@ -44,14 +47,14 @@ func (n *Notification) Insert(conn pg.Conn) error {
 	query := `
 INSERT INTO notifications
     (id, project_id, recipients, message, next_delivery_time,
-     delivery_delay)
+     delivery_delay, webhook_uri)
   VALUES
     ($1, $2, $3, $4, $5,
-     $6);
+     $6, $7);
 `
 	return pg.Exec(conn, query,
 		n.Id, n.ProjectId, n.Recipients, n.Message, n.NextDeliveryTime,
-		n.DeliveryDelay)
+		n.DeliveryDelay, n.WebhookURI)
 }
 
 func (n *Notification) Update(conn pg.Conn) error {
@@ -75,5 +78,5 @@ DELETE FROM notifications
 
 func (n *Notification) FromRow(row pgx.Row) error {
 	return row.Scan(&n.Id, &n.ProjectId, &n.Recipients, &n.Message,
-		&n.NextDeliveryTime, &n.DeliveryDelay)
+		&n.NextDeliveryTime, &n.DeliveryDelay, &n.WebhookURI)
 }
@@ -0,0 +1,122 @@
+package eventline
+
+import (
+	"fmt"
+	"unicode/utf8"
+
+	"go.n16f.net/ejson"
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/htmlindex"
+)
+
+// Step output is stored as UTF-8 text. Programs running on legacy systems
+// may produce output using another character encoding, in which case jobs can
+// declare it with the output_encoding setting so that output is transcoded.
+//
+// Whatever the encoding, invalid sequences are replaced by the Unicode
+// replacement character, and control characters which break the display of
+// the output are removed. Escape characters are kept since they are used by
+// ANSI escape sequences which are rendered in the web interface.
+//
+// Output is decoded line by line: a complete line is always available before
+// decoding, so multi-byte sequences split across reads are never corrupted.
+
+const DefaultOutputEncoding = "utf-8"
+
+// FindOutputEncoding returns the encoding associated with a name. Names are
+// the ones defined in the WHATWG Encoding Standard, e.g. "iso-8859-1" or
+// "windows-1252". Encodings in which the newline character is not encoded as
+// a single byte cannot be used since output is split in lines before
+// decoding.
+func FindOutputEncoding(name string) (encoding.Encoding, error) {
+	enc, err := htmlindex.Get(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+
+	canonicalName, err := htmlindex.Name(enc)
+	if err != nil {
+		return nil, fmt.Errorf("unknown encoding %q", name)
+	}
+
+	switch canonicalName {
+	case "utf-16be", "utf-16le", "replacement":
+		return nil, fmt.Errorf("unsupported encoding %q", name)
+	}
+
+	return enc, nil
+}
+
+func CheckOutputEncoding(v *ejson.Validator, token interface{}, name string) bool {
+	_, err := FindOutputEncoding(name)
+	return v.Check(token, err == nil, "invalid_output_encoding", "%v", err)
+}
+
+// OutputDecoder converts output to valid UTF-8 text. It is not safe for
+// concurrent use.
+type OutputDecoder struct {
+	decoder *encoding.Decoder // nil for UTF-8
+}
+
+func NewOutputDecoder(name string) (*OutputDecoder, error) {
+	var d OutputDecoder
+
+	if name == "" {
+		return &d, nil
+	}
+
+	enc, err := FindOutputEncoding(name)
+	if err != nil {
+		return nil, err
+	}
+
+	if canonicalName, _ := htmlindex.Name(enc); canonicalName != "utf-8" {
+		d.decoder = enc.NewDecoder()
+	}
+
+	return &d, nil
+}
+
+// Decode converts a complete line of output.
+func (d *OutputDecoder) Decode(data []byte) []byte {
+	if d.decoder != nil {
+		// Decoders replace invalid sequences by U+FFFD; if decoding fails
+		// anyway, we keep the original data which will be sanitized.
+		if decodedData, err := d.decoder.Bytes(data); err == nil {
+			data = decodedData
+		}
+	}
+
+	return sanitizeOutput(data)
+}
+
+func sanitizeOutput(data []byte) []byte {
+	buf := make([]byte, 0, len(data))
+
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+
+		switch {
+		case r == utf8.RuneError && size == 1:
+			buf = utf8.AppendRune(buf, utf8.RuneError)
+
+		case isOutputControlCharacter(r):
+
+		default:
+			buf = append(buf, data[:size]...)
+		}
+
+		data = data[size:]
+	}
+
+	return buf
+}
+
+func isOutputControlCharacter(r rune) bool {
+	switch r {
+	case '\t', '\n', '\r', '\x1b':
+		return false
+	}
+
+	return r < 0x20 || (r >= 0x7f && r < 0xa0)
+}
@@ -0,0 +1,53 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestOutputDecoder(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	decode := func(encoding, data string) string {
+		decoder, err := NewOutputDecoder(encoding)
+		require.NoError(err)
+
+		return string(decoder.Decode([]byte(data)))
+	}
+
+	// UTF-8
+	assert.Equal("hello\n", decode("", "hello\n"))
+	assert.Equal("café\n", decode("utf-8", "café\n"))
+	assert.Equal("caf�\n", decode("", "caf\xe9\n"))
+	assert.Equal("��\n", decode("", "\xc3\xa9"[:1]+"\xff\n"))
+
+	// Control characters
+	assert.Equal("a\tb\r\n", decode("", "a\x00\tb\x07\r\n"))
+	assert.Equal("\x1b[31mred\x1b[0m\n", decode("", "\x1b[31mred\x1b[0m\n"))
+	assert.Equal("ab\n", decode("", "a\u0085b\x7f\n"))
+
+	// Other encodings
+	assert.Equal("café\n", decode("latin1", "caf\xe9\n"))
+	assert.Equal("café\n", decode("iso-8859-1", "caf\xe9\n"))
+	assert.Equal("€ 10\n", decode("windows-1252", "\x80 10\n"))
+	assert.Equal("あ\n", decode("shift_jis", "\x82\xa0\n"))
+}
+
+func TestFindOutputEncoding(t *testing.T) {
+	assert := assert.New(t)
+
+	_, err := FindOutputEncoding("utf-8")
+	assert.NoError(err)
+
+	_, err = FindOutputEncoding("ISO-8859-15")
+	assert.NoError(err)
+
+	_, err = FindOutputEncoding("foo")
+	assert.Error(err)
+
+	_, err = FindOutputEncoding("utf-16le")
+	assert.Error(err)
+}
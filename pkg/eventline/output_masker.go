@@ -0,0 +1,81 @@
+package eventline
+
+import (
+	"bytes"
+)
+
+// Secrets shorter than this length are not masked: short values are likely
+// to appear in the output by chance, and masking them would make the output
+// unreadable while not protecting much.
+const MinMaskedSecretLength = 6
+
+var maskedSecret = []byte("********")
+
+// OutputMasker replaces secret values in the output of a step. Output is
+// processed in chunks, typically lines, and secrets can span several chunks,
+// for example multi-line private keys. The masker therefore holds back the
+// end of a chunk if it could be the beginning of a secret, until the next
+// chunk tells whether it is. Maskers are stateful, each output needs its own.
+type OutputMasker struct {
+	secrets [][]byte
+	pending []byte
+}
+
+func NewOutputMasker(secrets [][]byte) *OutputMasker {
+	return &OutputMasker{
+		secrets: secrets,
+	}
+}
+
+// Mask returns the part of the output which can be stored, with secrets
+// replaced.
+func (m *OutputMasker) Mask(data []byte) []byte {
+	if len(m.secrets) == 0 {
+		return data
+	}
+
+	buf := append(m.pending, data...)
+	m.pending = nil
+
+	for _, secret := range m.secrets {
+		if bytes.Contains(buf, secret) {
+			buf = bytes.ReplaceAll(buf, secret, maskedSecret)
+		}
+	}
+
+	n := m.partialSecretLength(buf)
+	if n == 0 {
+		return buf
+	}
+
+	m.pending = append([]byte(nil), buf[len(buf)-n:]...)
+	return buf[:len(buf)-n]
+}
+
+// Flush returns output held back at the end of the stream; it cannot contain
+// a secret since there is nothing left to complete it.
+func (m *OutputMasker) Flush() []byte {
+	pending := m.pending
+	m.pending = nil
+
+	return pending
+}
+
+// partialSecretLength returns the length of the longest suffix of data which
+// is the beginning of a secret.
+func (m *OutputMasker) partialSecretLength(data []byte) int {
+	var length int
+
+	for _, secret := range m.secrets {
+		n := min(len(secret)-1, len(data))
+
+		for ; n > length; n-- {
+			if bytes.HasSuffix(data, secret[:n]) {
+				length = n
+				break
+			}
+		}
+	}
+
+	return length
+}
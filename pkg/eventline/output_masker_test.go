@@ -0,0 +1,36 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputMasker(t *testing.T) {
+	assert := assert.New(t)
+
+	m := NewOutputMasker([][]byte{
+		[]byte("hunter2"),
+		[]byte("-----BEGIN KEY-----\nabcdef\n-----END KEY-----"),
+	})
+
+	mask := func(s string) string {
+		return string(m.Mask([]byte(s)))
+	}
+
+	assert.Equal("password: ********\n", mask("password: hunter2\n"))
+	assert.Equal("hello\n", mask("hello\n"))
+
+	// Secrets spanning several lines
+	assert.Equal("", mask("-----BEGIN KEY-----\n"))
+	assert.Equal("", mask("abcdef\n"))
+	assert.Equal("********\n", mask("-----END KEY-----\n"))
+
+	// Output held back which ends up not being a secret
+	assert.Equal("", mask("-----BEGIN KEY-----\n"))
+	assert.Equal("-----BEGIN KEY-----\nfoo\n", mask("foo\n"))
+
+	assert.Equal("", mask("-----BEGIN KEY-----\n"))
+	assert.Equal("-----BEGIN KEY-----\n", string(m.Flush()))
+	assert.Equal("", string(m.Flush()))
+}
@@ -0,0 +1,82 @@
+package eventline
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// OutputRateLimiter limits the number of bytes of output stored for a step
+// execution each second. Output exceeding the limit is dropped; markers are
+// inserted in the output to indicate when output starts being dropped and how
+// much was lost. Programs are never blocked, so steps keep running normally.
+type OutputRateLimiter struct {
+	Rate int // bytes per second
+
+	windowStart    time.Time
+	windowSize     int
+	nbDroppedBytes int
+
+	mutex sync.Mutex
+}
+
+func NewOutputRateLimiter(rate int) *OutputRateLimiter {
+	return &OutputRateLimiter{
+		Rate: rate,
+	}
+}
+
+// Filter returns the data to store for a chunk of output, which can be empty
+// if the chunk is dropped.
+func (l *OutputRateLimiter) Filter(data []byte) []byte {
+	return l.filter(data, time.Now())
+}
+
+func (l *OutputRateLimiter) filter(data []byte, now time.Time) []byte {
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	if now.Sub(l.windowStart) >= time.Second {
+		l.windowStart = now
+		l.windowSize = 0
+	}
+
+	available := l.Rate - l.windowSize
+
+	// Still dropping output in the current window
+	if l.nbDroppedBytes > 0 && available == 0 {
+		l.nbDroppedBytes += len(data)
+		return nil
+	}
+
+	var output []byte
+
+	if l.nbDroppedBytes > 0 {
+		marker := fmt.Sprintf("[%d bytes of output dropped]\n",
+			l.nbDroppedBytes)
+		output = append(output, marker...)
+		l.nbDroppedBytes = 0
+	}
+
+	if len(data) <= available {
+		l.windowSize += len(data)
+		return append(output, data...)
+	}
+
+	// Store the part of the chunk which fits in the window so that chunks
+	// larger than the rate are not dropped entirely.
+	kept := data[:available]
+	output = append(output, kept...)
+	if len(kept) > 0 && kept[len(kept)-1] != '\n' {
+		output = append(output, '\n')
+	}
+
+	marker := fmt.Sprintf("[output rate limit of %d bytes per second "+
+		"exceeded, dropping output]\n", l.Rate)
+	output = append(output, marker...)
+
+	l.windowSize = l.Rate
+	l.nbDroppedBytes = len(data) - available
+
+	return output
+}
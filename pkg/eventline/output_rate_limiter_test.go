@@ -0,0 +1,51 @@
+package eventline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputRateLimiter(t *testing.T) {
+	assert := assert.New(t)
+
+	l := NewOutputRateLimiter(10)
+
+	now := time.Now()
+	filter := func(s string, offset time.Duration) string {
+		return string(l.filter([]byte(s), now.Add(offset)))
+	}
+
+	assert.Equal("abcd\n", filter("abcd\n", 0))
+	assert.Equal("efgh\n", filter("efgh\n", 100*time.Millisecond))
+	assert.Equal("[output rate limit of 10 bytes per second exceeded, "+
+		"dropping output]\n", filter("ijkl\n", 200*time.Millisecond))
+	assert.Equal("", filter("mnop\n", 300*time.Millisecond))
+	assert.Equal("", filter("x\n", 400*time.Millisecond))
+
+	assert.Equal("[12 bytes of output dropped]\nqrst\n",
+		filter("qrst\n", 1100*time.Millisecond))
+	assert.Equal("uvwx\n", filter("uvwx\n", 1200*time.Millisecond))
+}
+
+func TestOutputRateLimiterLargeChunks(t *testing.T) {
+	assert := assert.New(t)
+
+	l := NewOutputRateLimiter(10)
+
+	now := time.Now()
+	filter := func(s string, offset time.Duration) string {
+		return string(l.filter([]byte(s), now.Add(offset)))
+	}
+
+	// Chunks larger than the rate are truncated instead of being dropped
+	assert.Equal("0123456789\n[output rate limit of 10 bytes per second "+
+		"exceeded, dropping output]\n",
+		filter("0123456789abcdef", 0))
+	assert.Equal("", filter("ghij", 100*time.Millisecond))
+
+	assert.Equal("[10 bytes of output dropped]\nabc\n012345\n[output rate "+
+		"limit of 10 bytes per second exceeded, dropping output]\n",
+		filter("abc\n"+"0123456789", 1100*time.Millisecond))
+}
@@ -1,9 +1,12 @@
 package eventline
 
 import (
+	"encoding/json"
 	"errors"
+	"fmt"
 	"net/mail"
 	"strings"
+	"time"
 
 	"go.n16f.net/service/pkg/pg"
 	"go.n16f.net/ejson"
@@ -18,11 +21,41 @@ type ProjectNotificationSettings struct {
 	OnAbortedJob           bool     `json:"on_aborted_job,omitempty"`
 	OnIdentityRefreshError bool     `json:"on_identity_refresh_error,omitempty"`
 	EmailAddresses         []string `json:"email_addresses"`
+	WebhookURI             string   `json:"webhook_uri,omitempty"`
+
+	// The webhook secret is write-only: it is never encoded, and an empty
+	// secret in input means that the current secret is kept.
+	WebhookSecret string `json:"webhook_secret,omitempty"`
+
+	// When the webhook secret is changed, the previous secret is still used
+	// to sign notifications until it expires, so that receivers can be
+	// updated without rejecting notifications.
+	PreviousWebhookSecret               string     `json:"-"`
+	PreviousWebhookSecretExpirationTime *time.Time `json:"-"`
+}
+
+// WebhookSecretRotationPeriod is the time during which the previous webhook
+// secret of a project is still used after the secret has been changed.
+const WebhookSecretRotationPeriod = 7 * 24 * time.Hour
+
+func (ps ProjectNotificationSettings) MarshalJSON() ([]byte, error) {
+	type ProjectNotificationSettings2 ProjectNotificationSettings
+
+	ps2 := ProjectNotificationSettings2(ps)
+	ps2.WebhookSecret = ""
+
+	return json.Marshal(ps2)
 }
 
-func (ps *ProjectNotificationSettings) Check(v *ejson.Validator) {
+func (ps *ProjectNotificationSettings) ValidateJSON(v *ejson.Validator) {
 	// Email addresses are validated in CheckEmailAddresses because we need
-	// access to the list of allowed domains.
+	// access to the list of allowed domains. The presence of the webhook
+	// secret is checked when settings are updated since an empty secret
+	// means that the current one is kept.
+
+	if ps.WebhookURI != "" {
+		v.CheckStringURI("webhook_uri", ps.WebhookURI)
+	}
 }
 
 func (ps *ProjectNotificationSettings) CheckEmailAddresses(v *ejson.Validator, allowedDomains []string) {
@@ -73,11 +106,45 @@ func (ps *ProjectNotificationSettings) CheckEmailAddresses(v *ejson.Validator, a
 	})
 }
 
+// UpdateWebhookSecret applies the webhook secret submitted in input to the
+// current settings of the project. An empty secret keeps the current one; a
+// new secret replaces it, the current secret becoming the previous secret
+// until the end of the rotation period.
+func (ps *ProjectNotificationSettings) UpdateWebhookSecret(currentSettings *ProjectNotificationSettings, now time.Time) {
+	ps.PreviousWebhookSecret = currentSettings.PreviousWebhookSecret
+	ps.PreviousWebhookSecretExpirationTime =
+		currentSettings.PreviousWebhookSecretExpirationTime
+
+	if ps.WebhookSecret == "" || ps.WebhookSecret == currentSettings.WebhookSecret {
+		ps.WebhookSecret = currentSettings.WebhookSecret
+		return
+	}
+
+	if currentSettings.WebhookSecret != "" {
+		expirationTime := now.Add(WebhookSecretRotationPeriod)
+
+		ps.PreviousWebhookSecret = currentSettings.WebhookSecret
+		ps.PreviousWebhookSecretExpirationTime = &expirationTime
+	}
+}
+
+// ActivePreviousWebhookSecret returns the previous webhook secret if it has
+// not expired yet, or an empty string.
+func (ps *ProjectNotificationSettings) ActivePreviousWebhookSecret(now time.Time) string {
+	expirationTime := ps.PreviousWebhookSecretExpirationTime
+	if expirationTime == nil || !now.Before(*expirationTime) {
+		return ""
+	}
+
+	return ps.PreviousWebhookSecret
+}
+
 func (ps *ProjectNotificationSettings) Load(conn pg.Conn, id Id) error {
 	query := `
 SELECT id, on_successful_job, on_first_successful_job,
        on_failed_job, on_aborted_job, on_identity_refresh_error,
-       email_addresses
+       email_addresses, webhook_uri, webhook_secret,
+       previous_webhook_secret, previous_webhook_secret_expiration_time
   FROM project_notification_settings
   WHERE id = $1
 `
@@ -90,23 +157,48 @@ SELECT id, on_successful_job, on_first_successful_job,
 }
 
 func (ps *ProjectNotificationSettings) Insert(conn pg.Conn) error {
+	encryptedSecret, err := encryptWebhookSecret(ps.WebhookSecret)
+	if err != nil {
+		return err
+	}
+
+	encryptedPreviousSecret, err :=
+		encryptWebhookSecret(ps.PreviousWebhookSecret)
+	if err != nil {
+		return err
+	}
+
 	query := `
 INSERT INTO project_notification_settings
     (id, on_successful_job, on_first_successful_job,
      on_failed_job, on_aborted_job, on_identity_refresh_error,
-     email_addresses)
+     email_addresses, webhook_uri, webhook_secret,
+     previous_webhook_secret, previous_webhook_secret_expiration_time)
   VALUES
     ($1, $2, $3,
      $4, $5, $6,
-     $7);
+     $7, $8, $9,
+     $10, $11);
 `
 	return pg.Exec(conn, query,
 		ps.Id, ps.OnSuccessfulJob, ps.OnFirstSuccessfulJob,
 		ps.OnFailedJob, ps.OnAbortedJob, ps.OnIdentityRefreshError,
-		ps.EmailAddresses)
+		ps.EmailAddresses, ps.WebhookURI, encryptedSecret,
+		encryptedPreviousSecret, ps.PreviousWebhookSecretExpirationTime)
 }
 
 func (ps *ProjectNotificationSettings) Update(conn pg.Conn) error {
+	encryptedSecret, err := encryptWebhookSecret(ps.WebhookSecret)
+	if err != nil {
+		return err
+	}
+
+	encryptedPreviousSecret, err :=
+		encryptWebhookSecret(ps.PreviousWebhookSecret)
+	if err != nil {
+		return err
+	}
+
 	query := `
 UPDATE project_notification_settings SET
     on_successful_job = $2,
@@ -114,17 +206,62 @@ UPDATE project_notification_settings SET
     on_failed_job = $4,
     on_aborted_job = $5,
     on_identity_refresh_error = $6,
-    email_addresses = $7
+    email_addresses = $7,
+    webhook_uri = $8,
+    webhook_secret = $9,
+    previous_webhook_secret = $10,
+    previous_webhook_secret_expiration_time = $11
   WHERE id = $1
 `
 	return pg.Exec(conn, query,
 		ps.Id, ps.OnSuccessfulJob, ps.OnFirstSuccessfulJob,
 		ps.OnFailedJob, ps.OnAbortedJob, ps.OnIdentityRefreshError,
-		ps.EmailAddresses)
+		ps.EmailAddresses, ps.WebhookURI, encryptedSecret,
+		encryptedPreviousSecret, ps.PreviousWebhookSecretExpirationTime)
+}
+
+func encryptWebhookSecret(secret string) ([]byte, error) {
+	if secret == "" {
+		return nil, nil
+	}
+
+	encryptedSecret, err := EncryptAES256([]byte(secret))
+	if err != nil {
+		return nil, fmt.Errorf("cannot encrypt webhook secret: %w", err)
+	}
+
+	return encryptedSecret, nil
 }
 
 func (ps *ProjectNotificationSettings) FromRow(row pgx.Row) error {
-	return row.Scan(&ps.Id, &ps.OnSuccessfulJob, &ps.OnFirstSuccessfulJob,
+	var encryptedSecret, encryptedPreviousSecret []byte
+
+	err := row.Scan(&ps.Id, &ps.OnSuccessfulJob, &ps.OnFirstSuccessfulJob,
 		&ps.OnFailedJob, &ps.OnAbortedJob, &ps.OnIdentityRefreshError,
-		&ps.EmailAddresses)
+		&ps.EmailAddresses, &ps.WebhookURI, &encryptedSecret,
+		&encryptedPreviousSecret, &ps.PreviousWebhookSecretExpirationTime)
+	if err != nil {
+		return err
+	}
+
+	if encryptedSecret != nil {
+		secret, err := DecryptAES256(encryptedSecret)
+		if err != nil {
+			return fmt.Errorf("cannot decrypt webhook secret: %w", err)
+		}
+
+		ps.WebhookSecret = string(secret)
+	}
+
+	if encryptedPreviousSecret != nil {
+		secret, err := DecryptAES256(encryptedPreviousSecret)
+		if err != nil {
+			return fmt.Errorf("cannot decrypt previous webhook secret: %w",
+				err)
+		}
+
+		ps.PreviousWebhookSecret = string(secret)
+	}
+
+	return nil
 }
@@ -0,0 +1,59 @@
+package eventline
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestProjectNotificationSettingsJSON(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	settings := ProjectNotificationSettings{
+		WebhookURI:    "https://example.com/notifications",
+		WebhookSecret: "foo",
+	}
+
+	data, err := json.Marshal(&settings)
+	require.NoError(err)
+	assert.NotContains(string(data), "webhook_secret")
+
+	data, err = json.Marshal(settings)
+	require.NoError(err)
+	assert.NotContains(string(data), "webhook_secret")
+
+	var settings2 ProjectNotificationSettings
+	err = json.Unmarshal([]byte(`{"webhook_secret":"bar"}`), &settings2)
+	require.NoError(err)
+	assert.Equal("bar", settings2.WebhookSecret)
+}
+
+func TestProjectNotificationSettingsWebhookSecretRotation(t *testing.T) {
+	assert := assert.New(t)
+
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+	currentSettings := ProjectNotificationSettings{WebhookSecret: "foo"}
+
+	// An empty secret keeps the current one
+	settings := ProjectNotificationSettings{}
+	settings.UpdateWebhookSecret(&currentSettings, now)
+	assert.Equal("foo", settings.WebhookSecret)
+	assert.Equal("", settings.ActivePreviousWebhookSecret(now))
+
+	// A new secret makes the current one the previous secret
+	settings = ProjectNotificationSettings{WebhookSecret: "bar"}
+	settings.UpdateWebhookSecret(&currentSettings, now)
+	assert.Equal("bar", settings.WebhookSecret)
+	assert.Equal("foo", settings.ActivePreviousWebhookSecret(now))
+
+	expirationTime := now.Add(WebhookSecretRotationPeriod)
+	assert.Equal("", settings.ActivePreviousWebhookSecret(expirationTime))
+
+	assert.Len(NotificationKeyId("foo"), 16)
+	assert.NotEqual(NotificationKeyId("foo"), NotificationKeyId("bar"))
+}
@@ -0,0 +1,24 @@
+package eventline
+
+import (
+	"time"
+
+	"go.n16f.net/service/pkg/pg"
+)
+
+// The job scheduler picks job executions from the project which was served
+// the longest time ago, so that a project creating a large number of job
+// executions cannot delay the execution of jobs of other projects. We keep
+// track of the last time a job execution was started for each project.
+
+func UpdateProjectScheduleTime(conn pg.Conn, projectId Id, t time.Time) error {
+	query := `
+INSERT INTO project_scheduling_times
+    (id, last_schedule_time)
+  VALUES
+    ($1, $2)
+  ON CONFLICT (id) DO UPDATE SET
+    last_schedule_time = EXCLUDED.last_schedule_time;
+`
+	return pg.Exec(conn, query, projectId, t)
+}
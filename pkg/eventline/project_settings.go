@@ -11,6 +11,9 @@ import (
 type ProjectSettings struct {
 	Id         Id     `json:"id"` // Ignored in input
 	CodeHeader string `json:"code_header"`
+
+	// If set, overrides the global event retention for the project
+	EventRetention int `json:"event_retention,omitempty"` // days
 }
 
 func (ps *ProjectSettings) ValidateJSON(v *ejson.Validator) {
@@ -18,11 +21,15 @@ func (ps *ProjectSettings) ValidateJSON(v *ejson.Validator) {
 	err := shebang.Parse(ps.CodeHeader)
 	v.Check("code_header", err == nil, "invalid_shebang",
 		"invalid shebang: %v", err)
+
+	if ps.EventRetention != 0 {
+		v.CheckIntMin("event_retention", ps.EventRetention, 1)
+	}
 }
 
 func (ps *ProjectSettings) Load(conn pg.Conn, id Id) error {
 	query := `
-SELECT id, code_header
+SELECT id, code_header, event_retention
   FROM project_settings
   WHERE id = $1
 `
@@ -37,24 +44,25 @@ SELECT id, code_header
 func (ps *ProjectSettings) Insert(conn pg.Conn) error {
 	query := `
 INSERT INTO project_settings
-    (id, code_header)
+    (id, code_header, event_retention)
   VALUES
-    ($1, $2);
+    ($1, $2, $3);
 `
 	return pg.Exec(conn, query,
-		ps.Id, ps.CodeHeader)
+		ps.Id, ps.CodeHeader, ps.EventRetention)
 }
 
 func (ps *ProjectSettings) Update(conn pg.Conn) error {
 	query := `
 UPDATE project_settings SET
-    code_header = $2
+    code_header = $2,
+    event_retention = $3
   WHERE id = $1
 `
 	return pg.Exec(conn, query,
-		ps.Id, ps.CodeHeader)
+		ps.Id, ps.CodeHeader, ps.EventRetention)
 }
 
 func (ps *ProjectSettings) FromRow(row pgx.Row) error {
-	return row.Scan(&ps.Id, &ps.CodeHeader)
+	return row.Scan(&ps.Id, &ps.CodeHeader, &ps.EventRetention)
 }
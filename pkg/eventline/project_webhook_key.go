@@ -0,0 +1,150 @@
+package eventline
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/cryptoutils"
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// ProjectWebhookKey is a secret key used to derive the secrets of the
+// webhooks created for a project. Each project has its own keys so that the
+// compromise of a key only affects a single project. Rotating the key of a
+// project creates a new key; previous keys are kept since existing webhooks
+// were signed with them.
+type ProjectWebhookKey struct {
+	Id           Id        `json:"id"`
+	ProjectId    Id        `json:"project_id"`
+	CreationTime time.Time `json:"creation_time"`
+	Key          []byte    `json:"-"`
+}
+
+func NewProjectWebhookKey(projectId Id) *ProjectWebhookKey {
+	return &ProjectWebhookKey{
+		Id:           GenerateId(),
+		ProjectId:    projectId,
+		CreationTime: time.Now().UTC(),
+		Key:          cryptoutils.RandomBytes(32),
+	}
+}
+
+// WebhookSecret derives the secret of a webhook from the key and from a value
+// identifying the webhook.
+func (k *ProjectWebhookKey) WebhookSecret(value string) string {
+	mac := hmac.New(sha256.New, k.Key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+func (k *ProjectWebhookKey) Load(conn pg.Conn, id Id) error {
+	query := `
+SELECT id, project_id, creation_time, key
+  FROM project_webhook_keys
+  WHERE id = $1
+`
+	return pg.QueryObject(conn, k, query, id)
+}
+
+// LoadCurrentProjectWebhookKey returns the most recent key of a project, or
+// nil if the project does not have any key.
+func LoadCurrentProjectWebhookKey(conn pg.Conn, projectId Id) (*ProjectWebhookKey, error) {
+	query := `
+SELECT id, project_id, creation_time, key
+  FROM project_webhook_keys
+  WHERE project_id = $1
+  ORDER BY creation_time DESC
+  LIMIT 1
+`
+	var k ProjectWebhookKey
+	err := pg.QueryObject(conn, &k, query, projectId)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	return &k, nil
+}
+
+// CreateMissingProjectWebhookKeys creates a webhook key for each project
+// which does not have any, i.e. projects created before the introduction of
+// webhook keys, and returns the number of keys created.
+func CreateMissingProjectWebhookKeys(conn pg.Conn) (int, error) {
+	ctx := context.Background()
+
+	query := `
+SELECT p.id
+  FROM projects AS p
+  WHERE NOT EXISTS (SELECT 1
+                      FROM project_webhook_keys AS k
+                      WHERE k.project_id = p.id)
+`
+	rows, err := conn.Query(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+
+	var projectIds Ids
+
+	for rows.Next() {
+		var id Id
+		if err := rows.Scan(&id); err != nil {
+			return 0, err
+		}
+
+		projectIds = append(projectIds, id)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, projectId := range projectIds {
+		key := NewProjectWebhookKey(projectId)
+		if err := key.Insert(conn); err != nil {
+			return 0, fmt.Errorf("cannot insert webhook key for project "+
+				"%q: %w", projectId, err)
+		}
+	}
+
+	return len(projectIds), nil
+}
+
+func (k *ProjectWebhookKey) Insert(conn pg.Conn) error {
+	query := `
+INSERT INTO project_webhook_keys
+    (id, project_id, creation_time, key)
+  VALUES
+    ($1, $2, $3, $4);
+`
+	encryptedKey, err := EncryptAES256(k.Key)
+	if err != nil {
+		return fmt.Errorf("cannot encrypt key: %w", err)
+	}
+
+	return pg.Exec(conn, query,
+		k.Id, k.ProjectId, k.CreationTime, encryptedKey)
+}
+
+func (k *ProjectWebhookKey) FromRow(row pgx.Row) error {
+	var encryptedKey []byte
+
+	err := row.Scan(&k.Id, &k.ProjectId, &k.CreationTime, &encryptedKey)
+	if err != nil {
+		return err
+	}
+
+	k.Key, err = DecryptAES256(encryptedKey)
+	if err != nil {
+		return fmt.Errorf("cannot decrypt webhook key %q: %w", k.Id, err)
+	}
+
+	return nil
+}
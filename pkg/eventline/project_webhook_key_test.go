@@ -0,0 +1,21 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProjectWebhookKeySecret(t *testing.T) {
+	assert := assert.New(t)
+
+	key1 := NewProjectWebhookKey(GenerateId())
+	key2 := NewProjectWebhookKey(GenerateId())
+
+	secret := key1.WebhookSecret("foo")
+
+	assert.Len(secret, 64)
+	assert.Equal(secret, key1.WebhookSecret("foo"))
+	assert.NotEqual(secret, key1.WebhookSecret("bar"))
+	assert.NotEqual(secret, key2.WebhookSecret("foo"))
+}
@@ -0,0 +1,90 @@
+package eventline
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"github.com/exograd/eventline/pkg/utils"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/shttp"
+)
+
+// Outbound HTTP requests sent on behalf of connectors (GitHub API calls,
+// OAuth2 token requests...) can go through a proxy. A global proxy is
+// configured with the outbound_proxy setting, and each connector can override
+// it with the proxy setting of its configuration. Without any explicit proxy,
+// the HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables are used.
+//
+// Proxy credentials are provided in the user information part of the URI.
+
+var ProxySchemes = []string{"http", "https", "socks5"}
+
+// ConnectorProxyCfg contains the proxy setting available in the configuration
+// of all connectors. It is decoded separately from the configuration of each
+// connector.
+type ConnectorProxyCfg struct {
+	Proxy string `json:"proxy,omitempty"`
+}
+
+func (cfg *ConnectorProxyCfg) ValidateJSON(v *ejson.Validator) {
+	if cfg.Proxy != "" {
+		CheckProxyURI(v, "proxy", cfg.Proxy)
+	}
+}
+
+func ParseProxyURI(s string) (*url.URL, error) {
+	uri, err := url.Parse(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uri: %w", err)
+	}
+
+	if !utils.StringsContain(ProxySchemes, uri.Scheme) {
+		return nil, fmt.Errorf("invalid scheme %q", uri.Scheme)
+	}
+
+	if uri.Host == "" {
+		return nil, fmt.Errorf("missing host")
+	}
+
+	if (uri.Path != "" && uri.Path != "/") || uri.RawQuery != "" ||
+		uri.Fragment != "" {
+		return nil, fmt.Errorf("proxy uris cannot contain a path, a query " +
+			"or a fragment")
+	}
+
+	return uri, nil
+}
+
+func CheckProxyURI(v *ejson.Validator, token interface{}, s string) bool {
+	_, err := ParseProxyURI(s)
+	return v.Check(token, err == nil, "invalid_proxy_uri", "invalid proxy "+
+		"uri: %v", err)
+}
+
+// NewHTTPClient creates a HTTP client sending requests through a proxy if
+// proxyURI is not nil.
+func NewHTTPClient(cfg shttp.ClientCfg, proxyURI *url.URL) (*shttp.Client, error) {
+	client, err := shttp.NewClient(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURI != nil {
+		rt, ok := client.Client.Transport.(*shttp.RoundTripper)
+		if !ok {
+			return nil, fmt.Errorf("unexpected round tripper type %T",
+				client.Client.Transport)
+		}
+
+		transport, ok := rt.RoundTripper.(*http.Transport)
+		if !ok {
+			return nil, fmt.Errorf("unexpected transport type %T",
+				rt.RoundTripper)
+		}
+
+		transport.Proxy = http.ProxyURL(proxyURI)
+	}
+
+	return client, nil
+}
@@ -0,0 +1,73 @@
+package eventline
+
+import (
+	"sync/atomic"
+	"time"
+
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/influx"
+)
+
+// QueryTimer records the latency of queries on hot paths, typically
+// subscription loading during event ingestion. Queries slower than the
+// threshold are logged and counted. If an Influx client is available, each
+// measure is also sent as a point.
+type QueryTimer struct {
+	Log       *log.Logger
+	Influx    *influx.Client
+	Threshold time.Duration
+
+	nbSlowQueries atomic.Int64
+}
+
+// GlobalQueryTimer is used by query functions shared by all connectors, e.g.
+// LoadSubscriptionsByMatchAttributes. Queries are not timed if it is nil.
+var GlobalQueryTimer *QueryTimer
+
+func NewQueryTimer(log *log.Logger, influxClient *influx.Client, threshold time.Duration) *QueryTimer {
+	return &QueryTimer{
+		Log:       log,
+		Influx:    influxClient,
+		Threshold: threshold,
+	}
+}
+
+func (t *QueryTimer) NbSlowQueries() int64 {
+	return t.nbSlowQueries.Load()
+}
+
+// Observe records the duration of a query started at startTime. It is meant
+// to be called with defer right before executing the query.
+func (t *QueryTimer) Observe(name string, tags map[string]string, startTime time.Time) {
+	if t == nil {
+		return
+	}
+
+	duration := time.Since(startTime)
+
+	slow := t.Threshold > 0 && duration >= t.Threshold
+	if slow {
+		n := t.nbSlowQueries.Add(1)
+
+		t.Log.Error("slow query %s: %v (%d slow queries so far)",
+			name, duration, n)
+	}
+
+	if t.Influx == nil {
+		return
+	}
+
+	pointTags := influx.Tags{"query": name}
+	for k, v := range tags {
+		pointTags[k] = v
+	}
+
+	fields := influx.Fields{
+		"duration":        duration.Microseconds(),
+		"slow":            slow,
+		"nb_slow_queries": t.nbSlowQueries.Load(),
+	}
+
+	t.Influx.EnqueuePoint(influx.NewPoint("eventline_queries", pointTags,
+		fields))
+}
@@ -0,0 +1,35 @@
+package eventline
+
+import (
+	"go.n16f.net/ejson"
+)
+
+// RawEventReplayingConnector is implemented by connectors able to run the raw
+// events they stored through high level event decoding again, e.g. to create
+// events of types which were not supported when the raw events were received.
+// ReplayRawEvents returns the number of events created.
+type RawEventReplayingConnector interface {
+	Connector
+
+	ReplayRawEvents(enames []string) (int, error)
+}
+
+type RawEventReplay struct {
+	Events []string `json:"events"`
+}
+
+type RawEventReplayResult struct {
+	NbEvents int `json:"nb_events"`
+}
+
+func (r *RawEventReplay) ValidateJSON(v *ejson.Validator) {
+	v.CheckArrayNotEmpty("events", r.Events)
+
+	v.WithChild("events", func() {
+		for i, ename := range r.Events {
+			// Replaying raw events into raw events would only duplicate them
+			v.Check(i, ename != "raw", "invalid_event",
+				"raw events cannot be replayed")
+		}
+	})
+}
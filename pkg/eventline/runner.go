@@ -7,6 +7,9 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
+	"net/url"
+	"os"
 	"path"
 	"strconv"
 	"sync"
@@ -16,6 +19,7 @@ import (
 	"go.n16f.net/ejson"
 	"go.n16f.net/log"
 	"go.n16f.net/program"
+	"go.n16f.net/service/pkg/influx"
 	"go.n16f.net/service/pkg/pg"
 )
 
@@ -23,12 +27,21 @@ var RunnerDefs = map[string]*RunnerDef{}
 
 type StepFailureError struct {
 	err error
+
+	// Set when the program executed for the step exited with a non-zero
+	// status or was killed by a signal.
+	ExitCode *int
+	Signal   *int
 }
 
 func NewStepFailureError(err error) *StepFailureError {
 	return &StepFailureError{err: err}
 }
 
+func NewStepExitFailureError(err error, exitCode, signal *int) *StepFailureError {
+	return &StepFailureError{err: err, ExitCode: exitCode, Signal: signal}
+}
+
 func (err *StepFailureError) Error() string {
 	return err.err.Error()
 }
@@ -41,6 +54,22 @@ type RunnerCfg interface {
 	ejson.Validatable
 }
 
+// CABundleRunnerCfg is implemented by runner configurations which can
+// reference a CA certificate bundle to inject in execution environments. The
+// bundle is added to the file set and common environment variables are set so
+// that most tools trust it.
+type CABundleRunnerCfg interface {
+	CABundleFilePath() string
+}
+
+const CABundleFileName = "ca-bundle.pem"
+
+var CABundleEnvironmentVariables = []string{
+	"SSL_CERT_FILE",
+	"REQUESTS_CA_BUNDLE",
+	"NODE_EXTRA_CA_CERTS",
+}
+
 type RunnerDef struct {
 	Name                  string
 	Cfg                   RunnerCfg
@@ -49,16 +78,24 @@ type RunnerDef struct {
 }
 
 type RunnerInitData struct {
-	Log *log.Logger
-	Pg  *pg.Client
+	Log    *log.Logger
+	Pg     *pg.Client
+	Influx *influx.Client // nil if Influx is not configured
+
+	LifecycleEvents *LifecycleEventBus
 
 	Def  *RunnerDef
 	Cfg  RunnerCfg
 	Data *RunnerData
 
 	TerminationChan chan<- Id
+	TerminationFunc func() // optional
 
 	RefreshInterval time.Duration
+	MaxOutputRate   int // bytes per second, 0 for no limit
+
+	OutboundProxyURI        *url.URL // nil if no proxy is configured
+	HTTPStepAllowedNetworks []*net.IPNet
 
 	StopChan <-chan struct{}
 	Wg       *sync.WaitGroup
@@ -84,9 +121,12 @@ type RunnerBehaviour interface {
 type Runner struct {
 	Log       *log.Logger
 	Pg        *pg.Client
+	Influx    *influx.Client // nil if Influx is not configured
 	Cfg       RunnerCfg
 	Behaviour RunnerBehaviour
 
+	LifecycleEvents *LifecycleEventBus
+
 	JobExecution     *JobExecution
 	StepExecutions   StepExecutions
 	ExecutionContext *ExecutionContext
@@ -105,8 +145,20 @@ type Runner struct {
 	jeId Id
 
 	refreshInterval time.Duration
+	maxOutputRate   int
+
+	outboundProxyURI        *url.URL
+	httpStepAllowedNetworks []*net.IPNet
+
+	// Values extracted from the responses of HTTP steps
+	httpStepOutputs map[string]interface{}
+
+	// Values of secret identity fields referenced in the environment, masked
+	// in step outputs
+	environmentSecrets [][]byte
 
 	terminationChan chan<- Id
+	terminationFunc func()
 
 	StopChan <-chan struct{}
 	Wg       *sync.WaitGroup
@@ -119,9 +171,12 @@ func NewRunner(data RunnerInitData) (*Runner, error) {
 	}
 
 	r := &Runner{
-		Log: data.Log,
-		Pg:  data.Pg,
-		Cfg: data.Cfg,
+		Log:    data.Log,
+		Pg:     data.Pg,
+		Influx: data.Influx,
+		Cfg:    data.Cfg,
+
+		LifecycleEvents: data.LifecycleEvents,
 
 		JobExecution:     data.Data.JobExecution,
 		StepExecutions:   data.Data.StepExecutions,
@@ -136,8 +191,15 @@ func NewRunner(data RunnerInitData) (*Runner, error) {
 		jeId: data.Data.JobExecution.Id,
 
 		refreshInterval: data.RefreshInterval,
+		maxOutputRate:   data.MaxOutputRate,
+
+		outboundProxyURI:        data.OutboundProxyURI,
+		httpStepAllowedNetworks: data.HTTPStepAllowedNetworks,
+
+		httpStepOutputs: make(map[string]interface{}),
 
 		terminationChan: data.TerminationChan,
+		terminationFunc: data.TerminationFunc,
 
 		StopChan: data.StopChan,
 		Wg:       data.Wg,
@@ -163,9 +225,31 @@ func NewRunner(data RunnerInitData) (*Runner, error) {
 
 	r.Environment["EVENTLINE_DIR"] = r.Behaviour.DirPath()
 
+	if cfg, ok := r.Cfg.(CABundleRunnerCfg); ok && cfg.CABundleFilePath() != "" {
+		if err := r.addCABundle(cfg.CABundleFilePath()); err != nil {
+			return nil, err
+		}
+	}
+
 	return r, nil
 }
 
+func (r *Runner) addCABundle(filePath string) error {
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return fmt.Errorf("cannot read ca bundle: %w", err)
+	}
+
+	r.FileSet.AddFile(CABundleFileName, data, 0644)
+
+	bundlePath := path.Join(r.Behaviour.DirPath(), CABundleFileName)
+	for _, name := range CABundleEnvironmentVariables {
+		r.Environment[name] = bundlePath
+	}
+
+	return nil
+}
+
 func (r *Runner) Start() error {
 	r.Wg.Add(1)
 	go r.main()
@@ -185,6 +269,10 @@ func (r *Runner) Stopping() bool {
 func (r *Runner) main() {
 	defer r.Wg.Done()
 
+	if r.terminationFunc != nil {
+		defer r.terminationFunc()
+	}
+
 	defer r.Behaviour.Terminate()
 
 	var cse *StepExecution
@@ -266,10 +354,13 @@ func (r *Runner) main() {
 
 	r.Log.Info("execution finished")
 
-	if _, err := r.updateJobExecutionSuccess(r.jeId, r.Scope); err != nil {
+	je, err := r.updateJobExecutionSuccess(r.jeId, r.Scope)
+	if err != nil {
 		r.Log.Error("cannot update job execution: %v", err)
 		return
 	}
+
+	r.JobExecution = je
 }
 
 func (r *Runner) mainRefresh(ctx context.Context, cancel context.CancelFunc) {
@@ -298,6 +389,16 @@ func (r *Runner) mainRefresh(ctx context.Context, cancel context.CancelFunc) {
 }
 
 func (r *Runner) initExecution(ctx context.Context) error {
+	for _, identity := range r.ExecutionContext.Identities {
+		if identity.Disabled {
+			return &DisabledIdentityError{Name: identity.Name}
+		}
+	}
+
+	if err := r.resolveEnvironmentReferences(); err != nil {
+		return err
+	}
+
 	if err := r.Behaviour.Init(ctx); err != nil {
 		switch {
 		case errors.Is(err, context.Canceled):
@@ -314,6 +415,55 @@ func (r *Runner) initExecution(ctx context.Context) error {
 	return nil
 }
 
+// resolveEnvironmentReferences replaces references in the environment values
+// of the job specification. Values set by parameters are never resolved since
+// they may come from event data.
+func (r *Runner) resolveEnvironmentReferences() error {
+	spec := r.JobExecution.JobSpec
+
+	resolver := EnvironmentResolver{
+		Project:    r.Project,
+		Identities: r.ExecutionContext.Identities,
+	}
+
+	parameterNames := make(map[string]struct{})
+	for _, param := range spec.Parameters {
+		if _, found := r.JobExecution.Parameters[param.Name]; found {
+			parameterNames[param.Environment] = struct{}{}
+		}
+	}
+
+	for name, value := range spec.Environment {
+		if _, found := parameterNames[name]; found {
+			continue
+		}
+
+		resolvedValue, err := resolver.Resolve(value)
+		if err != nil {
+			return fmt.Errorf("cannot resolve environment variable %q: %w",
+				name, err)
+		}
+
+		r.Environment[name] = resolvedValue
+	}
+
+	for _, secret := range resolver.Secrets {
+		if len(secret) < MinMaskedSecretLength {
+			if secret != "" {
+				r.Log.Info("secret identity value shorter than %d bytes "+
+					"referenced in the environment; it will not be masked "+
+					"in step outputs", MinMaskedSecretLength)
+			}
+
+			continue
+		}
+
+		r.environmentSecrets = append(r.environmentSecrets, []byte(secret))
+	}
+
+	return nil
+}
+
 func (r *Runner) executeStep(ctx context.Context, se *StepExecution, step *Step) error {
 	jeId := r.JobExecution.Id
 
@@ -325,13 +475,38 @@ func (r *Runner) executeStep(ctx context.Context, se *StepExecution, step *Step)
 	errChan := make(chan error, 2)
 	defer close(errChan)
 
+	// Both outputs share the same rate limit
+	var limiter *OutputRateLimiter
+	if r.maxOutputRate > 0 {
+		limiter = NewOutputRateLimiter(r.maxOutputRate)
+	}
+
+	// Decoders are stateful, each output needs its own
+	outputEncoding := r.JobExecution.JobSpec.OutputEncoding
+
+	stdoutDecoder, err := NewOutputDecoder(outputEncoding)
+	if err != nil {
+		return fmt.Errorf("invalid output encoding: %w", err)
+	}
+
+	stderrDecoder, err := NewOutputDecoder(outputEncoding)
+	if err != nil {
+		return fmt.Errorf("invalid output encoding: %w", err)
+	}
+
+	// Maskers are stateful too
+	stdoutMasker := NewOutputMasker(r.environmentSecrets)
+	stderrMasker := NewOutputMasker(r.environmentSecrets)
+
 	var wg sync.WaitGroup
 	wg.Add(2)
-	go r.readOutput(se, stdoutRead, "stdout", errChan, &wg)
-	go r.readOutput(se, stderrRead, "stderr", errChan, &wg)
+	go r.readOutput(se, stdoutRead, "stdout", stdoutDecoder, stdoutMasker,
+		limiter, errChan, &wg)
+	go r.readOutput(se, stderrRead, "stderr", stderrDecoder, stderrMasker,
+		limiter, errChan, &wg)
 
-	// Execute the step
-	err := r.Behaviour.ExecuteStep(ctx, se, step, stdoutWrite, stderrWrite)
+	// Execute the step, retrying it on failure if the step allows it
+	err = r.executeStepAttempts(ctx, se, step, stdoutWrite, stderrWrite)
 
 	// Close pipes and wait for output readers to terminate
 	stdoutRead.Close()
@@ -380,6 +555,9 @@ func (r *Runner) executeStep(ctx context.Context, se *StepExecution, step *Step)
 					se.Position, err)
 			}
 
+			r.Log.Info("step %d failed, continuing execution: %v",
+				se.Position, err)
+
 			return nil
 
 		default:
@@ -391,8 +569,14 @@ func (r *Runner) executeStep(ctx context.Context, se *StepExecution, step *Step)
 		}
 	}
 
-	// Mark the step as successful
-	_, _, err = r.updateStepExecutionSuccess(jeId, se.Id, r.Scope)
+	// Mark the step as successful; programs executed by runners always exit
+	// with status 0 on success.
+	var exitCode *int
+	if step.HTTP == nil {
+		exitCode = new(int)
+	}
+
+	_, _, err = r.updateStepExecutionSuccess(jeId, se.Id, exitCode, r.Scope)
 	if err != nil {
 		return fmt.Errorf("cannot update step %d: %w", se.Position, err)
 	}
@@ -400,12 +584,100 @@ func (r *Runner) executeStep(ctx context.Context, se *StepExecution, step *Step)
 	return nil
 }
 
-func (r *Runner) readOutput(se *StepExecution, output io.ReadCloser, name string, errChan chan<- error, wg *sync.WaitGroup) {
+// executeStepAttempts executes a step until it succeeds or the maximum number
+// of attempts is reached. Only step failures are retried: execution errors and
+// interruptions are returned immediately.
+//
+// The file set is uploaded once when the runner is initialized and is not
+// touched between attempts: every attempt uses the same files, including any
+// modification made by previous attempts.
+func (r *Runner) executeStepAttempts(ctx context.Context, se *StepExecution, step *Step, stdout, stderr io.WriteCloser) error {
+	nbAttempts := step.Retries + 1
+
+	for attempt := 1; ; attempt++ {
+		if nbAttempts > 1 {
+			r.Log.Info("executing step %d (attempt %d/%d)", se.Position,
+				attempt, nbAttempts)
+		}
+
+		err := r.executeStepAttempt(ctx, step, se, stdout, stderr)
+		if err == nil {
+			return nil
+		}
+
+		var stepFailureErr *StepFailureError
+		if !errors.As(err, &stepFailureErr) ||
+			errors.Is(err, context.Canceled) || ctx.Err() != nil {
+			return err
+		}
+
+		if attempt >= nbAttempts {
+			if nbAttempts > 1 {
+				err = NewStepExitFailureError(
+					fmt.Errorf("step failed after %d attempts: %w",
+						attempt, stepFailureErr.err),
+					stepFailureErr.ExitCode, stepFailureErr.Signal)
+			}
+
+			return err
+		}
+
+		delay := step.RetryDelayDuration(attempt)
+
+		r.Log.Info("step %d failed (attempt %d/%d), retrying in %v: %v",
+			se.Position, attempt, nbAttempts, delay, err)
+
+		timer := time.NewTimer(delay)
+
+		select {
+		case <-timer.C:
+
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		}
+	}
+}
+
+func (r *Runner) executeStepAttempt(ctx context.Context, step *Step, se *StepExecution, stdout, stderr io.WriteCloser) error {
+	// Runners abort the step when the context is done, whether the job
+	// execution was aborted or the step timed out.
+	stepCtx := ctx
+
+	if step.Timeout > 0 {
+		var cancel context.CancelFunc
+		stepCtx, cancel = context.WithTimeout(ctx,
+			time.Duration(step.Timeout)*time.Second)
+		defer cancel()
+	}
+
+	// HTTP steps do not involve the runner behaviour
+	var err error
+	if step.HTTP != nil {
+		err = r.executeHTTPStep(stepCtx, step.HTTP, stdout, stderr)
+	} else {
+		err = r.Behaviour.ExecuteStep(stepCtx, se, step, stdout, stderr)
+	}
+
+	// The error returned by runners for an aborted step depends on the
+	// runner; we only know that the step timed out if the step context
+	// expired while the parent context did not.
+	if err != nil && errors.Is(stepCtx.Err(), context.DeadlineExceeded) &&
+		ctx.Err() == nil {
+		err = NewStepFailureError(fmt.Errorf("step timed out after %v",
+			time.Duration(step.Timeout)*time.Second))
+	}
+
+	return err
+}
+
+func (r *Runner) readOutput(se *StepExecution, output io.ReadCloser, name string, decoder *OutputDecoder, masker *OutputMasker, limiter *OutputRateLimiter, errChan chan<- error, wg *sync.WaitGroup) {
 	defer wg.Done()
 
 	bufferedOutput := bufio.NewReader(output)
 	var outputSize int
 	var line []byte
+	var lineStart int
 
 	lastUpdate := time.Now()
 	updatePeriod := time.Duration(1 * time.Second)
@@ -429,14 +701,27 @@ func (r *Runner) readOutput(se *StepExecution, output io.ReadCloser, name string
 			line = append(line, '\n')
 		}
 
+		isEOF := errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe)
+
+		if lineStart < len(line) || isEOF {
+			filteredLine := decoder.Decode(line[lineStart:])
+			filteredLine = masker.Mask(filteredLine)
+			if isEOF {
+				filteredLine = append(filteredLine, masker.Flush()...)
+			}
+			if limiter != nil && len(filteredLine) > 0 {
+				filteredLine = limiter.Filter(filteredLine)
+			}
+			line = append(line[:lineStart], filteredLine...)
+		}
+		lineStart = len(line)
+
 		// There is no point in updating se.Output because we are not going to
 		// read it in the runner, so we may as well avoid allocating and
 		// copying data. This only works because se.Update does not modify the
 		// output column, so it will not be erased when updating the step
 		// execution later.
 
-		isEOF := errors.Is(err, io.EOF) || errors.Is(err, io.ErrClosedPipe)
-
 		if len(line) > 0 && (time.Since(lastUpdate) >= updatePeriod || isEOF) {
 			err = r.UpdateStepExecutionOutput(se, line)
 			if err != nil {
@@ -447,6 +732,7 @@ func (r *Runner) readOutput(se *StepExecution, output io.ReadCloser, name string
 
 			outputSize += len(line)
 			line = nil
+			lineStart = 0
 
 			lastUpdate = time.Now()
 		}
@@ -651,6 +937,8 @@ func (r *Runner) updateJobExecutionSuccess(jeId Id, scope Scope) (*JobExecution,
 		return nil, err
 	}
 
+	r.publishJobExecutionFinished(&je)
+
 	return &je, nil
 }
 
@@ -701,6 +989,8 @@ func (r *Runner) updateJobExecutionAbortion(jeId Id, scope Scope) (*JobExecution
 		return nil, nil, err
 	}
 
+	r.publishJobExecutionFinished(&je)
+
 	return &je, ses, nil
 }
 
@@ -752,6 +1042,8 @@ func (r *Runner) updateJobExecutionFailure(jeId Id, jeErr error, scope Scope) (*
 		return nil, nil, err
 	}
 
+	r.publishJobExecutionFinished(&je)
+
 	return &je, ses, nil
 }
 
@@ -763,6 +1055,8 @@ func (r *Runner) updateStepExecutionStart(jeId, seId Id, scope Scope) (*JobExecu
 		se.StartTime = &now
 		se.FailureMessage = ""
 		se.Output = ""
+		se.ExitCode = nil
+		se.Signal = nil
 	}, scope)
 }
 
@@ -775,12 +1069,13 @@ func (r *Runner) updateStepExecutionAborted(jeId, seId Id, scope Scope) (*JobExe
 	}, scope)
 }
 
-func (r *Runner) updateStepExecutionSuccess(jeId, seId Id, scope Scope) (*JobExecution, *StepExecution, error) {
+func (r *Runner) updateStepExecutionSuccess(jeId, seId Id, exitCode *int, scope Scope) (*JobExecution, *StepExecution, error) {
 	return r.updateStepExecution(jeId, seId, func(se *StepExecution) {
 		now := time.Now().UTC()
 
 		se.Status = StepExecutionStatusSuccessful
 		se.EndTime = &now
+		se.ExitCode = exitCode
 	}, scope)
 }
 
@@ -791,6 +1086,12 @@ func (r *Runner) updateStepExecutionFailure(jeId, seId Id, err error, scope Scop
 		se.Status = StepExecutionStatusFailed
 		se.EndTime = &now
 		se.FailureMessage = err.Error()
+
+		var stepFailureErr *StepFailureError
+		if errors.As(err, &stepFailureErr) {
+			se.ExitCode = stepFailureErr.ExitCode
+			se.Signal = stepFailureErr.Signal
+		}
 	}, scope)
 }
 
@@ -823,9 +1124,22 @@ func (r *Runner) updateStepExecution(jeId, seId Id, fn func(*StepExecution), sco
 		return nil, nil, err
 	}
 
+	eventType := LifecycleEventStepStarted
+	if se.Finished() {
+		eventType = LifecycleEventStepFinished
+	}
+
+	r.LifecycleEvents.Publish(NewStepExecutionLifecycleEvent(eventType,
+		&je, &se))
+
 	return &je, &se, nil
 }
 
+func (r *Runner) publishJobExecutionFinished(je *JobExecution) {
+	r.LifecycleEvents.Publish(NewJobExecutionLifecycleEvent(
+		LifecycleEventJobExecutionFinished, je))
+}
+
 func (r *Runner) UpdateStepExecutionOutput(se *StepExecution, data []byte) error {
 	return r.Pg.WithConn(func(conn pg.Conn) (err error) {
 		err = se.UpdateOutput(conn, data)
@@ -0,0 +1,93 @@
+package eventline
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+var ErrMissingSecretBackend = errors.New("missing secret backend")
+
+type UnknownSecretError struct {
+	Ref string
+}
+
+func (err UnknownSecretError) Error() string {
+	return fmt.Sprintf("unknown secret %q", err.Ref)
+}
+
+// SecretBackend stores identity data outside of the database. Identities
+// stored with a backend only contain a reference to their data, which is
+// only fetched when the data are actually needed.
+//
+// References designate a specific version of the secret: writing data
+// returns a new reference and never invalidates previous ones, so that
+// rolling back the transaction in which an identity was updated leaves it
+// with valid data.
+type SecretBackend interface {
+	Name() string
+
+	WriteIdentitySecret(ctx context.Context, id Id, data []byte) (string, error)
+	ReadSecret(ctx context.Context, ref string) ([]byte, error)
+
+	// DeleteSecret deletes all versions of a secret. It must only be called
+	// once the identity referencing the secret has been deleted or moved and
+	// the transaction has been committed; see InsertDeletedIdentitySecret.
+	DeleteSecret(ctx context.Context, ref string) error
+}
+
+// GlobalSecretBackend is the backend used to store identity data outside of
+// the database, or nil if there is none. Identity data are only written to
+// the backend if UseGlobalSecretBackend is true; otherwise the backend is
+// used to read identities which have not been moved back to the database
+// yet.
+var GlobalSecretBackend SecretBackend
+var UseGlobalSecretBackend bool
+
+// InsertDeletedIdentitySecret records the secret of a deleted identity so
+// that it is deleted from the backend once the transaction has been
+// committed.
+func InsertDeletedIdentitySecret(conn pg.Conn, ref string) error {
+	now := time.Now().UTC()
+
+	query := `
+INSERT INTO deleted_identity_secrets (secret_ref, deletion_time)
+  VALUES ($1, $2)
+  ON CONFLICT (secret_ref) DO NOTHING
+`
+	return pg.Exec(conn, query, ref, now)
+}
+
+func LoadDeletedIdentitySecretForProcessing(conn pg.Conn) (string, error) {
+	ctx := context.Background()
+
+	query := `
+SELECT secret_ref
+  FROM deleted_identity_secrets
+  ORDER BY deletion_time
+  LIMIT 1
+  FOR UPDATE SKIP LOCKED
+`
+	var ref string
+
+	err := conn.QueryRow(ctx, query).Scan(&ref)
+	if errors.Is(err, pgx.ErrNoRows) {
+		return "", nil
+	} else if err != nil {
+		return "", err
+	}
+
+	return ref, nil
+}
+
+func DeleteDeletedIdentitySecret(conn pg.Conn, ref string) error {
+	query := `
+DELETE FROM deleted_identity_secrets
+  WHERE secret_ref = $1
+`
+	return pg.Exec(conn, query, ref)
+}
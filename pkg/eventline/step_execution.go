@@ -45,10 +45,28 @@ type StepExecution struct {
 	EndTime        *time.Time          `json:"end_time,omitempty"`
 	FailureMessage string              `json:"failure_message,omitempty"`
 	Output         string              `json:"output,omitempty"`
+	ExitCode       *int                `json:"exit_code,omitempty"`
+	Signal         *int                `json:"signal,omitempty"`
 }
 
 type StepExecutions []*StepExecution
 
+// StepExecutionSummary is the result of a step execution. Exit codes and
+// signals are only available for steps executing a program. The output is
+// only included on request.
+type StepExecutionSummary struct {
+	Position       int                 `json:"position"`
+	Label          string              `json:"label,omitempty"`
+	Status         StepExecutionStatus `json:"status"`
+	FailureMessage string              `json:"failure_message,omitempty"`
+	ExitCode       *int                `json:"exit_code,omitempty"`
+	Signal         *int                `json:"signal,omitempty"`
+	Duration       *float64            `json:"duration,omitempty"` // seconds
+	Output         *string             `json:"output,omitempty"`
+}
+
+type StepExecutionSummaries []*StepExecutionSummary
+
 func (se *StepExecution) Finished() bool {
 	return se.Status != StepExecutionStatusCreated &&
 		se.Status != StepExecutionStatusStarted
@@ -63,10 +81,31 @@ func (se *StepExecution) Duration() *time.Duration {
 	return &d
 }
 
+func (se *StepExecution) Summary(step *Step) *StepExecutionSummary {
+	summary := StepExecutionSummary{
+		Position:       se.Position,
+		Status:         se.Status,
+		FailureMessage: se.FailureMessage,
+		ExitCode:       se.ExitCode,
+		Signal:         se.Signal,
+	}
+
+	if step != nil {
+		summary.Label = step.Label
+	}
+
+	if d := se.Duration(); d != nil {
+		seconds := d.Seconds()
+		summary.Duration = &seconds
+	}
+
+	return &summary
+}
+
 func (se *StepExecution) Load(conn pg.Conn, id Id, scope Scope) error {
 	query := fmt.Sprintf(`
 SELECT id, project_id, job_execution_id, position, status,
-       start_time, end_time, failure_message, output
+       start_time, end_time, failure_message, output, exit_code, signal
   FROM step_executions
   WHERE %s AND id = $1;
 `, scope.SQLCondition())
@@ -82,7 +121,7 @@ SELECT id, project_id, job_execution_id, position, status,
 func (ses *StepExecutions) LoadByJobExecutionId(conn pg.Conn, jeId Id) error {
 	query := `
 SELECT id, project_id, job_execution_id, position, status,
-       start_time, end_time, failure_message, output
+       start_time, end_time, failure_message, output, exit_code, signal
   FROM step_executions
   WHERE job_execution_id = $1
   ORDER BY position;
@@ -94,7 +133,7 @@ func (ses *StepExecutions) LoadByJobExecutionIdWithTruncatedOutput(conn pg.Conn,
 	query := `
 SELECT id, project_id, job_execution_id, position, status,
        start_time, end_time, failure_message,
-       truncate_string(output, $2, $3)
+       truncate_string(output, $2, $3), exit_code, signal
   FROM step_executions
   WHERE job_execution_id = $1
   ORDER BY position;
@@ -105,7 +144,7 @@ SELECT id, project_id, job_execution_id, position, status,
 func (ses *StepExecutions) LoadByJobExecutionIdForUpdate(conn pg.Conn, jeId Id) error {
 	query := `
 SELECT id, project_id, job_execution_id, position, status,
-       start_time, end_time, failure_message, output
+       start_time, end_time, failure_message, output, exit_code, signal
   FROM step_executions
   WHERE job_execution_id = $1
   ORDER BY position
@@ -118,14 +157,15 @@ func (se *StepExecution) Insert(conn pg.Conn) error {
 	query := `
 INSERT INTO step_executions
     (id, project_id, job_execution_id, position, status, start_time,
-     end_time, failure_message, output)
+     end_time, failure_message, output, exit_code, signal)
   VALUES
     ($1, $2, $3, $4, $5,
-     $6, $7, $8, $9);
+     $6, $7, $8, $9, $10, $11);
 `
 	return pg.Exec(conn, query,
 		se.Id, se.ProjectId, se.JobExecutionId, se.Position, se.Status,
-		se.StartTime, se.EndTime, se.FailureMessage, se.Output)
+		se.StartTime, se.EndTime, se.FailureMessage, se.Output, se.ExitCode,
+		se.Signal)
 }
 
 func (se *StepExecution) Update(conn pg.Conn) error {
@@ -134,11 +174,14 @@ UPDATE step_executions SET
     status = $2,
     start_time = $3,
     end_time = $4,
-    failure_message = $5
+    failure_message = $5,
+    exit_code = $6,
+    signal = $7
   WHERE id = $1;
 `
 	return pg.Exec(conn, query,
-		se.Id, se.Status, se.StartTime, se.EndTime, se.FailureMessage)
+		se.Id, se.Status, se.StartTime, se.EndTime, se.FailureMessage,
+		se.ExitCode, se.Signal)
 }
 
 func (se *StepExecution) UpdateOutput(conn pg.Conn, data []byte) error {
@@ -162,7 +205,8 @@ UPDATE step_executions SET
 
 func (se *StepExecution) FromRow(row pgx.Row) error {
 	return row.Scan(&se.Id, &se.ProjectId, &se.JobExecutionId, &se.Position,
-		&se.Status, &se.StartTime, &se.EndTime, &se.FailureMessage, &se.Output)
+		&se.Status, &se.StartTime, &se.EndTime, &se.FailureMessage, &se.Output,
+		&se.ExitCode, &se.Signal)
 }
 
 func (ses *StepExecutions) AddFromRow(row pgx.Row) error {
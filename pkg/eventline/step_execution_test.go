@@ -0,0 +1,44 @@
+package eventline
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepExecutionSummary(t *testing.T) {
+	assert := assert.New(t)
+
+	startTime := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	endTime := startTime.Add(1500 * time.Millisecond)
+	exitCode := 2
+
+	se := StepExecution{
+		Position:       1,
+		Status:         StepExecutionStatusFailed,
+		StartTime:      &startTime,
+		EndTime:        &endTime,
+		FailureMessage: "program exited with status 2",
+		ExitCode:       &exitCode,
+	}
+
+	summary := se.Summary(&Step{Label: "build"})
+	assert.Equal(1, summary.Position)
+	assert.Equal("build", summary.Label)
+	assert.Equal(StepExecutionStatusFailed, summary.Status)
+	if assert.NotNil(summary.ExitCode) {
+		assert.Equal(2, *summary.ExitCode)
+	}
+	assert.Nil(summary.Signal)
+	if assert.NotNil(summary.Duration) {
+		assert.InDelta(1.5, *summary.Duration, 0.001)
+	}
+
+	se = StepExecution{Position: 2, Status: StepExecutionStatusCreated}
+
+	summary = se.Summary(nil)
+	assert.Equal("", summary.Label)
+	assert.Nil(summary.ExitCode)
+	assert.Nil(summary.Duration)
+}
@@ -0,0 +1,446 @@
+package eventline
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"text/template"
+	"time"
+
+	"go.n16f.net/ejson"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/shttp"
+)
+
+const (
+	DefaultHTTPStepTimeout    = 30               // seconds
+	HTTPStepConnectionTimeout = 10               // seconds
+	MaxHTTPStepResponseSize   = 10 * 1024 * 1024 // bytes
+)
+
+var ErrForbiddenHTTPStepDestination = errors.New("forbidden destination")
+
+var HTTPStepMethodValues = []string{
+	"GET", "HEAD", "POST", "PUT", "PATCH", "DELETE", "OPTIONS",
+}
+
+// HTTPIdentityData is implemented by identities which can be used to
+// authenticate the requests sent by HTTP steps.
+type HTTPIdentityData interface {
+	IdentityData
+
+	AuthenticateHTTPRequest(*http.Request)
+}
+
+// StepHTTP is a step executed directly by Eventline by sending a HTTP
+// request, without involving the runner of the job. The URI, headers and body
+// are templates rendered with the execution context and the values extracted
+// by previous HTTP steps.
+type StepHTTP struct {
+	Method             string            `json:"method,omitempty"`
+	URI                string            `json:"uri"`
+	Headers            map[string]string `json:"headers,omitempty"`
+	Body               string            `json:"body,omitempty"`
+	Identity           string            `json:"identity,omitempty"`
+	SuccessStatusCodes []int             `json:"success_status_codes,omitempty"`
+	Timeout            int               `json:"timeout,omitempty"` // seconds
+
+	// Values to extract from the JSON response body, indexed by name. Each
+	// value is a JSON pointer.
+	Extract map[string]string `json:"extract,omitempty"`
+}
+
+type HTTPStepTemplateData struct {
+	Event      interface{}
+	Parameters map[string]interface{}
+	Identities map[string]interface{}
+	Outputs    map[string]interface{}
+}
+
+func (s *StepHTTP) ValidateJSON(v *ejson.Validator) {
+	if s.Method != "" {
+		v.CheckStringValue("method", s.Method, HTTPStepMethodValues)
+	}
+
+	if v.CheckStringNotEmpty("uri", s.URI) {
+		v.Check("uri", validTemplate(s.URI), "invalid_template",
+			"invalid template")
+	}
+
+	v.WithChild("headers", func() {
+		for name, value := range s.Headers {
+			v.Check(name, validTemplate(value), "invalid_template",
+				"invalid template")
+		}
+	})
+
+	if s.Body != "" {
+		v.Check("body", validTemplate(s.Body), "invalid_template",
+			"invalid template")
+	}
+
+	if s.Identity != "" {
+		CheckName(v, "identity", s.Identity)
+	}
+
+	v.WithChild("success_status_codes", func() {
+		for i, code := range s.SuccessStatusCodes {
+			v.CheckIntMinMax(i, code, 100, 599)
+		}
+	})
+
+	if s.Timeout != 0 {
+		v.CheckIntMin("timeout", s.Timeout, 1)
+	}
+
+	v.WithChild("extract", func() {
+		for name, value := range s.Extract {
+			CheckName(v, name, name)
+
+			var pointer ejson.Pointer
+			v.Check(name, pointer.Parse(value) == nil, "invalid_json_pointer",
+				"invalid json pointer")
+		}
+	})
+}
+
+func (s *StepHTTP) MethodOrDefault() string {
+	if s.Method == "" {
+		return "GET"
+	}
+
+	return s.Method
+}
+
+func (s *StepHTTP) IsSuccessStatusCode(code int) bool {
+	if len(s.SuccessStatusCodes) == 0 {
+		return code >= 200 && code < 300
+	}
+
+	for _, successCode := range s.SuccessStatusCodes {
+		if code == successCode {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ExtractValues returns the values referenced by the extract member of the
+// step from a JSON response body.
+func (s *StepHTTP) ExtractValues(body []byte) (map[string]interface{}, error) {
+	values := make(map[string]interface{})
+
+	if len(s.Extract) == 0 {
+		return values, nil
+	}
+
+	d := json.NewDecoder(bytes.NewReader(body))
+	d.UseNumber()
+
+	var value interface{}
+	if err := d.Decode(&value); err != nil {
+		return nil, fmt.Errorf("cannot decode response body: %w", err)
+	}
+
+	for name, pointerString := range s.Extract {
+		var pointer ejson.Pointer
+		if err := pointer.Parse(pointerString); err != nil {
+			return nil, fmt.Errorf("invalid json pointer %q: %w",
+				pointerString, err)
+		}
+
+		extractedValue := pointer.Find(value)
+		if extractedValue == nil {
+			return nil, fmt.Errorf("no value found at %q in response body",
+				pointerString)
+		}
+
+		values[name] = extractedValue
+	}
+
+	return values, nil
+}
+
+func (r *Runner) executeHTTPStep(ctx context.Context, s *StepHTTP, stdout, stderr io.Writer) error {
+	templateData, err := r.httpStepTemplateData()
+	if err != nil {
+		return err
+	}
+
+	render := func(name, s string) (string, error) {
+		value, err := renderTemplate(s, templateData)
+		if err != nil {
+			return "", NewStepFailureError(fmt.Errorf("cannot render %s: %w",
+				name, err))
+		}
+
+		return value, nil
+	}
+
+	uri, err := render("uri", s.URI)
+	if err != nil {
+		return err
+	}
+
+	body, err := render("body", s.Body)
+	if err != nil {
+		return err
+	}
+
+	timeout := s.Timeout
+	if timeout == 0 {
+		timeout = DefaultHTTPStepTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(timeout)*time.Second)
+	defer cancel()
+
+	method := s.MethodOrDefault()
+
+	req, err := http.NewRequestWithContext(ctx, method, uri,
+		strings.NewReader(body))
+	if err != nil {
+		return NewStepFailureError(fmt.Errorf("cannot create request: %w",
+			err))
+	}
+
+	for name, value := range s.Headers {
+		value, err := render("header "+name, value)
+		if err != nil {
+			return err
+		}
+
+		req.Header.Set(name, value)
+	}
+
+	if s.Identity != "" {
+		identity, found := r.ExecutionContext.Identities[s.Identity]
+		if !found {
+			return fmt.Errorf("missing identity %q", s.Identity)
+		}
+
+		idata, ok := identity.Data.(HTTPIdentityData)
+		if !ok {
+			return NewStepFailureError(fmt.Errorf("identity %q cannot be "+
+				"used to authenticate http requests", s.Identity))
+		}
+
+		idata.AuthenticateHTTPRequest(req)
+	}
+
+	fmt.Fprintf(stderr, "%s %s\n", method, uri)
+
+	client, err := NewHTTPStepClient(r.Log, timeout, r.outboundProxyURI,
+		r.httpStepAllowedNetworks)
+	if err != nil {
+		return fmt.Errorf("cannot create http client: %w", err)
+	}
+	defer client.CloseConnections()
+
+	res, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("request timed out after %ds", timeout)
+		}
+
+		return NewStepFailureError(err)
+	}
+	defer res.Body.Close()
+
+	fmt.Fprintf(stderr, "%s\n", res.Status)
+
+	resBody, err := io.ReadAll(io.LimitReader(res.Body,
+		MaxHTTPStepResponseSize))
+	if err != nil {
+		return NewStepFailureError(fmt.Errorf("cannot read response "+
+			"body: %w", err))
+	}
+
+	if len(resBody) > 0 {
+		stdout.Write(resBody)
+		if resBody[len(resBody)-1] != '\n' {
+			stdout.Write([]byte{'\n'})
+		}
+	}
+
+	if !s.IsSuccessStatusCode(res.StatusCode) {
+		return NewStepFailureError(fmt.Errorf("request failed with "+
+			"status %d", res.StatusCode))
+	}
+
+	values, err := s.ExtractValues(resBody)
+	if err != nil {
+		return NewStepFailureError(err)
+	}
+
+	for name, value := range values {
+		r.httpStepOutputs[name] = value
+	}
+
+	return nil
+}
+
+// NewHTTPStepClient returns a client for the requests of HTTP steps. Unless
+// they are part of allowed networks, connections to loopback, private,
+// link-local, multicast and unspecified addresses are refused, so that jobs
+// cannot reach internal services. The check is performed on the address
+// being connected to, after name resolution.
+//
+// If a proxy is used, the only connections are the ones to the proxy, which is
+// then responsible for filtering destinations.
+func NewHTTPStepClient(logger *log.Logger, timeout int, proxyURI *url.URL, allowedNetworks []*net.IPNet) (*shttp.Client, error) {
+	connectionTimeout := HTTPStepConnectionTimeout
+
+	client, err := NewHTTPClient(shttp.ClientCfg{
+		Log:               logger,
+		ConnectionTimeout: &connectionTimeout,
+		RequestTimeout:    &timeout,
+	}, proxyURI)
+	if err != nil {
+		return nil, err
+	}
+
+	if proxyURI != nil {
+		return client, nil
+	}
+
+	rt, ok := client.Client.Transport.(*shttp.RoundTripper)
+	if !ok {
+		return nil, fmt.Errorf("unexpected round tripper type %T",
+			client.Client.Transport)
+	}
+
+	transport, ok := rt.RoundTripper.(*http.Transport)
+	if !ok {
+		return nil, fmt.Errorf("unexpected transport type %T",
+			rt.RoundTripper)
+	}
+
+	dialer := net.Dialer{
+		Timeout:   time.Duration(connectionTimeout) * time.Second,
+		KeepAlive: 30 * time.Second,
+		Control: func(network, address string, c syscall.RawConn) error {
+			return CheckHTTPStepDestination(address, allowedNetworks)
+		},
+	}
+
+	// Without a custom TLS dialer, the transport uses DialContext for TLS
+	// connections too.
+	transport.DialContext = dialer.DialContext
+	transport.DialTLSContext = nil
+
+	return client, nil
+}
+
+func CheckHTTPStepDestination(address string, allowedNetworks []*net.IPNet) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return fmt.Errorf("invalid address %q: %w", address, err)
+	}
+
+	ip := net.ParseIP(host)
+	if ip == nil {
+		return fmt.Errorf("invalid ip address %q", host)
+	}
+
+	for _, network := range allowedNetworks {
+		if network.Contains(ip) {
+			return nil
+		}
+	}
+
+	if ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() ||
+		ip.IsLinkLocalMulticast() || ip.IsInterfaceLocalMulticast() ||
+		ip.IsMulticast() || ip.IsUnspecified() {
+		return fmt.Errorf("%w %s", ErrForbiddenHTTPStepDestination, ip)
+	}
+
+	return nil
+}
+
+func (r *Runner) httpStepTemplateData() (*HTTPStepTemplateData, error) {
+	ectx := r.ExecutionContext
+
+	data := HTTPStepTemplateData{
+		Parameters: ectx.Parameters,
+		Identities: make(map[string]interface{}),
+		Outputs:    r.httpStepOutputs,
+	}
+
+	if ectx.Event != nil {
+		value, err := jsonValue(ectx.Event.Data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode event data: %w", err)
+		}
+
+		data.Event = value
+	}
+
+	for name, identity := range ectx.Identities {
+		value, err := jsonValue(identity.Data)
+		if err != nil {
+			return nil, fmt.Errorf("cannot encode data of identity %q: %w",
+				name, err)
+		}
+
+		data.Identities[name] = value
+	}
+
+	return &data, nil
+}
+
+func validTemplate(s string) bool {
+	_, err := newHTTPStepTemplate(s)
+	return err == nil
+}
+
+func renderTemplate(s string, data interface{}) (string, error) {
+	tpl, err := newHTTPStepTemplate(s)
+	if err != nil {
+		return "", err
+	}
+
+	var buf bytes.Buffer
+	if err := tpl.Execute(&buf, data); err != nil {
+		return "", err
+	}
+
+	return buf.String(), nil
+}
+
+func newHTTPStepTemplate(s string) (*template.Template, error) {
+	funcMap := template.FuncMap{
+		"json": func(value interface{}) (string, error) {
+			data, err := json.Marshal(value)
+			return string(data), err
+		},
+	}
+
+	return template.New("").Option("missingkey=error").Funcs(funcMap).Parse(s)
+}
+
+func jsonValue(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+
+	var v interface{}
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+
+	return v, nil
+}
@@ -0,0 +1,106 @@
+package eventline
+
+import (
+	"encoding/json"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStepHTTPSuccessStatusCodes(t *testing.T) {
+	assert := assert.New(t)
+
+	var s StepHTTP
+	assert.True(s.IsSuccessStatusCode(200))
+	assert.True(s.IsSuccessStatusCode(204))
+	assert.False(s.IsSuccessStatusCode(302))
+	assert.False(s.IsSuccessStatusCode(404))
+
+	s.SuccessStatusCodes = []int{201, 404}
+	assert.False(s.IsSuccessStatusCode(200))
+	assert.True(s.IsSuccessStatusCode(201))
+	assert.True(s.IsSuccessStatusCode(404))
+}
+
+func TestStepHTTPExtractValues(t *testing.T) {
+	assert := assert.New(t)
+
+	s := StepHTTP{
+		Extract: map[string]string{
+			"id":   "/data/id",
+			"name": "/data/tags/1",
+		},
+	}
+
+	body := []byte(`{"data": {"id": 42, "tags": ["a", "b"]}}`)
+
+	values, err := s.ExtractValues(body)
+	if assert.NoError(err) {
+		assert.Equal(json.Number("42"), values["id"])
+		assert.Equal("b", values["name"])
+	}
+
+	_, err = s.ExtractValues([]byte(`{"data": {}}`))
+	assert.Error(err)
+
+	_, err = s.ExtractValues([]byte(`not json`))
+	assert.Error(err)
+}
+
+func TestStepHTTPTemplates(t *testing.T) {
+	assert := assert.New(t)
+
+	data := HTTPStepTemplateData{
+		Event:   map[string]interface{}{"ref": "main"},
+		Outputs: map[string]interface{}{"id": json.Number("42")},
+	}
+
+	s, err := renderTemplate("/refs/{{.Event.ref}}/{{.Outputs.id}}", &data)
+	if assert.NoError(err) {
+		assert.Equal("/refs/main/42", s)
+	}
+
+	s, err = renderTemplate(`{"ref": {{json .Event.ref}}}`, &data)
+	if assert.NoError(err) {
+		assert.Equal(`{"ref": "main"}`, s)
+	}
+
+	_, err = renderTemplate("{{.Outputs.unknown}}", &data)
+	assert.Error(err)
+
+	assert.False(validTemplate("{{.Event"))
+}
+
+func TestCheckHTTPStepDestination(t *testing.T) {
+	assert := assert.New(t)
+
+	_, allowedNetwork, err := net.ParseCIDR("10.1.0.0/16")
+	if !assert.NoError(err) {
+		return
+	}
+
+	allowedNetworks := []*net.IPNet{allowedNetwork}
+
+	check := func(address string) error {
+		return CheckHTTPStepDestination(address, allowedNetworks)
+	}
+
+	assert.NoError(check("93.184.216.34:443"))
+	assert.NoError(check("[2606:2800:220:1::]:443"))
+	assert.NoError(check("10.1.2.3:80"))
+
+	for _, address := range []string{
+		"127.0.0.1:80",
+		"[::1]:80",
+		"10.2.0.1:80",
+		"192.168.1.1:80",
+		"169.254.169.254:80",
+		"[fe80::1]:80",
+		"[fd00::1]:80",
+		"0.0.0.0:80",
+	} {
+		assert.ErrorIs(check(address), ErrForbiddenHTTPStepDestination,
+			address)
+	}
+}
@@ -159,15 +159,18 @@ func (s *Subscription) Insert(conn pg.Conn) error {
 	query := `
 INSERT INTO subscriptions
     (id, project_id, job_id, identity_id, connector, event, parameters,
-     creation_time, status, update_delay, last_update_time, next_update_time)
+     creation_time, status, update_delay, last_update_time, next_update_time,
+     match_attributes)
   VALUES
     ($1, $2, $3, $4, $5, $6, $7,
-     $8, $9, $10, $11, $12);
+     $8, $9, $10, $11, $12,
+     $13);
 `
 	return pg.Exec(conn, query,
 		s.Id, s.ProjectId, s.JobId, s.IdentityId,
 		s.Connector, s.Event, s.Parameters, s.CreationTime, s.Status,
-		s.UpdateDelay, s.LastUpdateTime, s.NextUpdateTime)
+		s.UpdateDelay, s.LastUpdateTime, s.NextUpdateTime,
+		SubscriptionMatchAttributes(s.Parameters))
 }
 
 func (s *Subscription) Update(conn pg.Conn) error {
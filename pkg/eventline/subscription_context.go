@@ -33,6 +33,10 @@ func (sctx *SubscriptionContext) Load(conn pg.Conn, subscription *Subscription)
 			return fmt.Errorf("cannot load identity: %w", err)
 		}
 
+		if err := identity.ResolveData(); err != nil {
+			return err
+		}
+
 		now := time.Now().UTC()
 		identity.LastUseTime = &now
 
@@ -0,0 +1,96 @@
+package eventline
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+
+	"github.com/exograd/eventline/pkg/utils"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/pg"
+	"gopkg.in/yaml.v3"
+)
+
+// SubscriptionExport is the portable representation of a subscription. Since
+// subscriptions are derived from job triggers, the stable key of a
+// subscription is the name of its job.
+type SubscriptionExport struct {
+	Job     string   `json:"job"`
+	Trigger *Trigger `json:"trigger"`
+}
+
+type SubscriptionExports []*SubscriptionExport
+
+type SubscriptionImportStatus string
+
+const (
+	SubscriptionImportStatusCreated   SubscriptionImportStatus = "created"
+	SubscriptionImportStatusUpdated   SubscriptionImportStatus = "updated"
+	SubscriptionImportStatusUnchanged SubscriptionImportStatus = "unchanged"
+	SubscriptionImportStatusFailed    SubscriptionImportStatus = "failed"
+)
+
+type SubscriptionImportResult struct {
+	Job              string                   `json:"job"`
+	Status           SubscriptionImportStatus `json:"status"`
+	Error            string                   `json:"error,omitempty"`
+	ValidationErrors ejson.ValidationErrors   `json:"validation_errors,omitempty"`
+}
+
+type SubscriptionImportResults []*SubscriptionImportResult
+
+func (e *SubscriptionExport) ValidateJSON(v *ejson.Validator) {
+	CheckName(v, "job", e.Job)
+	v.CheckObject("trigger", e.Trigger)
+}
+
+func (es *SubscriptionExports) ParseYAML(data []byte) error {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+
+	var yamlValue interface{}
+	if err := decoder.Decode(&yamlValue); err != nil {
+		return err
+	}
+
+	jsonValue, err := utils.YAMLValueToJSONValue(yamlValue)
+	if err != nil {
+		return fmt.Errorf("invalid yaml data: %w", err)
+	}
+
+	jsonData, err := json.Marshal(jsonValue)
+	if err != nil {
+		return fmt.Errorf("cannot encode json data: %w", err)
+	}
+
+	d := json.NewDecoder(bytes.NewReader(jsonData))
+	d.DisallowUnknownFields()
+	if err := d.Decode(es); err != nil {
+		return fmt.Errorf("cannot decode json data: %w", err)
+	}
+
+	return nil
+}
+
+func LoadSubscriptionExports(conn pg.Conn, scope Scope) (SubscriptionExports, error) {
+	query := fmt.Sprintf(`
+SELECT id, project_id, creation_time, update_time, disabled, spec
+  FROM jobs
+  WHERE %s AND spec->'trigger' IS NOT NULL
+  ORDER BY spec->>'name';
+`, scope.SQLCondition())
+
+	var jobs Jobs
+	if err := pg.QueryObjects(conn, &jobs, query); err != nil {
+		return nil, err
+	}
+
+	exports := make(SubscriptionExports, len(jobs))
+	for i, job := range jobs {
+		exports[i] = &SubscriptionExport{
+			Job:     job.Spec.Name,
+			Trigger: job.Spec.Trigger,
+		}
+	}
+
+	return exports, nil
+}
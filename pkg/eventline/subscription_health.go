@@ -0,0 +1,134 @@
+package eventline
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// Subscriptions record the last error which occurred while processing them,
+// e.g. when subscribing fails, when a poll fails or when a webhook request
+// dedicated to the subscription is rejected. The error is cleared after the
+// next successful operation.
+
+// The number of consecutive errors after which a subscription is considered
+// as failed instead of degraded.
+const SubscriptionFailureThreshold = 5
+
+type SubscriptionHealth string
+
+const (
+	SubscriptionHealthOk       SubscriptionHealth = "ok"
+	SubscriptionHealthDegraded SubscriptionHealth = "degraded"
+	SubscriptionHealthFailed   SubscriptionHealth = "failed"
+)
+
+func NewSubscriptionHealth(nbErrors int) SubscriptionHealth {
+	switch {
+	case nbErrors == 0:
+		return SubscriptionHealthOk
+	case nbErrors < SubscriptionFailureThreshold:
+		return SubscriptionHealthDegraded
+	default:
+		return SubscriptionHealthFailed
+	}
+}
+
+type SubscriptionHealthReport struct {
+	Id            Id                 `json:"id"`
+	JobId         *Id                `json:"job_id,omitempty"`
+	JobName       string             `json:"job_name,omitempty"`
+	Connector     string             `json:"connector"`
+	Event         string             `json:"event"`
+	Status        SubscriptionStatus `json:"status"`
+	Health        SubscriptionHealth `json:"health"`
+	NbErrors      int                `json:"nb_errors"`
+	LastError     string             `json:"last_error,omitempty"`
+	LastErrorTime *time.Time         `json:"last_error_time,omitempty"`
+}
+
+type SubscriptionHealthReports []*SubscriptionHealthReport
+
+func RecordSubscriptionError(conn pg.Conn, id Id, err error, now time.Time) error {
+	query := `
+UPDATE subscriptions SET
+    last_error = $2,
+    last_error_time = $3,
+    nb_errors = nb_errors + 1
+  WHERE id = $1
+`
+	return pg.Exec(conn, query, id, err.Error(), now)
+}
+
+func ClearSubscriptionErrors(conn pg.Conn, ids Ids) error {
+	query := `
+UPDATE subscriptions SET
+    last_error = NULL,
+    last_error_time = NULL,
+    nb_errors = 0
+  WHERE id = ANY ($1) AND nb_errors > 0
+`
+	return pg.Exec(conn, query, ids)
+}
+
+func LoadSubscriptionHealthReports(conn pg.Conn, unhealthyOnly bool, scope Scope) (SubscriptionHealthReports, error) {
+	var healthCond string
+	if unhealthyOnly {
+		healthCond = "AND s.nb_errors > 0"
+	}
+
+	query := fmt.Sprintf(`
+SELECT s.id, s.job_id, j.spec->>'name', s.connector, s.event, s.status,
+       s.nb_errors, s.last_error, s.last_error_time
+  FROM subscriptions AS s
+  LEFT JOIN jobs AS j ON j.id = s.job_id
+  WHERE %s %s
+  ORDER BY s.nb_errors DESC, j.spec->>'name'
+`, scope.SQLCondition2("s"), healthCond)
+
+	var reports SubscriptionHealthReports
+	if err := pg.QueryObjects(conn, &reports, query); err != nil {
+		return nil, err
+	}
+
+	return reports, nil
+}
+
+func (r *SubscriptionHealthReport) FromRow(row pgx.Row) error {
+	var jobId Id
+	var jobName, lastError *string
+
+	err := row.Scan(&r.Id, &jobId, &jobName, &r.Connector, &r.Event,
+		&r.Status, &r.NbErrors, &lastError, &r.LastErrorTime)
+	if err != nil {
+		return err
+	}
+
+	if !jobId.IsZero() {
+		r.JobId = &jobId
+	}
+
+	if jobName != nil {
+		r.JobName = *jobName
+	}
+
+	if lastError != nil {
+		r.LastError = *lastError
+	}
+
+	r.Health = NewSubscriptionHealth(r.NbErrors)
+
+	return nil
+}
+
+func (rs *SubscriptionHealthReports) AddFromRow(row pgx.Row) error {
+	var r SubscriptionHealthReport
+	if err := r.FromRow(row); err != nil {
+		return err
+	}
+
+	*rs = append(*rs, &r)
+	return nil
+}
@@ -0,0 +1,18 @@
+package eventline
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewSubscriptionHealth(t *testing.T) {
+	assert := assert.New(t)
+
+	assert.Equal(SubscriptionHealthOk, NewSubscriptionHealth(0))
+	assert.Equal(SubscriptionHealthDegraded, NewSubscriptionHealth(1))
+	assert.Equal(SubscriptionHealthDegraded,
+		NewSubscriptionHealth(SubscriptionFailureThreshold-1))
+	assert.Equal(SubscriptionHealthFailed,
+		NewSubscriptionHealth(SubscriptionFailureThreshold))
+}
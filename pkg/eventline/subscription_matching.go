@@ -0,0 +1,60 @@
+package eventline
+
+import (
+	"time"
+
+	"go.n16f.net/service/pkg/pg"
+)
+
+// MatchAttributes are used to find the subscriptions matching an incoming
+// event in a connector-agnostic way. Connectors whose subscription parameters
+// implement MatchableSubscriptionParameters have their attributes stored with
+// each subscription; when an event is received, the connector computes the
+// attributes of the event and the subscriptions whose attributes are equal
+// are selected.
+type MatchAttributes map[string]string
+
+type MatchableSubscriptionParameters interface {
+	SubscriptionParameters
+
+	MatchAttributes() MatchAttributes
+}
+
+func SubscriptionMatchAttributes(params SubscriptionParameters) MatchAttributes {
+	mparams, ok := params.(MatchableSubscriptionParameters)
+	if !ok {
+		return nil
+	}
+
+	return mparams.MatchAttributes()
+}
+
+func LoadSubscriptionsByMatchAttributes(conn pg.Conn, cname, ename string, attrs MatchAttributes) (Subscriptions, error) {
+	// This query runs for every incoming event of every connector
+	tags := map[string]string{"connector": cname, "event": ename}
+	defer GlobalQueryTimer.Observe("load_subscriptions_by_match_attributes",
+		tags, time.Now())
+
+	// Subscriptions being terminated are not associated with a job anymore,
+	// there is no point in creating events for them.
+	//
+	// A job has at most one subscription (this is enforced by a unique index
+	// on job_id), so the subscriptions returned never create several events
+	// for the same job.
+	query := `
+SELECT id, project_id, job_id, identity_id, connector, event, parameters,
+       creation_time, status, update_delay, last_update_time, next_update_time
+  FROM subscriptions
+  WHERE connector = $1
+    AND event = $2
+    AND match_attributes = $3
+    AND job_id IS NOT NULL
+`
+	var subs Subscriptions
+	err := pg.QueryObjects(conn, &subs, query, cname, ename, attrs)
+	if err != nil {
+		return nil, err
+	}
+
+	return subs, nil
+}
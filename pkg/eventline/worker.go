@@ -97,6 +97,12 @@ func (w *Worker) Start() error {
 	return nil
 }
 
+// Stop stops the behaviour of the worker. It does not stop the worker itself,
+// which terminates when the stop chan is closed.
+func (w *Worker) Stop() {
+	w.Cfg.Behaviour.Stop()
+}
+
 func (w *Worker) WakeUp() {
 	// We do not want to block when writing on the wake-up chan. This can
 	// happen if we are trying to wake up the worker while it is processing a
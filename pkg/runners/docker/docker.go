@@ -288,15 +288,14 @@ func (r *Runner) exec(ctx context.Context, se *eventline.StepExecution, step *ev
 	}
 
 	if code := inspectRes.ExitCode; code != 0 {
-		var err error
-
 		if code < 128 {
-			err = fmt.Errorf("program exited with status %d", code)
+			err := fmt.Errorf("program exited with status %d", code)
+			return eventline.NewStepExitFailureError(err, &code, nil)
 		} else {
-			err = fmt.Errorf("program killed by signal %d", code-128)
+			signal := code - 128
+			err := fmt.Errorf("program killed by signal %d", signal)
+			return eventline.NewStepExitFailureError(err, &code, &signal)
 		}
-
-		return eventline.NewStepFailureError(err)
 	}
 
 	return nil
@@ -2,6 +2,7 @@ package docker
 
 import (
 	"net/url"
+	"path"
 
 	"go.n16f.net/ejson"
 )
@@ -12,6 +13,8 @@ type RunnerCfg struct {
 	CertificatePath   string                `json:"certificate_path,omitempty"`
 	PrivateKeyPath    string                `json:"private_key_path,omitempty"`
 	MountPoints       []RunnerCfgMountPoint `json:"mount_points,omitempty"`
+
+	CABundlePath string `json:"ca_bundle_path,omitempty"`
 }
 
 type RunnerCfgMountPoint struct {
@@ -38,4 +41,13 @@ func (cfg *RunnerCfg) ValidateJSON(v *ejson.Validator) {
 				"string must be a valid uri")
 		}
 	}
+
+	if cfg.CABundlePath != "" {
+		v.Check("ca_bundle_path", path.IsAbs(cfg.CABundlePath),
+			"invalid_relative_path", "path must be absolute")
+	}
+}
+
+func (cfg *RunnerCfg) CABundleFilePath() string {
+	return cfg.CABundlePath
 }
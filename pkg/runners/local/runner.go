@@ -10,6 +10,7 @@ import (
 	"os/exec"
 	"path"
 	"syscall"
+	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/log"
@@ -56,6 +57,12 @@ func (r *Runner) DirPath() string {
 func (r *Runner) Init(ctx context.Context) error {
 	r.runner.Environment["HOME"] = r.rootPath
 
+	release, err := r.reserveDiskSpace()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	if err := r.runner.FileSet.Write(r.rootPath); err != nil {
 		return err
 	}
@@ -63,6 +70,30 @@ func (r *Runner) Init(ctx context.Context) error {
 	return nil
 }
 
+func (r *Runner) reserveDiskSpace() (func(), error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	if cfg.MinFreeDiskSpace == 0 {
+		return func() {}, nil
+	}
+
+	dirPath := cfg.RootDirectory
+	if err := os.MkdirAll(dirPath, 0700); err != nil {
+		return nil, fmt.Errorf("cannot create directory %q: %w", dirPath, err)
+	}
+
+	var stat syscall.Statfs_t
+	if err := syscall.Statfs(dirPath, &stat); err != nil {
+		return nil, fmt.Errorf("cannot obtain file system information for "+
+			"%q: %w", dirPath, err)
+	}
+
+	available := int64(uint64(stat.Bavail) * uint64(stat.Bsize))
+
+	return eventline.GlobalDiskSpaceReservations.Reserve("local:"+dirPath,
+		dirPath, available, cfg.MinFreeDiskSpace, r.runner.FileSet)
+}
+
 func (r *Runner) Terminate() {
 	if err := os.RemoveAll(r.rootPath); err != nil {
 		if !errors.Is(err, fs.ErrNotExist) {
@@ -87,6 +118,18 @@ func (r *Runner) ExecuteStep(ctx context.Context, se *eventline.StepExecution, s
 	cmd.Stdout = stdout
 	cmd.Stderr = stderr
 
+	// Run the command in its own process group so that all processes started
+	// by the step are killed when the execution is aborted, and not only the
+	// shell. Processes still holding the output pipes after that (e.g. after
+	// changing their process group) are not waited for.
+	cmd.SysProcAttr = &syscall.SysProcAttr{Setpgid: true}
+
+	cmd.Cancel = func() error {
+		return syscall.Kill(-cmd.Process.Pid, syscall.SIGKILL)
+	}
+
+	cmd.WaitDelay = 5 * time.Second
+
 	// Run the command
 	err := cmd.Run()
 
@@ -94,28 +137,38 @@ func (r *Runner) ExecuteStep(ctx context.Context, se *eventline.StepExecution, s
 	// messages.
 	var exitErr *exec.ExitError
 	if errors.As(err, &exitErr) {
-		return eventline.NewStepFailureError(r.translateExitError(exitErr))
+		return r.translateExitError(exitErr)
 	}
 
 	return err
 }
 
-func (r *Runner) translateExitError(err *exec.ExitError) error {
+func (r *Runner) translateExitError(err *exec.ExitError) *eventline.StepFailureError {
 	state := err.ProcessState
 	status := state.Sys().(syscall.WaitStatus)
 
 	switch {
 	case status.Exited():
-		if code := status.ExitStatus(); code < 128 {
-			return fmt.Errorf("program exited with status %d", code)
+		code := status.ExitStatus()
+
+		if code < 128 {
+			return eventline.NewStepExitFailureError(
+				fmt.Errorf("program exited with status %d", code), &code, nil)
 		} else {
-			return fmt.Errorf("program killed by signal %d", code-128)
+			// The shell reports programs killed by a signal with status
+			// 128+n.
+			signal := code - 128
+			return eventline.NewStepExitFailureError(
+				fmt.Errorf("program killed by signal %d", signal), &code,
+				&signal)
 		}
 
 	case status.Signaled():
-		return fmt.Errorf("program killed by signal %d", status.Signal())
+		signal := int(status.Signal())
+		return eventline.NewStepExitFailureError(
+			fmt.Errorf("program killed by signal %d", signal), nil, &signal)
 
 	default:
-		return err
+		return eventline.NewStepFailureError(err)
 	}
 }
@@ -7,7 +7,10 @@ import (
 )
 
 type RunnerCfg struct {
-	RootDirectory string `json:"root_directory"`
+	RootDirectory    string `json:"root_directory"`
+	MinFreeDiskSpace int64  `json:"min_free_disk_space,omitempty"` // bytes
+
+	CABundlePath string `json:"ca_bundle_path,omitempty"`
 }
 
 func (cfg *RunnerCfg) ValidateJSON(v *ejson.Validator) {
@@ -15,4 +18,15 @@ func (cfg *RunnerCfg) ValidateJSON(v *ejson.Validator) {
 		v.Check("root_directory", path.IsAbs(cfg.RootDirectory),
 			"invalid_relative_path", "path must be absolute")
 	}
+
+	v.CheckInt64Min("min_free_disk_space", cfg.MinFreeDiskSpace, 0)
+
+	if cfg.CABundlePath != "" {
+		v.Check("ca_bundle_path", path.IsAbs(cfg.CABundlePath),
+			"invalid_relative_path", "path must be absolute")
+	}
+}
+
+func (cfg *RunnerCfg) CABundleFilePath() string {
+	return cfg.CABundlePath
 }
@@ -0,0 +1,64 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"os"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
+)
+
+// The SSH agent of the Eventline server, identified by the SSH_AUTH_SOCK
+// environment variable, can be used for authentication, and can be forwarded
+// to remote servers so that steps can use it, e.g. to clone private Git
+// repositories.
+//
+// Forwarding the agent lets anyone with root access on the remote server use
+// the keys of the agent while the execution is running.
+
+func agentSocketPath() (string, error) {
+	socketPath := os.Getenv("SSH_AUTH_SOCK")
+	if socketPath == "" {
+		return "", fmt.Errorf("SSH_AUTH_SOCK environment variable not set")
+	}
+
+	return socketPath, nil
+}
+
+// agentAuthMethod returns an authentication method using the keys of the SSH
+// agent. The connection to the agent is closed by Terminate.
+func (r *Runner) agentAuthMethod() (ssh.AuthMethod, error) {
+	if r.agentConn == nil {
+		socketPath, err := agentSocketPath()
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err := net.Dial("unix", socketPath)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to ssh agent: %w", err)
+		}
+
+		r.agentConn = conn
+	}
+
+	client := agent.NewClient(r.agentConn)
+
+	return ssh.PublicKeysCallback(client.Signers), nil
+}
+
+// forwardAgent handles agent forwarding channels opened by the remote server
+// on a connection. Forwarding must still be requested for each session.
+func (r *Runner) forwardAgent(client *ssh.Client) error {
+	socketPath, err := agentSocketPath()
+	if err != nil {
+		return err
+	}
+
+	if err := agent.ForwardToRemote(client, socketPath); err != nil {
+		return fmt.Errorf("cannot forward ssh agent: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,183 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"os"
+	"path"
+	"strconv"
+	"time"
+
+	"go.n16f.net/ejson"
+	"golang.org/x/crypto/ssh"
+)
+
+// When a bastion is configured, we first connect to the bastion, then open a
+// connection to the remote host through the bastion (the equivalent of the
+// ProxyJump option of OpenSSH) and use it for the SSH connection to the
+// remote host.
+
+type BastionCfg struct {
+	Host           string `json:"host"`
+	Port           int    `json:"port,omitempty"`
+	User           string `json:"user"`
+	PrivateKeyPath string `json:"private_key_path,omitempty"`
+}
+
+func (cfg *BastionCfg) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringNotEmpty("host", cfg.Host)
+
+	if cfg.Port != 0 {
+		v.CheckIntMinMax("port", cfg.Port, 1, 65535)
+	}
+
+	v.CheckStringNotEmpty("user", cfg.User)
+
+	if cfg.PrivateKeyPath != "" {
+		v.Check("private_key_path", path.IsAbs(cfg.PrivateKeyPath),
+			"invalid_relative_path", "path must be absolute")
+	}
+}
+
+func (cfg *BastionCfg) Address() string {
+	port := cfg.Port
+	if port == 0 {
+		port = 22
+	}
+
+	return net.JoinHostPort(cfg.Host, strconv.Itoa(port))
+}
+
+func (r *Runner) bastionAuthMethods() ([]ssh.AuthMethod, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+	filePath := cfg.Bastion.PrivateKeyPath
+
+	// Without private key, we authenticate with the identity of the runner
+	if filePath == "" {
+		return r.authMethods()
+	}
+
+	data, err := os.ReadFile(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", filePath, err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("cannot parse private key %q: %w", filePath,
+			err)
+	}
+
+	return []ssh.AuthMethod{ssh.PublicKeys(signer)}, nil
+}
+
+// connectBastion connects to the bastion and monitors the connection so that
+// we can report a clear error if it is lost during the execution.
+func (r *Runner) connectBastion(ctx context.Context) (*ssh.Client, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+	address := cfg.Bastion.Address()
+
+	authMethods, err := r.bastionAuthMethods()
+	if err != nil {
+		return nil, err
+	}
+
+	clientCfg := ssh.ClientConfig{
+		User: cfg.Bastion.User,
+		Auth: authMethods,
+
+		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
+
+		Timeout: time.Duration(cfg.HandshakeTimeout) * time.Second,
+	}
+
+	if cfg.KnownHostsFile != "" {
+		callback, algorithms, err := r.knownHostsCallback(address)
+		if err != nil {
+			return nil, err
+		}
+
+		clientCfg.HostKeyCallback = callback
+		clientCfg.HostKeyAlgorithms = algorithms
+	}
+
+	dialer := net.Dialer{
+		Timeout: time.Duration(cfg.ConnectTimeout) * time.Second,
+	}
+
+	conn, err := dialer.DialContext(ctx, "tcp", address)
+	if err != nil {
+		return nil, fmt.Errorf("cannot connect to bastion %q: %w", address,
+			err)
+	}
+
+	client, err := handshake(ctx, conn, address, &clientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize ssh connection to bastion "+
+			"%q: %w", address, err)
+	}
+
+	r.bastionDone = make(chan struct{})
+
+	go func() {
+		client.Wait()
+		close(r.bastionDone)
+	}()
+
+	return client, nil
+}
+
+// dialThroughBastion opens a connection to the remote host through the
+// bastion. The SSH client does not support contexts or timeouts when opening
+// channels, so we close the bastion connection if the operation takes too
+// long or if the context is canceled.
+func (r *Runner) dialThroughBastion(ctx context.Context, address string) (net.Conn, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+	timeout := time.Duration(cfg.ConnectTimeout) * time.Second
+
+	timer := time.AfterFunc(timeout, func() { r.bastionClient.Close() })
+	stopCtxFunc := context.AfterFunc(ctx, func() { r.bastionClient.Close() })
+
+	conn, err := r.bastionClient.Dial("tcp", address)
+
+	timerStopped := timer.Stop()
+	ctxFuncStopped := stopCtxFunc()
+
+	if !timerStopped {
+		err = fmt.Errorf("connection timeout after %v", timeout)
+	} else if !ctxFuncStopped {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		if conn != nil {
+			conn.Close()
+		}
+
+		return nil, err
+	}
+
+	return conn, nil
+}
+
+// wrapBastionError returns an error indicating that the connection to the
+// bastion was lost if it is the case. Losing the bastion connection closes
+// the connection to the remote host, resulting in errors which do not
+// indicate the actual cause.
+func (r *Runner) wrapBastionError(err error) error {
+	if r.bastionDone == nil {
+		return err
+	}
+
+	select {
+	case <-r.bastionDone:
+	case <-time.After(100 * time.Millisecond):
+		return err
+	}
+
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	return fmt.Errorf("connection to bastion %q lost: %w",
+		cfg.Bastion.Address(), err)
+}
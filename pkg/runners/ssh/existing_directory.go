@@ -0,0 +1,158 @@
+package ssh
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// The execution directory may already exist when the execution starts, for
+// example when a job execution is restarted after a partial attempt or after
+// its directory was retained. Its content is then stale: files which are not
+// part of the current file set may still be present, and files interrupted
+// during upload may be truncated.
+
+type ExistingDirectoryMode string
+
+const (
+	// Upload the file set over the existing content (historical behaviour)
+	ExistingDirectoryModeKeep ExistingDirectoryMode = "keep"
+
+	// Delete all the content of the directory before uploading the file set
+	ExistingDirectoryModeClear ExistingDirectoryMode = "clear"
+
+	// Compare the content of the directory with the file set, re-uploading
+	// files which do not match and deleting files which are not part of the
+	// file set
+	ExistingDirectoryModeVerify ExistingDirectoryMode = "verify"
+)
+
+var ExistingDirectoryModeValues = []ExistingDirectoryMode{
+	ExistingDirectoryModeKeep,
+	ExistingDirectoryModeClear,
+	ExistingDirectoryModeVerify,
+}
+
+// prepareExistingDirectory handles the content of the execution directory if
+// it already exists. The boolean returned indicates whether the directory
+// existed.
+func (r *Runner) prepareExistingDirectory() (bool, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	if _, err := r.sftpClient.Stat(r.rootPath); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot stat %q: %w", r.rootPath, err)
+	}
+
+	r.log.Info("execution directory %q already exists", r.rootPath)
+
+	// Whatever the mode, the directory is not retained anymore; if we were
+	// to keep the retention file, the directory could be deleted by other
+	// executions while we are using it.
+	retentionFilePath := path.Join(r.rootPath, retentionFileName)
+	if err := r.sftpClient.Remove(retentionFilePath); err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, fmt.Errorf("cannot delete %q: %w",
+				retentionFilePath, err)
+		}
+	}
+
+	if cfg.ExistingDirectory == ExistingDirectoryModeClear {
+		if err := r.deleteDirectoryContent(r.rootPath); err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// syncFileSet uploads the file set to an existing directory. Directories must
+// already exist.
+func (r *Runner) syncFileSet() error {
+	files := r.runner.FileSet.Files
+
+	// Delete files which are not part of the file set
+	walker := r.sftpClient.Walk(r.rootPath)
+
+	for walker.Step() {
+		if err := walker.Err(); err != nil {
+			return fmt.Errorf("cannot list directory %q: %w", r.rootPath,
+				err)
+		}
+
+		if walker.Stat().IsDir() {
+			continue
+		}
+
+		filePath := walker.Path()
+		relPath := strings.TrimPrefix(filePath, r.rootPath+"/")
+
+		if _, found := files[relPath]; found {
+			continue
+		}
+
+		r.log.Info("deleting stale file %q", filePath)
+
+		if err := r.sftpClient.Remove(filePath); err != nil {
+			return fmt.Errorf("cannot delete %q: %w", filePath, err)
+		}
+	}
+
+	// Upload files which are missing or whose content or permissions do not
+	// match
+	for fp, f := range files {
+		filePath := path.Join(r.rootPath, fp)
+
+		match, err := r.fileMatches(filePath, f.Mode.Perm(), f.Content)
+		if err != nil {
+			return err
+		} else if match {
+			continue
+		}
+
+		if err := r.uploadFile(filePath, f.Mode.Perm(), f.Content); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) fileMatches(filePath string, mode os.FileMode, content []byte) (bool, error) {
+	info, err := r.sftpClient.Lstat(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("cannot stat %q: %w", filePath, err)
+	}
+
+	if !info.Mode().IsRegular() || info.Mode().Perm() != mode ||
+		info.Size() != int64(len(content)) {
+		return false, nil
+	}
+
+	// File sets only contain small files (step code, parameters...), so we
+	// compare the content directly instead of computing checksums.
+	file, err := r.sftpClient.Open(filePath)
+	if err != nil {
+		return false, fmt.Errorf("cannot open %q: %w", filePath, err)
+	}
+	defer file.Close()
+
+	remoteContent, err := io.ReadAll(io.LimitReader(file,
+		int64(len(content))+1))
+	if err != nil {
+		return false, fmt.Errorf("cannot read %q: %w", filePath, err)
+	}
+
+	return bytes.Equal(remoteContent, content), nil
+}
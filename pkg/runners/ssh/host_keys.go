@@ -0,0 +1,165 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+// Host keys are verified either with the host key set in the parameters of
+// the runner, or with a known_hosts file configured for all SSH runners. If
+// neither is available, host keys are not verified.
+//
+// With the trust on first use mode, the key of a host absent from the
+// known_hosts file is accepted and added to the file; keys of hosts already
+// present in the file must still match.
+
+// Several executions can connect to unknown hosts at the same time; appending
+// entries to the known_hosts file must be serialized.
+var knownHostsFileMutex sync.Mutex
+
+type HostKeyMismatchError struct {
+	Address string
+	Key     ssh.PublicKey
+}
+
+func (err *HostKeyMismatchError) Error() string {
+	return fmt.Sprintf("host key %s %s of %q does not match known host keys",
+		err.Key.Type(), ssh.FingerprintSHA256(err.Key), err.Address)
+}
+
+type UnknownHostError struct {
+	Address string
+	Key     ssh.PublicKey
+}
+
+func (err *UnknownHostError) Error() string {
+	return fmt.Sprintf("unknown host %q with host key %s %s", err.Address,
+		err.Key.Type(), ssh.FingerprintSHA256(err.Key))
+}
+
+type knownHostsAddr string
+
+func (a knownHostsAddr) Network() string {
+	return "tcp"
+}
+
+func (a knownHostsAddr) String() string {
+	return string(a)
+}
+
+// knownHostsCallback returns a host key callback using the known_hosts file
+// of the configuration, and the host key algorithms to use to make sure the
+// server at address presents a key of a type we know about.
+func (r *Runner) knownHostsCallback(address string) (ssh.HostKeyCallback, []string, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+	filePath := cfg.KnownHostsFile
+
+	if cfg.TrustOnFirstUse {
+		// knownhosts.New fails if the file does not exist
+		file, err := os.OpenFile(filePath, os.O_CREATE|os.O_RDONLY, 0600)
+		if err != nil {
+			return nil, nil, fmt.Errorf("cannot create %q: %w", filePath, err)
+		}
+		file.Close()
+	}
+
+	callback, err := knownhosts.New(filePath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("cannot load known hosts file %q: %w",
+			filePath, err)
+	}
+
+	algorithms := knownHostKeyAlgorithms(callback, address)
+
+	checkHostKey := func(hostname string, remote net.Addr, key ssh.PublicKey) error {
+		err := callback(hostname, remote, key)
+		if err == nil {
+			return nil
+		}
+
+		var keyErr *knownhosts.KeyError
+		if !errors.As(err, &keyErr) {
+			return err
+		}
+
+		if len(keyErr.Want) > 0 {
+			return &HostKeyMismatchError{Address: hostname, Key: key}
+		}
+
+		if !cfg.TrustOnFirstUse {
+			return &UnknownHostError{Address: hostname, Key: key}
+		}
+
+		if err := addKnownHost(filePath, hostname, key); err != nil {
+			return fmt.Errorf("cannot add %q to known hosts file %q: %w",
+				hostname, filePath, err)
+		}
+
+		r.log.Info("added host key %s %s of %q to known hosts file %q",
+			key.Type(), ssh.FingerprintSHA256(key), hostname, filePath)
+
+		return nil
+	}
+
+	return checkHostKey, algorithms, nil
+}
+
+// knownHostKeyAlgorithms returns the host key algorithms matching the keys
+// known for an address, or nil if there are none. We obtain known keys by
+// checking a key which cannot be part of the file: the resulting error
+// contains the list of known keys.
+func knownHostKeyAlgorithms(callback ssh.HostKeyCallback, address string) []string {
+	publicKey := ed25519.NewKeyFromSeed(make([]byte, ed25519.SeedSize)).Public()
+
+	key, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil
+	}
+
+	var keyErr *knownhosts.KeyError
+	if err := callback(address, knownHostsAddr(address), key); !errors.As(err, &keyErr) {
+		return nil
+	}
+
+	var algorithms []string
+	for _, knownKey := range keyErr.Want {
+		switch keyType := knownKey.Key.Type(); keyType {
+		case ssh.KeyAlgoRSA:
+			// RSA keys can be used with SHA-2 signatures, and recent
+			// servers do not accept SHA-1 signatures anymore.
+			algorithms = append(algorithms, ssh.KeyAlgoRSASHA512,
+				ssh.KeyAlgoRSASHA256, ssh.KeyAlgoRSA)
+		default:
+			algorithms = append(algorithms, keyType)
+		}
+	}
+
+	return algorithms
+}
+
+func addKnownHost(filePath, address string, key ssh.PublicKey) error {
+	knownHostsFileMutex.Lock()
+	defer knownHostsFileMutex.Unlock()
+
+	file, err := os.OpenFile(filePath, os.O_WRONLY|os.O_APPEND|os.O_CREATE,
+		0600)
+	if err != nil {
+		return err
+	}
+
+	line := knownhosts.Line([]string{knownhosts.Normalize(address)}, key)
+
+	if _, err := file.WriteString(line + "\n"); err != nil {
+		file.Close()
+		return err
+	}
+
+	return file.Close()
+}
@@ -0,0 +1,45 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"path"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/knownhosts"
+)
+
+func TestKnownHosts(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	filePath := path.Join(t.TempDir(), "known_hosts")
+
+	newKey := func() ssh.PublicKey {
+		publicKey, _, err := ed25519.GenerateKey(rand.Reader)
+		require.NoError(err)
+
+		key, err := ssh.NewPublicKey(publicKey)
+		require.NoError(err)
+
+		return key
+	}
+
+	address := "example.com:2222"
+	key := newKey()
+
+	require.NoError(addKnownHost(filePath, address, key))
+
+	callback, err := knownhosts.New(filePath)
+	require.NoError(err)
+
+	assert.NoError(callback(address, knownHostsAddr(address), key))
+	assert.Error(callback(address, knownHostsAddr(address), newKey()))
+
+	assert.Equal([]string{ssh.KeyAlgoED25519},
+		knownHostKeyAlgorithms(callback, address))
+	assert.Nil(knownHostKeyAlgorithms(callback, "example.org:22"))
+}
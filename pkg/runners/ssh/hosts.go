@@ -0,0 +1,131 @@
+package ssh
+
+import (
+	"context"
+	"sync"
+
+	"go.n16f.net/service/pkg/influx"
+)
+
+// Each job execution opens its own connection to the remote host. When many
+// jobs target the same host, the host can be overwhelmed, so we account for
+// connections and step sessions per host across all executions, and make
+// executions wait when the limits configured for the runner are reached.
+
+type hostResource int
+
+const (
+	hostResourceConnection hostResource = iota
+	hostResourceSession
+)
+
+type hostStats struct {
+	nbConnections int
+	nbSessions    int
+}
+
+type hostState struct {
+	hostStats
+
+	// Closed and replaced each time a connection or session is released in
+	// order to wake up waiting executions.
+	releaseChan chan struct{}
+}
+
+type hostRegistry struct {
+	hosts map[string]*hostState
+	mutex sync.Mutex
+}
+
+var hosts = &hostRegistry{
+	hosts: make(map[string]*hostState),
+}
+
+func (h *hostState) counter(resource hostResource) *int {
+	switch resource {
+	case hostResourceConnection:
+		return &h.nbConnections
+	default:
+		return &h.nbSessions
+	}
+}
+
+// acquire waits until the number of resources in use on the host is lower
+// than the limit, then reserves a resource. A limit of 0 means no limit. The
+// boolean returned indicates whether the caller had to wait.
+func (reg *hostRegistry) acquire(ctx context.Context, address string, resource hostResource, limit int, influxClient *influx.Client) (bool, error) {
+	waited := false
+
+	for {
+		reg.mutex.Lock()
+
+		h, found := reg.hosts[address]
+		if !found {
+			h = &hostState{releaseChan: make(chan struct{})}
+			reg.hosts[address] = h
+		}
+
+		counter := h.counter(resource)
+		if limit == 0 || *counter < limit {
+			*counter++
+			stats := h.hostStats
+			reg.mutex.Unlock()
+
+			reporthostStats(influxClient, address, stats)
+			return waited, nil
+		}
+
+		releaseChan := h.releaseChan
+
+		reg.mutex.Unlock()
+
+		waited = true
+
+		select {
+		case <-releaseChan:
+		case <-ctx.Done():
+			return waited, ctx.Err()
+		}
+	}
+}
+
+func (reg *hostRegistry) release(address string, resource hostResource, influxClient *influx.Client) {
+	reg.mutex.Lock()
+
+	h, found := reg.hosts[address]
+	if !found {
+		reg.mutex.Unlock()
+		return
+	}
+
+	*h.counter(resource)--
+
+	close(h.releaseChan)
+	h.releaseChan = make(chan struct{})
+
+	stats := h.hostStats
+
+	if h.nbConnections == 0 && h.nbSessions == 0 {
+		delete(reg.hosts, address)
+	}
+
+	reg.mutex.Unlock()
+
+	reporthostStats(influxClient, address, stats)
+}
+
+func reporthostStats(influxClient *influx.Client, address string, stats hostStats) {
+	if influxClient == nil {
+		return
+	}
+
+	tags := influx.Tags{"host": address}
+
+	fields := influx.Fields{
+		"nb_connections": stats.nbConnections,
+		"nb_sessions":    stats.nbSessions,
+	}
+
+	influxClient.EnqueuePoint(influx.NewPoint("eventline_ssh_hosts", tags,
+		fields))
+}
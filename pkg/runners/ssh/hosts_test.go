@@ -0,0 +1,52 @@
+package ssh
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHostRegistry(t *testing.T) {
+	assert := assert.New(t)
+
+	reg := &hostRegistry{hosts: make(map[string]*hostState)}
+
+	ctx := context.Background()
+	address := "example.com:22"
+
+	waited, err := reg.acquire(ctx, address, hostResourceConnection, 1, nil)
+	assert.NoError(err)
+	assert.False(waited)
+
+	// Sessions are limited independently of connections
+	_, err = reg.acquire(ctx, address, hostResourceSession, 1, nil)
+	assert.NoError(err)
+
+	// The host is saturated
+	ctx2, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+	_, err = reg.acquire(ctx2, address, hostResourceConnection, 1, nil)
+	assert.ErrorIs(err, context.DeadlineExceeded)
+	cancel()
+
+	// Waiting executions are woken up when a connection is released
+	go func() {
+		time.Sleep(10 * time.Millisecond)
+		reg.release(address, hostResourceConnection, nil)
+	}()
+
+	waited, err = reg.acquire(ctx, address, hostResourceConnection, 1, nil)
+	assert.NoError(err)
+	assert.True(waited)
+
+	reg.release(address, hostResourceConnection, nil)
+	reg.release(address, hostResourceSession, nil)
+	assert.Empty(reg.hosts)
+
+	// No limit
+	for i := 0; i < 10; i++ {
+		_, err = reg.acquire(ctx, address, hostResourceSession, 0, nil)
+		assert.NoError(err)
+	}
+}
@@ -0,0 +1,84 @@
+package ssh
+
+import (
+	"fmt"
+	"time"
+)
+
+// Firewalls and NAT gateways often drop idle connections. When a step does
+// not produce any output for a long time, nothing is sent on the connection,
+// and if it is dropped, we would wait for the end of the step forever. We
+// therefore send keepalive requests while steps are running; if the server
+// does not answer several of them in a row, we consider the connection lost
+// and close it.
+
+// startKeepalive sends keepalive requests until the stop channel is closed.
+// If the connection is considered lost, it is closed and an error is sent on
+// the returned channel.
+func (r *Runner) startKeepalive(stop <-chan struct{}) <-chan error {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	errChan := make(chan error, 1)
+
+	if cfg.KeepaliveInterval == 0 {
+		return errChan
+	}
+
+	interval := time.Duration(cfg.KeepaliveInterval) * time.Second
+
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		nbFailures := 0
+
+		for {
+			select {
+			case <-stop:
+				return
+
+			case <-ticker.C:
+			}
+
+			if err := r.sendKeepalive(interval); err != nil {
+				nbFailures++
+
+				r.log.Error("keepalive request failed (%d/%d): %v",
+					nbFailures, cfg.MaxKeepaliveFailures, err)
+			} else {
+				nbFailures = 0
+			}
+
+			if nbFailures >= cfg.MaxKeepaliveFailures {
+				errChan <- fmt.Errorf("connection lost: %d consecutive "+
+					"keepalive requests failed", nbFailures)
+
+				// Closing the client interrupts the session of the step
+				r.sshClient.Close()
+				return
+			}
+		}
+	}()
+
+	return errChan
+}
+
+func (r *Runner) sendKeepalive(timeout time.Duration) error {
+	errChan := make(chan error, 1)
+
+	go func() {
+		// The server can reject the request: any answer means that the
+		// connection is alive.
+		_, _, err := r.sshClient.SendRequest("keepalive@openssh.com", true,
+			nil)
+		errChan <- err
+	}()
+
+	select {
+	case err := <-errChan:
+		return err
+
+	case <-time.After(timeout):
+		return fmt.Errorf("timeout")
+	}
+}
@@ -0,0 +1,138 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// When a proxy command is configured, we do not connect to the remote host
+// directly: we execute the command and use its standard input and output as
+// the connection, the same way OpenSSH handles the ProxyCommand option. This
+// is typically used to go through a HTTP proxy (e.g. with "nc -X connect -x
+// proxy:3128 %h %p") or a bastion host (e.g. with "ssh -W %h:%p bastion").
+
+// expandProxyCommand replaces %h, %p and %r by the host, port and user, and
+// %% by a single percent sign. Other sequences are left untouched.
+func expandProxyCommand(command, host string, port int, user string) string {
+	var buf strings.Builder
+
+	for i := 0; i < len(command); i++ {
+		c := command[i]
+
+		if c != '%' || i == len(command)-1 {
+			buf.WriteByte(c)
+			continue
+		}
+
+		switch command[i+1] {
+		case 'h':
+			buf.WriteString(host)
+		case 'p':
+			buf.WriteString(strconv.Itoa(port))
+		case 'r':
+			buf.WriteString(user)
+		case '%':
+			buf.WriteByte('%')
+		default:
+			buf.WriteByte(c)
+			continue
+		}
+
+		i++
+	}
+
+	return buf.String()
+}
+
+type proxyCommandConn struct {
+	cmd     *exec.Cmd
+	stdin   io.WriteCloser
+	stdout  io.ReadCloser
+	address string
+}
+
+func dialProxyCommand(command, address string) (*proxyCommandConn, error) {
+	cmd := exec.Command("/bin/sh", "-c", command)
+
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create stdin pipe: %w", err)
+	}
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, fmt.Errorf("cannot create stdout pipe: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("cannot start proxy command: %w", err)
+	}
+
+	conn := proxyCommandConn{
+		cmd:     cmd,
+		stdin:   stdin,
+		stdout:  stdout,
+		address: address,
+	}
+
+	return &conn, nil
+}
+
+func (c *proxyCommandConn) Read(data []byte) (int, error) {
+	return c.stdout.Read(data)
+}
+
+func (c *proxyCommandConn) Write(data []byte) (int, error) {
+	return c.stdin.Write(data)
+}
+
+func (c *proxyCommandConn) Close() error {
+	c.stdin.Close()
+
+	if c.cmd.Process != nil {
+		c.cmd.Process.Kill()
+	}
+
+	// The exit status does not matter since we killed the process
+	c.cmd.Wait()
+
+	return nil
+}
+
+func (c *proxyCommandConn) LocalAddr() net.Addr {
+	return proxyCommandAddr("proxy-command")
+}
+
+func (c *proxyCommandConn) RemoteAddr() net.Addr {
+	return proxyCommandAddr(c.address)
+}
+
+// Pipes do not support deadlines; the SSH client does not need them once the
+// connection is established.
+
+func (c *proxyCommandConn) SetDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *proxyCommandConn) SetReadDeadline(t time.Time) error {
+	return nil
+}
+
+func (c *proxyCommandConn) SetWriteDeadline(t time.Time) error {
+	return nil
+}
+
+type proxyCommandAddr string
+
+func (a proxyCommandAddr) Network() string {
+	return "proxy-command"
+}
+
+func (a proxyCommandAddr) String() string {
+	return string(a)
+}
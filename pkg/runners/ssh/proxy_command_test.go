@@ -0,0 +1,22 @@
+package ssh
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExpandProxyCommand(t *testing.T) {
+	assert := assert.New(t)
+
+	expand := func(command string) string {
+		return expandProxyCommand(command, "example.com", 2222, "bob")
+	}
+
+	assert.Equal("nc example.com 2222", expand("nc %h %p"))
+	assert.Equal("ssh -W example.com:2222 -l bob bastion",
+		expand("ssh -W %h:%p -l %r bastion"))
+	assert.Equal("echo 100% %x", expand("echo 100%% %x"))
+	assert.Equal("echo %", expand("echo %"))
+	assert.Equal("", expand(""))
+}
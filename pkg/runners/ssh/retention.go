@@ -0,0 +1,177 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+// Execution directories can be retained after the end of the execution to
+// help debugging. Each retained directory contains a file indicating when it
+// expires. Expired directories are deleted by the next executions connecting
+// to the host with the same user.
+
+const retentionFileName = ".eventline-retention"
+
+type DirectoryRetentionMode string
+
+const (
+	DirectoryRetentionModeNever   DirectoryRetentionMode = "never"
+	DirectoryRetentionModeFailure DirectoryRetentionMode = "failure"
+	DirectoryRetentionModeAlways  DirectoryRetentionMode = "always"
+)
+
+var DirectoryRetentionModeValues = []DirectoryRetentionMode{
+	DirectoryRetentionModeNever,
+	DirectoryRetentionModeFailure,
+	DirectoryRetentionModeAlways,
+}
+
+type retainedDirectory struct {
+	path           string
+	expirationTime time.Time
+}
+
+func (r *Runner) retainDirectory() bool {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	switch cfg.RetainDirectory {
+	case DirectoryRetentionModeAlways:
+		return true
+
+	case DirectoryRetentionModeFailure:
+		je := r.runner.JobExecution
+		return je == nil || je.Status != eventline.JobExecutionStatusSuccessful
+	}
+
+	return false
+}
+
+func (r *Runner) writeRetentionFile() error {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	retention := time.Duration(cfg.DirectoryRetention) * time.Second
+	expirationTime := time.Now().UTC().Add(retention)
+
+	filePath := path.Join(r.rootPath, retentionFileName)
+	content := []byte(expirationTime.Format(time.RFC3339) + "\n")
+
+	return r.uploadFile(filePath, 0600, content)
+}
+
+// reapRetainedDirectories deletes retained directories which have expired, and
+// the oldest ones if there are more than the configured maximum.
+func (r *Runner) reapRetainedDirectories() error {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	dirs, err := r.loadRetainedDirectories(path.Dir(r.rootPath))
+	if err != nil {
+		return err
+	}
+
+	sort.Slice(dirs, func(i, j int) bool {
+		return dirs[i].expirationTime.After(dirs[j].expirationTime)
+	})
+
+	now := time.Now()
+
+	for i, dir := range dirs {
+		if now.Before(dir.expirationTime) && i < cfg.MaxRetainedDirectories {
+			continue
+		}
+
+		r.log.Info("deleting retained directory %q", dir.path)
+
+		if err := r.deleteDirectory(dir.path); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *Runner) loadRetainedDirectories(dirPath string) ([]*retainedDirectory, error) {
+	entries, err := r.sftpClient.ReadDir(dirPath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("cannot list directory %q: %w", dirPath, err)
+	}
+
+	var dirs []*retainedDirectory
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+
+		childPath := path.Join(dirPath, entry.Name())
+		if childPath == r.rootPath {
+			continue
+		}
+
+		expirationTime, err := r.readRetentionFile(childPath)
+		if err != nil {
+			return nil, err
+		} else if expirationTime == nil {
+			// Either a directory being used by a running execution or a
+			// directory which was not created by Eventline.
+			continue
+		}
+
+		dirs = append(dirs, &retainedDirectory{
+			path:           childPath,
+			expirationTime: *expirationTime,
+		})
+	}
+
+	return dirs, nil
+}
+
+func (r *Runner) readRetentionFile(dirPath string) (*time.Time, error) {
+	filePath := path.Join(dirPath, retentionFileName)
+
+	file, err := r.sftpClient.Open(filePath)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+
+		return nil, fmt.Errorf("cannot open %q: %w", filePath, err)
+	}
+	defer file.Close()
+
+	data, err := io.ReadAll(io.LimitReader(file, 64))
+	if err != nil {
+		return nil, fmt.Errorf("cannot read %q: %w", filePath, err)
+	}
+
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(string(data)))
+	if err != nil {
+		return nil, fmt.Errorf("invalid expiration time in %q: %w",
+			filePath, err)
+	}
+
+	return &t, nil
+}
+
+func (r *Runner) deleteDirectory(dirPath string) error {
+	if err := r.deleteDirectoryContent(dirPath); err != nil {
+		return err
+	}
+
+	if err := r.sftpClient.RemoveDirectory(dirPath); err != nil {
+		return fmt.Errorf("cannot delete directory %q: %w", dirPath, err)
+	}
+
+	return nil
+}
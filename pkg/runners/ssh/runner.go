@@ -5,12 +5,18 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"net"
 	"path"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/exograd/eventline/pkg/utils"
 	"go.n16f.net/log"
 	"github.com/pkg/sftp"
 	"golang.org/x/crypto/ssh"
+	"golang.org/x/crypto/ssh/agent"
 )
 
 type Runner struct {
@@ -18,9 +24,24 @@ type Runner struct {
 	log    *log.Logger
 
 	rootPath string
+	address  string
+
+	hostConnectionAcquired bool
+
+	environmentFilePath string
+	inlineEnvironment   bool
+
+	agentConn net.Conn
+
+	bastionClient *ssh.Client
+	bastionDone   chan struct{}
 
 	sshClient  *ssh.Client
 	sftpClient *sftp.Client
+
+	sessionSemaphore chan struct{}
+
+	reaperWg sync.WaitGroup
 }
 
 func RunnerDef() *eventline.RunnerDef {
@@ -28,6 +49,27 @@ func RunnerDef() *eventline.RunnerDef {
 		Name: "ssh",
 		Cfg: &RunnerCfg{
 			RootDirectory: "/tmp/eventline/execution",
+
+			// Default value of MaxSessions for OpenSSH
+			MaxSessions: 10,
+
+			RetainDirectory:        DirectoryRetentionModeNever,
+			DirectoryRetention:     86400,
+			MaxRetainedDirectories: 10,
+
+			ExistingDirectory: ExistingDirectoryModeKeep,
+
+			ConnectTimeout:   30,
+			HandshakeTimeout: 30,
+
+			KeepaliveInterval:    60,
+			MaxKeepaliveFailures: 3,
+
+			TerminationGracePeriod: 5,
+
+			EnvironmentFile:         EnvironmentFileModeAuto,
+			MaxEnvironmentVariables: 100,
+			MaxEnvironmentSize:      32 * 1024,
 		},
 		InstantiateParameters: NewRunnerParameters,
 		InstantiateBehaviour:  NewRunner,
@@ -43,11 +85,18 @@ func NewRunner(r *eventline.Runner) eventline.RunnerBehaviour {
 	rootDirPath := cfg.RootDirectory
 	rootPath := path.Join(rootDirPath, params.User, je.Id.String())
 
+	address := net.JoinHostPort(params.Host, strconv.Itoa(params.Port))
+
 	return &Runner{
 		runner: r,
 		log:    r.Log,
 
 		rootPath: rootPath,
+		address:  address,
+
+		inlineEnvironment: cfg.InlineEnvironment,
+
+		sessionSemaphore: make(chan struct{}, cfg.MaxSessions),
 	}
 }
 
@@ -58,14 +107,24 @@ func (r *Runner) DirPath() string {
 func (r *Runner) Init(ctx context.Context) error {
 	cfg := r.runner.Cfg.(*RunnerCfg)
 
+	if err := r.acquireHostConnection(ctx); err != nil {
+		return err
+	}
+
 	sshClient, err := r.connect(ctx)
 	if err != nil {
 		return err
 	}
 	r.sshClient = sshClient
 
+	// The sftp subsystem runs in its own session for the entire execution
+	if err := r.acquireSession(ctx); err != nil {
+		return err
+	}
+
 	r.sftpClient, err = sftp.NewClient(r.sshClient)
 	if err != nil {
+		r.releaseSession()
 		return fmt.Errorf("cannot create sftp client: %w", err)
 	}
 
@@ -83,89 +142,225 @@ func (r *Runner) Init(ctx context.Context) error {
 			cfg.RootDirectory, err)
 	}
 
+	// Retained directories are deleted in the background while the execution
+	// is running; Terminate waits for completion before closing the sftp
+	// client.
+	r.reaperWg.Add(1)
+	go func() {
+		defer r.reaperWg.Done()
+
+		if err := r.reapRetainedDirectories(); err != nil {
+			r.log.Error("cannot delete retained directories: %v", err)
+		}
+	}()
+
+	release, err := r.reserveDiskSpace()
+	if err != nil {
+		return err
+	}
+	defer release()
+
 	if err := r.uploadFileSet(ctx); err != nil {
 		return err
 	}
 
+	if cfg.UseEnvironmentFile(r.runner.Environment) {
+		if err := r.uploadEnvironmentFile(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (r *Runner) Terminate() {
-	cfg := r.runner.Cfg.(*RunnerCfg)
-
 	if r.sftpClient != nil {
-		// Note that we delete all files *in* the root directory, but not the
-		// root directory itself; it could be provided by the user, and could
-		// for example have specific permissions.
-		if err := r.deleteDirectoryContent(cfg.RootDirectory); err != nil {
-			r.log.Error("cannot delete directory %q: %v", r.rootPath, err)
+		r.reaperWg.Wait()
+
+		if r.retainDirectory() {
+			if err := r.writeRetentionFile(); err != nil {
+				r.log.Error("cannot retain directory %q: %v",
+					r.rootPath, err)
+			} else {
+				r.log.Info("retaining directory %q", r.rootPath)
+			}
+
+			// The environment file contains identity secrets
+			if filePath := r.environmentFilePath; filePath != "" {
+				if err := r.sftpClient.Remove(filePath); err != nil {
+					r.log.Error("cannot delete %q: %v", filePath, err)
+				}
+			}
+		} else {
+			// Only delete the directory of the execution: the root directory
+			// is shared by all executions on the host, could be provided by
+			// the user, and could for example have specific permissions.
+			if err := r.deleteDirectory(r.rootPath); err != nil {
+				r.log.Error("cannot delete directory %q: %v", r.rootPath, err)
+			}
 		}
 
 		r.sftpClient.Close()
+		r.releaseSession()
 	}
 
 	if r.sshClient != nil {
 		r.sshClient.Close()
 	}
+
+	// The connection to the remote host goes through the bastion, so the
+	// bastion connection must be closed last
+	if r.bastionClient != nil {
+		r.bastionClient.Close()
+	}
+
+	if r.agentConn != nil {
+		r.agentConn.Close()
+	}
+
+	if r.hostConnectionAcquired {
+		hosts.release(r.address, hostResourceConnection, r.runner.Influx)
+	}
 }
 
 func (r *Runner) ExecuteStep(ctx context.Context, se *eventline.StepExecution, step *eventline.Step, stdout, stderr io.WriteCloser) error {
 	// Create and initialize a new session
-	session, err := r.sshClient.NewSession()
+	session, err := r.newSession(ctx)
 	if err != nil {
-		return fmt.Errorf("cannot open session: %w", err)
+		return r.wrapBastionError(err)
+	}
+	defer r.releaseStepSession()
+
+	// Deferred functions run in reverse order: the session is closed before
+	// its slot is released.
+	defer session.Close()
+
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	if cfg.ForwardAgent {
+		if err := agent.RequestAgentForwarding(session); err != nil {
+			return fmt.Errorf("cannot request agent forwarding: %w", err)
+		}
 	}
 
 	session.Stdout = stdout
 	session.Stderr = stderr
 
-	for k, v := range r.runner.Environment {
-		if err := session.Setenv(k, v); err != nil {
-			return fmt.Errorf("cannot set environment variable %q: %w", k, err)
+	if r.environmentFilePath == "" && !r.inlineEnvironment {
+		for k, v := range r.runner.Environment {
+			if err := session.Setenv(k, v); err != nil {
+				// Servers without AcceptEnv setting reject setenv requests;
+				// the session is still usable.
+				r.log.Info("cannot set environment variable %q (%v), "+
+					"exporting variables in commands instead", k, err)
+				r.inlineEnvironment = true
+				break
+			}
 		}
 	}
 
 	// Run the command and wait for completion
 	cmd := r.runner.StepCommandString(se, step, r.rootPath)
 
+	if r.environmentFilePath != "" {
+		cmd = ". " + utils.ShellEscape(r.environmentFilePath) + " && " + cmd
+	} else if r.inlineEnvironment {
+		script, err := environmentScript(r.runner.Environment, "; ")
+		if err != nil {
+			return err
+		}
+
+		cmd = script + cmd
+	}
+
 	if err := session.Start(cmd); err != nil {
-		return fmt.Errorf("cannot start command: %w", err)
+		return r.wrapBastionError(fmt.Errorf("cannot start command: %w", err))
 	}
 
-	errChan := make(chan error)
+	errChan := waitProgram(session.Wait)
 
-	go func() {
-		errChan <- session.Wait()
-		close(errChan)
-	}()
+	stopKeepalive := make(chan struct{})
+	defer close(stopKeepalive)
+
+	keepaliveErrChan := r.startKeepalive(stopKeepalive)
 
 	select {
 	case err = <-errChan:
 		var exitErr *ssh.ExitError
 		if errors.As(err, &exitErr) {
-			err = eventline.NewStepFailureError(r.translateExitError(exitErr))
+			err = r.translateExitError(exitErr)
+		} else if err != nil {
+			err = r.wrapBastionError(err)
 		}
 
-	case <-ctx.Done():
-		if err := session.Signal(ssh.SIGKILL); err != nil {
-			r.log.Error("cannot kill program: %v", err)
-		}
+	case err = <-keepaliveErrChan:
 
+	case <-ctx.Done():
+		r.terminateProgram(session, errChan)
 		err = context.Canceled
 	}
 
-	// Cleanup
-	session.Close()
-
 	return err
 }
 
-func (r *Runner) translateExitError(err *ssh.ExitError) error {
-	if code := err.ExitStatus(); code != 0 {
-		return fmt.Errorf("program exited with status %d", code)
-	} else if sigName := err.Signal(); sigName != "" {
-		return fmt.Errorf("program killed by signal %s", sigName)
+// waitProgram calls wait in a goroutine and returns a channel receiving its
+// result. The channel is buffered so that the goroutine always terminates,
+// even if nobody reads the result, e.g. when the step is aborted.
+func waitProgram(wait func() error) <-chan error {
+	errChan := make(chan error, 1)
+
+	go func() {
+		errChan <- wait()
+		close(errChan)
+	}()
+
+	return errChan
+}
+
+// terminateProgram sends SIGTERM to the program so that it can clean up, and
+// SIGKILL if it has not exited at the end of the grace period.
+func (r *Runner) terminateProgram(session *ssh.Session, errChan <-chan error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	if cfg.TerminationGracePeriod > 0 {
+		if err := session.Signal(ssh.SIGTERM); err != nil {
+			r.log.Error("cannot terminate program: %v", err)
+		} else {
+			gracePeriod :=
+				time.Duration(cfg.TerminationGracePeriod) * time.Second
+
+			select {
+			case <-errChan:
+				return
+			case <-time.After(gracePeriod):
+			}
+
+			r.log.Info("program still running %v after SIGTERM", gracePeriod)
+		}
+	}
+
+	if err := session.Signal(ssh.SIGKILL); err != nil {
+		r.log.Error("cannot kill program: %v", err)
+	}
+}
+
+func (r *Runner) translateExitError(err *ssh.ExitError) *eventline.StepFailureError {
+	code := err.ExitStatus()
+
+	if sigName := err.Signal(); sigName != "" {
+		// The ssh package reports known signals with status 128+n
+		var signal *int
+		if code > 128 {
+			n := code - 128
+			signal = &n
+		}
+
+		return eventline.NewStepExitFailureError(
+			fmt.Errorf("program killed by signal %s", sigName), nil, signal)
+	} else if code != 0 {
+		return eventline.NewStepExitFailureError(
+			fmt.Errorf("program exited with status %d", code), &code, nil)
 	}
 
-	return err
+	return eventline.NewStepFailureError(err)
 }
@@ -6,8 +6,58 @@ import (
 	"go.n16f.net/ejson"
 )
 
+type EnvironmentFileMode string
+
+const (
+	EnvironmentFileModeAuto   EnvironmentFileMode = "auto"
+	EnvironmentFileModeAlways EnvironmentFileMode = "always"
+	EnvironmentFileModeNever  EnvironmentFileMode = "never"
+)
+
+var EnvironmentFileModeValues = []EnvironmentFileMode{
+	EnvironmentFileModeAuto,
+	EnvironmentFileModeAlways,
+	EnvironmentFileModeNever,
+}
+
 type RunnerCfg struct {
-	RootDirectory string `json:"root_directory"`
+	RootDirectory    string `json:"root_directory"`
+	MaxSessions      int    `json:"max_sessions"`
+	MinFreeDiskSpace int64  `json:"min_free_disk_space,omitempty"` // bytes
+
+	RetainDirectory        DirectoryRetentionMode `json:"retain_directory"`
+	DirectoryRetention     int                    `json:"directory_retention"`      // seconds
+	MaxRetainedDirectories int                    `json:"max_retained_directories"` // per user
+
+	MaxConnectionsPerHost int `json:"max_connections_per_host,omitempty"`
+	MaxSessionsPerHost    int `json:"max_sessions_per_host,omitempty"`
+
+	ArchiveFileSet    bool                  `json:"archive_file_set,omitempty"`
+	ExistingDirectory ExistingDirectoryMode `json:"existing_directory"`
+
+	ConnectTimeout   int `json:"connect_timeout"`   // seconds
+	HandshakeTimeout int `json:"handshake_timeout"` // seconds
+
+	KeepaliveInterval    int `json:"keepalive_interval"` // seconds
+	MaxKeepaliveFailures int `json:"max_keepalive_failures"`
+
+	TerminationGracePeriod int `json:"termination_grace_period"` // seconds
+
+	ProxyCommand string      `json:"proxy_command,omitempty"`
+	Bastion      *BastionCfg `json:"bastion,omitempty"`
+
+	UseAgent     bool `json:"use_agent,omitempty"`
+	ForwardAgent bool `json:"forward_agent,omitempty"`
+
+	KnownHostsFile  string `json:"known_hosts_file,omitempty"`
+	TrustOnFirstUse bool   `json:"trust_on_first_use,omitempty"`
+
+	EnvironmentFile         EnvironmentFileMode `json:"environment_file"`
+	MaxEnvironmentVariables int                 `json:"max_environment_variables"`
+	MaxEnvironmentSize      int                 `json:"max_environment_size"`
+	InlineEnvironment       bool                `json:"inline_environment,omitempty"`
+
+	CABundlePath string `json:"ca_bundle_path,omitempty"`
 }
 
 func (cfg *RunnerCfg) ValidateJSON(v *ejson.Validator) {
@@ -15,4 +65,82 @@ func (cfg *RunnerCfg) ValidateJSON(v *ejson.Validator) {
 		v.Check("root_directory", path.IsAbs(cfg.RootDirectory),
 			"invalid_relative_path", "path must be absolute")
 	}
+
+	v.CheckIntMin("max_sessions", cfg.MaxSessions, 2)
+	v.CheckInt64Min("min_free_disk_space", cfg.MinFreeDiskSpace, 0)
+
+	v.CheckStringValue("retain_directory", cfg.RetainDirectory,
+		DirectoryRetentionModeValues)
+	v.CheckIntMin("directory_retention", cfg.DirectoryRetention, 1)
+	v.CheckIntMin("max_retained_directories", cfg.MaxRetainedDirectories, 0)
+
+	v.CheckIntMin("max_connections_per_host", cfg.MaxConnectionsPerHost, 0)
+	v.CheckIntMin("max_sessions_per_host", cfg.MaxSessionsPerHost, 0)
+
+	v.CheckIntMin("connect_timeout", cfg.ConnectTimeout, 1)
+	v.CheckIntMin("handshake_timeout", cfg.HandshakeTimeout, 1)
+
+	v.CheckIntMin("keepalive_interval", cfg.KeepaliveInterval, 0)
+	v.CheckIntMin("max_keepalive_failures", cfg.MaxKeepaliveFailures, 1)
+
+	v.CheckIntMin("termination_grace_period", cfg.TerminationGracePeriod, 0)
+
+	v.CheckStringValue("existing_directory", cfg.ExistingDirectory,
+		ExistingDirectoryModeValues)
+
+	v.CheckStringValue("environment_file", cfg.EnvironmentFile,
+		EnvironmentFileModeValues)
+	v.CheckIntMin("max_environment_variables", cfg.MaxEnvironmentVariables, 1)
+	v.CheckIntMin("max_environment_size", cfg.MaxEnvironmentSize, 1)
+
+	if cfg.Bastion != nil {
+		v.Check("bastion", cfg.ProxyCommand == "", "incompatible_settings",
+			"cannot use both a proxy command and a bastion")
+		v.CheckObject("bastion", cfg.Bastion)
+	}
+
+	if cfg.KnownHostsFile != "" {
+		v.Check("known_hosts_file", path.IsAbs(cfg.KnownHostsFile),
+			"invalid_relative_path", "path must be absolute")
+	}
+
+	if cfg.TrustOnFirstUse {
+		v.Check("trust_on_first_use", cfg.KnownHostsFile != "",
+			"missing_known_hosts_file",
+			"trust on first use requires a known hosts file")
+	}
+
+	if cfg.CABundlePath != "" {
+		v.Check("ca_bundle_path", path.IsAbs(cfg.CABundlePath),
+			"invalid_relative_path", "path must be absolute")
+	}
+}
+
+func (cfg *RunnerCfg) CABundleFilePath() string {
+	return cfg.CABundlePath
+}
+
+// UseEnvironmentFile indicates whether environment variables must be written
+// to a file sourced before each command instead of being sent with setenv
+// requests. SSH servers limit the number and size of variables they accept
+// (OpenSSH silently ignores variables after the first 128 ones), so we switch
+// to a file when the environment is too large.
+func (cfg *RunnerCfg) UseEnvironmentFile(env map[string]string) bool {
+	switch cfg.EnvironmentFile {
+	case EnvironmentFileModeAlways:
+		return true
+	case EnvironmentFileModeNever:
+		return false
+	}
+
+	if len(env) > cfg.MaxEnvironmentVariables {
+		return true
+	}
+
+	var size int
+	for name, value := range env {
+		size += len(name) + len(value)
+	}
+
+	return size > cfg.MaxEnvironmentSize
 }
@@ -0,0 +1,34 @@
+package ssh
+
+import (
+	"runtime"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestWaitProgramCancellation(t *testing.T) {
+	assert := assert.New(t)
+
+	nbGoroutines := runtime.NumGoroutine()
+
+	// Simulate a step aborted while the program is running: the result of
+	// the program is never read.
+	exitChan := make(chan struct{})
+
+	waitProgram(func() error {
+		<-exitChan
+		return nil
+	})
+
+	close(exitChan)
+
+	// We cannot use assert.Eventually since it starts its own goroutines
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > nbGoroutines && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	assert.LessOrEqual(runtime.NumGoroutine(), nbGoroutines)
+}
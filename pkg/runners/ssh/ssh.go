@@ -2,67 +2,104 @@ package ssh
 
 import (
 	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
 	"os"
 	"path"
-	"strconv"
+	"sort"
+	"strings"
 	"time"
 
 	cgeneric "github.com/exograd/eventline/pkg/connectors/generic"
+	"github.com/exograd/eventline/pkg/eventline"
 	"golang.org/x/crypto/ssh"
 )
 
-func (r *Runner) authMethod() (ssh.AuthMethod, error) {
+func (r *Runner) authMethods() ([]ssh.AuthMethod, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	var methods []ssh.AuthMethod
+
+	// The agent is used first; the identity, if there is one, is used if
+	// the keys of the agent are not accepted by the server.
+	if cfg.UseAgent {
+		method, err := r.agentAuthMethod()
+		if err != nil {
+			return nil, err
+		}
+
+		methods = append(methods, method)
+	}
+
 	identity := r.runner.RunnerIdentity
 	if identity == nil {
+		if len(methods) > 0 {
+			return methods, nil
+		}
+
 		return nil, fmt.Errorf("missing runner identity for authentication")
 	}
 
-	var method ssh.AuthMethod
-
 	switch i := identity.Data.(type) {
 	case *cgeneric.PasswordIdentity:
-		method = ssh.Password(i.Password)
+		// Some servers (e.g. network appliances) only support the
+		// keyboard-interactive method for password authentication.
+		password := i.Password
+
+		keyboardInteractive := func(name, instruction string, questions []string, echos []bool) ([]string, error) {
+			answers := make([]string, len(questions))
+			for i := range questions {
+				answers[i] = password
+			}
+
+			return answers, nil
+		}
+
+		methods = append(methods,
+			ssh.Password(password),
+			ssh.KeyboardInteractive(keyboardInteractive))
 
 	case *cgeneric.SSHKeyIdentity:
-		signer, err := ssh.ParsePrivateKey([]byte(i.PrivateKey))
+		signer, err := i.Signer()
 		if err != nil {
 			return nil, fmt.Errorf("cannot parse private key of identity "+
 				"%q: %w", identity.Name, err)
 		}
 
-		method = ssh.PublicKeys(signer)
+		methods = append(methods, ssh.PublicKeys(signer))
 
 	default:
 		return nil, fmt.Errorf("identity %q cannot be used for ssh "+
 			"authentication", identity.Name)
 	}
 
-	return method, nil
+	return methods, nil
 }
 
 func (r *Runner) connect(ctx context.Context) (*ssh.Client, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
 	je := r.runner.JobExecution
 	params := je.JobSpec.Runner.Parameters.(*RunnerParameters)
 
 	// Prepare connection data
-	address := net.JoinHostPort(params.Host, strconv.Itoa(params.Port))
+	address := r.address
 
-	authMethod, err := r.authMethod()
+	authMethods, err := r.authMethods()
 	if err != nil {
 		return nil, err
 	}
 
 	clientCfg := ssh.ClientConfig{
 		User: params.User,
-		Auth: []ssh.AuthMethod{authMethod},
+		Auth: authMethods,
 
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(),
 
-		Timeout: 30 * time.Second,
+		Timeout: time.Duration(cfg.HandshakeTimeout) * time.Second,
 	}
 
 	if params.HostKey != nil {
@@ -73,29 +110,225 @@ func (r *Runner) connect(ctx context.Context) (*ssh.Client, error) {
 
 		clientCfg.HostKeyCallback = ssh.FixedHostKey(hostKey)
 		clientCfg.HostKeyAlgorithms = []string{params.HostKeyAlgorithm}
+	} else if cfg.KnownHostsFile != "" {
+		callback, algorithms, err := r.knownHostsCallback(address)
+		if err != nil {
+			return nil, err
+		}
+
+		clientCfg.HostKeyCallback = callback
+		clientCfg.HostKeyAlgorithms = algorithms
 	}
 
 	// Connect to the remote host
-	var dialer net.Dialer
-	conn, err := dialer.DialContext(ctx, "tcp", address)
-	if err != nil {
-		return nil, fmt.Errorf("cannot connect to %q: %w", address, err)
+	var conn net.Conn
+
+	if cfg.Bastion != nil {
+		r.bastionClient, err = r.connectBastion(ctx)
+		if err != nil {
+			return nil, err
+		}
+
+		conn, err = r.dialThroughBastion(ctx, address)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to %q through bastion "+
+				"%q: %w", address, cfg.Bastion.Address(), err)
+		}
+	} else if cfg.ProxyCommand == "" {
+		dialer := net.Dialer{
+			Timeout: time.Duration(cfg.ConnectTimeout) * time.Second,
+		}
+
+		conn, err = dialer.DialContext(ctx, "tcp", address)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to %q: %w", address, err)
+		}
+	} else {
+		command := expandProxyCommand(cfg.ProxyCommand, params.Host,
+			params.Port, params.User)
+
+		conn, err = dialProxyCommand(command, address)
+		if err != nil {
+			return nil, fmt.Errorf("cannot connect to %q: %w", address, err)
+		}
 	}
 
-	// Initialize the SSH connection itself
-	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, &clientCfg)
+	client, err := handshake(ctx, conn, address, &clientCfg)
 	if err != nil {
 		return nil, fmt.Errorf("cannot initialize ssh connection to %q: %w",
 			address, err)
 	}
 
-	client := ssh.NewClient(sshConn, chans, reqs)
+	if cfg.ForwardAgent {
+		if err := r.forwardAgent(client); err != nil {
+			client.Close()
+			return nil, err
+		}
+	}
 
 	return client, nil
 }
 
+// handshake initializes a SSH connection. Connections established with a
+// proxy command or through a bastion do not support deadlines, so we close the
+// connection if the handshake takes too long or if the context is canceled.
+func handshake(ctx context.Context, conn net.Conn, address string, clientCfg *ssh.ClientConfig) (*ssh.Client, error) {
+	timer := time.AfterFunc(clientCfg.Timeout, func() { conn.Close() })
+	stopCtxFunc := context.AfterFunc(ctx, func() { conn.Close() })
+
+	sshConn, chans, reqs, err := ssh.NewClientConn(conn, address, clientCfg)
+
+	timerStopped := timer.Stop()
+	ctxFuncStopped := stopCtxFunc()
+
+	if !timerStopped {
+		err = fmt.Errorf("handshake timeout after %v", clientCfg.Timeout)
+	} else if !ctxFuncStopped {
+		err = ctx.Err()
+	}
+
+	if err != nil {
+		if sshConn != nil {
+			sshConn.Close()
+		}
+
+		conn.Close()
+		return nil, err
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
+}
+
+// SSH servers limit the number of sessions which can be open at the same time
+// on a connection (MaxSessions for OpenSSH); going over this limit causes
+// "administratively prohibited" errors. We therefore wait for a free slot
+// before opening a session.
+func (r *Runner) acquireSession(ctx context.Context) error {
+	select {
+	case r.sessionSemaphore <- struct{}{}:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) releaseSession() {
+	<-r.sessionSemaphore
+}
+
+func (r *Runner) acquireHostConnection(ctx context.Context) error {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	waited, err := hosts.acquire(ctx, r.address, hostResourceConnection,
+		cfg.MaxConnectionsPerHost, r.runner.Influx)
+	if err != nil {
+		return fmt.Errorf("cannot acquire connection slot for %q: %w",
+			r.address, err)
+	}
+
+	if waited {
+		r.log.Info("waited for a connection slot on %q", r.address)
+	}
+
+	r.hostConnectionAcquired = true
+	return nil
+}
+
+// newSession opens a session for a step once a slot is available, both on
+// the host and on the connection. The caller must call releaseStepSession
+// after closing the session.
+//
+// The session used for file transfers is not accounted for in the host
+// limit: it is open during the entire execution, and counting it could
+// prevent executions holding it from ever running their steps.
+func (r *Runner) newSession(ctx context.Context) (*ssh.Session, error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	waited, err := hosts.acquire(ctx, r.address, hostResourceSession,
+		cfg.MaxSessionsPerHost, r.runner.Influx)
+	if err != nil {
+		return nil, err
+	}
+
+	if waited {
+		r.log.Info("waited for a session slot on %q", r.address)
+	}
+
+	if err := r.acquireSession(ctx); err != nil {
+		hosts.release(r.address, hostResourceSession, r.runner.Influx)
+		return nil, err
+	}
+
+	session, err := r.sshClient.NewSession()
+	if err != nil {
+		r.releaseStepSession()
+		return nil, fmt.Errorf("cannot open session: %w", err)
+	}
+
+	return session, nil
+}
+
+func (r *Runner) releaseStepSession() {
+	r.releaseSession()
+	hosts.release(r.address, hostResourceSession, r.runner.Influx)
+}
+
+// reserveDiskSpace relies on the statvfs@openssh.com sftp extension,
+// supported by OpenSSH.
+func (r *Runner) reserveDiskSpace() (func(), error) {
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	if cfg.MinFreeDiskSpace == 0 {
+		return func() {}, nil
+	}
+
+	dirPath := cfg.RootDirectory
+
+	stat, err := r.sftpClient.StatVFS(dirPath)
+	if err != nil {
+		return nil, fmt.Errorf("cannot obtain file system information for "+
+			"%q: %w", dirPath, err)
+	}
+
+	available := int64(stat.Bavail * stat.Frsize)
+
+	return eventline.GlobalDiskSpaceReservations.Reserve(
+		"ssh:"+r.address+":"+dirPath, dirPath, available,
+		cfg.MinFreeDiskSpace, r.runner.FileSet)
+}
+
 func (r *Runner) uploadFileSet(ctx context.Context) error {
-	// Directories
+	cfg := r.runner.Cfg.(*RunnerCfg)
+
+	exists, err := r.prepareExistingDirectory()
+	if err != nil {
+		return err
+	}
+
+	if err := r.createFileSetDirectories(ctx); err != nil {
+		return err
+	}
+
+	if exists && cfg.ExistingDirectory == ExistingDirectoryModeVerify {
+		return r.syncFileSet()
+	}
+
+	if cfg.ArchiveFileSet {
+		err := r.uploadFileSetArchive(ctx)
+		if err == nil {
+			return nil
+		} else if !errors.Is(err, errTarUnavailable) {
+			return err
+		}
+
+		r.log.Info("tar is not available on %q, uploading files one by one",
+			r.address)
+	}
+
+	return r.uploadFileSetFiles()
+}
+
+func (r *Runner) createFileSetDirectories(ctx context.Context) error {
 	dirPaths := make(map[string]struct{})
 	for fp := range r.runner.FileSet.Files {
 		dirPaths[path.Dir(path.Join(r.rootPath, fp))] = struct{}{}
@@ -107,37 +340,177 @@ func (r *Runner) uploadFileSet(ctx context.Context) error {
 		}
 	}
 
-	// Files
-	openFlags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	return nil
+}
+
+// uploadFileSetArchive transfers the file set as a single compressed tar
+// archive extracted by tar on the remote host, avoiding one sftp round trip
+// per file operation. Directories must already exist.
+func (r *Runner) uploadFileSetArchive(ctx context.Context) error {
+	var tarBuf bytes.Buffer
+	if err := r.runner.FileSet.TarArchive(&tarBuf); err != nil {
+		return fmt.Errorf("cannot create archive: %w", err)
+	}
+
+	var archiveBuf bytes.Buffer
+
+	gzipWriter := gzip.NewWriter(&archiveBuf)
+	if _, err := io.Copy(gzipWriter, &tarBuf); err != nil {
+		return fmt.Errorf("cannot compress archive: %w", err)
+	}
+
+	if err := gzipWriter.Close(); err != nil {
+		return fmt.Errorf("cannot compress archive: %w", err)
+	}
+
+	session, err := r.newSession(ctx)
+	if err != nil {
+		return err
+	}
+	defer r.releaseStepSession()
+	defer session.Close()
+
+	var stderr bytes.Buffer
+
+	session.Stdin = &archiveBuf
+	session.Stderr = &stderr
+
+	// The -p option preserves file modes regardless of the umask of the user
+	cmd := fmt.Sprintf("{ command -v tar && command -v gzip; } >/dev/null "+
+		"2>&1 || exit %d; tar -x -z -p -f - -C %s",
+		tarUnavailableExitStatus, shellQuote(r.rootPath))
+
+	if err := session.Run(cmd); err != nil {
+		var exitErr *ssh.ExitError
+		if errors.As(err, &exitErr) {
+			if exitErr.ExitStatus() == tarUnavailableExitStatus {
+				return errTarUnavailable
+			}
+
+			return fmt.Errorf("cannot extract archive: tar exited with "+
+				"status %d: %s", exitErr.ExitStatus(),
+				strings.TrimSpace(stderr.String()))
+		}
 
+		return fmt.Errorf("cannot extract archive: %w", err)
+	}
+
+	return nil
+}
+
+func (r *Runner) uploadFileSetFiles() error {
 	for fp, f := range r.runner.FileSet.Files {
 		filePath := path.Join(r.rootPath, fp)
 
-		// The sftp package does not support setting permissions when opening
-		// the file. See https://github.com/pkg/sftp/issues/335 for more
-		// information.
-		file, err := r.sftpClient.OpenFile(filePath, openFlags)
-		if err != nil {
-			return fmt.Errorf("cannot open %q: %w", filePath, err)
+		if err := r.uploadFile(filePath, f.Mode.Perm(), f.Content); err != nil {
+			return err
 		}
+	}
 
-		if err := file.Chmod(f.Mode.Perm()); err != nil {
-			return fmt.Errorf("cannot change permissions of %q: %w",
-				filePath, err)
-		}
+	return nil
+}
 
-		if _, err := io.Copy(file, bytes.NewReader(f.Content)); err != nil {
-			return fmt.Errorf("cannot write %q: %w", filePath, err)
-		}
+// Status used by the archive extraction command when tar or gzip is not
+// available on the remote host; 127 is the status used by shells for unknown
+// commands.
+const tarUnavailableExitStatus = 127
+
+var errTarUnavailable = errors.New("tar not available")
+
+func (r *Runner) uploadEnvironmentFile() error {
+	script, err := environmentScript(r.runner.Environment, "\n")
+	if err != nil {
+		return err
+	}
+
+	filePath := path.Join(r.rootPath, ".environment")
+
+	if err := r.uploadFile(filePath, 0600, []byte(script)); err != nil {
+		return err
+	}
+
+	r.environmentFilePath = filePath
+
+	return nil
+}
+
+// environmentScript returns shell commands exporting environment variables,
+// each command being followed by a separator.
+func environmentScript(env map[string]string, separator string) (string, error) {
+	var buf bytes.Buffer
 
-		if err := file.Close(); err != nil {
-			return fmt.Errorf("cannot close %q: %w", filePath, err)
+	names := make([]string, 0, len(env))
+	for name := range env {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if !isShellVariableName(name) {
+			return "", fmt.Errorf("invalid environment variable name %q",
+				name)
 		}
+
+		value := env[name]
+
+		fmt.Fprintf(&buf, "export %s=%s%s", name, shellQuote(value),
+			separator)
+	}
+
+	return buf.String(), nil
+}
+
+func (r *Runner) uploadFile(filePath string, mode os.FileMode, content []byte) error {
+	openFlags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	// The sftp package does not support setting permissions when opening the
+	// file. See https://github.com/pkg/sftp/issues/335 for more information.
+	file, err := r.sftpClient.OpenFile(filePath, openFlags)
+	if err != nil {
+		return fmt.Errorf("cannot open %q: %w", filePath, err)
+	}
+
+	if err := file.Chmod(mode); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot change permissions of %q: %w",
+			filePath, err)
+	}
+
+	if _, err := io.Copy(file, bytes.NewReader(content)); err != nil {
+		file.Close()
+		return fmt.Errorf("cannot write %q: %w", filePath, err)
+	}
+
+	if err := file.Close(); err != nil {
+		return fmt.Errorf("cannot close %q: %w", filePath, err)
 	}
 
 	return nil
 }
 
+func isShellVariableName(s string) bool {
+	if s == "" {
+		return false
+	}
+
+	for i, c := range s {
+		switch {
+		case c == '_', c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+		case c >= '0' && c <= '9' && i > 0:
+		default:
+			return false
+		}
+	}
+
+	return true
+}
+
+// shellQuote quotes a string with single quotes, which is the only way to
+// protect newlines and all other special characters.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
 func (r *Runner) createDirectory(ctx context.Context, dirPath string, mode os.FileMode) error {
 	// We do not try to chmod if the permissions are already correct. This is
 	// annoying because it is an extra operation which is not useful most of
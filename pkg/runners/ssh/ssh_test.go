@@ -0,0 +1,85 @@
+package ssh
+
+import (
+	"io"
+	"os"
+	"path"
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/pkg/sftp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEnvironmentScript(t *testing.T) {
+	assert := assert.New(t)
+
+	script, err := environmentScript(map[string]string{
+		"FOO":   "bar",
+		"EMPTY": "",
+		"QUOTE": `it's "quoted" $HOME`,
+	}, "; ")
+	if assert.NoError(err) {
+		assert.Equal(`export EMPTY=''; export FOO='bar'; `+
+			`export QUOTE='it'\''s "quoted" $HOME'; `, script)
+	}
+
+	_, err = environmentScript(map[string]string{"FOO; rm -rf /": ""}, "; ")
+	assert.Error(err)
+}
+
+// newTestSFTPClient returns a sftp client connected to an in-process server
+// serving the local file system.
+func newTestSFTPClient(t *testing.T) *sftp.Client {
+	clientReader, serverWriter := io.Pipe()
+	serverReader, clientWriter := io.Pipe()
+
+	server, err := sftp.NewServer(struct {
+		io.Reader
+		io.WriteCloser
+	}{serverReader, serverWriter})
+	require.NoError(t, err)
+
+	go server.Serve()
+
+	client, err := sftp.NewClientPipe(clientReader, clientWriter)
+	require.NoError(t, err)
+
+	// Closing the server first unblocks the reception loop of the client
+	t.Cleanup(func() {
+		server.Close()
+		client.Close()
+	})
+
+	return client
+}
+
+func TestUploadFileSetFilesPermissions(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	rootPath := t.TempDir()
+
+	fileSet := eventline.NewFileSet()
+	fileSet.AddFile("script.sh", []byte("#!/bin/sh\necho hello\n"), 0755)
+	fileSet.AddFile("data.json", []byte("{}\n"), 0600)
+
+	r := Runner{
+		runner:     &eventline.Runner{FileSet: fileSet},
+		rootPath:   rootPath,
+		sftpClient: newTestSFTPClient(t),
+	}
+
+	require.NoError(r.uploadFileSetFiles())
+
+	for filePath, mode := range map[string]os.FileMode{
+		"script.sh": 0755,
+		"data.json": 0600,
+	} {
+		info, err := os.Stat(path.Join(rootPath, filePath))
+		if assert.NoError(err) {
+			assert.Equal(mode, info.Mode().Perm(), filePath)
+		}
+	}
+}
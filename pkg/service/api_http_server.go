@@ -51,6 +51,8 @@ func (s *APIHTTPServer) initHTTPServer() {
 	s.setupJobRoutes()
 	s.setupJobExecutionRoutes()
 	s.setupEventRoutes()
+	s.setupSubscriptionRoutes()
+	s.setupConnectorRoutes()
 }
 
 func (s *APIHTTPServer) hStatusHEAD(h *HTTPHandler) {
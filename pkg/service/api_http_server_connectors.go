@@ -0,0 +1,91 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/service/pkg/pg"
+)
+
+func (s *APIHTTPServer) setupConnectorRoutes() {
+	s.route("/connectors/quarantines", "GET",
+		s.hConnectorsQuarantinesGET,
+		HTTPRouteOptions{Admin: true})
+
+	s.route("/connectors/name/{name}/quarantine", "DELETE",
+		s.hConnectorsNameQuarantineDELETE,
+		HTTPRouteOptions{Admin: true})
+
+	s.route("/connectors/name/{name}/raw_events/replay", "POST",
+		s.hConnectorsNameRawEventsReplayPOST,
+		HTTPRouteOptions{Admin: true})
+}
+
+func (s *APIHTTPServer) hConnectorsQuarantinesGET(h *HTTPHandler) {
+	quarantines := eventline.ConnectorQuarantines{}
+
+	err := s.Pg.WithConn(func(conn pg.Conn) error {
+		if err := quarantines.LoadAll(conn); err != nil {
+			return fmt.Errorf("cannot load connector quarantines: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		h.ReplyInternalError(500, "%v", err)
+		return
+	}
+
+	h.ReplyJSON(200, quarantines)
+}
+
+func (s *APIHTTPServer) hConnectorsNameQuarantineDELETE(h *HTTPHandler) {
+	name := h.PathVariable("name")
+
+	if err := s.Service.ClearConnectorQuarantine(name); err != nil {
+		var unknownConnectorErr *eventline.UnknownConnectorDefError
+		var unknownQuarantineErr *eventline.UnknownConnectorQuarantineError
+
+		if errors.As(err, &unknownConnectorErr) {
+			h.ReplyError(404, "unknown_connector", "%v", err)
+		} else if errors.As(err, &unknownQuarantineErr) {
+			h.ReplyError(404, "unknown_connector_quarantine", "%v", err)
+		} else {
+			h.ReplyInternalError(500, "%v", err)
+		}
+
+		return
+	}
+
+	h.ReplyEmpty(204)
+}
+
+func (s *APIHTTPServer) hConnectorsNameRawEventsReplayPOST(h *HTTPHandler) {
+	name := h.PathVariable("name")
+
+	var replay eventline.RawEventReplay
+	if err := h.JSONRequestData(&replay); err != nil {
+		return
+	}
+
+	result, err := s.Service.ReplayRawEvents(name, &replay)
+	if err != nil {
+		var unknownConnectorErr *eventline.UnknownConnectorDefError
+		var unknownEventErr *eventline.UnknownEventDefError
+
+		if errors.As(err, &unknownConnectorErr) {
+			h.ReplyError(404, "unknown_connector", "%v", err)
+		} else if errors.Is(err, ErrRawEventReplayNotSupported) {
+			h.ReplyError(400, "raw_event_replay_not_supported", "%v", err)
+		} else if errors.As(err, &unknownEventErr) {
+			h.ReplyError(400, "unknown_event", "%v", err)
+		} else {
+			h.ReplyInternalError(500, "%v", err)
+		}
+
+		return
+	}
+
+	h.ReplyJSON(200, result)
+}
@@ -0,0 +1,54 @@
+package service
+
+import (
+	"net/http"
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAPIRawEventReplay(t *testing.T) {
+	require := require.New(t)
+
+	var req *TestRequest
+	var res *http.Response
+	var err error
+
+	client := NewTestAPIClient(t)
+
+	// Replay raw events
+	req = client.NewRequest("POST", "/connectors/name/github/raw_events/replay")
+	req.SetJSONBody(&eventline.RawEventReplay{Events: []string{"push"}})
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(200, res.StatusCode)
+
+	var result eventline.RawEventReplayResult
+	assertResponseJSONBody(t, res, &result)
+
+	// Unknown event
+	req = client.NewRequest("POST", "/connectors/name/github/raw_events/replay")
+	req.SetJSONBody(&eventline.RawEventReplay{Events: []string{"foo"}})
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(400, res.StatusCode)
+
+	// Raw events cannot be replayed into raw events
+	req = client.NewRequest("POST", "/connectors/name/github/raw_events/replay")
+	req.SetJSONBody(&eventline.RawEventReplay{Events: []string{"raw"}})
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(400, res.StatusCode)
+
+	// Connector without raw events
+	req = client.NewRequest("POST", "/connectors/name/time/raw_events/replay")
+	req.SetJSONBody(&eventline.RawEventReplay{Events: []string{"tick"}})
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(400, res.StatusCode)
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 
 	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
 	"go.n16f.net/service/pkg/pg"
 )
 
@@ -26,6 +27,23 @@ func (s *APIHTTPServer) setupIdentityRoutes() {
 
 	s.route("/identities/id/{id}", "DELETE", s.hIdentitiesIdDELETE,
 		HTTPRouteOptions{Project: true})
+
+	s.route("/identities/id/{id}/enable", "POST", s.hIdentitiesIdEnablePOST,
+		HTTPRouteOptions{Project: true})
+
+	s.route("/identities/id/{id}/disable", "POST", s.hIdentitiesIdDisablePOST,
+		HTTPRouteOptions{Project: true})
+
+	s.route("/identities/id/{id}/test", "POST", s.hIdentitiesIdTestPOST,
+		HTTPRouteOptions{Project: true})
+
+	s.route("/identities/id/{id}/rotate_key", "POST",
+		s.hIdentitiesIdRotateKeyPOST,
+		HTTPRouteOptions{Project: true})
+
+	s.route("/identities/id/{id}/retire_key", "POST",
+		s.hIdentitiesIdRetireKeyPOST,
+		HTTPRouteOptions{Project: true})
 }
 
 func (s *APIHTTPServer) hIdentitiesGET(h *HTTPHandler) {
@@ -168,3 +186,117 @@ func (s *APIHTTPServer) hIdentitiesIdDELETE(h *HTTPHandler) {
 
 	h.ReplyEmpty(204)
 }
+
+func (s *APIHTTPServer) hIdentitiesIdEnablePOST(h *HTTPHandler) {
+	identityId, err := h.IdPathVariable("id")
+	if err != nil {
+		return
+	}
+
+	if err := s.EnableIdentity(h, identityId); err != nil {
+		return
+	}
+
+	h.ReplyEmpty(204)
+}
+
+func (s *APIHTTPServer) hIdentitiesIdDisablePOST(h *HTTPHandler) {
+	identityId, err := h.IdPathVariable("id")
+	if err != nil {
+		return
+	}
+
+	if err := s.DisableIdentity(h, identityId); err != nil {
+		return
+	}
+
+	h.ReplyEmpty(204)
+}
+
+func (s *APIHTTPServer) hIdentitiesIdTestPOST(h *HTTPHandler) {
+	scope := h.Context.ProjectScope()
+
+	identityId, err := h.IdPathVariable("id")
+	if err != nil {
+		return
+	}
+
+	result, err := s.Service.TestIdentity(identityId, scope)
+	if err != nil {
+		var unknownIdentityErr *eventline.UnknownIdentityError
+
+		if errors.As(err, &unknownIdentityErr) {
+			h.ReplyError(404, "unknown_identity", "%v", err)
+		} else if errors.Is(err, ErrIdentityNotTestable) {
+			h.ReplyError(400, "identity_not_testable", "%v", err)
+		} else {
+			h.ReplyInternalError(500, "cannot test identity: %v", err)
+		}
+
+		return
+	}
+
+	h.ReplyJSON(200, result)
+}
+
+func (s *APIHTTPServer) hIdentitiesIdRotateKeyPOST(h *HTTPHandler) {
+	scope := h.Context.ProjectScope()
+
+	identityId, err := h.IdPathVariable("id")
+	if err != nil {
+		return
+	}
+
+	var rotation eventline.IdentityKeyRotation
+	if err := h.JSONRequestData(&rotation); err != nil {
+		return
+	}
+
+	identity, err := s.Service.RotateIdentityKey(identityId, &rotation, scope)
+	if err != nil {
+		replyIdentityKeyUpdateError(h, err)
+		return
+	}
+
+	h.ReplyJSON(200, identity)
+}
+
+func (s *APIHTTPServer) hIdentitiesIdRetireKeyPOST(h *HTTPHandler) {
+	scope := h.Context.ProjectScope()
+
+	identityId, err := h.IdPathVariable("id")
+	if err != nil {
+		return
+	}
+
+	var retirement eventline.IdentityKeyRetirement
+	if err := h.JSONRequestData(&retirement); err != nil {
+		return
+	}
+
+	identity, err := s.Service.RetireIdentityKey(identityId, &retirement,
+		scope)
+	if err != nil {
+		replyIdentityKeyUpdateError(h, err)
+		return
+	}
+
+	h.ReplyJSON(200, identity)
+}
+
+func replyIdentityKeyUpdateError(h *HTTPHandler, err error) {
+	var unknownIdentityErr *eventline.UnknownIdentityError
+	var validationErrs ejson.ValidationErrors
+
+	if errors.As(err, &unknownIdentityErr) {
+		h.ReplyError(404, "unknown_identity", "%v", err)
+	} else if errors.Is(err, ErrIdentityKeysNotRotatable) {
+		h.ReplyError(400, "identity_keys_not_rotatable", "%v", err)
+	} else if errors.Is(err, eventline.ErrMissingSecondaryKey) {
+		h.ReplyError(400, "missing_secondary_key", "%v", err)
+	} else if errors.As(err, &validationErrs) {
+		h.ReplyError(400, "invalid_identity_data", "%v", err)
+	} else {
+		h.ReplyInternalError(500, "cannot update identity: %v", err)
+	}
+}
@@ -73,6 +73,72 @@ func TestAPIIdentities(t *testing.T) {
 	require.Equal("foobar-2",
 		updatedIdentity.Data.(*cgeneric.APIKeyIdentity).Key)
 
+	// Disable it
+	req = client.NewRequest("POST",
+		"/identities/id/"+url.PathEscape(identityId.String())+"/disable")
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(204, res.StatusCode)
+
+	req = client.NewRequest("GET",
+		"/identities/id/"+url.PathEscape(identityId.String()))
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(200, res.StatusCode)
+
+	var disabledIdentity eventline.Identity
+	assertResponseJSONBody(t, res, &disabledIdentity)
+
+	require.True(disabledIdentity.Disabled)
+
+	// Enable it again
+	req = client.NewRequest("POST",
+		"/identities/id/"+url.PathEscape(identityId.String())+"/enable")
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(204, res.StatusCode)
+
+	// Rotate its key
+	req = client.NewRequest("POST",
+		"/identities/id/"+url.PathEscape(identityId.String())+"/rotate_key")
+	req.SetJSONBody(&eventline.IdentityKeyRotation{Key: "foobar-3"})
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(200, res.StatusCode)
+
+	var rotatedIdentity eventline.Identity
+	assertResponseJSONBody(t, res, &rotatedIdentity)
+
+	require.Equal([]string{"foobar-3", "foobar-2"},
+		rotatedIdentity.Data.(*cgeneric.APIKeyIdentity).Keys())
+
+	// Retire the previous key
+	req = client.NewRequest("POST",
+		"/identities/id/"+url.PathEscape(identityId.String())+"/retire_key")
+	req.SetJSONBody(&eventline.IdentityKeyRetirement{Key: "secondary"})
+
+	res, err = req.Send()
+	require.NoError(err)
+	require.Equal(200, res.StatusCode)
+
+	var retiredIdentity eventline.Identity
+	assertResponseJSONBody(t, res, &retiredIdentity)
+
+	require.Equal([]string{"foobar-3"},
+		retiredIdentity.Data.(*cgeneric.APIKeyIdentity).Keys())
+
+	// The primary key cannot be retired without a secondary key
+	req = client.NewRequest("POST",
+		"/identities/id/"+url.PathEscape(identityId.String())+"/retire_key")
+	req.SetJSONBody(&eventline.IdentityKeyRetirement{Key: "primary"})
+
+	_, err = req.Send()
+	assertRequestError(t, err, 400, "missing_secondary_key")
+
 	// Delete it
 	req = client.NewRequest("DELETE",
 		"/identities/id/"+url.PathEscape(identityId.String()))
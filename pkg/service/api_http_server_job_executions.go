@@ -1,6 +1,17 @@
 package service
 
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
 func (s *APIHTTPServer) setupJobExecutionRoutes() {
+	s.route("/job_executions/events", "GET", s.hJobExecutionsEventsGET,
+		HTTPRouteOptions{Project: true})
+
 	s.route("/job_executions/id/{id}", "GET", s.hJobExecutionsIdGET,
 		HTTPRouteOptions{Project: true})
 
@@ -19,12 +30,14 @@ func (s *APIHTTPServer) hJobExecutionsIdGET(h *HTTPHandler) {
 		return
 	}
 
-	je, err := s.LoadJobExecution(h, jeId)
+	withOutput := h.HasQueryParameter("output")
+
+	result, err := s.LoadJobExecutionResult(h, jeId, withOutput)
 	if err != nil {
 		return
 	}
 
-	h.ReplyJSON(200, je)
+	h.ReplyJSON(200, result)
 }
 
 func (s *APIHTTPServer) hJobExecutionsIdAbortPOST(h *HTTPHandler) {
@@ -52,3 +65,56 @@ func (s *APIHTTPServer) hJobExecutionsIdRestartPOST(h *HTTPHandler) {
 
 	h.ReplyEmpty(204)
 }
+
+func (s *APIHTTPServer) hJobExecutionsEventsGET(h *HTTPHandler) {
+	// Lifecycle events are streamed using the server-sent events format, see
+	// https://html.spec.whatwg.org/multipage/server-sent-events.html.
+
+	projectId := *h.Context.ProjectId
+
+	listener := s.Service.LifecycleEvents.Listen(projectId)
+	defer s.Service.LifecycleEvents.Unlisten(listener)
+
+	header := h.ResponseWriter.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+
+	h.ResponseWriter.WriteHeader(200)
+	h.ResponseWriter.(http.Flusher).Flush()
+
+	// Comments are regularly sent to keep the connection alive through
+	// proxies.
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+
+	ctx := h.Request.Context()
+
+	for {
+		var buf bytes.Buffer
+
+		select {
+		case e, ok := <-listener.C:
+			if !ok {
+				return
+			}
+
+			data, err := json.Marshal(e)
+			if err != nil {
+				h.Log.Error("cannot encode lifecycle event: %v", err)
+				return
+			}
+
+			fmt.Fprintf(&buf, "event: %s\ndata: %s\n\n", e.Type, data)
+
+		case <-ticker.C:
+			buf.WriteString(": keepalive\n\n")
+
+		case <-ctx.Done():
+			return
+		}
+
+		if err := h.ReplyChunk(&buf); err != nil {
+			return
+		}
+	}
+}
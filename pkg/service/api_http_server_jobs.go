@@ -4,12 +4,18 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/ejson"
 	"go.n16f.net/service/pkg/pg"
 )
 
+const (
+	DefaultJobExecutionWaitTimeout = 300  // seconds
+	MaxJobExecutionWaitTimeout     = 3600 // seconds
+)
+
 func (s *APIHTTPServer) setupJobRoutes() {
 	s.route("/jobs", "GET", s.hJobsGET,
 		HTTPRouteOptions{Project: true})
@@ -318,10 +324,120 @@ func (s *APIHTTPServer) hJobsIdExecutePOST(h *HTTPHandler) {
 		return
 	}
 
+	if !h.HasQueryParameter("wait") {
+		jobExecution, err := s.ExecuteJob(h, jobId, &input)
+		if err != nil {
+			return
+		}
+
+		h.ReplyJSON(200, jobExecution)
+		return
+	}
+
+	timeout := DefaultJobExecutionWaitTimeout
+	if value := h.QueryParameter("timeout"); value != "" {
+		i, err := strconv.Atoi(value)
+		if err != nil || i < 1 || i > MaxJobExecutionWaitTimeout {
+			h.ReplyError(400, "invalid_query_parameter",
+				"invalid timeout %q: value must be an integer between 1 "+
+					"and %d", value, MaxJobExecutionWaitTimeout)
+			return
+		}
+
+		timeout = i
+	}
+
+	// We start listening before creating the execution so that we cannot
+	// miss the end of executions which finish immediately.
+	listener := s.Service.LifecycleEvents.Listen(*h.Context.ProjectId)
+	defer s.Service.LifecycleEvents.Unlisten(listener)
+
 	jobExecution, err := s.ExecuteJob(h, jobId, &input)
 	if err != nil {
 		return
 	}
 
-	h.ReplyJSON(200, jobExecution)
+	finished, err := s.waitForJobExecution(h, jobExecution.Id, listener,
+		time.Duration(timeout)*time.Second)
+	if err != nil {
+		return
+	}
+
+	result, err := s.LoadJobExecutionResult(h, jobExecution.Id,
+		h.HasQueryParameter("output"))
+	if err != nil {
+		return
+	}
+
+	status := 200
+	if !finished {
+		status = 202
+	}
+
+	h.ReplyJSON(status, result)
+}
+
+// waitForJobExecution waits until a job execution is finished or until the
+// timeout is reached. We rely on lifecycle events to react as soon as the
+// execution finishes, but the job execution is also regularly reloaded: the
+// execution may be handled by another Eventline instance, and lifecycle
+// events are dropped for listeners which are not fast enough.
+//
+// If the client disconnects, the job execution keeps running unless the
+// abort_on_disconnect query parameter was set. In both cases, nothing is sent
+// to the client and an error is returned.
+func (s *APIHTTPServer) waitForJobExecution(h *HTTPHandler, jeId eventline.Id, listener *eventline.LifecycleEventListener, timeout time.Duration) (bool, error) {
+	scope := h.Context.ProjectScope()
+	ctx := h.Request.Context()
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+
+	ticker := time.NewTicker(10 * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case e, ok := <-listener.C:
+			if !ok {
+				return false, nil
+			}
+
+			if e.Type == eventline.LifecycleEventJobExecutionFinished &&
+				e.JobExecutionId == jeId {
+				return true, nil
+			}
+
+		case <-ticker.C:
+			var je eventline.JobExecution
+
+			err := s.Pg.WithConn(func(conn pg.Conn) error {
+				return je.Load(conn, jeId, scope)
+			})
+			if err != nil {
+				err = fmt.Errorf("cannot load job execution: %w", err)
+				h.ReplyInternalError(500, "%v", err)
+				return false, err
+			}
+
+			if je.Finished() {
+				return true, nil
+			}
+
+		case <-timer.C:
+			return false, nil
+
+		case <-ctx.Done():
+			if h.HasQueryParameter("abort_on_disconnect") {
+				_, err := s.Service.AbortJobExecution(jeId, scope)
+
+				var finishedErr *eventline.JobExecutionFinishedError
+				if err != nil && !errors.As(err, &finishedErr) {
+					h.Log.Error("cannot abort job execution %q: %v", jeId, err)
+				}
+			}
+
+			return false, ctx.Err()
+		}
+	}
 }
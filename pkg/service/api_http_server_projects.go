@@ -22,6 +22,10 @@ func (s *APIHTTPServer) setupProjectRoutes() {
 
 	s.route("/projects/id/{id}", "DELETE", s.hProjectsIdDELETE,
 		HTTPRouteOptions{Admin: true})
+
+	s.route("/projects/id/{id}/webhook_key/rotate", "POST",
+		s.hProjectsIdWebhookKeyRotatePOST,
+		HTTPRouteOptions{Admin: true})
 }
 
 func (s *APIHTTPServer) hProjectsGET(h *HTTPHandler) {
@@ -103,3 +107,17 @@ func (s *APIHTTPServer) hProjectsIdDELETE(h *HTTPHandler) {
 
 	h.ReplyEmpty(204)
 }
+
+func (s *APIHTTPServer) hProjectsIdWebhookKeyRotatePOST(h *HTTPHandler) {
+	projectId, err := h.IdPathVariable("id")
+	if err != nil {
+		return
+	}
+
+	key, err := s.RotateProjectWebhookKey(h, projectId)
+	if err != nil {
+		return
+	}
+
+	h.ReplyJSON(200, key)
+}
@@ -0,0 +1,163 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/pg"
+)
+
+func (s *APIHTTPServer) setupSubscriptionRoutes() {
+	s.route("/subscriptions/export", "GET",
+		s.hSubscriptionsExportGET,
+		HTTPRouteOptions{Project: true})
+
+	s.route("/subscriptions/import", "POST",
+		s.hSubscriptionsImportPOST,
+		HTTPRouteOptions{Project: true})
+
+	s.route("/subscriptions/health", "GET",
+		s.hSubscriptionsHealthGET,
+		HTTPRouteOptions{Project: true})
+}
+
+func (s *APIHTTPServer) hSubscriptionsExportGET(h *HTTPHandler) {
+	scope := h.Context.ProjectScope()
+
+	var exports eventline.SubscriptionExports
+
+	err := s.Pg.WithConn(func(conn pg.Conn) (err error) {
+		exports, err = eventline.LoadSubscriptionExports(conn, scope)
+		if err != nil {
+			err = fmt.Errorf("cannot load subscriptions: %w", err)
+		}
+		return
+	})
+	if err != nil {
+		h.ReplyInternalError(500, "%v", err)
+		return
+	}
+
+	if exports == nil {
+		exports = eventline.SubscriptionExports{}
+	}
+
+	h.ReplyJSON(200, exports)
+}
+
+func (s *APIHTTPServer) hSubscriptionsHealthGET(h *HTTPHandler) {
+	scope := h.Context.ProjectScope()
+
+	unhealthyOnly := h.HasQueryParameter("unhealthy")
+
+	var reports eventline.SubscriptionHealthReports
+
+	err := s.Pg.WithConn(func(conn pg.Conn) (err error) {
+		reports, err = eventline.LoadSubscriptionHealthReports(conn,
+			unhealthyOnly, scope)
+		if err != nil {
+			err = fmt.Errorf("cannot load subscriptions: %w", err)
+		}
+		return
+	})
+	if err != nil {
+		h.ReplyInternalError(500, "%v", err)
+		return
+	}
+
+	if reports == nil {
+		reports = eventline.SubscriptionHealthReports{}
+	}
+
+	h.ReplyJSON(200, reports)
+}
+
+func (s *APIHTTPServer) hSubscriptionsImportPOST(h *HTTPHandler) {
+	scope := h.Context.ProjectScope()
+
+	// Each subscription is decoded and imported separately so that an
+	// invalid subscription does not prevent the import of the others.
+	var elements []json.RawMessage
+	if err := h.JSONRequestData(&elements); err != nil {
+		return
+	}
+
+	results := make(eventline.SubscriptionImportResults, len(elements))
+	var subscriptionsCreatedOrUpdated bool
+
+	for i, element := range elements {
+		result, subscriptionCreatedOrUpdated :=
+			s.importSubscription(element, scope)
+
+		results[i] = result
+
+		if subscriptionCreatedOrUpdated {
+			subscriptionsCreatedOrUpdated = true
+		}
+	}
+
+	if subscriptionsCreatedOrUpdated {
+		if w := s.Service.FindWorker("subscription-worker"); w != nil {
+			w.WakeUp()
+		}
+	}
+
+	h.ReplyJSON(200, results)
+}
+
+func (s *APIHTTPServer) importSubscription(data json.RawMessage, scope eventline.Scope) (*eventline.SubscriptionImportResult, bool) {
+	result := eventline.SubscriptionImportResult{
+		Status: eventline.SubscriptionImportStatusFailed,
+	}
+
+	fail := func(err error) (*eventline.SubscriptionImportResult, bool) {
+		var validationErrs ejson.ValidationErrors
+
+		if errors.As(err, &validationErrs) {
+			result.Error = "invalid subscription"
+			result.ValidationErrors = validationErrs
+		} else {
+			result.Error = err.Error()
+		}
+
+		return &result, false
+	}
+
+	var export eventline.SubscriptionExport
+	if err := json.Unmarshal(data, &export); err != nil {
+		return fail(fmt.Errorf("invalid subscription: %w", err))
+	}
+
+	result.Job = export.Job
+
+	v := ejson.NewValidator()
+	export.ValidateJSON(v)
+	if err := v.Error(); err != nil {
+		return fail(err)
+	}
+
+	var subscriptionCreatedOrUpdated bool
+
+	err := s.Service.Pg.WithTx(func(conn pg.Conn) error {
+		id1 := PgAdvisoryLockId1
+		id2 := PgAdvisoryLockId2JobDeployment
+
+		if err := pg.TakeAdvisoryTxLock(conn, id1, id2); err != nil {
+			return fmt.Errorf("cannot take advisory lock: %w", err)
+		}
+
+		var err error
+		result.Status, subscriptionCreatedOrUpdated, err =
+			s.Service.ImportSubscription(conn, &export, scope)
+		return err
+	})
+	if err != nil {
+		result.Status = eventline.SubscriptionImportStatusFailed
+		return fail(err)
+	}
+
+	return &result, subscriptionCreatedOrUpdated
+}
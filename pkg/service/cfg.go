@@ -2,6 +2,7 @@ package service
 
 import (
 	"encoding/json"
+	"net"
 
 	"github.com/exograd/eventline/pkg/cryptoutils"
 	"github.com/exograd/eventline/pkg/eventline"
@@ -29,20 +30,39 @@ type ServiceCfg struct {
 
 	EncryptionKey cryptoutils.AES256Key `json:"encryption_key"`
 
+	SecretBackend *SecretBackendCfg `json:"secret_backend"`
+
 	WebHTTPServerURI    string `json:"web_http_server_uri"`
 	InsecureHTTPCookies bool   `json:"insecure_http_cookies"`
 
-	Connectors map[string]json.RawMessage `json:"connectors"`
+	OutboundProxy string `json:"outbound_proxy,omitempty"`
+
+	Connectors          map[string]json.RawMessage `json:"connectors"`
+	ConnectorQuarantine *ConnectorQuarantineCfg    `json:"connector_quarantine"`
 
 	Workers map[string]eventline.WorkerCfg `json:"workers"`
 
-	MaxParallelJobExecutions    int `json:"max_parallel_job_executions"`
-	JobExecutionRetention       int `json:"job_execution_retention"`        // days
-	JobExecutionRefreshInterval int `json:"job_execution_refresh_interval"` // seconds
-	JobExecutionTimeout         int `json:"job_execution_timeout"`          // seconds
+	MaxParallelJobExecutions           int `json:"max_parallel_job_executions"`
+	MaxParallelJobExecutionsPerProject int `json:"max_parallel_job_executions_per_project"`
+	JobSchedulingBatchSize             int `json:"job_scheduling_batch_size"`
+	MaxJobExecutionStartAttempts       int `json:"max_job_execution_start_attempts"`
+	JobSchedulerDrainTimeout           int `json:"job_scheduler_drain_timeout"`    // seconds
+	JobExecutionRetention              int `json:"job_execution_retention"`        // days
+	JobExecutionRefreshInterval        int `json:"job_execution_refresh_interval"` // seconds
+	JobExecutionTimeout                int `json:"job_execution_timeout"`          // seconds
+	MaxStepOutputRate                  int `json:"max_step_output_rate"`           // bytes per second
+
+	HTTPStepAllowedNetworks []string `json:"http_step_allowed_networks"`
+
+	SlowQueryThreshold int `json:"slow_query_threshold"` // milliseconds
 
 	SessionRetention int `json:"session_retention"` // days
 
+	EventRetention   int `json:"event_retention"` // days
+	EventGCBatchSize int `json:"event_gc_batch_size"`
+
+	SubscriptionUpdateSplay int `json:"subscription_update_splay"` // percents
+
 	AllowedRunners []string                   `json:"allowed_runners"`
 	Runners        map[string]json.RawMessage `json:"runners"`
 
@@ -77,10 +97,23 @@ func DefaultServiceCfg() ServiceCfg {
 			SchemaNames: []string{"eventline"},
 		},
 
+		SecretBackend: DefaultSecretBackendCfg(),
+
 		WebHTTPServerURI: "http://localhost:8087",
 
-		JobExecutionRefreshInterval: 10,
-		JobExecutionTimeout:         120,
+		JobSchedulingBatchSize:       1,
+		MaxJobExecutionStartAttempts: 5,
+		JobSchedulerDrainTimeout:     10,
+		JobExecutionRefreshInterval:  10,
+		JobExecutionTimeout:          120,
+
+		SlowQueryThreshold: 500,
+
+		SubscriptionUpdateSplay: 20,
+
+		EventGCBatchSize: 1000,
+
+		ConnectorQuarantine: DefaultConnectorQuarantineCfg(),
 
 		Notifications: DefaultNotificationsCfg(),
 	}
@@ -116,13 +149,32 @@ func (cfg *ServiceCfg) Check(v *ejson.Validator, s *Service) {
 	v.Check("encryption_key", !cfg.EncryptionKey.IsZero(),
 		"invalid_value", "missing encryption key")
 
+	v.CheckObject("secret_backend", cfg.SecretBackend)
+
 	v.CheckStringURI("web_http_server_uri", cfg.WebHTTPServerURI)
 
+	if cfg.OutboundProxy != "" {
+		eventline.CheckProxyURI(v, "outbound_proxy", cfg.OutboundProxy)
+	}
+
+	v.CheckObject("connector_quarantine", cfg.ConnectorQuarantine)
+
 	if cfg.MaxParallelJobExecutions != 0 {
 		v.CheckIntMin("max_parallel_job_executions",
 			cfg.MaxParallelJobExecutions, 1)
 	}
 
+	if cfg.MaxParallelJobExecutionsPerProject != 0 {
+		v.CheckIntMin("max_parallel_job_executions_per_project",
+			cfg.MaxParallelJobExecutionsPerProject, 1)
+	}
+
+	v.CheckIntMin("job_scheduling_batch_size", cfg.JobSchedulingBatchSize, 1)
+	v.CheckIntMin("max_job_execution_start_attempts",
+		cfg.MaxJobExecutionStartAttempts, 1)
+	v.CheckIntMin("job_scheduler_drain_timeout",
+		cfg.JobSchedulerDrainTimeout, 1)
+
 	if cfg.JobExecutionRetention != 0 {
 		v.CheckIntMin("job_execution_retention", cfg.JobExecutionRetention, 1)
 	}
@@ -136,10 +188,31 @@ func (cfg *ServiceCfg) Check(v *ejson.Validator, s *Service) {
 		v.CheckIntMin("job_execution_timeout", cfg.JobExecutionTimeout, 1)
 	}
 
+	v.CheckIntMin("max_step_output_rate", cfg.MaxStepOutputRate, 0)
+
+	v.CheckIntMin("slow_query_threshold", cfg.SlowQueryThreshold, 0)
+
+	v.WithChild("http_step_allowed_networks", func() {
+		for i, network := range cfg.HTTPStepAllowedNetworks {
+			_, _, err := net.ParseCIDR(network)
+			v.Check(i, err == nil, "invalid_network",
+				"invalid network %q", network)
+		}
+	})
+
 	if cfg.SessionRetention != 0 {
 		v.CheckIntMin("session_retention", cfg.SessionRetention, 1)
 	}
 
+	if cfg.EventRetention != 0 {
+		v.CheckIntMin("event_retention", cfg.EventRetention, 1)
+	}
+
+	v.CheckIntMin("event_gc_batch_size", cfg.EventGCBatchSize, 1)
+
+	v.CheckIntMinMax("subscription_update_splay", cfg.SubscriptionUpdateSplay,
+		0, 100)
+
 	v.WithChild("allowed_runners", func() {
 		for i, r := range cfg.AllowedRunners {
 			v.CheckStringValue(i, r, s.runnerNames)
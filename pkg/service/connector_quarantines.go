@@ -0,0 +1,132 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
+	"go.n16f.net/service/pkg/pg"
+)
+
+type ConnectorQuarantineCfg struct {
+	FailureThreshold int `json:"failure_threshold"` // 0 to disable quarantines
+	FailureRate      int `json:"failure_rate"`      // percents
+	Window           int `json:"window"`            // seconds
+}
+
+func DefaultConnectorQuarantineCfg() *ConnectorQuarantineCfg {
+	return &ConnectorQuarantineCfg{
+		FailureRate: 50,
+		Window:      300,
+	}
+}
+
+func (cfg *ConnectorQuarantineCfg) ValidateJSON(v *ejson.Validator) {
+	v.CheckIntMin("failure_threshold", cfg.FailureThreshold, 0)
+	v.CheckIntMinMax("failure_rate", cfg.FailureRate, 1, 100)
+	v.CheckIntMin("window", cfg.Window, 1)
+}
+
+func (s *Service) initConnectorFailureTracker(name string) {
+	cfg := s.Cfg.ConnectorQuarantine
+
+	s.connectorFailureTrackers[name] = &eventline.ConnectorFailureTracker{
+		Window:           time.Duration(cfg.Window) * time.Second,
+		FailureThreshold: cfg.FailureThreshold,
+		FailureRate:      cfg.FailureRate,
+	}
+}
+
+// CheckConnectorQuarantine returns true if the connector is quarantined, in
+// which case the delivery is recorded and must not be processed.
+func (s *Service) CheckConnectorQuarantine(name string) (bool, error) {
+	now := time.Now().UTC()
+
+	var quarantined bool
+
+	err := s.Pg.WithConn(func(conn pg.Conn) (err error) {
+		quarantined, err = eventline.RecordConnectorQuarantineDelivery(conn,
+			name, now)
+		return
+	})
+	if err != nil {
+		return false, fmt.Errorf("cannot record delivery: %w", err)
+	}
+
+	if quarantined {
+		// The quarantine may have been created by another instance: there
+		// is no point in keeping failures which were already accounted for,
+		// and they would cause a new quarantine as soon as the current one is
+		// cleared.
+		s.connectorFailureMutex.Lock()
+		s.connectorFailureTrackers[name].Reset()
+		s.connectorFailureMutex.Unlock()
+	}
+
+	return quarantined, nil
+}
+
+// RecordConnectorDelivery tracks the result of the processing of a delivery
+// and quarantines the connector if failures are too frequent. processingErr
+// must only be set for errors which are not caused by the request itself.
+func (s *Service) RecordConnectorDelivery(name string, processingErr error) {
+	now := time.Now().UTC()
+
+	s.connectorFailureMutex.Lock()
+	tracker := s.connectorFailureTrackers[name]
+	tracker.Record(now, processingErr != nil)
+	tripped := tracker.Tripped(now)
+	if tripped {
+		tracker.Reset()
+	}
+	s.connectorFailureMutex.Unlock()
+
+	if !tripped {
+		return
+	}
+
+	quarantine := eventline.ConnectorQuarantine{
+		Connector: name,
+		StartTime: now,
+		Reason:    processingErr.Error(),
+	}
+
+	err := s.Pg.WithConn(func(conn pg.Conn) error {
+		return quarantine.Insert(conn)
+	})
+	if err != nil {
+		s.Log.Error("cannot quarantine connector %q: %v", name, err)
+		return
+	}
+
+	s.Log.Error("connector %q quarantined after repeated failures: %v",
+		name, processingErr)
+}
+
+func (s *Service) ClearConnectorQuarantine(name string) error {
+	if _, found := s.connectors[name]; !found {
+		return &eventline.UnknownConnectorDefError{Name: name}
+	}
+
+	err := s.Pg.WithConn(func(conn pg.Conn) error {
+		return eventline.DeleteConnectorQuarantine(conn, name)
+	})
+	if err != nil {
+		var unknownQuarantineErr *eventline.UnknownConnectorQuarantineError
+		if errors.As(err, &unknownQuarantineErr) {
+			return err
+		}
+
+		return fmt.Errorf("cannot delete connector quarantine: %w", err)
+	}
+
+	s.connectorFailureMutex.Lock()
+	s.connectorFailureTrackers[name].Reset()
+	s.connectorFailureMutex.Unlock()
+
+	s.Log.Info("connector %q quarantine cleared", name)
+
+	return nil
+}
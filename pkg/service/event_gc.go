@@ -0,0 +1,83 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// EventGC deletes old events in batches so that each transaction only locks a
+// bounded number of rows. A run is a sequence of batches; the total number of
+// events deleted is logged at the end of each run.
+type EventGC struct {
+	Log     *log.Logger
+	Service *Service
+
+	nbDeleted int64 // for the current run
+}
+
+func NewEventGC(s *Service) *EventGC {
+	return &EventGC{
+		Service: s,
+	}
+}
+
+func (egc *EventGC) Init(w *eventline.Worker) {
+	egc.Log = w.Log
+}
+
+func (egc *EventGC) Start() error {
+	return nil
+}
+
+func (egc *EventGC) Stop() {
+}
+
+func (egc *EventGC) ProcessJob() (bool, error) {
+	var n int64
+
+	retention := egc.Service.Cfg.EventRetention
+	batchSize := egc.Service.Cfg.EventGCBatchSize
+
+	err := egc.Service.Pg.WithTx(func(conn pg.Conn) error {
+		// Only one instance deletes events at a time; the others would
+		// compete for the same rows.
+		id1 := PgAdvisoryLockId1
+		id2 := PgAdvisoryLockId2EventGC
+
+		if err := pg.TakeAdvisoryTxLock(conn, id1, id2); err != nil {
+			return fmt.Errorf("cannot take advisory lock: %w", err)
+		}
+
+		now := time.Now().UTC()
+
+		var err error
+		n, err = eventline.DeleteOldEvents(conn, retention, now, batchSize)
+		if err != nil {
+			return fmt.Errorf("cannot delete events: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	egc.nbDeleted += n
+
+	if n == 0 {
+		if egc.nbDeleted > 0 {
+			egc.Log.Info("%d events deleted", egc.nbDeleted)
+			egc.nbDeleted = 0
+		}
+
+		return false, nil
+	}
+
+	egc.Log.Debug(1, "%d events deleted in batch", n)
+
+	return true, nil
+}
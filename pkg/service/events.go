@@ -25,6 +25,7 @@ func (s *Service) ReplayEvent(eventId eventline.Id, scope eventline.Scope) (*eve
 		event.CreationTime = now
 		event.Processed = false
 		event.OriginalEventId = &eventId
+		event.DropReason = ""
 
 		if err := event.Insert(conn); err != nil {
 			return fmt.Errorf("cannot insert event: %w", err)
@@ -59,9 +60,17 @@ func (s *Service) ProcessEvent(conn pg.Conn, event *eventline.Event, scope event
 		filtersMatch := true
 		if trigger := job.Spec.Trigger; trigger != nil {
 			filtersMatch = trigger.Filters.Match(event.DataValue)
+
+			now := time.Now().UTC()
+
+			if filtersMatch && trigger.EventExpired(event, now) {
+				event.DropReason = trigger.EventExpirationMessage()
+				s.Log.Info("dropping event %q: %s", event.Id,
+					event.DropReason)
+			}
 		}
 
-		if filtersMatch {
+		if filtersMatch && event.DropReason == "" {
 			_, err := s.InstantiateJob(conn, &job, event, nil, scope)
 			if err != nil {
 				return false, fmt.Errorf("cannot instantiate job %q: %w",
@@ -32,6 +32,11 @@ func (s *HTTPServer) LoadIdentity(h *HTTPHandler, identityId eventline.Id) (*eve
 		return nil, err
 	}
 
+	if err := identity.ResolveData(); err != nil {
+		h.ReplyInternalError(500, "%v", err)
+		return nil, err
+	}
+
 	return &identity, nil
 }
 
@@ -59,5 +64,44 @@ func (s *HTTPServer) LoadIdentityByName(h *HTTPHandler, identityName string) (*e
 		return nil, err
 	}
 
+	if err := identity.ResolveData(); err != nil {
+		h.ReplyInternalError(500, "%v", err)
+		return nil, err
+	}
+
 	return &identity, nil
 }
+
+func (s *HTTPServer) EnableIdentity(h *HTTPHandler, identityId eventline.Id) error {
+	scope := h.Context.ProjectScope()
+
+	_, err := s.Service.EnableIdentity(identityId, scope)
+	if err != nil {
+		s.replyIdentityUpdateError(h, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *HTTPServer) DisableIdentity(h *HTTPHandler, identityId eventline.Id) error {
+	scope := h.Context.ProjectScope()
+
+	_, err := s.Service.DisableIdentity(identityId, scope)
+	if err != nil {
+		s.replyIdentityUpdateError(h, err)
+		return err
+	}
+
+	return nil
+}
+
+func (s *HTTPServer) replyIdentityUpdateError(h *HTTPHandler, err error) {
+	var unknownIdentityErr *eventline.UnknownIdentityError
+
+	if errors.As(err, &unknownIdentityErr) {
+		h.ReplyError(404, "unknown_identity", "%v", err)
+	} else {
+		h.ReplyInternalError(500, "cannot update identity: %v", err)
+	}
+}
@@ -35,6 +35,56 @@ func (s *HTTPServer) LoadJobExecution(h *HTTPHandler, jeId eventline.Id) (*event
 	return &je, nil
 }
 
+// LoadJobExecutionResult loads a job execution and the result of its steps.
+// Step outputs are only included if withOutput is true.
+func (s *HTTPServer) LoadJobExecutionResult(h *HTTPHandler, jeId eventline.Id, withOutput bool) (*eventline.JobExecutionResult, error) {
+	scope := h.Context.ProjectScope()
+
+	var je eventline.JobExecution
+	var ses eventline.StepExecutions
+
+	maxOutputSize := 0
+	if withOutput {
+		maxOutputSize = 1_000_000
+	}
+
+	err := s.Pg.WithConn(func(conn pg.Conn) error {
+		if err := je.Load(conn, jeId, scope); err != nil {
+			return fmt.Errorf("cannot load job execution: %w", err)
+		}
+
+		err := ses.LoadByJobExecutionIdWithTruncatedOutput(conn, jeId,
+			maxOutputSize, "\n[truncated]\n")
+		if err != nil {
+			return fmt.Errorf("cannot load step executions: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		var unknownJobExecutionErr *eventline.UnknownJobExecutionError
+
+		if errors.As(err, &unknownJobExecutionErr) {
+			h.ReplyError(404, "unknown_job_execution", "%v", err)
+		} else {
+			h.ReplyInternalError(500, "%v", err)
+		}
+
+		return nil, err
+	}
+
+	result := eventline.NewJobExecutionResult(&je, ses)
+
+	if withOutput {
+		for i, se := range ses {
+			output := se.Output
+			result.Steps[i].Output = &output
+		}
+	}
+
+	return result, nil
+}
+
 func (s *HTTPServer) AbortJobExecution(h *HTTPHandler, jeId eventline.Id) error {
 	scope := h.Context.ProjectScope()
 
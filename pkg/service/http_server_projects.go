@@ -161,3 +161,21 @@ func (s *HTTPServer) DeleteProject(h *HTTPHandler, projectId eventline.Id) error
 
 	return nil
 }
+
+func (s *HTTPServer) RotateProjectWebhookKey(h *HTTPHandler, projectId eventline.Id) (*eventline.ProjectWebhookKey, error) {
+	key, err := s.Service.RotateProjectWebhookKey(projectId)
+	if err != nil {
+		var unknownProjectErr *eventline.UnknownProjectError
+
+		if errors.As(err, &unknownProjectErr) {
+			h.ReplyError(404, "unknown_project", "%v", err)
+		} else {
+			h.ReplyInternalError(500, "cannot rotate project webhook key: %v",
+				err)
+		}
+
+		return nil, fmt.Errorf("cannot rotate project webhook key: %w", err)
+	}
+
+	return key, nil
+}
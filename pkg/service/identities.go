@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/http"
@@ -9,6 +10,7 @@ import (
 	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
 	"go.n16f.net/log"
 	"go.n16f.net/service/pkg/pg"
 	"go.n16f.net/service/pkg/shttp"
@@ -16,6 +18,10 @@ import (
 
 var (
 	ErrIdentityNotRefreshable = errors.New("identity is not refreshable")
+	ErrIdentityNotTestable    = errors.New("identity is not testable")
+
+	ErrIdentityKeysNotRotatable = errors.New(
+		"identity does not support key rotation")
 )
 
 type DuplicateIdentityNameError struct {
@@ -69,6 +75,8 @@ func (s *Service) CreateIdentity(newIdentity *eventline.NewIdentity, scope event
 			Data:         newIdentity.Data,
 		}
 
+		scheduleIdentityRefresh(identity)
+
 		if err := identity.Insert(conn); err != nil {
 			return fmt.Errorf("cannot insert identity: %w", err)
 		}
@@ -79,6 +87,8 @@ func (s *Service) CreateIdentity(newIdentity *eventline.NewIdentity, scope event
 		return nil, err
 	}
 
+	s.wakeUpIdentityRefresher(identity)
+
 	return identity, nil
 }
 
@@ -116,6 +126,8 @@ func (s *Service) UpdateIdentity(identityId eventline.Id, newIdentity *eventline
 		identity.Type = newIdentity.Type
 		identity.Data = newIdentity.Data
 
+		scheduleIdentityRefresh(&identity)
+
 		if err := identity.Update(conn); err != nil {
 			return fmt.Errorf("cannot update identity: %w", err)
 		}
@@ -126,9 +138,38 @@ func (s *Service) UpdateIdentity(identityId eventline.Id, newIdentity *eventline
 		return nil, err
 	}
 
+	s.wakeUpIdentityRefresher(&identity)
+
 	return &identity, nil
 }
 
+// scheduleIdentityRefresh schedules an immediate refresh for refreshable
+// identities which do not go through an OAuth2 authorization flow, so that
+// their access token is obtained without waiting for a user action.
+func scheduleIdentityRefresh(identity *eventline.Identity) {
+	if _, ok := identity.Data.(eventline.OAuth2IdentityData); ok {
+		return
+	}
+
+	identityData, ok := identity.Data.(eventline.RefreshableIdentityData)
+	if !ok {
+		return
+	}
+
+	refreshTime := identityData.RefreshTime()
+	identity.RefreshTime = &refreshTime
+}
+
+func (s *Service) wakeUpIdentityRefresher(identity *eventline.Identity) {
+	if identity.RefreshTime == nil {
+		return
+	}
+
+	if w := s.FindWorker("identity-refresher"); w != nil {
+		w.WakeUp()
+	}
+}
+
 func (s *Service) DeleteIdentity(identityId eventline.Id, scope eventline.Scope) error {
 	return s.Pg.WithTx(func(conn pg.Conn) error {
 		var identity eventline.Identity
@@ -152,6 +193,115 @@ func (s *Service) DeleteIdentity(identityId eventline.Id, scope eventline.Scope)
 	})
 }
 
+func (s *Service) EnableIdentity(identityId eventline.Id, scope eventline.Scope) (*eventline.Identity, error) {
+	identity, err := s.updateIdentityDisabled(identityId, false, scope)
+	if err != nil {
+		return nil, err
+	}
+
+	// Disabled identities are not refreshed, so the refresh time may have
+	// passed while the identity was disabled.
+	s.wakeUpIdentityRefresher(identity)
+
+	return identity, nil
+}
+
+func (s *Service) DisableIdentity(identityId eventline.Id, scope eventline.Scope) (*eventline.Identity, error) {
+	return s.updateIdentityDisabled(identityId, true, scope)
+}
+
+func (s *Service) updateIdentityDisabled(identityId eventline.Id, disabled bool, scope eventline.Scope) (*eventline.Identity, error) {
+	var identity eventline.Identity
+
+	err := s.Pg.WithTx(func(conn pg.Conn) error {
+		if err := identity.LoadForUpdate(conn, identityId, scope); err != nil {
+			return fmt.Errorf("cannot load identity: %w", err)
+		}
+
+		if identity.Disabled == disabled {
+			return nil
+		}
+
+		identity.Disabled = disabled
+		identity.UpdateTime = time.Now().UTC()
+
+		if err := identity.Update(conn); err != nil {
+			return fmt.Errorf("cannot update identity: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
+func (s *Service) RotateIdentityKey(identityId eventline.Id, rotation *eventline.IdentityKeyRotation, scope eventline.Scope) (*eventline.Identity, error) {
+	return s.updateIdentityKeys(identityId, scope,
+		func(data eventline.KeyRotatableIdentityData) error {
+			data.RotateKey(rotation.Key)
+			return nil
+		})
+}
+
+func (s *Service) RetireIdentityKey(identityId eventline.Id, retirement *eventline.IdentityKeyRetirement, scope eventline.Scope) (*eventline.Identity, error) {
+	return s.updateIdentityKeys(identityId, scope,
+		func(data eventline.KeyRotatableIdentityData) error {
+			switch retirement.Key {
+			case "primary":
+				return data.RetirePrimaryKey()
+			case "secondary":
+				data.RetireSecondaryKey()
+			}
+
+			return nil
+		})
+}
+
+func (s *Service) updateIdentityKeys(identityId eventline.Id, scope eventline.Scope, fn func(eventline.KeyRotatableIdentityData) error) (*eventline.Identity, error) {
+	var identity eventline.Identity
+
+	err := s.Pg.WithTx(func(conn pg.Conn) error {
+		if err := identity.LoadForUpdate(conn, identityId, scope); err != nil {
+			return fmt.Errorf("cannot load identity: %w", err)
+		}
+
+		if err := identity.ResolveData(); err != nil {
+			return err
+		}
+
+		data, ok := identity.Data.(eventline.KeyRotatableIdentityData)
+		if !ok {
+			return ErrIdentityKeysNotRotatable
+		}
+
+		if err := fn(data); err != nil {
+			return err
+		}
+
+		// Rotating keys can produce invalid data, e.g. when the new key is
+		// the current primary key.
+		if err := ejson.Validate(data); err != nil {
+			return err
+		}
+
+		identity.UpdateTime = time.Now().UTC()
+
+		if err := identity.Update(conn); err != nil {
+			return fmt.Errorf("cannot update identity: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &identity, nil
+}
+
 func (s *Service) IdentityRedirectionURI(identity *eventline.Identity, sessionId eventline.Id, defaultURI string) (string, error) {
 	// For the time being, OAuth2 identities are the only ones using a
 	// redirection mechanism.
@@ -161,7 +311,7 @@ func (s *Service) IdentityRedirectionURI(identity *eventline.Identity, sessionId
 		return "", fmt.Errorf("cannot encode oauth2 state: %w", err)
 	}
 
-	httpClient, err := s.oauth2HTTPClient(identity.Id, &sessionId)
+	httpClient, err := s.oauth2HTTPClient(identity, &sessionId)
 	if err != nil {
 		return "", fmt.Errorf("cannot create http client: %w", err)
 	}
@@ -206,10 +356,57 @@ func (s *Service) RefreshIdentity(identityId eventline.Id, scope eventline.Scope
 	return refreshErr
 }
 
+// TestIdentity checks that the credentials of an identity are valid without
+// running any job. The latency of the result is the duration of the whole
+// test.
+func (s *Service) TestIdentity(identityId eventline.Id, scope eventline.Scope) (*eventline.IdentityTestResult, error) {
+	var identity eventline.Identity
+
+	err := s.Pg.WithConn(func(conn pg.Conn) error {
+		if err := identity.Load(conn, identityId, scope); err != nil {
+			return fmt.Errorf("cannot load identity: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	if err := identity.ResolveData(); err != nil {
+		return nil, err
+	}
+
+	c := eventline.GetConnector(identity.Connector)
+
+	c2, ok := c.(eventline.TestableConnector)
+	if !ok {
+		return nil, ErrIdentityNotTestable
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	start := time.Now()
+
+	result, err := c2.TestIdentity(ctx, &identity)
+	if err != nil {
+		return nil, fmt.Errorf("cannot test identity: %w", err)
+	}
+
+	result.Latency = int(time.Since(start).Milliseconds())
+
+	return result, nil
+}
+
 func (s *Service) refreshIdentity(conn pg.Conn, identity *eventline.Identity, scope eventline.Scope) error {
-	identityData := identity.Data.(eventline.RefreshableOAuth2IdentityData)
+	if err := identity.ResolveData(); err != nil {
+		return err
+	}
+
+	identityData := identity.Data.(eventline.RefreshableIdentityData)
 
-	httpClient, err := s.oauth2HTTPClient(identity.Id, nil)
+	httpClient, err := s.oauth2HTTPClient(identity, nil)
 	if err != nil {
 		return fmt.Errorf("cannot create http client: %w", err)
 	}
@@ -228,10 +425,10 @@ func (s *Service) refreshIdentity(conn pg.Conn, identity *eventline.Identity, sc
 	return nil
 }
 
-func (s *Service) oauth2HTTPClient(identityId eventline.Id, sessionId *eventline.Id) (*http.Client, error) {
+func (s *Service) oauth2HTTPClient(identity *eventline.Identity, sessionId *eventline.Id) (*http.Client, error) {
 
 	logger := s.Log.Child("oauth2", log.Data{
-		"identity": identityId.String(),
+		"identity": identity.Id.String(),
 	})
 
 	if sessionId != nil {
@@ -243,7 +440,9 @@ func (s *Service) oauth2HTTPClient(identityId eventline.Id, sessionId *eventline
 		LogRequests: true,
 	}
 
-	httpClient, err := shttp.NewClient(httpClientCfg)
+	proxyURI := s.connectorProxies[identity.Connector]
+
+	httpClient, err := eventline.NewHTTPClient(httpClientCfg, proxyURI)
 	if err != nil {
 		return nil, fmt.Errorf("cannot create http client: %w", err)
 	}
@@ -31,12 +31,12 @@ func (w *JobExecutionWatcher) Stop() {
 }
 
 func (w *JobExecutionWatcher) ProcessJob() (bool, error) {
-	var deleted bool
+	var je *eventline.JobExecution
 
-	err := w.Service.Pg.WithTx(func(conn pg.Conn) error {
+	err := w.Service.Pg.WithTx(func(conn pg.Conn) (err error) {
 		timeout := w.Service.Cfg.JobExecutionTimeout
 
-		je, err := eventline.LoadDeadJobExecution(conn, timeout)
+		je, err = eventline.LoadDeadJobExecution(conn, timeout)
 		if err != nil {
 			return fmt.Errorf("cannot load job execution: %w", err)
 		} else if je == nil {
@@ -51,12 +51,16 @@ func (w *JobExecutionWatcher) ProcessJob() (bool, error) {
 			return fmt.Errorf("cannot update job execution %q: %w", je.Id, err)
 		}
 
-		deleted = true
 		return nil
 	})
 	if err != nil {
 		return false, err
+	} else if je == nil {
+		return false, nil
 	}
 
-	return deleted, nil
+	w.Service.LifecycleEvents.Publish(eventline.NewJobExecutionLifecycleEvent(
+		eventline.LifecycleEventJobExecutionFinished, je))
+
+	return true, nil
 }
@@ -24,9 +24,52 @@ func (s *Service) LoadJobExecutionContext(conn pg.Conn, je *eventline.JobExecuti
 	return &ctx, nil
 }
 
+// Identities expiring within this delay are refreshed before the job
+// execution starts.
+const IdentityExpirationMargin = 5 * time.Minute
+
+func (s *Service) refreshExpiringIdentities(conn pg.Conn, ectx *eventline.ExecutionContext, scope eventline.Scope) error {
+	limit := time.Now().UTC().Add(IdentityExpirationMargin)
+
+	for name, identity := range ectx.Identities {
+		data, ok := identity.Data.(eventline.ExpirableIdentityData)
+		if !ok || !data.ExpiresBefore(limit) {
+			continue
+		}
+
+		var identity2 eventline.Identity
+		if err := identity2.LoadForUpdate(conn, identity.Id, scope); err != nil {
+			return fmt.Errorf("cannot load identity %q: %w", name, err)
+		}
+
+		if err := s.refreshIdentity(conn, &identity2, scope); err != nil {
+			return fmt.Errorf("identity %q has expired and cannot be "+
+				"refreshed (%v); it must be re-authorized", name, err)
+		}
+
+		identity2.LastUseTime = identity.LastUseTime
+		ectx.Identities[name] = &identity2
+	}
+
+	return nil
+}
+
 func (s *Service) StartJobExecution(conn pg.Conn, je *eventline.JobExecution, scope eventline.Scope) error {
 	now := time.Now().UTC()
 
+	// Do not start the job execution if the event which triggered it has
+	// expired, for example because of a backlog of job executions.
+	if trigger := je.JobSpec.Trigger; trigger != nil && je.EventId != nil {
+		var event eventline.Event
+		if err := event.Load(conn, *je.EventId, scope); err != nil {
+			return fmt.Errorf("cannot load event: %w", err)
+		}
+
+		if trigger.EventExpired(&event, now) {
+			return s.expireJobExecution(conn, je, trigger, now)
+		}
+	}
+
 	// Mark the job execution as started and update it
 	je.Status = eventline.JobExecutionStatusStarted
 	je.StartTime = &now
@@ -37,6 +80,9 @@ func (s *Service) StartJobExecution(conn pg.Conn, je *eventline.JobExecution, sc
 		return fmt.Errorf("cannot update job execution %q: %w", je.Id, err)
 	}
 
+	s.LifecycleEvents.Publish(eventline.NewJobExecutionLifecycleEvent(
+		eventline.LifecycleEventJobExecutionStarted, je))
+
 	// Load step executions
 	var ses eventline.StepExecutions
 	if err := ses.LoadByJobExecutionId(conn, je.Id); err != nil {
@@ -49,6 +95,11 @@ func (s *Service) StartJobExecution(conn pg.Conn, je *eventline.JobExecution, sc
 		return fmt.Errorf("cannot load execution context: %w", err)
 	}
 
+	// Make sure identities will not expire while the job is running
+	if err := s.refreshExpiringIdentities(conn, ectx, scope); err != nil {
+		return err
+	}
+
 	// Load the project and its settings
 	projectId := scope.(*eventline.ProjectScope).ProjectId
 
@@ -78,6 +129,39 @@ func (s *Service) StartJobExecution(conn pg.Conn, je *eventline.JobExecution, sc
 	return nil
 }
 
+// expireJobExecution aborts a job execution whose event has expired. The
+// caller is responsible for publishing the lifecycle event once the
+// transaction has been committed.
+func (s *Service) expireJobExecution(conn pg.Conn, je *eventline.JobExecution, trigger *eventline.Trigger, now time.Time) error {
+	s.Log.Info("aborting job execution %q: %s", je.Id,
+		trigger.EventExpirationMessage())
+
+	je.Status = eventline.JobExecutionStatusAborted
+	je.EndTime = &now
+	je.FailureMessage = trigger.EventExpirationMessage()
+	je.UpdateTime = now
+	je.RefreshTime = nil
+
+	if err := je.Update(conn); err != nil {
+		return fmt.Errorf("cannot update job execution %q: %w", je.Id, err)
+	}
+
+	var ses eventline.StepExecutions
+	if err := ses.LoadByJobExecutionIdForUpdate(conn, je.Id); err != nil {
+		return fmt.Errorf("cannot load step executions: %w", err)
+	}
+
+	for _, se := range ses {
+		se.Status = eventline.StepExecutionStatusAborted
+
+		if err := se.Update(conn); err != nil {
+			return fmt.Errorf("cannot update step execution: %w", err)
+		}
+	}
+
+	return nil
+}
+
 func (s *Service) AbortJobExecution(jeId eventline.Id, scope eventline.Scope) (*eventline.JobExecution, error) {
 	var je eventline.JobExecution
 
@@ -132,6 +216,9 @@ func (s *Service) AbortJobExecution(jeId eventline.Id, scope eventline.Scope) (*
 		return nil, err
 	}
 
+	s.LifecycleEvents.Publish(eventline.NewJobExecutionLifecycleEvent(
+		eventline.LifecycleEventJobExecutionFinished, &je))
+
 	return &je, nil
 }
 
@@ -172,6 +259,8 @@ func (s *Service) RestartJobExecution(jeId eventline.Id, scope eventline.Scope)
 			se.EndTime = nil
 			se.FailureMessage = ""
 			se.Output = ""
+			se.ExitCode = nil
+			se.Signal = nil
 
 			if err := se.Update(conn); err != nil {
 				return fmt.Errorf("cannot update step execution: %w", err)
@@ -190,9 +279,15 @@ func (s *Service) RestartJobExecution(jeId eventline.Id, scope eventline.Scope)
 		return nil, err
 	}
 
+	s.LifecycleEvents.Publish(eventline.NewJobExecutionLifecycleEvent(
+		eventline.LifecycleEventJobExecutionQueued, &je))
+
 	return &je, nil
 }
 
+// UpdateJobExecutionFailure marks a job execution and its unfinished steps as
+// failed. The caller is responsible for publishing the lifecycle event once
+// the transaction has been committed.
 func (s *Service) UpdateJobExecutionFailure(conn pg.Conn, je *eventline.JobExecution, format string, args ...interface{}) error {
 	var ses eventline.StepExecutions
 
@@ -255,6 +350,11 @@ func (s *Service) handleJobExecutionTermination(jeId eventline.Id) error {
 			return fmt.Errorf("cannot send notification: %w", err)
 		}
 
+		err := s.SendJobExecutionWebhookNotification(conn, &je)
+		if err != nil {
+			return fmt.Errorf("cannot send webhook notification: %w", err)
+		}
+
 		return nil
 	})
 }
@@ -329,3 +429,34 @@ func (s *Service) SendJobExecutionNotification(conn pg.Conn, je *eventline.JobEx
 	return s.CreateNotification(conn, settings.EmailAddresses, subject,
 		templateName, templateData, scope)
 }
+
+// SendJobExecutionWebhookNotification sends a summary of a finished job
+// execution to the notification webhook of the project if there is one.
+// Contrary to email notifications, webhook notifications are sent for all
+// job executions, whatever their status.
+func (s *Service) SendJobExecutionWebhookNotification(conn pg.Conn, je *eventline.JobExecution) error {
+	var settings eventline.ProjectNotificationSettings
+	if err := settings.Load(conn, je.ProjectId); err != nil {
+		return fmt.Errorf("cannot load notification settings: %w", err)
+	}
+
+	if settings.WebhookURI == "" {
+		return nil
+	}
+
+	var ses eventline.StepExecutions
+	if err := ses.LoadByJobExecutionId(conn, je.Id); err != nil {
+		return fmt.Errorf("cannot load step executions: %w", err)
+	}
+
+	jePath := path.Join("/job_executions", "id", je.Id.String())
+	jeURI := s.WebHTTPServerURI.ResolveReference(&url.URL{Path: jePath})
+
+	notification := eventline.NewJobExecutionNotification(je, ses,
+		jeURI.String())
+
+	scope := eventline.NewProjectScope(je.ProjectId)
+
+	return s.CreateWebhookNotification(conn, settings.WebhookURI,
+		notification, scope)
+}
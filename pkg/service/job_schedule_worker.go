@@ -0,0 +1,101 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+// JobScheduleWorker instantiates jobs which have a cron schedule when their
+// next tick is due.
+type JobScheduleWorker struct {
+	Log     *log.Logger
+	Service *Service
+}
+
+func NewJobScheduleWorker(s *Service) *JobScheduleWorker {
+	return &JobScheduleWorker{
+		Service: s,
+	}
+}
+
+func (w *JobScheduleWorker) Init(ew *eventline.Worker) {
+	w.Log = ew.Log
+}
+
+func (w *JobScheduleWorker) Start() error {
+	return nil
+}
+
+func (w *JobScheduleWorker) Stop() {
+}
+
+func (w *JobScheduleWorker) ProcessJob() (bool, error) {
+	var processed, jeCreated bool
+
+	err := w.Service.Pg.WithTx(func(conn pg.Conn) error {
+		schedule, err := eventline.LoadJobScheduleForProcessing(conn)
+		if err != nil {
+			return fmt.Errorf("cannot load job schedule: %w", err)
+		} else if schedule == nil {
+			return nil
+		}
+
+		w.Log.Info("processing schedule of job %q", schedule.JobId)
+
+		scope := eventline.NewProjectScope(schedule.ProjectId)
+
+		var job eventline.Job
+		if err := job.Load(conn, schedule.JobId, scope); err != nil {
+			return fmt.Errorf("cannot load job %q: %w", schedule.JobId, err)
+		}
+
+		now := time.Now().UTC()
+
+		ticks, nextTime, err := schedule.Ticks(now)
+		if err != nil {
+			return fmt.Errorf("cannot compute ticks of job %q: %w",
+				job.Id, err)
+		}
+
+		// Ticks of disabled jobs are consumed without creating any
+		// execution, so that enabling a job does not trigger a burst of
+		// executions.
+		if !job.Disabled {
+			for _, tick := range ticks {
+				_, err := w.Service.InstantiateScheduledJob(conn, &job, tick,
+					scope)
+				if err != nil {
+					return fmt.Errorf("cannot instantiate job %q: %w",
+						job.Id, err)
+				}
+
+				jeCreated = true
+			}
+		}
+
+		schedule.LastTime = &now
+		schedule.NextTime = nextTime
+
+		if err := schedule.Upsert(conn); err != nil {
+			return fmt.Errorf("cannot update job schedule: %w", err)
+		}
+
+		processed = true
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	if jeCreated {
+		if w := w.Service.FindWorker("job-scheduler"); w != nil {
+			w.WakeUp()
+		}
+	}
+
+	return processed, nil
+}
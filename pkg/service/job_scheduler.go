@@ -2,15 +2,38 @@ package service
 
 import (
 	"fmt"
+	"sync"
+	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/influx"
 	"go.n16f.net/service/pkg/pg"
 )
 
+// The job scheduler reports, in the eventline_job_scheduler measurement, the
+// number of pending job executions and the duration of each processing
+// iteration, and in the eventline_job_scheduling measurement, the delay
+// between the scheduled time of each job execution and its start.
+//
+// Counting pending job executions requires a full scan of the pending ones,
+// so it is only done if Influx is configured, and at most once per
+// pendingJobExecutionCountInterval instead of at each iteration.
+
+const pendingJobExecutionCountInterval = 10 * time.Second
+
 type JobScheduler struct {
 	Log     *log.Logger
 	Service *Service
+	Influx  *influx.Client // nil if Influx is not configured
+
+	// When the service stops, the scheduler stops starting job executions
+	// and waits for the current processing iteration to finish.
+	draining     bool
+	drainMutex   sync.Mutex
+	processingWg sync.WaitGroup
+
+	lastPendingCountTime time.Time
 }
 
 func NewJobScheduler(s *Service) *JobScheduler {
@@ -24,14 +47,66 @@ func (js *JobScheduler) Init(w *eventline.Worker) {
 }
 
 func (js *JobScheduler) Start() error {
+	js.Influx = js.Service.Service.Influx
 	return nil
 }
 
 func (js *JobScheduler) Stop() {
+	js.drainMutex.Lock()
+	js.draining = true
+	js.drainMutex.Unlock()
+
+	doneChan := make(chan struct{})
+
+	go func() {
+		js.processingWg.Wait()
+		close(doneChan)
+	}()
+
+	timeout := time.Duration(js.Service.Cfg.JobSchedulerDrainTimeout) *
+		time.Second
+
+	select {
+	case <-doneChan:
+	case <-time.After(timeout):
+		js.Log.Error("job executions still being started after %v", timeout)
+	}
+}
+
+func (js *JobScheduler) startProcessing() bool {
+	js.drainMutex.Lock()
+	defer js.drainMutex.Unlock()
+
+	if js.draining {
+		return false
+	}
+
+	js.processingWg.Add(1)
+	return true
+}
+
+func (js *JobScheduler) isDraining() bool {
+	js.drainMutex.Lock()
+	defer js.drainMutex.Unlock()
+
+	return js.draining
 }
 
 func (js *JobScheduler) ProcessJob() (bool, error) {
+	if !js.startProcessing() {
+		return false, nil
+	}
+	defer js.processingWg.Done()
+
 	var processed bool
+	var startErr error
+
+	// Job executions which were aborted or failed instead of being started;
+	// their lifecycle events are only published once the transaction has
+	// been committed.
+	var finishedJes []*eventline.JobExecution
+
+	startTime := time.Now()
 
 	err := js.Service.Pg.WithTx(func(conn pg.Conn) error {
 		id1 := PgAdvisoryLockId1
@@ -41,41 +116,233 @@ func (js *JobScheduler) ProcessJob() (bool, error) {
 			return fmt.Errorf("cannot take advisory lock: %w", err)
 		}
 
-		if max := js.Service.Cfg.MaxParallelJobExecutions; max > 0 {
-			globalScope := eventline.NewGlobalScope()
+		for i := 0; i < js.Service.Cfg.JobSchedulingBatchSize; i++ {
+			if js.isDraining() {
+				break
+			}
 
-			n, err := eventline.CountStartedJobExecutions(conn, globalScope)
+			started, err := js.startJobExecution(conn, &finishedJes)
 			if err != nil {
-				return fmt.Errorf("cannot count job executions: %w", err)
+				// Job executions already started in this transaction
+				// must not be rolled back.
+				startErr = err
+				break
+			} else if !started {
+				break
 			}
 
-			if n >= int64(max) {
-				return nil
-			}
+			processed = true
+		}
+
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+
+	for _, je := range finishedJes {
+		js.Service.LifecycleEvents.Publish(
+			eventline.NewJobExecutionLifecycleEvent(
+				eventline.LifecycleEventJobExecutionFinished, je))
+	}
+
+	js.reportProcessing(time.Since(startTime), processed)
+
+	return processed, startErr
+}
+
+// startJobExecution starts the next job execution if there is one. The
+// operation runs in a savepoint so that a failure does not affect other job
+// executions started in the same transaction.
+//
+// If the job execution cannot be started, the failure is recorded so that it
+// is retried later, and true is returned so that other job executions can be
+// processed.
+//
+// Job executions which end up finished, either because their event expired
+// or because they could not be started at all, are added to finishedJes.
+func (js *JobScheduler) startJobExecution(conn pg.Conn, finishedJes *[]*eventline.JobExecution) (bool, error) {
+	if err := pg.Exec(conn, "SAVEPOINT start_job_execution"); err != nil {
+		return false, fmt.Errorf("cannot create savepoint: %w", err)
+	}
+
+	je, err := js.startNextJobExecution(conn)
+	if err != nil {
+		query := "ROLLBACK TO SAVEPOINT start_job_execution"
+		if rollbackErr := pg.Exec(conn, query); rollbackErr != nil {
+			js.Log.Error("cannot rollback to savepoint: %v", rollbackErr)
+			return false, err
 		}
 
-		je, err := eventline.LoadJobExecutionForScheduling(conn)
+		if je == nil {
+			return false, err
+		}
+
+		failedJe, err := js.handleStartFailure(conn, je.Id, err)
 		if err != nil {
-			return fmt.Errorf("cannot load job execution: %w", err)
-		} else if je == nil {
-			return nil
+			return false, err
+		} else if failedJe != nil {
+			*finishedJes = append(*finishedJes, failedJe)
 		}
 
-		js.Log.Info("processing job execution %q", je.Id)
+		return true, nil
+	}
 
-		scope := eventline.NewProjectScope(je.ProjectId)
+	query := "RELEASE SAVEPOINT start_job_execution"
+	if err := pg.Exec(conn, query); err != nil {
+		return false, fmt.Errorf("cannot release savepoint: %w", err)
+	}
 
-		if err := js.Service.StartJobExecution(conn, je, scope); err != nil {
-			return fmt.Errorf("cannot start job execution %q: %w",
-				je.Id, err)
+	if je != nil && je.Finished() {
+		*finishedJes = append(*finishedJes, je)
+	}
+
+	return je != nil, nil
+}
+
+// startNextJobExecution loads and starts the next job execution. If the job
+// execution cannot be started, it is returned with the error.
+func (js *JobScheduler) startNextJobExecution(conn pg.Conn) (*eventline.JobExecution, error) {
+	if max := js.Service.Cfg.MaxParallelJobExecutions; max > 0 {
+		globalScope := eventline.NewGlobalScope()
+
+		n, err := eventline.CountStartedJobExecutions(conn, globalScope)
+		if err != nil {
+			return nil, fmt.Errorf("cannot count job executions: %w", err)
 		}
 
-		processed = true
-		return nil
-	})
+		if n >= int64(max) {
+			return nil, nil
+		}
+	}
+
+	maxPerProject := js.Service.Cfg.MaxParallelJobExecutionsPerProject
+	now := time.Now().UTC()
+
+	je, err := eventline.LoadJobExecutionForScheduling(conn, maxPerProject,
+		now)
 	if err != nil {
-		return false, err
+		return nil, fmt.Errorf("cannot load job execution: %w", err)
+	} else if je == nil {
+		return nil, nil
+	}
+
+	js.Log.Info("processing job execution %q", je.Id)
+
+	// Update the schedule time of the project first: once the runner is
+	// started, nothing else should be able to fail.
+	err = eventline.UpdateProjectScheduleTime(conn, je.ProjectId, now)
+	if err != nil {
+		return je, fmt.Errorf("cannot update project schedule time: %w",
+			err)
+	}
+
+	scope := eventline.NewProjectScope(je.ProjectId)
+
+	if err := js.Service.StartJobExecution(conn, je, scope); err != nil {
+		return je, fmt.Errorf("cannot start job execution %q: %w",
+			je.Id, err)
+	}
+
+	js.reportSchedulingLatency(je, now)
+
+	return je, nil
+}
+
+// handleStartFailure records the failure to start a job execution. If the
+// maximum number of attempts has been reached, the job execution is marked as
+// failed and returned.
+func (js *JobScheduler) handleStartFailure(conn pg.Conn, jeId eventline.Id, startErr error) (*eventline.JobExecution, error) {
+	now := time.Now().UTC()
+
+	failure, err := eventline.RecordJobExecutionStartFailure(conn, jeId, now)
+	if err != nil {
+		return nil, fmt.Errorf("cannot record start failure of job execution "+
+			"%q: %w", jeId, err)
+	}
+
+	maxAttempts := js.Service.Cfg.MaxJobExecutionStartAttempts
+
+	if failure.NbFailures < maxAttempts {
+		js.Log.Error("%v (attempt %d/%d, next attempt in %v)", startErr,
+			failure.NbFailures, maxAttempts,
+			failure.NextAttemptTime.Sub(now).Round(time.Second))
+		return nil, nil
+	}
+
+	js.Log.Error("%v (attempt %d/%d, giving up)", startErr,
+		failure.NbFailures, maxAttempts)
+
+	// The job execution was modified before the failure; the changes were
+	// rolled back, so we reload it.
+	var je eventline.JobExecution
+	if err := je.LoadForUpdateNoScope(conn, jeId); err != nil {
+		return nil, fmt.Errorf("cannot load job execution %q: %w", jeId, err)
+	}
+
+	err = js.Service.UpdateJobExecutionFailure(conn, &je,
+		"cannot start job execution after %d attempts: %v",
+		failure.NbFailures, startErr)
+	if err != nil {
+		return nil, fmt.Errorf("cannot update job execution %q: %w",
+			jeId, err)
+	}
+
+	return &je, nil
+}
+
+func (js *JobScheduler) reportProcessing(duration time.Duration, processed bool) {
+	if processed {
+		js.Log.Debug(1, "processing done in %v", duration)
+	}
+
+	if js.Influx == nil {
+		return
+	}
+
+	fields := influx.Fields{
+		"duration": duration.Microseconds(),
+	}
+
+	now := time.Now()
+
+	if now.Sub(js.lastPendingCountTime) >= pendingJobExecutionCountInterval {
+		js.lastPendingCountTime = now
+
+		var nbPending int64
+
+		err := js.Service.Pg.WithConn(func(conn pg.Conn) (err error) {
+			scope := eventline.NewGlobalScope()
+			nbPending, err = eventline.CountPendingJobExecutions(conn, scope)
+			return
+		})
+		if err != nil {
+			js.Log.Error("cannot count pending job executions: %v", err)
+		} else {
+			fields["nb_pending_job_executions"] = nbPending
+
+			js.Log.Debug(1, "%d pending job executions", nbPending)
+		}
+	}
+
+	point := influx.NewPoint("eventline_job_scheduler", influx.Tags{}, fields)
+	js.Influx.EnqueuePoint(point)
+}
+
+func (js *JobScheduler) reportSchedulingLatency(je *eventline.JobExecution, now time.Time) {
+	latency := now.Sub(je.ScheduledTime)
+
+	js.Log.Debug(1, "job execution %q started %v after its scheduled time",
+		je.Id, latency)
+
+	if js.Influx == nil {
+		return
+	}
+
+	fields := influx.Fields{
+		"latency": latency.Milliseconds(),
 	}
 
-	return processed, nil
+	point := influx.NewPoint("eventline_job_scheduling", influx.Tags{}, fields)
+	js.Influx.EnqueuePoint(point)
 }
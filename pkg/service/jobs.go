@@ -70,6 +70,12 @@ func (v *JobSpecValidator) checkJobSpec() error {
 			"jobs with mandatory parameters cannot have a trigger")
 	}
 
+	if hasMandatoryParams && v.JobSpec.Schedule != nil {
+		v.Validator.AddError("schedule",
+			"invalid_schedule_with_mandatory_parameters",
+			"jobs with mandatory parameters cannot have a schedule")
+	}
+
 	// Trigger
 	if trigger := v.JobSpec.Trigger; trigger != nil {
 		v.Validator.WithChild("trigger", func() {
@@ -93,6 +99,20 @@ func (v *JobSpecValidator) checkJobSpec() error {
 		}
 	})
 
+	// Environment
+	v.Validator.WithChild("environment", func() {
+		for name, value := range v.JobSpec.Environment {
+			// Syntax errors are reported by JobSpec.ValidateJSON
+			refs, _ := eventline.ParseEnvironmentReferences(value)
+
+			for _, ref := range refs {
+				if ref.Type == eventline.EnvironmentReferenceTypeIdentity {
+					v.checkIdentityReference(name, ref)
+				}
+			}
+		}
+	})
+
 	return nil
 }
 
@@ -123,6 +143,12 @@ func (v *JobSpecValidator) checkIdentityName(token interface{}, name string) {
 		return
 	}
 
+	if identity.Disabled {
+		v.Validator.AddError(token, "disabled_identity",
+			"identity %q is disabled", name)
+		return
+	}
+
 	switch identity.Status {
 	case eventline.IdentityStatusPending:
 		v.Validator.AddError(token, "pending_identity",
@@ -135,6 +161,20 @@ func (v *JobSpecValidator) checkIdentityName(token interface{}, name string) {
 	}
 }
 
+func (v *JobSpecValidator) checkIdentityReference(token interface{}, ref *eventline.EnvironmentReference) {
+	v.checkIdentityName(token, ref.Identity)
+
+	identity, found := v.Identities[ref.Identity]
+	if !found {
+		return
+	}
+
+	if identity.DataDef().Entry(ref.Field) == nil {
+		v.Validator.AddError(token, "unknown_identity_field",
+			"unknown field %q for identity %q", ref.Field, ref.Identity)
+	}
+}
+
 func (s *Service) CreateOrUpdateJob(conn pg.Conn, spec *eventline.JobSpec, scope eventline.Scope) (*eventline.Job, bool, error) {
 	if spec.Runner == nil {
 		spec.Runner = &eventline.JobRunner{
@@ -169,6 +209,11 @@ func (s *Service) CreateOrUpdateJob(conn pg.Conn, spec *eventline.JobSpec, scope
 
 	job.Id = id
 
+	// Schedule handling
+	if err := s.updateJobSchedule(conn, &job, now); err != nil {
+		return nil, false, fmt.Errorf("cannot update schedule: %w", err)
+	}
+
 	// Subscription handling
 	subscription := new(eventline.Subscription)
 	err = subscription.LoadByJobForUpdate(conn, job.Id, scope)
@@ -234,6 +279,52 @@ func (s *Service) CreateOrUpdateJob(conn pg.Conn, spec *eventline.JobSpec, scope
 	return &job, subscriptionCreatedOrUpdated, nil
 }
 
+func (s *Service) updateJobSchedule(conn pg.Conn, job *eventline.Job, now time.Time) error {
+	schedule, err := eventline.LoadJobSchedule(conn, job.Id)
+	if err != nil {
+		return fmt.Errorf("cannot load schedule: %w", err)
+	}
+
+	spec := job.Spec.Schedule
+
+	if spec == nil {
+		if schedule != nil {
+			if err := eventline.DeleteJobSchedule(conn, job.Id); err != nil {
+				return fmt.Errorf("cannot delete schedule: %w", err)
+			}
+		}
+
+		return nil
+	}
+
+	// If the cron expression and timezone did not change, we keep the next
+	// tick so that redeploying a job does not affect its executions.
+	if schedule == nil || schedule.Spec.Cron != spec.Cron ||
+		schedule.Spec.Timezone != spec.Timezone {
+		nextTime, err := spec.NextTime(now)
+		if err != nil {
+			return err
+		}
+
+		if schedule == nil {
+			schedule = &eventline.JobSchedule{
+				JobId:     job.Id,
+				ProjectId: job.ProjectId,
+			}
+		}
+
+		schedule.NextTime = nextTime
+	}
+
+	schedule.Spec = spec
+
+	if err := schedule.Upsert(conn); err != nil {
+		return fmt.Errorf("cannot upsert schedule: %w", err)
+	}
+
+	return nil
+}
+
 func (s *Service) DeleteJob(conn pg.Conn, job *eventline.Job, scope eventline.Scope) error {
 	if job.Spec.Trigger != nil {
 		var subscription eventline.Subscription
@@ -273,27 +364,38 @@ func (s *Service) RenameJob(conn pg.Conn, jobId eventline.Id, data *eventline.Jo
 }
 
 func (s *Service) InstantiateJob(conn pg.Conn, job *eventline.Job, event *eventline.Event, params map[string]interface{}, scope eventline.Scope) (*eventline.JobExecution, error) {
+	scheduledTime := time.Now().UTC()
+	if event != nil {
+		scheduledTime = event.EventTime
+	}
+
+	return s.instantiateJob(conn, job, event, params, scheduledTime, scope)
+}
+
+func (s *Service) InstantiateScheduledJob(conn pg.Conn, job *eventline.Job, tick time.Time, scope eventline.Scope) (*eventline.JobExecution, error) {
+	return s.instantiateJob(conn, job, nil, nil, tick, scope)
+}
+
+func (s *Service) instantiateJob(conn pg.Conn, job *eventline.Job, event *eventline.Event, params map[string]interface{}, scheduledTime time.Time, scope eventline.Scope) (*eventline.JobExecution, error) {
 	now := time.Now().UTC()
 
 	projectId := scope.(*eventline.ProjectScope).ProjectId
 
 	// Job
 	jobExecution := eventline.JobExecution{
-		Id:           eventline.GenerateId(),
-		ProjectId:    projectId,
-		JobId:        job.Id,
-		JobSpec:      job.Spec,
-		Parameters:   params,
-		CreationTime: now,
-		UpdateTime:   now,
-		Status:       eventline.JobExecutionStatusCreated,
-	}
-
-	if event == nil {
-		jobExecution.ScheduledTime = now
-	} else {
+		Id:            eventline.GenerateId(),
+		ProjectId:     projectId,
+		JobId:         job.Id,
+		JobSpec:       job.Spec,
+		Parameters:    params,
+		CreationTime:  now,
+		UpdateTime:    now,
+		ScheduledTime: scheduledTime,
+		Status:        eventline.JobExecutionStatusCreated,
+	}
+
+	if event != nil {
 		jobExecution.EventId = &event.Id
-		jobExecution.ScheduledTime = event.EventTime
 	}
 
 	if err := jobExecution.Insert(conn); err != nil {
@@ -320,6 +422,9 @@ func (s *Service) InstantiateJob(conn pg.Conn, job *eventline.Job, event *eventl
 		}
 	}
 
+	s.LifecycleEvents.Publish(eventline.NewJobExecutionLifecycleEvent(
+		eventline.LifecycleEventJobExecutionQueued, &jobExecution))
+
 	return &jobExecution, nil
 }
 
@@ -2,8 +2,11 @@ package service
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
 	"net"
+	"net/http"
 	"net/smtp"
 	"path"
 	"time"
@@ -12,6 +15,7 @@ import (
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/ejson"
 	"go.n16f.net/service/pkg/pg"
+	"go.n16f.net/service/pkg/shttp"
 )
 
 type NotificationsCfg struct {
@@ -111,6 +115,32 @@ func (s *Service) CreateNotification(conn pg.Conn, recipients []string, subject,
 	return nil
 }
 
+func (s *Service) CreateWebhookNotification(conn pg.Conn, uri string, value interface{}, scope eventline.Scope) error {
+	projectId := scope.(*eventline.ProjectScope).ProjectId
+	now := time.Now().UTC()
+
+	body, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("cannot encode message: %w", err)
+	}
+
+	notification := eventline.Notification{
+		Id:               eventline.GenerateId(),
+		ProjectId:        projectId,
+		Recipients:       []string{},
+		Message:          body,
+		NextDeliveryTime: now,
+		DeliveryDelay:    0,
+		WebhookURI:       uri,
+	}
+
+	if err := notification.Insert(conn); err != nil {
+		return fmt.Errorf("cannot insert notification: %w", err)
+	}
+
+	return nil
+}
+
 func (s *Service) RenderNotificationText(name string, data interface{}) ([]byte, error) {
 	name = path.Join("notifications", name)
 
@@ -124,6 +154,10 @@ func (s *Service) RenderNotificationText(name string, data interface{}) ([]byte,
 }
 
 func (s *Service) DeliverNotification(conn pg.Conn, n *eventline.Notification) error {
+	if n.WebhookURI != "" {
+		return s.deliverWebhookNotification(conn, n)
+	}
+
 	cfg := s.Cfg.Notifications
 	smtpCfg := cfg.SMTPServer
 
@@ -141,3 +175,59 @@ func (s *Service) DeliverNotification(conn pg.Conn, n *eventline.Notification) e
 	return smtp.SendMail(smtpCfg.Address, auth, cfg.FromAddress, n.Recipients,
 		n.Message)
 }
+
+func (s *Service) deliverWebhookNotification(conn pg.Conn, n *eventline.Notification) error {
+	// The secret is loaded at delivery time so that notifications are
+	// always signed with the current secret of the project.
+	var settings eventline.ProjectNotificationSettings
+	if err := settings.Load(conn, n.ProjectId); err != nil {
+		return fmt.Errorf("cannot load notification settings: %w", err)
+	}
+
+	client, err := eventline.NewHTTPClient(shttp.ClientCfg{
+		Log:                 s.Log.Child("notifications", nil),
+		DisableRedirections: true,
+	}, s.outboundProxyURI)
+	if err != nil {
+		return fmt.Errorf("cannot create http client: %w", err)
+	}
+	defer client.CloseConnections()
+
+	req, err := http.NewRequest("POST", n.WebhookURI,
+		bytes.NewReader(n.Message))
+	if err != nil {
+		return fmt.Errorf("cannot create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+
+	if secret := settings.WebhookSecret; secret != "" {
+		req.Header.Set(eventline.NotificationSignatureHeader,
+			eventline.NotificationSignature(n.Message, secret))
+		req.Header.Set(eventline.NotificationKeyIdHeader,
+			eventline.NotificationKeyId(secret))
+	}
+
+	now := time.Now().UTC()
+
+	if secret := settings.ActivePreviousWebhookSecret(now); secret != "" {
+		req.Header.Set(eventline.NotificationPreviousSignatureHeader,
+			eventline.NotificationSignature(n.Message, secret))
+		req.Header.Set(eventline.NotificationPreviousKeyIdHeader,
+			eventline.NotificationKeyId(secret))
+	}
+
+	res, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("cannot send request: %w", err)
+	}
+	defer res.Body.Close()
+
+	io.Copy(io.Discard, res.Body)
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("request failed with status %d", res.StatusCode)
+	}
+
+	return nil
+}
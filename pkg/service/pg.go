@@ -10,4 +10,5 @@ const (
 	PgAdvisoryLockId2ServiceInit   uint32 = 0x0001
 	PgAdvisoryLockId2JobScheduling uint32 = 0x0002
 	PgAdvisoryLockId2JobDeployment uint32 = 0x0003
+	PgAdvisoryLockId2EventGC       uint32 = 0x0004
 )
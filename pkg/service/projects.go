@@ -22,6 +22,8 @@ func (cfg *ProjectConfiguration) ValidateJSON(v *ejson.Validator) {
 	v.CheckObject("project_notification_settings", cfg.ProjectNotificationSettings)
 }
 
+var ErrMissingWebhookSecret = errors.New("missing webhook secret")
+
 type DuplicateProjectNameError struct {
 	Name string
 }
@@ -75,6 +77,11 @@ func (s *Service) createProject(conn pg.Conn, newProject *eventline.NewProject,
 			"settings: %w", err)
 	}
 
+	webhookKey := eventline.NewProjectWebhookKey(project.Id)
+	if err := webhookKey.Insert(conn); err != nil {
+		return nil, fmt.Errorf("cannot insert project webhook key: %w", err)
+	}
+
 	return project, nil
 }
 
@@ -151,6 +158,32 @@ func (s *Service) DeleteProject(projectId eventline.Id, hctx *HTTPContext) error
 	})
 }
 
+// RotateProjectWebhookKey creates a new webhook key for a project. Webhooks
+// created from now on use the new key; existing webhooks keep using the key
+// they were created with until their subscription is terminated.
+func (s *Service) RotateProjectWebhookKey(projectId eventline.Id) (*eventline.ProjectWebhookKey, error) {
+	var key *eventline.ProjectWebhookKey
+
+	err := s.Pg.WithTx(func(conn pg.Conn) error {
+		var project eventline.Project
+		if err := project.LoadForUpdate(conn, projectId); err != nil {
+			return fmt.Errorf("cannot load project: %w", err)
+		}
+
+		key = eventline.NewProjectWebhookKey(projectId)
+		if err := key.Insert(conn); err != nil {
+			return fmt.Errorf("cannot insert project webhook key: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return key, nil
+}
+
 func (s *Service) TerminateSubscriptions(conn pg.Conn, scope eventline.Scope) error {
 	var subscriptions eventline.Subscriptions
 	if err := subscriptions.LoadAllForUpdate(conn, scope); err != nil {
@@ -242,7 +275,25 @@ func (s *Service) UpdateProjectConfiguration(projectId eventline.Id, cfg *Projec
 			return fmt.Errorf("cannot update project settings: %w", err)
 		}
 
-		if err := cfg.ProjectNotificationSettings.Update(conn); err != nil {
+		notificationSettings := cfg.ProjectNotificationSettings
+
+		if notificationSettings.WebhookURI == "" {
+			notificationSettings.WebhookSecret = ""
+		} else {
+			var currentSettings eventline.ProjectNotificationSettings
+			if err := currentSettings.Load(conn, projectId); err != nil {
+				return fmt.Errorf("cannot load project notification "+
+					"settings: %w", err)
+			}
+
+			notificationSettings.UpdateWebhookSecret(&currentSettings, now)
+
+			if notificationSettings.WebhookSecret == "" {
+				return ErrMissingWebhookSecret
+			}
+		}
+
+		if err := notificationSettings.Update(conn); err != nil {
 			return fmt.Errorf("cannot update project notification "+
 				"settings: %w", err)
 		}
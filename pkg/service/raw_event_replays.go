@@ -0,0 +1,41 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+var ErrRawEventReplayNotSupported = errors.New("connector does not support " +
+	"raw event replays")
+
+func (s *Service) ReplayRawEvents(cname string, replay *eventline.RawEventReplay) (*eventline.RawEventReplayResult, error) {
+	c, found := s.connectors[cname]
+	if !found {
+		return nil, &eventline.UnknownConnectorDefError{Name: cname}
+	}
+
+	rc, ok := c.(eventline.RawEventReplayingConnector)
+	if !ok {
+		return nil, ErrRawEventReplayNotSupported
+	}
+
+	cdef := c.Definition()
+
+	for _, ename := range replay.Events {
+		if err := cdef.ValidateEventName(ename); err != nil {
+			return nil, err
+		}
+	}
+
+	nbEvents, err := rc.ReplayRawEvents(replay.Events)
+	if err != nil {
+		return nil, fmt.Errorf("cannot replay raw events: %w", err)
+	}
+
+	s.Log.Info("%d events created by replaying %s raw events", nbEvents,
+		cname)
+
+	return &eventline.RawEventReplayResult{NbEvents: nbEvents}, nil
+}
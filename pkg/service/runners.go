@@ -1,11 +1,16 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"reflect"
 	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
+	"go.n16f.net/ejson"
 	"go.n16f.net/log"
+	goservice "go.n16f.net/service/pkg/service"
 )
 
 type Runner interface {
@@ -15,9 +20,9 @@ type Runner interface {
 func (s *Service) StartRunner(data *eventline.RunnerData) (Runner, error) {
 	name := data.JobExecution.JobSpec.Runner.Name
 
-	def, found := s.runnerDefs[name]
-	if !found {
-		return nil, fmt.Errorf("unknown runner %q", name)
+	def, err := s.acquireRunnerDef(name)
+	if err != nil {
+		return nil, err
 	}
 
 	logger := s.Log.Child("runner", log.Data{
@@ -29,16 +34,24 @@ func (s *Service) StartRunner(data *eventline.RunnerData) (Runner, error) {
 	refreshInterval := time.Duration(refreshIntervalSeconds) * time.Second
 
 	initData := eventline.RunnerInitData{
-		Log: logger,
-		Pg:  s.Pg,
+		Log:    logger,
+		Pg:     s.Pg,
+		Influx: s.Service.Influx,
+
+		LifecycleEvents: s.LifecycleEvents,
 
 		Def:  def,
 		Cfg:  def.Cfg,
 		Data: data,
 
 		TerminationChan: s.jobExecutionTerminationChan,
+		TerminationFunc: func() { s.releaseRunnerDef(def) },
 
 		RefreshInterval: refreshInterval,
+		MaxOutputRate:   s.Cfg.MaxStepOutputRate,
+
+		OutboundProxyURI:        s.outboundProxyURI,
+		HTTPStepAllowedNetworks: s.httpStepAllowedNetworks,
 
 		StopChan: s.runnerStopChan,
 		Wg:       &s.runnerWg,
@@ -46,12 +59,159 @@ func (s *Service) StartRunner(data *eventline.RunnerData) (Runner, error) {
 
 	runner, err := eventline.NewRunner(initData)
 	if err != nil {
+		s.releaseRunnerDef(def)
 		return nil, err
 	}
 
 	if err := runner.Start(); err != nil {
+		s.releaseRunnerDef(def)
 		return nil, err
 	}
 
 	return runner, nil
 }
+
+// Runner definitions are replaced when runners are reloaded. Each execution
+// keeps using the definition, and therefore the configuration, it was started
+// with; we count executions for each definition to know when a replaced
+// definition is not used anymore.
+
+func (s *Service) acquireRunnerDef(name string) (*eventline.RunnerDef, error) {
+	s.runnerMutex.Lock()
+	defer s.runnerMutex.Unlock()
+
+	def, found := s.runnerDefs[name]
+	if !found {
+		return nil, fmt.Errorf("unknown runner %q", name)
+	}
+
+	s.runnerExecutions[def]++
+
+	return def, nil
+}
+
+func (s *Service) releaseRunnerDef(def *eventline.RunnerDef) {
+	s.runnerMutex.Lock()
+	defer s.runnerMutex.Unlock()
+
+	s.runnerExecutions[def]--
+
+	if s.runnerExecutions[def] > 0 {
+		return
+	}
+
+	delete(s.runnerExecutions, def)
+
+	if s.runnerDefs[def.Name] != def {
+		s.Log.Info("all executions using the previous configuration of "+
+			"runner %q have finished", def.Name)
+	}
+}
+
+func (s *Service) watchRunnerReloadSignals() {
+	for range s.runnerReloadChan {
+		s.Log.Info("reloading runners")
+
+		if err := s.ReloadRunners(); err != nil {
+			s.Log.Error("cannot reload runners: %v", err)
+		}
+	}
+}
+
+// ReloadRunners reads the configuration file again and replaces the
+// definition of each runner whose configuration changed. New executions use
+// the new configuration while running executions finish with the previous
+// one. If the configuration of any runner is invalid, no runner is reloaded.
+func (s *Service) ReloadRunners() error {
+	p := s.Service.Program
+	if !p.IsOptionSet("cfg-file") {
+		return fmt.Errorf("no configuration file")
+	}
+
+	cfg := DefaultServiceCfg()
+
+	templateData := map[string]interface{}{
+		"Program": p,
+	}
+
+	if err := goservice.LoadCfg(p.OptionValue("cfg-file"), templateData,
+		&cfg); err != nil {
+		return fmt.Errorf("cannot load configuration: %w", err)
+	}
+
+	newDefs := make(map[string]*eventline.RunnerDef)
+
+	s.runnerMutex.Lock()
+	defer s.runnerMutex.Unlock()
+
+	for _, name := range s.runnerNames {
+		cfgData := cfg.Runners[name]
+		if bytes.Equal(cfgData, s.Cfg.Runners[name]) {
+			continue
+		}
+
+		def, err := s.newRunnerDef(s.runnerDefs[name], cfgData)
+		if err != nil {
+			return fmt.Errorf("cannot reload runner %q: %w", name, err)
+		}
+
+		newDefs[name] = def
+	}
+
+	if len(newDefs) == 0 {
+		s.Log.Info("runner configuration unchanged")
+		return nil
+	}
+
+	if s.Cfg.Runners == nil {
+		s.Cfg.Runners = make(map[string]json.RawMessage)
+	}
+
+	for name, def := range newDefs {
+		oldDef := s.runnerDefs[name]
+
+		s.runnerDefs[name] = def
+		s.Cfg.Runners[name] = cfg.Runners[name]
+
+		nbExecutions := s.runnerExecutions[oldDef]
+
+		s.Log.Info("runner %q reloaded (%d running executions using the "+
+			"previous configuration)", name, nbExecutions)
+	}
+
+	return nil
+}
+
+func (s *Service) newRunnerDef(def *eventline.RunnerDef, cfgData json.RawMessage) (*eventline.RunnerDef, error) {
+	cfgType := reflect.TypeOf(def.Cfg).Elem()
+	cfg := reflect.New(cfgType).Interface().(eventline.RunnerCfg)
+
+	if err := json.Unmarshal(s.runnerDefaultCfgs[def.Name], cfg); err != nil {
+		return nil, fmt.Errorf("cannot decode default configuration: %w", err)
+	}
+
+	if cfgData != nil {
+		if err := decodeRunnerCfg(cfgData, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	newDef := *def
+	newDef.Cfg = cfg
+
+	return &newDef, nil
+}
+
+func decodeRunnerCfg(data json.RawMessage, cfg eventline.RunnerCfg) error {
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return fmt.Errorf("cannot decode configuration: %w", err)
+	}
+
+	validator := ejson.NewValidator()
+	cfg.ValidateJSON(validator)
+	if err := validator.Error(); err != nil {
+		return fmt.Errorf("invalid configuration: %w", err)
+	}
+
+	return nil
+}
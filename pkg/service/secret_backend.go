@@ -0,0 +1,188 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/exograd/eventline/pkg/vault"
+	"go.n16f.net/ejson"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/pg"
+)
+
+var SecretBackendTypeValues = []string{"local", "vault"}
+
+type SecretBackendCfg struct {
+	Type string `json:"type"`
+
+	// If the type is local, the Vault configuration is optional and is used
+	// to move identities stored in Vault back to the database.
+	Vault *vault.ClientCfg `json:"vault,omitempty"`
+}
+
+func DefaultSecretBackendCfg() *SecretBackendCfg {
+	return &SecretBackendCfg{
+		Type: "local",
+	}
+}
+
+func (cfg *SecretBackendCfg) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringValue("type", cfg.Type, SecretBackendTypeValues)
+
+	if cfg.Type == "vault" {
+		v.CheckObject("vault", cfg.Vault)
+	} else {
+		v.CheckOptionalObject("vault", cfg.Vault)
+	}
+}
+
+func (s *Service) initSecretBackend() error {
+	cfg := s.Cfg.SecretBackend
+
+	eventline.GlobalSecretBackend = nil
+	eventline.UseGlobalSecretBackend = false
+
+	if cfg.Vault != nil {
+		logger := s.Log.Child("vault", nil)
+
+		client, err := vault.NewClient(*cfg.Vault, logger)
+		if err != nil {
+			return fmt.Errorf("cannot create vault client: %w", err)
+		}
+
+		eventline.GlobalSecretBackend = vault.NewSecretBackend(client)
+		eventline.UseGlobalSecretBackend = cfg.Type == "vault"
+	}
+
+	if backend := eventline.GlobalSecretBackend; backend != nil {
+		if eventline.UseGlobalSecretBackend {
+			s.Log.Info("storing identity data with the %s secret backend",
+				backend.Name())
+		} else {
+			s.Log.Info("moving identity data from the %s secret backend "+
+				"to the database", backend.Name())
+		}
+	}
+
+	return nil
+}
+
+// IdentitySecretWorker deletes the backend secrets of deleted identities, and
+// moves identity data between the database and the secret backend when the
+// secret backend configuration changes.
+type IdentitySecretWorker struct {
+	Log     *log.Logger
+	Service *Service
+
+	w *eventline.Worker
+}
+
+func NewIdentitySecretWorker(s *Service) *IdentitySecretWorker {
+	return &IdentitySecretWorker{
+		Service: s,
+	}
+}
+
+func (sw *IdentitySecretWorker) Init(w *eventline.Worker) {
+	sw.Log = w.Log
+}
+
+func (sw *IdentitySecretWorker) Start() error {
+	return nil
+}
+
+func (sw *IdentitySecretWorker) Stop() {
+}
+
+func (sw *IdentitySecretWorker) ProcessJob() (bool, error) {
+	if eventline.GlobalSecretBackend == nil {
+		return false, nil
+	}
+
+	if processed, err := sw.deleteSecret(); err != nil || processed {
+		return processed, err
+	}
+
+	return sw.migrateIdentity()
+}
+
+func (sw *IdentitySecretWorker) deleteSecret() (bool, error) {
+	backend := eventline.GlobalSecretBackend
+
+	var processed bool
+
+	err := sw.Service.Pg.WithTx(func(conn pg.Conn) error {
+		ref, err := eventline.LoadDeletedIdentitySecretForProcessing(conn)
+		if err != nil {
+			return fmt.Errorf("cannot load deleted secret: %w", err)
+		} else if ref == "" {
+			return nil
+		}
+
+		// Deleting a secret which does not exist anymore is not an error,
+		// so it does not matter if the transaction fails after the secret
+		// has been deleted.
+		ctx := context.Background()
+
+		if err := backend.DeleteSecret(ctx, ref); err != nil {
+			return fmt.Errorf("cannot delete secret %q: %w", ref, err)
+		}
+
+		if err := eventline.DeleteDeletedIdentitySecret(conn, ref); err != nil {
+			return fmt.Errorf("cannot delete deleted secret: %w", err)
+		}
+
+		processed = true
+		return nil
+	})
+
+	return processed, err
+}
+
+func (sw *IdentitySecretWorker) migrateIdentity() (bool, error) {
+	backend := eventline.GlobalSecretBackend
+	toBackend := eventline.UseGlobalSecretBackend
+
+	var processed bool
+
+	err := sw.Service.Pg.WithTx(func(conn pg.Conn) error {
+		identity, err := eventline.LoadIdentityForDataMigration(conn,
+			toBackend)
+		if err != nil {
+			return fmt.Errorf("cannot load identity: %w", err)
+		} else if identity == nil {
+			return nil
+		}
+
+		if err := identity.ResolveData(); err != nil {
+			return err
+		}
+
+		if toBackend {
+			sw.Log.Info("moving data of identity %q to the %s secret backend",
+				identity.Id, backend.Name())
+		} else {
+			sw.Log.Info("moving data of identity %q to the database",
+				identity.Id)
+
+			// The secret is deleted once the transaction has been committed
+			ref := *identity.SecretRef
+			if err := eventline.InsertDeletedIdentitySecret(conn, ref); err != nil {
+				return fmt.Errorf("cannot record deleted secret: %w", err)
+			}
+
+			identity.SecretRef = nil
+		}
+
+		if err := identity.Update(conn); err != nil {
+			return fmt.Errorf("cannot update identity %q: %w",
+				identity.Id, err)
+		}
+
+		processed = true
+		return nil
+	})
+
+	return processed, err
+}
@@ -5,9 +5,14 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"net"
 	"net/url"
+	"os"
+	"os/signal"
 	"path"
 	"sync"
+	"syscall"
+	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
 	"go.n16f.net/ejson"
@@ -26,23 +31,36 @@ type Service struct {
 
 	Pg *pg.Client
 
+	LifecycleEvents *eventline.LifecycleEventBus
+
 	APIHTTPServer *APIHTTPServer
 	WebHTTPServer *WebHTTPServer
 
 	BuildIdHash      string
 	WebHTTPServerURI *url.URL
 
+	outboundProxyURI        *url.URL // nil if no proxy is configured
+	httpStepAllowedNetworks []*net.IPNet
+
 	workers                map[string]*eventline.Worker
 	workerStopChan         chan struct{}
 	workerNotificationChan chan interface{}
 	workerWg               sync.WaitGroup
 
-	connectors map[string]eventline.Connector
+	connectors       map[string]eventline.Connector
+	connectorProxies map[string]*url.URL
 
-	runnerNames    []string
-	runnerDefs     map[string]*eventline.RunnerDef
-	runnerStopChan chan struct{}
-	runnerWg       sync.WaitGroup
+	connectorFailureTrackers map[string]*eventline.ConnectorFailureTracker
+	connectorFailureMutex    sync.Mutex
+
+	runnerNames       []string
+	runnerDefs        map[string]*eventline.RunnerDef
+	runnerDefaultCfgs map[string][]byte
+	runnerExecutions  map[*eventline.RunnerDef]int
+	runnerMutex       sync.Mutex
+	runnerStopChan    chan struct{}
+	runnerWg          sync.WaitGroup
+	runnerReloadChan  chan os.Signal
 
 	jobExecutionTerminationChan chan eventline.Id
 }
@@ -62,15 +80,23 @@ func NewService(data ServiceData) *Service {
 
 		BuildIdHash: buildIdHash,
 
+		LifecycleEvents: eventline.NewLifecycleEventBus(),
+
 		workers:                make(map[string]*eventline.Worker),
 		workerStopChan:         make(chan struct{}),
 		workerNotificationChan: make(chan interface{}),
 
-		connectors: make(map[string]eventline.Connector),
+		connectors:       make(map[string]eventline.Connector),
+		connectorProxies: make(map[string]*url.URL),
 
-		runnerNames:    runnerNames,
-		runnerDefs:     make(map[string]*eventline.RunnerDef),
-		runnerStopChan: make(chan struct{}),
+		connectorFailureTrackers: make(map[string]*eventline.ConnectorFailureTracker),
+
+		runnerNames:       runnerNames,
+		runnerDefs:        make(map[string]*eventline.RunnerDef),
+		runnerDefaultCfgs: make(map[string][]byte),
+		runnerExecutions:  make(map[*eventline.RunnerDef]int),
+		runnerStopChan:    make(chan struct{}),
+		runnerReloadChan:  make(chan os.Signal, 1),
 
 		jobExecutionTerminationChan: make(chan eventline.Id),
 	}
@@ -153,10 +179,24 @@ func (s *Service) Init(ss *goservice.Service) error {
 		return err
 	}
 
+	if err := s.initSecretBackend(); err != nil {
+		return err
+	}
+
 	if err := s.initWebHTTPServerURI(); err != nil {
 		return err
 	}
 
+	if err := s.initOutboundProxy(); err != nil {
+		return err
+	}
+
+	if err := s.initHTTPSteps(); err != nil {
+		return err
+	}
+
+	s.initQueryTimer()
+
 	if err := s.initConnectors(); err != nil {
 		return err
 	}
@@ -200,6 +240,42 @@ func (s *Service) initEncryptionKey() error {
 	return nil
 }
 
+func (s *Service) initOutboundProxy() error {
+	if s.Cfg.OutboundProxy == "" {
+		return nil
+	}
+
+	proxyURI, err := eventline.ParseProxyURI(s.Cfg.OutboundProxy)
+	if err != nil {
+		return fmt.Errorf("invalid outbound proxy uri: %w", err)
+	}
+
+	s.outboundProxyURI = proxyURI
+
+	return nil
+}
+
+func (s *Service) initHTTPSteps() error {
+	for _, network := range s.Cfg.HTTPStepAllowedNetworks {
+		_, ipNet, err := net.ParseCIDR(network)
+		if err != nil {
+			return fmt.Errorf("invalid http step network %q: %w",
+				network, err)
+		}
+
+		s.httpStepAllowedNetworks = append(s.httpStepAllowedNetworks, ipNet)
+	}
+
+	return nil
+}
+
+func (s *Service) initQueryTimer() {
+	threshold := time.Duration(s.Cfg.SlowQueryThreshold) * time.Millisecond
+
+	eventline.GlobalQueryTimer = eventline.NewQueryTimer(
+		s.Log.Child("queries", nil), s.Service.Influx, threshold)
+}
+
 func (s *Service) initConnectors() error {
 	for _, c := range s.Data.Connectors {
 		if err := s.initConnector(c); err != nil {
@@ -218,15 +294,40 @@ func (s *Service) initConnector(c eventline.Connector) error {
 	initData := eventline.ConnectorInitData{
 		Log:              s.Log.Child("connectors."+name, nil),
 		Pg:               s.Pg,
+		Influx:           s.Service.Influx,
 		WebHTTPServerURI: s.WebHTTPServerURI,
+		RecordDelivery: func(err error) {
+			s.RecordConnectorDelivery(name, err)
+		},
 	}
 
 	cfg := c.DefaultCfg()
 
+	proxy := s.Cfg.OutboundProxy
+
 	if cfgData, found := s.Cfg.Connectors[name]; found {
 		if err := ejson.Unmarshal(cfgData, cfg); err != nil {
 			return fmt.Errorf("invalid configuration: %w", err)
 		}
+
+		var proxyCfg eventline.ConnectorProxyCfg
+		if err := ejson.Unmarshal(cfgData, &proxyCfg); err != nil {
+			return fmt.Errorf("invalid configuration: %w", err)
+		}
+
+		if proxyCfg.Proxy != "" {
+			proxy = proxyCfg.Proxy
+		}
+	}
+
+	if proxy != "" {
+		proxyURI, err := eventline.ParseProxyURI(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy uri: %w", err)
+		}
+
+		initData.Proxy = proxyURI
+		s.connectorProxies[name] = proxyURI
 	}
 
 	if err := c.Init(cfg, initData); err != nil {
@@ -234,6 +335,7 @@ func (s *Service) initConnector(c eventline.Connector) error {
 	}
 
 	s.connectors[name] = c
+	s.initConnectorFailureTracker(name)
 
 	eventline.Connectors[name] = c
 
@@ -252,15 +354,18 @@ func (s *Service) initRunners() error {
 }
 
 func (s *Service) initRunner(def *eventline.RunnerDef) error {
-	if cfgData, found := s.Cfg.Runners[def.Name]; found {
-		if err := json.Unmarshal(cfgData, def.Cfg); err != nil {
-			return fmt.Errorf("cannot decode configuration: %w", err)
-		}
+	// Keep the default configuration to be able to build new configurations
+	// when runners are reloaded.
+	defaultCfgData, err := json.Marshal(def.Cfg)
+	if err != nil {
+		return fmt.Errorf("cannot encode default configuration: %w", err)
+	}
 
-		validator := ejson.NewValidator()
-		def.Cfg.ValidateJSON(validator)
-		if err := validator.Error(); err != nil {
-			return fmt.Errorf("invalid configuration: %w", err)
+	s.runnerDefaultCfgs[def.Name] = defaultCfgData
+
+	if cfgData, found := s.Cfg.Runners[def.Name]; found {
+		if err := decodeRunnerCfg(cfgData, def.Cfg); err != nil {
+			return err
 		}
 	}
 
@@ -289,6 +394,15 @@ func (s *Service) initPg() error {
 			return err
 		}
 
+		// Webhook keys are created with projects; projects created before
+		// the introduction of webhook keys obtain their first key here.
+		n, err := eventline.CreateMissingProjectWebhookKeys(conn)
+		if err != nil {
+			return fmt.Errorf("cannot create project webhook keys: %w", err)
+		} else if n > 0 {
+			s.Log.Info("%d project webhook keys created", n)
+		}
+
 		return nil
 	})
 }
@@ -348,10 +462,13 @@ func (s *Service) initWorkers() {
 	}
 
 	init("identity-refresher", NewIdentityRefresher(s), nil)
+	init("identity-secret-worker", NewIdentitySecretWorker(s), nil)
 	init("subscription-worker", NewSubscriptionWorker(s), nil)
 	init("event-worker", NewEventWorker(s), nil)
 	init("job-scheduler", NewJobScheduler(s), nil)
+	init("job-schedule-worker", NewJobScheduleWorker(s), nil)
 	init("job-execution-gc", NewJobExecutionGC(s), nil)
+	init("event-gc", NewEventGC(s), nil)
 	init("job-execution-watcher", NewJobExecutionWatcher(s), nil)
 	init("notification-worker", NewNotificationWorker(s), nil)
 	if s.Cfg.SessionRetention > 0 {
@@ -381,6 +498,9 @@ func (s *Service) FindConnectorWorker(cname string) *eventline.Worker {
 func (s *Service) Start(ss *goservice.Service) error {
 	go s.processWorkerNotifications()
 
+	signal.Notify(s.runnerReloadChan, syscall.SIGHUP)
+	go s.watchRunnerReloadSignals()
+
 	for _, w := range s.workers {
 		if err := w.Start(); err != nil {
 			return fmt.Errorf("cannot start worker %q: %w", w.Name, err)
@@ -397,9 +517,22 @@ func (s *Service) Start(ss *goservice.Service) error {
 }
 
 func (s *Service) Stop(ss *goservice.Service) {
+	// Terminate lifecycle event streams, otherwise the HTTP servers would
+	// wait for them to end.
+	s.LifecycleEvents.Close()
+
 	// Note that we do *not* close the job execution termination chan until
 	// all runners have terminated. If we did, they would crash when writing
 	// the job execution id at the end.
+	signal.Stop(s.runnerReloadChan)
+	close(s.runnerReloadChan)
+
+	// Stop starting job executions before stopping runners, otherwise new
+	// runners could be started while we are waiting for existing ones.
+	if w := s.FindWorker("job-scheduler"); w != nil {
+		w.Stop()
+	}
+
 	close(s.runnerStopChan)
 	s.runnerWg.Wait()
 
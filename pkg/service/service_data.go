@@ -1,12 +1,17 @@
 package service
 
 import (
+	ccloudevents "github.com/exograd/eventline/pkg/connectors/cloudevents"
 	cdockerhub "github.com/exograd/eventline/pkg/connectors/dockerhub"
+	cemail "github.com/exograd/eventline/pkg/connectors/email"
 	ceventline "github.com/exograd/eventline/pkg/connectors/eventline"
 	cgeneric "github.com/exograd/eventline/pkg/connectors/generic"
 	cgithub "github.com/exograd/eventline/pkg/connectors/github"
+	cgitlab "github.com/exograd/eventline/pkg/connectors/gitlab"
 	cpostgresql "github.com/exograd/eventline/pkg/connectors/postgresql"
+	cslack "github.com/exograd/eventline/pkg/connectors/slack"
 	ctime "github.com/exograd/eventline/pkg/connectors/time"
+	cwebhook "github.com/exograd/eventline/pkg/connectors/webhook"
 	"github.com/exograd/eventline/pkg/eventline"
 	rdocker "github.com/exograd/eventline/pkg/runners/docker"
 	rlocal "github.com/exograd/eventline/pkg/runners/local"
@@ -25,12 +30,17 @@ type ServiceData struct {
 }
 
 var Connectors = []eventline.Connector{
+	ccloudevents.NewConnector(),
 	cdockerhub.NewConnector(),
+	cemail.NewConnector(),
 	ceventline.NewConnector(),
 	cgeneric.NewConnector(),
 	cgithub.NewConnector(),
+	cgitlab.NewConnector(),
 	cpostgresql.NewConnector(),
+	cslack.NewConnector(),
 	ctime.NewConnector(),
+	cwebhook.NewConnector(),
 }
 
 var Runners = []*eventline.RunnerDef{
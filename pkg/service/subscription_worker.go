@@ -3,6 +3,7 @@ package service
 import (
 	"errors"
 	"fmt"
+	"math/rand/v2"
 	"time"
 
 	"github.com/exograd/eventline/pkg/eventline"
@@ -78,7 +79,8 @@ func (sw *SubscriptionWorker) ProcessJob() (bool, error) {
 			}
 
 			updateDelayDuration := time.Duration(updateDelay) * time.Second
-			nextUpdate := now.Add(updateDelayDuration)
+			nextUpdate := now.Add(updateDelayDuration +
+				sw.updateSplay(updateDelayDuration))
 
 			subscription.UpdateDelay = updateDelay
 			subscription.LastUpdateTime = &now
@@ -90,6 +92,18 @@ func (sw *SubscriptionWorker) ProcessJob() (bool, error) {
 				subscription.Id, err)
 		}
 
+		if processingErr != nil {
+			err = eventline.RecordSubscriptionError(conn, subscription.Id,
+				processingErr, *subscription.LastUpdateTime)
+		} else {
+			err = eventline.ClearSubscriptionErrors(conn,
+				eventline.Ids{subscription.Id})
+		}
+		if err != nil {
+			return fmt.Errorf("cannot update health of subscription %q: %w",
+				subscription.Id, err)
+		}
+
 		processed = true
 		return nil
 	})
@@ -103,3 +117,15 @@ func (sw *SubscriptionWorker) ProcessJob() (bool, error) {
 
 	return processed, nil
 }
+
+// updateSplay returns a random duration added to the delay before the next
+// update of a subscription, so that subscriptions sharing the same delay do
+// not end up being updated at the same time.
+func (sw *SubscriptionWorker) updateSplay(delay time.Duration) time.Duration {
+	max := int64(delay) * int64(sw.Service.Cfg.SubscriptionUpdateSplay) / 100
+	if max <= 0 {
+		return 0
+	}
+
+	return time.Duration(rand.Int64N(max))
+}
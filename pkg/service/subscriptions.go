@@ -1,6 +1,8 @@
 package service
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"time"
 
@@ -97,6 +99,13 @@ func (s *Service) ProcessSubscription(conn pg.Conn, sctx *eventline.Subscription
 }
 
 func (s *Service) processInactiveSubscription(conn pg.Conn, sctx *eventline.SubscriptionContext) error {
+	// The subscription will be retried later, hopefully once the identity has
+	// been enabled again.
+	if identity := sctx.Identity; identity != nil && identity.Disabled {
+		return eventline.NewExternalSubscriptionError(
+			&eventline.DisabledIdentityError{Name: identity.Name})
+	}
+
 	c := eventline.GetConnector(sctx.Subscription.Connector)
 
 	if c2, ok := c.(eventline.SubscribableConnector); ok {
@@ -145,3 +154,58 @@ func (s *Service) processTerminatingSubscription(conn pg.Conn, sctx *eventline.S
 
 	return nil
 }
+
+// ImportSubscription sets the trigger of an existing job, creating, updating
+// or leaving its subscription untouched.
+func (s *Service) ImportSubscription(conn pg.Conn, export *eventline.SubscriptionExport, scope eventline.Scope) (eventline.SubscriptionImportStatus, bool, error) {
+	var job eventline.Job
+	if err := job.LoadByName(conn, export.Job, scope); err != nil {
+		return "", false, err
+	}
+
+	oldTrigger := job.Spec.Trigger
+
+	if oldTrigger != nil {
+		oldData, err := json.Marshal(oldTrigger)
+		if err != nil {
+			return "", false, fmt.Errorf("cannot encode trigger: %w", err)
+		}
+
+		newData, err := json.Marshal(export.Trigger)
+		if err != nil {
+			return "", false, fmt.Errorf("cannot encode trigger: %w", err)
+		}
+
+		if bytes.Equal(oldData, newData) {
+			return eventline.SubscriptionImportStatusUnchanged, false, nil
+		}
+	}
+
+	job.Spec.Trigger = export.Trigger
+
+	if err := s.ValidateJobSpec(conn, job.Spec, scope); err != nil {
+		return "", false, err
+	}
+
+	newJob, subscriptionCreatedOrUpdated, err := s.CreateOrUpdateJob(conn,
+		job.Spec, scope)
+	if err != nil {
+		return "", false, fmt.Errorf("cannot update job: %w", err)
+	}
+
+	// Importing a subscription must not enable a disabled job
+	if job.Disabled {
+		newJob.Disabled = true
+
+		if err := newJob.Update(conn, scope); err != nil {
+			return "", false, fmt.Errorf("cannot update job: %w", err)
+		}
+	}
+
+	status := eventline.SubscriptionImportStatusUpdated
+	if oldTrigger == nil {
+		status = eventline.SubscriptionImportStatusCreated
+	}
+
+	return status, subscriptionCreatedOrUpdated, nil
+}
@@ -9,7 +9,7 @@ func (s *WebHTTPServer) setupAssetRoutes() {
 		s.hFaviconGET,
 		HTTPRouteOptions{Public: true})
 
-	s.route("/assets/*subpath", "GET",
+	s.route("/assets/{subpath...}", "GET",
 		s.hAssetsGET,
 		HTTPRouteOptions{Public: true})
 }
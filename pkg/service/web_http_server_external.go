@@ -1,9 +1,15 @@
 package service
 
 import (
+	"errors"
 	"path"
 
+	ccloudevents "github.com/exograd/eventline/pkg/connectors/cloudevents"
+	cdockerhub "github.com/exograd/eventline/pkg/connectors/dockerhub"
 	cgithub "github.com/exograd/eventline/pkg/connectors/github"
+	cgitlab "github.com/exograd/eventline/pkg/connectors/gitlab"
+	cslack "github.com/exograd/eventline/pkg/connectors/slack"
+	cwebhook "github.com/exograd/eventline/pkg/connectors/webhook"
 	"github.com/exograd/eventline/pkg/eventline"
 	"github.com/google/go-github/v45/github"
 )
@@ -23,9 +29,109 @@ func (s *WebHTTPServer) setupExternalRoutes() {
 		}
 	}
 
-	s.route("/ext/connectors/github/hooks/*subpath", "POST",
+	s.route("/ext/connectors/cloudevents/events", "POST",
+		s.hExtConnectorsCloudEventsEventsPOST,
+		HTTPRouteOptions{Public: true})
+
+	s.route("/ext/connectors/dockerhub/hooks/{token}", "POST",
+		s.hExtConnectorsDockerHubHooksPOST,
+		HTTPRouteOptions{Public: true})
+
+	s.route("/ext/connectors/github/hooks/{subpath...}", "POST",
 		s.hExtConnectorsGithubHooksPOST,
 		HTTPRouteOptions{Public: true})
+
+	s.route("/ext/connectors/github/subscriptions/{token}", "POST",
+		s.hExtConnectorsGithubSubscriptionsPOST,
+		HTTPRouteOptions{Public: true})
+
+	s.route("/ext/connectors/gitlab/hooks", "POST",
+		s.hExtConnectorsGitlabHooksPOST,
+		HTTPRouteOptions{Public: true})
+
+	s.route("/ext/connectors/slack/events", "POST",
+		s.hExtConnectorsSlackEventsPOST,
+		HTTPRouteOptions{Public: true})
+
+	s.route("/ext/connectors/webhook/hooks/{name}", "POST",
+		s.hExtConnectorsWebhookHooksPOST,
+		HTTPRouteOptions{Public: true})
+}
+
+func (s *WebHTTPServer) hExtConnectorsCloudEventsEventsPOST(h *HTTPHandler) {
+	if s.checkConnectorQuarantine(h, "cloudevents") {
+		return
+	}
+
+	c := eventline.GetConnector("cloudevents")
+	c2 := c.(*ccloudevents.Connector)
+
+	if err := c2.ProcessRequest(h.Request); err != nil {
+		var invalidEventErr *ccloudevents.InvalidEventError
+
+		switch {
+		case errors.Is(err, ccloudevents.ErrConnectorDisabled):
+			h.ReplyError(404, "connector_disabled", "%v", err)
+
+		case errors.Is(err, ccloudevents.ErrInvalidAccessToken):
+			h.ReplyError(401, "invalid_access_token", "%v", err)
+
+		case errors.Is(err, ccloudevents.ErrRequestTooLarge):
+			h.ReplyError(413, "request_too_large", "%v", err)
+
+		case errors.As(err, &invalidEventErr):
+			h.ReplyError(400, "invalid_cloudevent", "%v", err)
+
+		default:
+			s.Service.RecordConnectorDelivery("cloudevents", err)
+			h.ReplyInternalError(500, "cannot process request: %v", err)
+		}
+
+		return
+	}
+
+	s.Service.RecordConnectorDelivery("cloudevents", nil)
+
+	h.ReplyEmpty(204)
+}
+
+func (s *WebHTTPServer) hExtConnectorsDockerHubHooksPOST(h *HTTPHandler) {
+	if s.checkConnectorQuarantine(h, "dockerhub") {
+		return
+	}
+
+	token := h.PathVariable("token")
+
+	c := eventline.GetConnector("dockerhub")
+	c2 := c.(*cdockerhub.Connector)
+
+	if err := c2.ProcessWebhookRequest(h.Request, token); err != nil {
+		var invalidPayloadErr *cdockerhub.InvalidPayloadError
+
+		switch {
+		case errors.Is(err, cdockerhub.ErrConnectorDisabled):
+			h.ReplyError(404, "connector_disabled", "%v", err)
+
+		case errors.Is(err, cdockerhub.ErrInvalidWebhookToken):
+			h.ReplyError(404, "unknown_webhook_token", "%v", err)
+
+		case errors.Is(err, cdockerhub.ErrRequestTooLarge):
+			h.ReplyError(413, "request_too_large", "%v", err)
+
+		case errors.As(err, &invalidPayloadErr):
+			h.ReplyError(400, "invalid_webhook_payload", "%v", err)
+
+		default:
+			s.Service.RecordConnectorDelivery("dockerhub", err)
+			h.ReplyInternalError(500, "cannot process request: %v", err)
+		}
+
+		return
+	}
+
+	s.Service.RecordConnectorDelivery("dockerhub", nil)
+
+	h.ReplyEmpty(204)
 }
 
 func (s *WebHTTPServer) hExtConnectorsGithubHooksPOST(h *HTTPHandler) {
@@ -33,6 +139,10 @@ func (s *WebHTTPServer) hExtConnectorsGithubHooksPOST(h *HTTPHandler) {
 		h.Log.Data["github_delivery_id"] = deliveryId
 	}
 
+	if s.checkConnectorQuarantine(h, "github") {
+		return
+	}
+
 	target := h.PathVariable("subpath")
 
 	var params cgithub.Parameters
@@ -42,8 +152,233 @@ func (s *WebHTTPServer) hExtConnectorsGithubHooksPOST(h *HTTPHandler) {
 	c2 := c.(*cgithub.Connector)
 
 	if err := c2.ProcessWebhookRequest(h.Request, &params); err != nil {
+		if replyGithubWebhookError(h, err) {
+			return
+		}
+
+		s.Service.RecordConnectorDelivery("github", err)
 		h.Log.Error("cannot process request: %v", err)
+	} else {
+		// The outcome of the delivery is recorded by the delivery worker
+		s.wakeUpGithubDeliveryWorker()
 	}
 
 	h.ReplyEmpty(204)
 }
+
+// wakeUpGithubDeliveryWorker makes the github connector worker process queued
+// deliveries without waiting for its next run.
+func (s *WebHTTPServer) wakeUpGithubDeliveryWorker() {
+	if w := s.Service.FindConnectorWorker("github"); w != nil {
+		w.WakeUp()
+	}
+}
+
+func (s *WebHTTPServer) hExtConnectorsGithubSubscriptionsPOST(h *HTTPHandler) {
+	if deliveryId := github.DeliveryID(h.Request); deliveryId != "" {
+		h.Log.Data["github_delivery_id"] = deliveryId
+	}
+
+	if s.checkConnectorQuarantine(h, "github") {
+		return
+	}
+
+	token := h.PathVariable("token")
+
+	c := eventline.GetConnector("github")
+	c2 := c.(*cgithub.Connector)
+
+	err := c2.ProcessSubscriptionWebhookRequest(h.Request, token)
+	if err != nil {
+		if errors.Is(err, cgithub.ErrUnknownWebhookToken) {
+			h.ReplyError(404, "unknown_webhook_token", "%v", err)
+			return
+		} else if replyGithubWebhookError(h, err) {
+			return
+		}
+
+		s.Service.RecordConnectorDelivery("github", err)
+		h.Log.Error("cannot process request: %v", err)
+	} else {
+		// The outcome of the delivery is recorded by the delivery worker
+		s.wakeUpGithubDeliveryWorker()
+	}
+
+	h.ReplyEmpty(204)
+}
+
+// replyGithubWebhookError replies to requests which failed for reasons not
+// related to the connector itself, and returns true if it did. Other errors
+// are recorded as connector failures by the caller. Requests with an invalid
+// signature can be sent by anyone and must never be recorded.
+func replyGithubWebhookError(h *HTTPHandler, err error) bool {
+	var invalidPayloadErr *cgithub.InvalidPayloadError
+	var invalidEventErr *cgithub.InvalidWebhookEventError
+
+	switch {
+	case errors.Is(err, cgithub.ErrWebhookTimeout):
+		h.ReplyError(503, "service_unavailable", "%v", err)
+
+	case errors.Is(err, cgithub.ErrRequestTooLarge):
+		h.ReplyError(413, "request_too_large", "%v", err)
+
+	case errors.Is(err, cgithub.ErrInvalidSignature):
+		h.ReplyError(401, "invalid_signature", "%v", err)
+
+	case errors.As(err, &invalidPayloadErr),
+		errors.As(err, &invalidEventErr):
+		h.ReplyError(400, "invalid_webhook_payload", "%v", err)
+
+	default:
+		return false
+	}
+
+	return true
+}
+
+func (s *WebHTTPServer) hExtConnectorsGitlabHooksPOST(h *HTTPHandler) {
+	if s.checkConnectorQuarantine(h, "gitlab") {
+		return
+	}
+
+	c := eventline.GetConnector("gitlab")
+	c2 := c.(*cgitlab.Connector)
+
+	if err := c2.ProcessWebhookRequest(h.Request); err != nil {
+		var invalidPayloadErr *cgitlab.InvalidPayloadError
+
+		switch {
+		case errors.Is(err, cgitlab.ErrConnectorDisabled):
+			h.ReplyError(404, "connector_disabled", "%v", err)
+
+		case errors.Is(err, cgitlab.ErrInvalidWebhookToken):
+			h.ReplyError(401, "invalid_webhook_token", "%v", err)
+
+		case errors.Is(err, cgitlab.ErrRequestTooLarge):
+			h.ReplyError(413, "request_too_large", "%v", err)
+
+		case errors.As(err, &invalidPayloadErr):
+			h.ReplyError(400, "invalid_webhook_payload", "%v", err)
+
+		default:
+			s.Service.RecordConnectorDelivery("gitlab", err)
+			h.ReplyInternalError(500, "cannot process request: %v", err)
+		}
+
+		return
+	}
+
+	s.Service.RecordConnectorDelivery("gitlab", nil)
+
+	h.ReplyEmpty(204)
+}
+
+func (s *WebHTTPServer) hExtConnectorsSlackEventsPOST(h *HTTPHandler) {
+	if s.checkConnectorQuarantine(h, "slack") {
+		return
+	}
+
+	c := eventline.GetConnector("slack")
+	c2 := c.(*cslack.Connector)
+
+	res, err := c2.ProcessRequest(h.Request)
+	if err != nil {
+		var invalidPayloadErr *cslack.InvalidPayloadError
+
+		switch {
+		case errors.Is(err, cslack.ErrConnectorDisabled):
+			h.ReplyError(404, "connector_disabled", "%v", err)
+
+		case errors.Is(err, cslack.ErrMissingSignature),
+			errors.Is(err, cslack.ErrInvalidSignature),
+			errors.Is(err, cslack.ErrExpiredRequest):
+			h.ReplyError(401, "invalid_signature", "%v", err)
+
+		case errors.Is(err, cslack.ErrRequestTooLarge):
+			h.ReplyError(413, "request_too_large", "%v", err)
+
+		case errors.As(err, &invalidPayloadErr):
+			h.ReplyError(400, "invalid_webhook_payload", "%v", err)
+
+		default:
+			s.Service.RecordConnectorDelivery("slack", err)
+			h.ReplyInternalError(500, "cannot process request: %v", err)
+		}
+
+		return
+	}
+
+	s.Service.RecordConnectorDelivery("slack", nil)
+
+	// Slack expects a 200 status code; the URL verification challenge must
+	// be echoed in the response body.
+	if res != nil {
+		h.ReplyJSON(200, res)
+		return
+	}
+
+	h.ReplyEmpty(200)
+}
+
+func (s *WebHTTPServer) hExtConnectorsWebhookHooksPOST(h *HTTPHandler) {
+	if s.checkConnectorQuarantine(h, "webhook") {
+		return
+	}
+
+	name := h.PathVariable("name")
+
+	c := eventline.GetConnector("webhook")
+	c2 := c.(*cwebhook.Connector)
+
+	if err := c2.ProcessWebhookRequest(h.Request, name); err != nil {
+		var invalidPayloadErr *cwebhook.InvalidPayloadError
+
+		switch {
+		case errors.Is(err, cwebhook.ErrConnectorDisabled):
+			h.ReplyError(404, "connector_disabled", "%v", err)
+
+		case errors.Is(err, cwebhook.ErrMissingSignature),
+			errors.Is(err, cwebhook.ErrInvalidSignature):
+			h.ReplyError(401, "invalid_signature", "%v", err)
+
+		case errors.Is(err, cwebhook.ErrRequestTooLarge):
+			h.ReplyError(413, "request_too_large", "%v", err)
+
+		case errors.As(err, &invalidPayloadErr):
+			h.ReplyError(400, "invalid_webhook_payload", "%v", err)
+
+		default:
+			s.Service.RecordConnectorDelivery("webhook", err)
+			h.ReplyInternalError(500, "cannot process request: %v", err)
+		}
+
+		return
+	}
+
+	s.Service.RecordConnectorDelivery("webhook", nil)
+
+	h.ReplyEmpty(204)
+}
+
+// checkConnectorQuarantine replies with a 503 status and returns true if the
+// connector is quarantined.
+//
+// Note that only processing errors are recorded as failures by webhook
+// handlers: webhook timeouts and invalid requests do not indicate that the
+// connector itself is broken.
+func (s *WebHTTPServer) checkConnectorQuarantine(h *HTTPHandler, name string) bool {
+	quarantined, err := s.Service.CheckConnectorQuarantine(name)
+	if err != nil {
+		h.ReplyInternalError(500, "cannot check connector quarantine: %v",
+			err)
+		return true
+	}
+
+	if quarantined {
+		h.ReplyError(503, "connector_quarantined",
+			"connector %q is quarantined after repeated failures", name)
+		return true
+	}
+
+	return false
+}
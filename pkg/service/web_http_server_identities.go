@@ -185,6 +185,11 @@ func (s *WebHTTPServer) hIdentitiesIdGET(h *HTTPHandler) {
 		return
 	}
 
+	if err := identity.ResolveData(); err != nil {
+		h.ReplyInternalError(500, "%v", err)
+		return
+	}
+
 	bodyData := struct {
 		Identity *eventline.Identity
 		Jobs     eventline.Jobs
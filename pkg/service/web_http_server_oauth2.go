@@ -54,6 +54,10 @@ func (s *WebHTTPServer) processOAuth2Request(h *HTTPHandler) {
 			return fmt.Errorf("cannot load identity: %w", err)
 		}
 
+		if err := identity.ResolveData(); err != nil {
+			return err
+		}
+
 		cdef := eventline.GetConnectorDef(identity.Connector)
 		idef := cdef.Identity(identity.Type)
 
@@ -63,7 +67,7 @@ func (s *WebHTTPServer) processOAuth2Request(h *HTTPHandler) {
 		if authErr == nil {
 			// Use the OAuth2 code to fetch token data and update identity
 			// data.
-			httpClient, err := s.Service.oauth2HTTPClient(identityId,
+			httpClient, err := s.Service.oauth2HTTPClient(&identity,
 				&sessionId)
 			if err != nil {
 				return fmt.Errorf("cannot create http client: %w", err)
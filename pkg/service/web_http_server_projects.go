@@ -240,6 +240,8 @@ func (s *WebHTTPServer) hProjectsIdConfigurationPOST(h *HTTPHandler) {
 			h.ReplyError(404, "unknown_project", "%v", err)
 		} else if errors.As(err, &duplicateProjectNameErr) {
 			h.ReplyError(400, "duplicate_project_name", "%v", err)
+		} else if errors.Is(err, ErrMissingWebhookSecret) {
+			h.ReplyError(400, "missing_webhook_secret", "%v", err)
 		} else {
 			h.ReplyInternalError(500, "%v", err)
 		}
@@ -0,0 +1,251 @@
+package vault
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"go.n16f.net/ejson"
+	"go.n16f.net/log"
+	"go.n16f.net/service/pkg/shttp"
+)
+
+type ClientCfg struct {
+	URI       string `json:"uri"`
+	Token     string `json:"token"`
+	Namespace string `json:"namespace,omitempty"`
+
+	// The mount path of the KV version 2 secrets engine and the path under
+	// which identity secrets are stored.
+	Mount      string `json:"mount,omitempty"`
+	PathPrefix string `json:"path_prefix,omitempty"`
+
+	RequestTimeout int `json:"request_timeout,omitempty"` // seconds
+
+	TLS *shttp.TLSClientCfg `json:"tls,omitempty"`
+}
+
+type APIError struct {
+	Status int
+	Errors []string
+}
+
+func (err *APIError) Error() string {
+	if len(err.Errors) == 0 {
+		return fmt.Sprintf("request failed with status %d", err.Status)
+	}
+
+	return fmt.Sprintf("request failed with status %d: %s", err.Status,
+		strings.Join(err.Errors, ", "))
+}
+
+type Client struct {
+	Cfg ClientCfg
+
+	HTTPClient *shttp.Client
+
+	baseURI *url.URL
+}
+
+const (
+	DefaultMount          = "secret"
+	DefaultPathPrefix     = "eventline/identities"
+	DefaultRequestTimeout = 10 // seconds
+)
+
+func (cfg *ClientCfg) ValidateJSON(v *ejson.Validator) {
+	v.CheckStringURI("uri", cfg.URI)
+	v.CheckStringNotEmpty("token", cfg.Token)
+
+	if cfg.RequestTimeout != 0 {
+		v.CheckIntMin("request_timeout", cfg.RequestTimeout, 1)
+	}
+}
+
+func NewClient(cfg ClientCfg, logger *log.Logger) (*Client, error) {
+	baseURI, err := url.Parse(cfg.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid uri: %w", err)
+	}
+
+	if cfg.Mount == "" {
+		cfg.Mount = DefaultMount
+	}
+
+	if cfg.PathPrefix == "" {
+		cfg.PathPrefix = DefaultPathPrefix
+	}
+
+	if cfg.RequestTimeout == 0 {
+		cfg.RequestTimeout = DefaultRequestTimeout
+	}
+
+	requestTimeout := cfg.RequestTimeout
+
+	httpClientCfg := shttp.ClientCfg{
+		Log:            logger,
+		RequestTimeout: &requestTimeout,
+		TLS:            cfg.TLS,
+	}
+
+	httpClient, err := shttp.NewClient(httpClientCfg)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create http client: %w", err)
+	}
+
+	c := Client{
+		Cfg: cfg,
+
+		HTTPClient: httpClient,
+
+		baseURI: baseURI,
+	}
+
+	return &c, nil
+}
+
+// ReadKV returns the data of a version of a KV secret, or nil if the secret or
+// the version does not exist. If version is zero, the latest version is
+// returned.
+func (c *Client) ReadKV(ctx context.Context, path string, version int) (json.RawMessage, error) {
+	var res struct {
+		Data struct {
+			Data json.RawMessage `json:"data"`
+		} `json:"data"`
+	}
+
+	uriPath := c.kvPath("data", path)
+	if version > 0 {
+		uriPath += "?version=" + strconv.Itoa(version)
+	}
+
+	status, err := c.sendRequest(ctx, "GET", uriPath, nil, &res)
+	if err != nil {
+		if status == 404 {
+			return nil, nil
+		}
+
+		return nil, err
+	}
+
+	// Deleted versions are returned with null data
+	if string(res.Data.Data) == "null" {
+		return nil, nil
+	}
+
+	return res.Data.Data, nil
+}
+
+// WriteKV creates a new version of a KV secret and returns its version
+// number. Data must be a JSON object.
+func (c *Client) WriteKV(ctx context.Context, path string, data json.RawMessage) (int, error) {
+	body := struct {
+		Data json.RawMessage `json:"data"`
+	}{
+		Data: data,
+	}
+
+	var res struct {
+		Data struct {
+			Version int `json:"version"`
+		} `json:"data"`
+	}
+
+	_, err := c.sendRequest(ctx, "POST", c.kvPath("data", path), body, &res)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.Data.Version, nil
+}
+
+// DeleteKV deletes all versions of a KV secret.
+func (c *Client) DeleteKV(ctx context.Context, path string) error {
+	status, err := c.sendRequest(ctx, "DELETE", c.kvPath("metadata", path),
+		nil, nil)
+	if err != nil && status != 404 {
+		return err
+	}
+
+	return nil
+}
+
+func (c *Client) kvPath(kind, path string) string {
+	return "/v1/" + strings.Trim(c.Cfg.Mount, "/") + "/" + kind + "/" +
+		strings.Trim(path, "/")
+}
+
+func (c *Client) sendRequest(ctx context.Context, method, path string, body, dest interface{}) (int, error) {
+	var bodyReader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return 0, fmt.Errorf("cannot encode request body: %w", err)
+		}
+
+		bodyReader = bytes.NewReader(data)
+	}
+
+	uriPath, query, _ := strings.Cut(path, "?")
+
+	uri := *c.baseURI
+	uri.Path = strings.TrimSuffix(uri.Path, "/") + uriPath
+	uri.RawQuery = query
+
+	req, err := http.NewRequestWithContext(ctx, method, uri.String(),
+		bodyReader)
+	if err != nil {
+		return 0, fmt.Errorf("cannot create request: %w", err)
+	}
+
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	req.Header.Set("X-Vault-Token", c.Cfg.Token)
+
+	if c.Cfg.Namespace != "" {
+		req.Header.Set("X-Vault-Namespace", c.Cfg.Namespace)
+	}
+
+	res, err := c.HTTPClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer res.Body.Close()
+
+	resBody, err := io.ReadAll(res.Body)
+	if err != nil {
+		return res.StatusCode, fmt.Errorf("cannot read response body: %w",
+			err)
+	}
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		apiErr := APIError{Status: res.StatusCode}
+
+		var errBody struct {
+			Errors []string `json:"errors"`
+		}
+
+		if err := json.Unmarshal(resBody, &errBody); err == nil {
+			apiErr.Errors = errBody.Errors
+		}
+
+		return res.StatusCode, &apiErr
+	}
+
+	if dest != nil {
+		if err := json.Unmarshal(resBody, dest); err != nil {
+			return res.StatusCode,
+				fmt.Errorf("cannot decode response body: %w", err)
+		}
+	}
+
+	return res.StatusCode, nil
+}
@@ -0,0 +1,176 @@
+package vault
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/exograd/eventline/pkg/eventline"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestServer() *httptest.Server {
+	secrets := make(map[string][]json.RawMessage)
+
+	return httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, req *http.Request) {
+			if req.Header.Get("X-Vault-Token") != "test-token" {
+				w.WriteHeader(403)
+				w.Write([]byte(`{"errors":["permission denied"]}`))
+				return
+			}
+
+			dataPath, isData := strings.CutPrefix(req.URL.Path,
+				"/v1/kv/data/")
+			metadataPath, isMetadata := strings.CutPrefix(req.URL.Path,
+				"/v1/kv/metadata/")
+
+			switch {
+			case req.Method == "POST" && isData:
+				data, _ := io.ReadAll(req.Body)
+
+				var body struct {
+					Data json.RawMessage `json:"data"`
+				}
+				json.Unmarshal(data, &body)
+
+				secrets[dataPath] = append(secrets[dataPath], body.Data)
+				w.Write([]byte(`{"data":{"version":` +
+					strconv.Itoa(len(secrets[dataPath])) + `}}`))
+
+			case req.Method == "GET" && isData:
+				versions := secrets[dataPath]
+
+				version := len(versions)
+				if s := req.URL.Query().Get("version"); s != "" {
+					version, _ = strconv.Atoi(s)
+				}
+
+				if version < 1 || version > len(versions) {
+					w.WriteHeader(404)
+					w.Write([]byte(`{"errors":[]}`))
+					return
+				}
+
+				w.Write([]byte(`{"data":{"data":` +
+					string(versions[version-1]) + `}}`))
+
+			case req.Method == "DELETE" && isMetadata:
+				delete(secrets, metadataPath)
+				w.WriteHeader(204)
+
+			default:
+				w.WriteHeader(400)
+				w.Write([]byte(`{"errors":["invalid request"]}`))
+			}
+		}))
+}
+
+func TestClientKV(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	server := newTestServer()
+	defer server.Close()
+
+	client, err := NewClient(ClientCfg{
+		URI:   server.URL,
+		Token: "test-token",
+		Mount: "kv",
+	}, nil)
+	require.NoError(err)
+
+	ctx := context.Background()
+	secretPath := "eventline/identities/abc"
+
+	data, err := client.ReadKV(ctx, secretPath, 0)
+	require.NoError(err)
+	assert.Nil(data)
+
+	version, err := client.WriteKV(ctx, secretPath,
+		json.RawMessage(`{"password":"foo"}`))
+	require.NoError(err)
+	assert.Equal(1, version)
+
+	version, err = client.WriteKV(ctx, secretPath,
+		json.RawMessage(`{"password":"bar"}`))
+	require.NoError(err)
+	assert.Equal(2, version)
+
+	data, err = client.ReadKV(ctx, secretPath, 0)
+	require.NoError(err)
+	assert.JSONEq(`{"password":"bar"}`, string(data))
+
+	data, err = client.ReadKV(ctx, secretPath, 1)
+	require.NoError(err)
+	assert.JSONEq(`{"password":"foo"}`, string(data))
+
+	require.NoError(client.DeleteKV(ctx, secretPath))
+
+	data, err = client.ReadKV(ctx, secretPath, 0)
+	require.NoError(err)
+	assert.Nil(data)
+
+	client.Cfg.Token = "invalid-token"
+
+	_, err = client.ReadKV(ctx, secretPath, 0)
+	var apiErr *APIError
+	if assert.ErrorAs(err, &apiErr) {
+		assert.Equal(403, apiErr.Status)
+		assert.Equal([]string{"permission denied"}, apiErr.Errors)
+	}
+}
+
+func TestSecretBackend(t *testing.T) {
+	assert := assert.New(t)
+	require := require.New(t)
+
+	server := newTestServer()
+	defer server.Close()
+
+	client, err := NewClient(ClientCfg{
+		URI:   server.URL,
+		Token: "test-token",
+		Mount: "kv",
+	}, nil)
+	require.NoError(err)
+
+	backend := NewSecretBackend(client)
+
+	ctx := context.Background()
+
+	id := eventline.GenerateId()
+	secretPath := "eventline/identities/" + id.String()
+
+	ref1, err := backend.WriteIdentitySecret(ctx, id, []byte(`{"key":"1"}`))
+	require.NoError(err)
+	assert.Equal(secretPath+"?version=1", ref1)
+
+	ref2, err := backend.WriteIdentitySecret(ctx, id, []byte(`{"key":"2"}`))
+	require.NoError(err)
+	assert.Equal(secretPath+"?version=2", ref2)
+
+	// Previous references stay valid, e.g. if the transaction which stored
+	// the second version was rolled back.
+	data, err := backend.ReadSecret(ctx, ref1)
+	require.NoError(err)
+	assert.JSONEq(`{"key":"1"}`, string(data))
+
+	data, err = backend.ReadSecret(ctx, ref2)
+	require.NoError(err)
+	assert.JSONEq(`{"key":"2"}`, string(data))
+
+	require.NoError(backend.DeleteSecret(ctx, ref2))
+
+	_, err = backend.ReadSecret(ctx, ref1)
+	assert.ErrorAs(err, &eventline.UnknownSecretError{})
+
+	_, _, err = ParseSecretRef("eventline/identities/abc?version=foo")
+	assert.Error(err)
+}
@@ -0,0 +1,89 @@
+package vault
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/exograd/eventline/pkg/eventline"
+)
+
+// SecretBackend stores identity data as KV secrets, one secret per identity.
+// References have the form "<path>?version=<version>" so that they always
+// designate the version which was written.
+type SecretBackend struct {
+	Client *Client
+}
+
+func NewSecretBackend(client *Client) *SecretBackend {
+	return &SecretBackend{
+		Client: client,
+	}
+}
+
+func (b *SecretBackend) Name() string {
+	return "vault"
+}
+
+func (b *SecretBackend) WriteIdentitySecret(ctx context.Context, id eventline.Id, data []byte) (string, error) {
+	secretPath := path.Join(b.Client.Cfg.PathPrefix, id.String())
+
+	version, err := b.Client.WriteKV(ctx, secretPath, data)
+	if err != nil {
+		return "", err
+	}
+
+	return FormatSecretRef(secretPath, version), nil
+}
+
+func (b *SecretBackend) ReadSecret(ctx context.Context, ref string) ([]byte, error) {
+	secretPath, version, err := ParseSecretRef(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := b.Client.ReadKV(ctx, secretPath, version)
+	if err != nil {
+		return nil, err
+	} else if data == nil {
+		return nil, eventline.UnknownSecretError{Ref: ref}
+	}
+
+	return data, nil
+}
+
+func (b *SecretBackend) DeleteSecret(ctx context.Context, ref string) error {
+	secretPath, _, err := ParseSecretRef(ref)
+	if err != nil {
+		return err
+	}
+
+	return b.Client.DeleteKV(ctx, secretPath)
+}
+
+func FormatSecretRef(secretPath string, version int) string {
+	if version == 0 {
+		return secretPath
+	}
+
+	return secretPath + "?version=" + strconv.Itoa(version)
+}
+
+// ParseSecretRef returns the path and version of a secret reference. The
+// version is zero, i.e. the latest version, if the reference does not contain
+// one.
+func ParseSecretRef(ref string) (string, int, error) {
+	secretPath, versionString, found := strings.Cut(ref, "?version=")
+	if !found {
+		return ref, 0, nil
+	}
+
+	version, err := strconv.Atoi(versionString)
+	if err != nil || version < 1 {
+		return "", 0, fmt.Errorf("invalid secret reference %q", ref)
+	}
+
+	return secretPath, version, nil
+}